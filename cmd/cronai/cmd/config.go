@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rshade/cronai/internal/cron"
+	"github.com/rshade/cronai/internal/queue"
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertOutput string
+	convertForce  bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Work with configuration files",
+	Long: `Work with CronAI configuration files.
+
+CronAI reads two configuration formats. The line format (cronai.config) has one
+task per line. The structured YAML format (any file ending in .yaml or .yml)
+describes tasks, queues, bot rules, model profiles and defaults, and can carry
+values with spaces, multiple processors and per-processor options. Both formats
+load into the same tasks.`,
+	Example: `  # Convert a line configuration to YAML
+  cronai config convert cronai.config --output=cronai.yaml
+
+  # Print the JSON Schema of the YAML format
+  cronai config schema > cronai.schema.json`,
+}
+
+var configConvertCmd = &cobra.Command{
+	Use:   "convert [file]",
+	Short: "Convert a line configuration file to YAML",
+	Long: `Convert a line configuration file to the structured YAML format.
+
+Task options such as name=, after=, overlap= and catchup= become fields of
+their own, model parameters become a map, and queue, provider, calendar, freeze,
+check, budget and profile definitions are moved to their own sections. Includes and
+environment references have no YAML equivalent and are reported as errors.
+The file defaults to --config or ./cronai.config, and the YAML is written to
+standard output unless --output is given.`,
+	Example: `  # Preview the converted configuration
+  cronai config convert
+
+  # Write the converted configuration to a file
+  cronai config convert /etc/cronai/cronai.config --output=/etc/cronai/cronai.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		inputPath := cfgFile
+		if len(args) > 0 {
+			inputPath = args[0]
+		}
+		if inputPath == "" {
+			inputPath = "./cronai.config"
+		}
+
+		file, err := convertLineConfig(inputPath)
+		if err != nil {
+			fmt.Printf("Error converting configuration: %v\n", err)
+			return
+		}
+		data, err := file.Marshal()
+		if err != nil {
+			fmt.Printf("Error encoding configuration: %v\n", err)
+			return
+		}
+
+		if convertOutput == "" {
+			fmt.Print(string(data))
+			return
+		}
+		if _, err := os.Stat(convertOutput); err == nil && !convertForce {
+			fmt.Printf("Error: %s already exists, use --force to overwrite it\n", convertOutput)
+			return
+		}
+		if err := os.WriteFile(convertOutput, data, 0o644); err != nil {
+			fmt.Printf("Error writing configuration: %v\n", err)
+			return
+		}
+		fmt.Printf("Converted %d tasks and %d queues from %s to %s\n",
+			len(file.Tasks), len(file.Queues), inputPath, convertOutput)
+	},
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema of the YAML configuration format",
+	Long: `Print the JSON Schema of the structured YAML configuration format.
+
+Editors with YAML language support can use the schema for completion and
+validation, for example with a "# yaml-language-server: $schema=..." comment.`,
+	Run: func(_ *cobra.Command, _ []string) {
+		fmt.Print(string(config.Schema()))
+	},
+}
+
+// convertLineConfig reads a line configuration file into the structured format
+func convertLineConfig(path string) (file *config.File, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close config file: %w", closeErr)
+		}
+	}()
+
+	lines, err := cron.ReadConfigLines(f)
+	if err != nil {
+		return nil, err
+	}
+
+	file = &config.File{Version: config.FileVersion}
+	var convertErrors *multierror.Error
+	providers := make(map[string]bool) // named providers defined so far
+	for _, configLine := range lines {
+		line := configLine.Text
+
+		// Includes and environment references are resolved when the file is
+		// read, the structured format has neither
+		if strings.HasPrefix(strings.TrimSpace(line), "include ") {
+			convertErrors = multierror.Append(convertErrors,
+				configLine.Wrap(fmt.Errorf("include can't be converted, convert the included files and merge them")))
+			continue
+		}
+		if strings.Contains(line, "${") && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			convertErrors = multierror.Append(convertErrors,
+				configLine.Wrap(fmt.Errorf("environment references such as ${NAME} can't be converted")))
+			continue
+		}
+
+		if queue.IsQueueConfig(line) {
+			queueTask, err := queue.ParseQueueConfig(line)
+			if err != nil {
+				convertErrors = multierror.Append(convertErrors, configLine.Wrap(err))
+				continue
+			}
+			file.Queues = append(file.Queues, queueTask.QueueConfig())
+			continue
+		}
+
+		calendarConfig, err := cron.ParseCalendarLine(line)
+		if err == nil && calendarConfig != nil {
+			file.Calendars = append(file.Calendars, *calendarConfig)
+			continue
+		}
+		if err == nil {
+			var freezeConfig *config.FreezeConfig
+			if freezeConfig, err = cron.ParseFreezeLine(line); err == nil && freezeConfig != nil {
+				file.Freezes = append(file.Freezes, *freezeConfig)
+				continue
+			}
+		}
+		if err == nil {
+			var checkConfig *config.CheckConfig
+			if checkConfig, err = cron.ParseCheckLine(line); err == nil && checkConfig != nil {
+				file.Checks = append(file.Checks, *checkConfig)
+				continue
+			}
+		}
+		if err == nil {
+			var budgetConfig *config.BudgetConfig
+			if budgetConfig, err = cron.ParseBudgetLine(line); err == nil && budgetConfig != nil {
+				file.Budgets = append(file.Budgets, *budgetConfig)
+				continue
+			}
+		}
+		if err == nil {
+			var profileConfig *config.ProfileConfig
+			if profileConfig, err = cron.ParseProfileLine(line); err == nil && profileConfig != nil {
+				file.Profiles = append(file.Profiles, *profileConfig)
+				continue
+			}
+		}
+		if err == nil {
+			var providerConfig *config.ProviderConfig
+			if providerConfig, err = cron.ParseProviderLine(line); err == nil && providerConfig != nil {
+				file.Providers = append(file.Providers, *providerConfig)
+				providers[providerConfig.Name] = true
+				continue
+			}
+		}
+		if err != nil {
+			convertErrors = multierror.Append(convertErrors, configLine.Wrap(err))
+			continue
+		}
+
+		task, err := cron.ParseConfigLineWithProviders(line, providers)
+		if err == nil && task != nil {
+			var taskConfig config.TaskConfig
+			if taskConfig, err = task.TaskConfig(); err == nil {
+				file.Tasks = append(file.Tasks, taskConfig)
+			}
+		}
+		if err != nil {
+			convertErrors = multierror.Append(convertErrors, configLine.Wrap(err))
+		}
+	}
+	return file, convertErrors.ErrorOrNil()
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configConvertCmd)
+	configCmd.AddCommand(configSchemaCmd)
+
+	configConvertCmd.Flags().StringVarP(&convertOutput, "output", "o", "", "File to write the YAML configuration to (default is standard output)")
+	configConvertCmd.Flags().BoolVar(&convertForce, "force", false, "Overwrite the output file if it exists")
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rshade/cronai/pkg/config"
+)
+
+func TestConfigCommand(t *testing.T) {
+	if configCmd.Use != "config" {
+		t.Errorf("Expected config command Use to be 'config', got %s", configCmd.Use)
+	}
+
+	subcommands := map[string]bool{}
+	for _, sub := range configCmd.Commands() {
+		subcommands[sub.Name()] = true
+	}
+	for _, name := range []string{"convert", "schema"} {
+		if !subcommands[name] {
+			t.Errorf("Expected config subcommand '%s'", name)
+		}
+	}
+
+	if configConvertCmd.Flags().Lookup("output") == nil {
+		t.Error("Expected convert flag 'output' to exist")
+	}
+}
+
+func TestConvertLineConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "cronai.config")
+	content := `# Daily report
+0 8 * * * openai:temperature=0.5 product_manager slack-product name=daily_pm,team=product,overlap=skip
+@after claude summary console after=daily_pm,on=failure
+0 9 * * 1-5 openai standup slack-team skip_calendar=holidays,group=standups,tags=standups
+calendar holidays holidays.ics
+freeze year-end 2026-12-20 2027-01-03 groups=standups
+profile prod tag=standups,model=claude,processor=slack-prod
+budget provider:openai $20/day policy=downgrade,fallback=openai:gpt-4o-mini
+provider gateway openai-compatible base_url=https://gateway.internal/v1 key_env=GATEWAY_KEY
+0 7 * * * gateway:model=llama3-70b digest console
+
+queue main rabbitmq amqp://localhost:5672 tasks retry_limit=5
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	file, err := convertLineConfig(configPath)
+	if err != nil {
+		t.Fatalf("convertLineConfig failed: %v", err)
+	}
+	if len(file.Tasks) != 4 || len(file.Queues) != 1 {
+		t.Fatalf("Expected 4 tasks and 1 queue, got %d and %d", len(file.Tasks), len(file.Queues))
+	}
+	if len(file.Calendars) != 1 || file.Calendars[0].Path != "holidays.ics" {
+		t.Errorf("Unexpected calendars: %+v", file.Calendars)
+	}
+	if len(file.Freezes) != 1 || file.Freezes[0].End != "2027-01-03" || file.Freezes[0].Groups[0] != "standups" {
+		t.Errorf("Unexpected freezes: %+v", file.Freezes)
+	}
+	if standup := file.Tasks[2]; standup.SkipCalendar != "holidays" || standup.Group != "standups" {
+		t.Errorf("Unexpected calendar options: %+v", standup)
+	}
+	if tags := file.Tasks[2].Tags; len(tags) != 1 || tags[0] != "standups" {
+		t.Errorf("Unexpected tags: %v", tags)
+	}
+	if len(file.Budgets) != 1 || file.Budgets[0].Provider != "openai" || file.Budgets[0].Fallback != "openai:gpt-4o-mini" {
+		t.Errorf("Unexpected budgets: %+v", file.Budgets)
+	}
+	if len(file.Profiles) != 1 || file.Profiles[0].Tag != "standups" || file.Profiles[0].Processors[0].Name != "slack-prod" {
+		t.Errorf("Unexpected profiles: %+v", file.Profiles)
+	}
+	if len(file.Providers) != 1 || file.Providers[0].BaseURL != "https://gateway.internal/v1" || file.Providers[0].KeyEnv != "GATEWAY_KEY" {
+		t.Errorf("Unexpected providers: %+v", file.Providers)
+	}
+	if digest := file.Tasks[3]; digest.Model != "gateway" || digest.ModelParams["model"] != "llama3-70b" {
+		t.Errorf("Unexpected named provider task: %+v", digest)
+	}
+
+	pm := file.Tasks[0]
+	if pm.Name != "daily_pm" || pm.Overlap != "skip" || pm.ModelParams["temperature"] != "0.5" {
+		t.Errorf("Unexpected task: %+v", pm)
+	}
+	if _, ok := pm.Variables["overlap"]; ok {
+		t.Error("Task options should not be converted to variables")
+	}
+	if summary := file.Tasks[1]; summary.Schedule != "" || summary.After != "daily_pm" || summary.On != "failure" {
+		t.Errorf("Unexpected dependent task: %+v", summary)
+	}
+
+	// The converted configuration is valid YAML in the structured format
+	data, err := file.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if _, err := config.ParseFile(data); err != nil {
+		t.Errorf("Converted configuration does not parse: %v\n%s", err, data)
+	}
+}
+
+func TestConvertLineConfigErrors(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "cronai.config")
+	content := "0 8 * * * openai report console\n0 8 * * * llama report console\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	_, err := convertLineConfig(configPath)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error for line 2, got %v", err)
+	}
+
+	if err := os.WriteFile(configPath, []byte("include conf.d/*.config\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if _, err := convertLineConfig(configPath); err == nil || !strings.Contains(err.Error(), "include can't be converted") {
+		t.Errorf("Expected an error for an include, got %v", err)
+	}
+
+	if _, err := convertLineConfig(filepath.Join(t.TempDir(), "missing.config")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
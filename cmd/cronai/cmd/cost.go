@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	costTask   string
+	costPrompt string
+	costModel  string
+	costSince  string
+	costUntil  string
+	costBy     string
+)
+
+var costCmd = &cobra.Command{
+	Use:   "cost",
+	Short: "Report token usage and estimated cost",
+	Long: `Report the tokens used by model calls and their estimated cost, from the
+execution history.
+
+Executions are grouped by task, prompt and model by default; --by selects
+other dimensions. Costs are estimated when a response is received, from the
+price table of the models (MODEL_PRICES adds or overrides prices). Models
+without a price report tokens but no cost.`,
+	Example: `  # What did the last 30 days cost?
+  cronai cost
+
+  # Cost per model in September
+  cronai cost --by=model --since=2026-09-01 --until=2026-10-01
+
+  # Cost of a single task this week
+  cronai cost --task=weekly_report --since=7d`,
+	Run: func(_ *cobra.Command, _ []string) {
+		filter, groupBy, err := buildCostReport(time.Now())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		records, err := history.GetStore().List(filter)
+		if err != nil {
+			fmt.Printf("Error reading history: %v\n", err)
+			return
+		}
+
+		summaries := history.SummarizeCost(records, groupBy)
+		if len(summaries) == 0 {
+			fmt.Println("No model calls found")
+			return
+		}
+		if err := printCostReport(os.Stdout, summaries, groupBy); err != nil {
+			fmt.Printf("Error writing report: %v\n", err)
+		}
+	},
+}
+
+// buildCostReport builds the history filter and grouping from the command flags
+func buildCostReport(now time.Time) (history.Filter, []string, error) {
+	filter := history.Filter{Task: costTask, Prompt: costPrompt, Model: costModel}
+
+	var err error
+	if costSince != "" {
+		if filter.Since, err = parseTimeFlag(costSince, now); err != nil {
+			return filter, nil, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if costUntil != "" {
+		if filter.Until, err = parseTimeFlag(costUntil, now); err != nil {
+			return filter, nil, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	groupBy, err := history.ParseGroupBy(costBy)
+	if err != nil {
+		return filter, nil, fmt.Errorf("invalid --by: %w", err)
+	}
+	return filter, groupBy, nil
+}
+
+// printCostReport prints a table of the summaries followed by their total
+func printCostReport(out io.Writer, summaries []history.CostSummary, groupBy []string) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	header := make([]string, 0, len(groupBy)+5)
+	for _, dimension := range groupBy {
+		header = append(header, strings.ToUpper(dimension))
+	}
+	header = append(header, "RUNS", "PROMPT TOKENS", "CACHED", "COMPLETION TOKENS", "COST")
+	if _, err := fmt.Fprintln(w, strings.Join(header, "\t")); err != nil {
+		return err
+	}
+
+	var total history.CostSummary
+	for _, summary := range summaries {
+		row := make([]string, 0, len(header))
+		for _, dimension := range groupBy {
+			row = append(row, costDimension(summary, dimension))
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(append(row, costColumns(summary)...), "\t")); err != nil {
+			return err
+		}
+
+		total.Runs += summary.Runs
+		total.PromptTokens += summary.PromptTokens
+		total.CachedTokens += summary.CachedTokens
+		total.CompletionTokens += summary.CompletionTokens
+		total.Cost += summary.Cost
+	}
+
+	if len(summaries) > 1 {
+		row := make([]string, len(groupBy))
+		row[0] = "TOTAL"
+		if _, err := fmt.Fprintln(w, strings.Join(append(row, costColumns(total)...), "\t")); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// costDimension returns the value of a summary for a grouping dimension
+func costDimension(summary history.CostSummary, dimension string) string {
+	var value string
+	switch dimension {
+	case history.GroupTask:
+		value = summary.Task
+	case history.GroupPrompt:
+		value = summary.Prompt
+	case history.GroupModel:
+		value = summary.Model
+	}
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// costColumns formats the totals of a summary
+func costColumns(summary history.CostSummary) []string {
+	return []string{
+		fmt.Sprint(summary.Runs),
+		fmt.Sprint(summary.PromptTokens),
+		fmt.Sprint(summary.CachedTokens),
+		fmt.Sprint(summary.CompletionTokens),
+		fmt.Sprintf("$%.4f", summary.Cost),
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(costCmd)
+
+	costCmd.Flags().StringVar(&costTask, "task", "", "Only executions of this task")
+	costCmd.Flags().StringVar(&costPrompt, "prompt", "", "Only executions of this prompt")
+	costCmd.Flags().StringVar(&costModel, "model", "", "Only executions of this requested or answering model")
+	costCmd.Flags().StringVar(&costSince, "since", "30d", "Only executions at or after this time (YYYY-MM-DD, RFC3339, or 24h/7d ago)")
+	costCmd.Flags().StringVar(&costUntil, "until", "", "Only executions before this time (YYYY-MM-DD, RFC3339, or 24h/7d ago)")
+	costCmd.Flags().StringVar(&costBy, "by", "task,prompt,model", "Dimensions to group by (task, prompt, model)")
+}
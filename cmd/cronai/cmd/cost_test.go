@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/history"
+)
+
+func TestCostCommand(t *testing.T) {
+	found := false
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == "cost" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("Cost command not found in root command")
+	}
+
+	for _, flagName := range []string{"task", "prompt", "model", "since", "until", "by"} {
+		if costCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist on cost", flagName)
+		}
+	}
+}
+
+func TestBuildCostReport(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+	defer func() { costSince, costUntil, costBy, costTask = "30d", "", "task,prompt,model", "" }()
+
+	costSince, costUntil, costBy, costTask = "7d", "2026-10-16", "model", "weekly"
+	filter, groupBy, err := buildCostReport(now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !filter.Since.Equal(now.AddDate(0, 0, -7)) || filter.Until.IsZero() || filter.Task != "weekly" {
+		t.Errorf("Unexpected filter %+v", filter)
+	}
+	if len(groupBy) != 1 || groupBy[0] != history.GroupModel {
+		t.Errorf("Expected grouping by model, got %v", groupBy)
+	}
+
+	costBy = "team"
+	if _, _, err := buildCostReport(now); err == nil || !strings.Contains(err.Error(), "invalid --by") {
+		t.Errorf("Expected invalid --by error, got %v", err)
+	}
+}
+
+func TestPrintCostReport(t *testing.T) {
+	summaries := []history.CostSummary{
+		{Task: "weekly", Model: "gpt-4o", Runs: 2, PromptTokens: 400, CachedTokens: 200, CompletionTokens: 60, Cost: 1.5},
+		{Model: "claude-3-5-sonnet", Runs: 1, PromptTokens: 50, CompletionTokens: 10, Cost: 0.25},
+	}
+
+	var out bytes.Buffer
+	if err := printCostReport(&out, summaries, []string{history.GroupTask, history.GroupModel}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected header, 2 rows and total, got:\n%s", out.String())
+	}
+	for i, want := range [][]string{
+		{"TASK", "MODEL", "RUNS", "COST"},
+		{"weekly", "gpt-4o", "400", "$1.5000"},
+		{"-", "claude-3-5-sonnet", "$0.2500"},
+		{"TOTAL", "450", "70", "$1.7500"},
+	} {
+		for _, field := range want {
+			if !strings.Contains(lines[i], field) {
+				t.Errorf("Line %d %q doesn't contain %q", i, lines[i], field)
+			}
+		}
+	}
+}
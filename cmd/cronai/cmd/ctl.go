@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rshade/cronai/internal/cron"
+	"github.com/spf13/cobra"
+)
+
+var ctlSocket string
+
+var ctlCmd = &cobra.Command{
+	Use:   "ctl",
+	Short: "Control a running cron service",
+	Long: `Control a running cron service through its control socket.
+
+The cron service listens on .cronai/control.sock in its working directory by
+default. Set CRONAI_CONTROL_SOCKET, or pass --socket, to use another path, and
+CRONAI_CONTROL_SOCKET=none to disable the socket.
+
+Tasks are referred to by their name= field. Paused tasks skip their scheduled
+runs, catch-up runs and downstream triggers until resumed, and stay paused when
+the service restarts.`,
+	Example: `  # Show every task with its live state
+  cronai ctl list
+
+  # Stop a task from running while its upstream system is down
+  cronai ctl pause daily_pm
+  cronai ctl resume daily_pm
+
+  # Run a task right away on the service
+  cronai ctl trigger daily_pm
+
+  # Apply changes to the configuration file
+  cronai ctl reload --socket=/etc/cronai/.cronai/control.sock`,
+}
+
+var ctlListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tasks with their live state, last run and next run",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		statuses, err := cron.NewControlClient(ctlSocket).Tasks(context.Background())
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		if len(statuses) == 0 {
+			fmt.Println("No tasks scheduled")
+			return
+		}
+		if err := writeTaskStatuses(os.Stdout, statuses); err != nil {
+			fmt.Printf("Error writing to tabwriter: %v\n", err)
+		}
+	},
+}
+
+var ctlPauseCmd = &cobra.Command{
+	Use:   "pause <task>",
+	Short: "Pause a task's scheduled runs",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		runCtlAction(args[0], "Paused", (*cron.ControlClient).Pause)
+	},
+}
+
+var ctlResumeCmd = &cobra.Command{
+	Use:   "resume <task>",
+	Short: "Resume a paused task",
+	Args:  cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		runCtlAction(args[0], "Resumed", (*cron.ControlClient).Resume)
+	},
+}
+
+var ctlTriggerCmd = &cobra.Command{
+	Use:   "trigger <task>",
+	Short: "Run a task now on the running service",
+	Long: `Run a task now on the running service, even if it is paused. The run honors
+the task's overlap policy, triggers its downstream tasks and is recorded in
+history with source trigger.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		runCtlAction(args[0], "Triggered", (*cron.ControlClient).Trigger)
+	},
+}
+
+var ctlReloadCmd = &cobra.Command{
+	Use:   "reload",
+	Short: "Reload the configuration file of the running service",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		if err := cron.NewControlClient(ctlSocket).Reload(context.Background()); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		fmt.Println("Configuration reloaded")
+	},
+}
+
+// runCtlAction sends a task action to the running service and reports the outcome
+func runCtlAction(task, done string, action func(*cron.ControlClient, context.Context, string) error) {
+	if err := action(cron.NewControlClient(ctlSocket), context.Background(), task); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("%s task %s\n", done, task)
+}
+
+// writeTaskStatuses prints the live state of each task as a table
+func writeTaskStatuses(out io.Writer, statuses []cron.TaskStatus) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, "TASK\tSCHEDULE\tSTATE\tLAST RUN\tNEXT RUN"); err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			status.ID,
+			status.Schedule,
+			taskState(status),
+			formatStatusTime(status.LastRun),
+			nextRunColumn(status),
+		); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+// taskState describes whether a task is paused and how many runs are in progress
+func taskState(status cron.TaskStatus) string {
+	var states []string
+	if status.Paused {
+		states = append(states, "paused")
+	}
+	switch {
+	case status.Running == 1:
+		states = append(states, "running")
+	case status.Running > 1:
+		states = append(states, fmt.Sprintf("running (%d)", status.Running))
+	}
+	if len(states) == 0 {
+		return "idle"
+	}
+	return strings.Join(states, ", ")
+}
+
+// nextRunColumn shows the next fire time, which paused tasks will skip
+func nextRunColumn(status cron.TaskStatus) string {
+	next := formatStatusTime(status.NextRun)
+	if status.Paused && !status.NextRun.IsZero() {
+		next += " (skipped)"
+	}
+	return next
+}
+
+// formatStatusTime formats a time in the local timezone, or a dash when unset
+func formatStatusTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
+
+func init() {
+	rootCmd.AddCommand(ctlCmd)
+	ctlCmd.AddCommand(ctlListCmd, ctlPauseCmd, ctlResumeCmd, ctlTriggerCmd, ctlReloadCmd)
+
+	ctlCmd.PersistentFlags().StringVar(&ctlSocket, "socket", "", "Control socket of the service (default is $CRONAI_CONTROL_SOCKET or .cronai/control.sock)")
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/cron"
+)
+
+func TestCtlCommands(t *testing.T) {
+	expected := map[string]bool{"list": false, "pause": false, "resume": false, "trigger": false, "reload": false}
+	for _, cmd := range ctlCmd.Commands() {
+		if _, ok := expected[cmd.Name()]; ok {
+			expected[cmd.Name()] = true
+		}
+	}
+	for name, found := range expected {
+		if !found {
+			t.Errorf("ctl %s command not found", name)
+		}
+	}
+}
+
+func TestTaskState(t *testing.T) {
+	tests := []struct {
+		status   cron.TaskStatus
+		expected string
+	}{
+		{cron.TaskStatus{}, "idle"},
+		{cron.TaskStatus{Running: 1}, "running"},
+		{cron.TaskStatus{Running: 3}, "running (3)"},
+		{cron.TaskStatus{Paused: true}, "paused"},
+		{cron.TaskStatus{Paused: true, Running: 1}, "paused, running"},
+	}
+
+	for _, tt := range tests {
+		if got := taskState(tt.status); got != tt.expected {
+			t.Errorf("taskState(%+v) = %q, want %q", tt.status, got, tt.expected)
+		}
+	}
+}
+
+func TestWriteTaskStatuses(t *testing.T) {
+	next := time.Date(2026, 10, 17, 8, 0, 0, 0, time.Local)
+	statuses := []cron.TaskStatus{
+		{ID: "daily_pm", Schedule: "0 8 * * *", Paused: true, NextRun: next},
+		{ID: "digest", Schedule: "@after"},
+	}
+
+	var b strings.Builder
+	if err := writeTaskStatuses(&b, statuses); err != nil {
+		t.Fatalf("writeTaskStatuses() error = %v", err)
+	}
+	output := b.String()
+
+	for _, expected := range []string{"TASK", "NEXT RUN", "daily_pm", "paused", "2026-10-17 08:00:00 (skipped)", "digest", "idle"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+		}
+	}
+}
@@ -37,6 +37,9 @@ Available Commands:
   start       Start the CronAI service with scheduled tasks
   run         Execute a single AI task immediately
   list        Display all scheduled tasks
+  history     Show past task executions
+  cost        Report token usage and estimated cost
+  config      Convert configuration files and print the YAML schema
   prompt      Manage and explore prompt templates
   validate    Check template files for syntax errors
   help        Show this help information
@@ -47,6 +50,9 @@ Configuration:
 
   Each line follows: schedule model prompt processor [variables]
 
+  Files ending in .yaml or .yml use the structured YAML format instead,
+  see 'cronai config schema' and 'cronai config convert'.
+
 Examples:
   # Start the service
   cronai start --config=./cronai.config
@@ -109,7 +115,7 @@ Usage:
   cronai run --model=MODEL --prompt=PROMPT --processor=PROCESSOR [flags]
 
 Required Flags:
-  --model string      AI model to use (openai, claude, gemini)
+  --model string      AI model to use (openai, claude, gemini, ollama)
   --prompt string     Name of prompt file in cron_prompts directory
   --processor string  Response processor (email, slack, webhook, file)
 
@@ -119,9 +125,14 @@ Optional Flags:
   --model-params string Model parameters (temperature=0.7,max_tokens=1024)
 
 Special Variables:
-  {{CURRENT_DATE}}     Replaced with current date (YYYY-MM-DD)
-  {{CURRENT_TIME}}     Replaced with current time (HH:MM:SS)
-  {{CURRENT_DATETIME}} Replaced with date and time
+  {{CURRENT_DATE}}          Current date (YYYY-MM-DD)
+  {{CURRENT_TIME}}          Current time (HH:MM:SS)
+  {{CURRENT_DATETIME}}      Current date and time
+  {{date -7d "Jan 2"}}      Date with offsets (h, d, w, m, y) and a Go layout
+  {{week_start}}            Monday of this week, also week_end, month_start, month_end
+  {{month_name -1m}}        Name of last month, also weekday and year
+  {{env "REGION" "eu"}}     Environment variable with an optional default
+  {{hostname}}              Name of the host
 
 Examples:
   # Basic execution
@@ -136,9 +147,9 @@ Examples:
   cronai run --model=gemini --prompt=creative_writing --processor=file \
     --model-params="temperature=0.9,max_tokens=2000"
 
-  # With special variables
+  # With dynamic variables
   cronai run --model=openai --prompt=status_check --processor=slack \
-    --vars="date={{CURRENT_DATE}},system=production"
+    --vars='date={{CURRENT_DATE}},since={{date -7d}},system=production'
 `)
 
 	case "list":
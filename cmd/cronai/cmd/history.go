@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyTask   string
+	historyPrompt string
+	historySource string
+	historyStatus string
+	historyModel  string
+	historySince  string
+	historyUntil  string
+	historyLimit  int
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show past task executions",
+	Long: `Show past task executions recorded by CronAI.
+
+Every cron, queue and run execution is recorded with its task, prompt hash,
+the model that actually answered after fallback, duration, response,
+processor outcome and error category. Use the filters to find a specific
+run and 'history show' to read its full response.
+
+History is stored in .cronai/history.jsonl by default. Set CRONAI_HISTORY_PATH
+to change the location or CRONAI_HISTORY_STORE=none to disable recording.`,
+	Example: `  # Show the most recent executions
+  cronai history
+
+  # What did the product manager report say on Tuesday?
+  cronai history list --prompt=product_manager --since=2026-10-13 --until=2026-10-14
+  cronai history show 20261013T080000-1a2b3c4d
+
+  # Show failed executions from the last 24 hours
+  cronai history list --status=failed --since=24h`,
+	Run: func(_ *cobra.Command, _ []string) {
+		listHistory()
+	},
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List past task executions",
+	Long:  `List past task executions, newest first, optionally filtered by task, prompt, source, status, model and time range.`,
+	Example: `  # List the last 50 executions of a prompt
+  cronai history list --prompt=weekly_report --limit=50
+
+  # List queue executions since a date
+  cronai history list --source=queue --since=2026-10-01`,
+	Run: func(_ *cobra.Command, _ []string) {
+		listHistory()
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show <execution-id>",
+	Short: "Show a past task execution",
+	Long:  `Show the details and full response of a past task execution. A unique prefix of the execution ID is enough.`,
+	Example: `  # Show an execution
+  cronai history show 20261013T080000-1a2b3c4d`,
+	Args: cobra.ExactArgs(1),
+	Run: func(_ *cobra.Command, args []string) {
+		record, err := history.GetStore().Get(args[0])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+		printHistoryRecord(record)
+	},
+}
+
+// listHistory prints executions matching the filter flags
+func listHistory() {
+	filter, err := buildHistoryFilter(time.Now())
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	records, err := history.GetStore().List(filter)
+	if err != nil {
+		fmt.Printf("Error reading history: %v\n", err)
+		return
+	}
+
+	if len(records) == 0 {
+		fmt.Println("No executions found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if _, err := fmt.Fprintln(w, "ID\tSTARTED\tSOURCE\tPROMPT\tMODEL\tSTATUS\tDURATION"); err != nil {
+		fmt.Printf("Error writing to tabwriter: %v\n", err)
+		return
+	}
+	for _, r := range records {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.ID,
+			r.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			r.Source,
+			r.Prompt,
+			modelColumn(r),
+			r.Status,
+			r.Duration.Round(time.Millisecond),
+		); err != nil {
+			fmt.Printf("Error writing to tabwriter: %v\n", err)
+			return
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Printf("Error flushing output: %v\n", err)
+	}
+}
+
+// modelColumn shows the requested model and, if different, the model that answered
+func modelColumn(r *history.Record) string {
+	if r.ModelUsed != "" && r.ModelUsed != r.Model {
+		return fmt.Sprintf("%s->%s", r.Model, r.ModelUsed)
+	}
+	return r.Model
+}
+
+// printHistoryRecord prints all details of an execution
+func printHistoryRecord(r *history.Record) {
+	fmt.Printf("Execution:   %s\n", r.ID)
+	fmt.Printf("Started:     %s\n", r.StartedAt.Local().Format(time.RFC3339))
+	fmt.Printf("Duration:    %s\n", r.Duration.Round(time.Millisecond))
+	fmt.Printf("Source:      %s\n", r.Source)
+	if r.Task != "" {
+		fmt.Printf("Task:        %s\n", r.Task)
+	}
+	if r.Schedule != "" {
+		fmt.Printf("Schedule:    %s\n", r.Schedule)
+	}
+	fmt.Printf("Prompt:      %s\n", r.Prompt)
+	if r.PromptHash != "" {
+		fmt.Printf("Prompt hash: %s\n", r.PromptHash)
+	}
+	fmt.Printf("Model:       %s\n", modelColumn(r))
+	if r.ModelVersion != "" {
+		fmt.Printf("Version:     %s\n", r.ModelVersion)
+	}
+	fmt.Printf("Processor:   %s (%s)\n", r.Processor, r.ProcessorOutcome)
+	if r.DeliveryRetries > 0 {
+		fmt.Printf("Retries:     %d\n", r.DeliveryRetries)
+	}
+	if r.PromptTokens > 0 || r.CompletionTokens > 0 {
+		fmt.Printf("Tokens:      %d prompt (%d cached), %d completion\n", r.PromptTokens, r.CachedTokens, r.CompletionTokens)
+	}
+	if r.Cost > 0 {
+		fmt.Printf("Cost:        $%.4f\n", r.Cost)
+	}
+	fmt.Printf("Status:      %s\n", r.Status)
+	if r.Reason != "" {
+		fmt.Printf("Reason:      %s\n", r.Reason)
+	}
+	if r.Error != "" {
+		fmt.Printf("Failed at:   %s\n", r.Stage)
+		fmt.Printf("Category:    %s\n", r.ErrorCategory)
+		fmt.Printf("Error:       %s\n", r.Error)
+	}
+	if len(r.Variables) > 0 {
+		fmt.Println("Variables:")
+		for k, v := range r.Variables {
+			fmt.Printf("  %s: %s\n", k, v)
+		}
+	}
+	if r.Response != "" {
+		fmt.Println("\nResponse:")
+		fmt.Println(r.Response)
+	}
+}
+
+// buildHistoryFilter builds a history filter from the command flags
+func buildHistoryFilter(now time.Time) (history.Filter, error) {
+	filter := history.Filter{
+		Task:   historyTask,
+		Prompt: historyPrompt,
+		Source: historySource,
+		Status: history.Status(historyStatus),
+		Model:  historyModel,
+		Limit:  historyLimit,
+	}
+
+	var err error
+	if historySince != "" {
+		if filter.Since, err = parseTimeFlag(historySince, now); err != nil {
+			return filter, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if historyUntil != "" {
+		if filter.Until, err = parseTimeFlag(historyUntil, now); err != nil {
+			return filter, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+	return filter, nil
+}
+
+// parseTimeFlag parses an absolute time (date, date and time, RFC3339) or a
+// duration relative to now such as 24h or 7d.
+func parseTimeFlag(value string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+
+	if t, err := parseAbsoluteTime(value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time '%s' (use YYYY-MM-DD, RFC3339, or a duration like 24h or 7d)", value)
+}
+
+// parseAbsoluteTime parses a date, a date and time, or an RFC3339 timestamp.
+// Times without a zone are in the local timezone.
+func parseAbsoluteTime(value string) (time.Time, error) {
+	layouts := []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02T15:04", "2006-01-02"}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time '%s' (use YYYY-MM-DD or RFC3339)", value)
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+
+	for _, cmd := range []*cobra.Command{historyCmd, historyListCmd} {
+		cmd.Flags().StringVar(&historyTask, "task", "", "Filter by task")
+		cmd.Flags().StringVar(&historyPrompt, "prompt", "", "Filter by prompt name")
+		cmd.Flags().StringVar(&historySource, "source", "", "Filter by source (cron, catchup, dependency, trigger, queue, run)")
+		cmd.Flags().StringVar(&historyStatus, "status", "", "Filter by status (success, failed, skipped, interrupted)")
+		cmd.Flags().StringVar(&historyModel, "model", "", "Filter by requested or answering model")
+		cmd.Flags().StringVar(&historySince, "since", "", "Only executions at or after this time (YYYY-MM-DD, RFC3339, or 24h/7d ago)")
+		cmd.Flags().StringVar(&historyUntil, "until", "", "Only executions before this time (YYYY-MM-DD, RFC3339, or 24h/7d ago)")
+		cmd.Flags().IntVar(&historyLimit, "limit", 20, "Maximum number of executions to show (0 for all)")
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistoryCommand(t *testing.T) {
+	if historyCmd.Use != "history" {
+		t.Errorf("Expected history command Use to be 'history', got %s", historyCmd.Use)
+	}
+
+	found := false
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == "history" {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Error("History command not found in root command")
+	}
+
+	subcommands := []string{"list", "show"}
+	for _, subCmd := range subcommands {
+		found := false
+		for _, cmd := range historyCmd.Commands() {
+			if cmd.Name() == subCmd {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("Subcommand '%s' not found in history command", subCmd)
+		}
+	}
+
+	for _, flagName := range []string{"task", "prompt", "source", "status", "model", "since", "until", "limit"} {
+		if historyListCmd.Flags().Lookup(flagName) == nil {
+			t.Errorf("Expected flag '%s' to exist on history list", flagName)
+		}
+	}
+}
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"24h", now.Add(-24 * time.Hour), false},
+		{"7d", now.AddDate(0, 0, -7), false},
+		{"2026-10-13", time.Date(2026, 10, 13, 0, 0, 0, 0, time.Local), false},
+		{"2026-10-13 08:30", time.Date(2026, 10, 13, 8, 30, 0, 0, time.Local), false},
+		{"2026-10-13T08:00:00Z", time.Date(2026, 10, 13, 8, 0, 0, 0, time.UTC), false},
+		{"last tuesday", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseTimeFlag(tt.value, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q", tt.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseTimeFlag(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -3,23 +3,42 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/rshade/cronai/internal/cron"
 	"github.com/spf13/cobra"
 )
 
+var (
+	listNext    int
+	listBetween string
+)
+
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all scheduled AI tasks",
 	Long: `List all scheduled AI tasks from the configuration file.
 
 Displays each task with its schedule, AI model, prompt name, and processor.
-This helps you understand what the CronAI agent is configured to do.`,
+This helps you understand what the CronAI agent is configured to do.
+
+Use --next or --between to preview when each task will fire. Times are shown
+in the schedule's timezone, changes of UTC offset (such as daylight saving
+time) are marked, fires skipped by a calendar or freeze window show why, and
+tasks that never fire or that fire in the same minute as another task are
+flagged.`,
 	Example: `  # List tasks from default config
   cronai list
 
   # List tasks from custom config  
-  cronai list --config=/etc/cronai/production.config`,
+  cronai list --config=/etc/cronai/production.config
+
+  # Show the next 5 fire times of every task
+  cronai list --next 5
+
+  # Show every fire time around a daylight saving time change
+  cronai list --between 2026-10-31,2026-11-03`,
 	Run: func(_ *cobra.Command, _ []string) {
 		// Get config file path
 		configPath := cfgFile
@@ -42,13 +61,159 @@ This helps you understand what the CronAI agent is configured to do.`,
 			return
 		}
 
+		if listNext > 0 || listBetween != "" {
+			previews, err := previewSchedule(tasks, time.Now())
+			if err != nil {
+				fmt.Printf("Error previewing schedule: %v\n", err)
+				return
+			}
+			fmt.Print(formatSchedulePreview(previews))
+			return
+		}
+
 		fmt.Println("Scheduled tasks:")
 		for i, task := range tasks {
-			fmt.Printf("%d. %s %s %s %s\n", i+1, task.Schedule, task.Model, task.Prompt, task.Processor)
+			fmt.Printf("%d. %s%s%s %s %s %s\n", i+1, taskLabel(task), taskSchedule(task), taskZone(task), task.Model, task.Prompt, taskProcessors(task))
 		}
 	},
 }
 
+// previewSchedule computes the fire times requested by --next or --between
+func previewSchedule(tasks []cron.Task, now time.Time) ([]cron.Preview, error) {
+	if listBetween == "" {
+		return cron.NextFires(tasks, now, listNext)
+	}
+
+	bounds := strings.Split(listBetween, ",")
+	if len(bounds) != 2 {
+		return nil, fmt.Errorf("invalid --between '%s' (use start,end)", listBetween)
+	}
+	start, err := parseAbsoluteTime(strings.TrimSpace(bounds[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --between start: %w", err)
+	}
+	end, err := parseAbsoluteTime(strings.TrimSpace(bounds[1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --between end: %w", err)
+	}
+	return cron.FiresBetween(tasks, start, end)
+}
+
+// formatSchedulePreview lists each task's fire times followed by warnings
+// about tasks that never fire and tasks that fire in the same minute
+func formatSchedulePreview(previews []cron.Preview) string {
+	const layout = "Mon 2006-01-02 15:04:05 MST"
+
+	var b strings.Builder
+	var warnings []string
+	fmt.Fprintln(&b, "Upcoming fire times:")
+	for i, preview := range previews {
+		task := preview.Task
+		fmt.Fprintf(&b, "%d. %s%s%s %s %s %s\n", i+1, taskLabel(task), taskSchedule(task), taskZone(task), task.Model, task.Prompt, taskProcessors(task))
+
+		switch {
+		case task.Schedule == cron.ScheduleAfter:
+			fmt.Fprintf(&b, "   runs when %s finishes\n", task.After)
+		case preview.Never:
+			fmt.Fprintln(&b, "   never fires")
+			warnings = append(warnings, fmt.Sprintf("task %s never fires: '%s' matches no date", task.ID(), task.Schedule))
+		case len(preview.Fires) == 0:
+			fmt.Fprintln(&b, "   no fire times in range")
+		}
+
+		for j, fire := range preview.Fires {
+			line := "   " + fire.Format(layout)
+			if j > 0 {
+				_, prevOffset := preview.Fires[j-1].Zone()
+				if _, offset := fire.Zone(); offset != prevOffset {
+					line += fmt.Sprintf("  (UTC offset changes to %s)", fire.Format("-07:00"))
+				}
+			}
+			if reason := preview.SkipReason(j); reason != "" {
+				line += fmt.Sprintf("  skipped: %s", reason)
+			}
+			fmt.Fprintln(&b, line)
+		}
+	}
+
+	for _, collision := range cron.Collisions(previews) {
+		warnings = append(warnings, fmt.Sprintf("%s: %s fire in the same minute",
+			collision.Time.Format("2006-01-02 15:04 MST"), strings.Join(collision.Tasks, ", ")))
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintln(&b, "\nWarnings:")
+		for _, warning := range warnings {
+			fmt.Fprintf(&b, "  ! %s\n", warning)
+		}
+	}
+	return b.String()
+}
+
+// taskLabel returns the task's name as a prefix, if it has one
+func taskLabel(task cron.Task) string {
+	if task.Name == "" {
+		return ""
+	}
+	return fmt.Sprintf("[%s] ", task.Name)
+}
+
+// taskSchedule describes when a task runs, including its upstream trigger.
+// The timezone is shown separately by taskZone.
+func taskSchedule(task cron.Task) string {
+	_, schedule := cron.SplitTimezone(task.Schedule)
+	if task.After == "" {
+		return schedule
+	}
+
+	on := task.On
+	if on == "" {
+		on = cron.TriggerOnSuccess
+	}
+	trigger := fmt.Sprintf("after %s (on %s)", task.After, on)
+	if task.Schedule == cron.ScheduleAfter {
+		return trigger
+	}
+	return fmt.Sprintf("%s, %s", schedule, trigger)
+}
+
+// taskZone returns the timezone a task's schedule is evaluated in, for display
+// after the schedule. Tasks that only run after another task have none.
+func taskZone(task cron.Task) string {
+	if task.Schedule == cron.ScheduleAfter {
+		return ""
+	}
+	if timezone := task.Timezone(); timezone != "" {
+		return fmt.Sprintf(" (%s)", timezone)
+	}
+	return fmt.Sprintf(" (local, %s)", localZoneName())
+}
+
+// localZoneName names the local timezone, falling back to its abbreviation
+// when the zone was read from /etc/localtime
+func localZoneName() string {
+	if name := time.Local.String(); name != "Local" {
+		return name
+	}
+	name, _ := time.Now().Zone()
+	return name
+}
+
+// taskProcessors lists the processors a task delivers its response to
+func taskProcessors(task cron.Task) string {
+	if len(task.Processors) == 0 {
+		return task.Processor
+	}
+	names := make([]string, len(task.Processors))
+	for i, procConfig := range task.Processors {
+		names[i] = procConfig.Name
+	}
+	return strings.Join(names, ",")
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().IntVar(&listNext, "next", 0, "Show the next N fire times of each task")
+	listCmd.Flags().StringVar(&listBetween, "between", "", "Show the fire times of each task in a range (start,end as YYYY-MM-DD or RFC3339)")
+	listCmd.MarkFlagsMutuallyExclusive("next", "between")
 }
@@ -3,6 +3,10 @@ package cmd
 import (
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/cron"
+	"github.com/rshade/cronai/pkg/config"
 )
 
 func TestListCommand(t *testing.T) {
@@ -59,3 +63,123 @@ func TestListCommandExamples(t *testing.T) {
 		}
 	}
 }
+
+func TestTaskSchedule(t *testing.T) {
+	tests := []struct {
+		task     cron.Task
+		expected string
+	}{
+		{cron.Task{Schedule: "0 8 * * *"}, "0 8 * * *"},
+		{cron.Task{Schedule: cron.ScheduleAfter, After: "collect"}, "after collect (on success)"},
+		{cron.Task{Schedule: "0 9 * * *", After: "collect", On: cron.TriggerOnFailure}, "0 9 * * *, after collect (on failure)"},
+		{cron.Task{Schedule: "CRON_TZ=Europe/Berlin 0 8 * * *"}, "0 8 * * *"},
+	}
+
+	for _, tt := range tests {
+		if got := taskSchedule(tt.task); got != tt.expected {
+			t.Errorf("taskSchedule() = %q, want %q", got, tt.expected)
+		}
+	}
+
+	if got := taskLabel(cron.Task{Name: "collect"}); got != "[collect] " {
+		t.Errorf("taskLabel() = %q, want %q", got, "[collect] ")
+	}
+}
+
+func TestTaskZone(t *testing.T) {
+	if got := taskZone(cron.Task{Schedule: "CRON_TZ=Europe/Berlin 0 8 * * *"}); got != " (Europe/Berlin)" {
+		t.Errorf("taskZone() = %q, want %q", got, " (Europe/Berlin)")
+	}
+	if got := taskZone(cron.Task{Schedule: cron.ScheduleAfter, After: "collect"}); got != "" {
+		t.Errorf("taskZone() = %q, want no zone for dependent tasks", got)
+	}
+	if got := taskZone(cron.Task{Schedule: "@daily"}); !strings.HasPrefix(got, " (local, ") {
+		t.Errorf("taskZone() = %q, want the local zone", got)
+	}
+}
+
+func TestTaskProcessors(t *testing.T) {
+	if got := taskProcessors(cron.Task{Processor: "console"}); got != "console" {
+		t.Errorf("taskProcessors() = %q, want %q", got, "console")
+	}
+
+	task := cron.Task{
+		Processor:  "slack-ops",
+		Processors: []config.ProcessorConfig{{Name: "slack-ops"}, {Name: "file-report.log", Template: "daily"}},
+	}
+	if got := taskProcessors(task); got != "slack-ops,file-report.log" {
+		t.Errorf("taskProcessors() = %q, want %q", got, "slack-ops,file-report.log")
+	}
+}
+
+func TestPreviewSchedule(t *testing.T) {
+	defer func() {
+		listNext = 0
+		listBetween = ""
+	}()
+	tasks := []cron.Task{{Name: "daily", Schedule: "0 8 * * *"}}
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+
+	listNext = 2
+	previews, err := previewSchedule(tasks, now)
+	if err != nil {
+		t.Fatalf("previewSchedule failed: %v", err)
+	}
+	if len(previews[0].Fires) != 2 || previews[0].Fires[0].Day() != 17 {
+		t.Errorf("Unexpected fire times: %v", previews[0].Fires)
+	}
+
+	listNext = 0
+	listBetween = "2026-10-20, 2026-10-23"
+	previews, err = previewSchedule(tasks, now)
+	if err != nil {
+		t.Fatalf("previewSchedule failed: %v", err)
+	}
+	if len(previews[0].Fires) != 3 {
+		t.Errorf("Expected 3 fire times, got %v", previews[0].Fires)
+	}
+
+	for _, between := range []string{"2026-10-20", "2026-10-20,soon", "2026-10-23,2026-10-20"} {
+		listBetween = between
+		if _, err := previewSchedule(tasks, now); err == nil {
+			t.Errorf("Expected an error for --between %q", between)
+		}
+	}
+}
+
+func TestFormatSchedulePreview(t *testing.T) {
+	at := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+	output := formatSchedulePreview([]cron.Preview{
+		{Task: cron.Task{Name: "a", Schedule: "0 8 * * *"}, Fires: []time.Time{at}},
+		{Task: cron.Task{Name: "b", Schedule: "0 8 * * *"}, Fires: []time.Time{at}},
+		{Task: cron.Task{Name: "c", Schedule: cron.ScheduleAfter, After: "a"}},
+		{Task: cron.Task{Name: "feb30", Schedule: "0 0 30 2 *"}, Never: true},
+	})
+
+	for _, expected := range []string{
+		"Sat 2026-10-17 08:00:00 UTC",
+		"runs when a finishes",
+		"task feb30 never fires",
+		"2026-10-17 08:00 UTC: a, b fire in the same minute",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected preview to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	// Changes of UTC offset are marked
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	output = formatSchedulePreview([]cron.Preview{{
+		Task: cron.Task{Name: "dst", Schedule: "0 8 * * *"},
+		Fires: []time.Time{
+			time.Date(2026, 10, 31, 8, 0, 0, 0, newYork),
+			time.Date(2026, 11, 1, 8, 0, 0, 0, newYork),
+		},
+	}})
+	if !strings.Contains(output, "(UTC offset changes to -05:00)") {
+		t.Errorf("Expected the offset change to be marked, got:\n%s", output)
+	}
+}
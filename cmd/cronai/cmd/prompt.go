@@ -6,8 +6,10 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/internal/variable"
 	"github.com/spf13/cobra"
 )
 
@@ -218,10 +220,16 @@ var promptPreviewCmd = &cobra.Command{
 			}
 		}
 
-		// Add special variables
-		variables["CURRENT_DATE"] = "2025-05-12"              // This would be time.Now().Format("2006-01-02") in a real implementation
-		variables["CURRENT_TIME"] = "12:00:00"                // This would be time.Now().Format("15:04:05") in a real implementation
-		variables["CURRENT_DATETIME"] = "2025-05-12 12:00:00" // This would be time.Now().Format("2006-01-02 15:04:05") in a real implementation
+		// Evaluate dynamic variables such as {{date -7d}} and add the special variables
+		now := time.Now()
+		variables, err = variable.New(now).ExpandAll(variables)
+		if err != nil {
+			fmt.Printf("Error evaluating variables: %v\n", err)
+			return
+		}
+		variables["CURRENT_DATE"] = now.Format(variable.DateLayout)
+		variables["CURRENT_TIME"] = now.Format(variable.TimeLayout)
+		variables["CURRENT_DATETIME"] = now.Format(variable.DatetimeLayout)
 
 		// Load prompt with variables
 		content, err := prompt.LoadPromptWithVariables(promptName, variables)
@@ -5,11 +5,14 @@ import (
 	"os"
 
 	"github.com/joho/godotenv"
+	"github.com/rshade/cronai/internal/cron"
 	"github.com/spf13/cobra"
 )
 
 var cfgFile string
 
+var profile string
+
 var rootCmd = &cobra.Command{
 	Use:   "cronai",
 	Short: "AI agent for scheduled prompt execution",
@@ -79,6 +82,7 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./cronai.config)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "profile whose overrides apply to the configuration (default is $"+cron.EnvProfile+")")
 	rootCmd.Flags().BoolP("version", "v", false, "Print the version of CronAI and exit")
 }
 
@@ -89,6 +93,14 @@ func initConfig() {
 		fmt.Println("Warning: No .env file found or error loading it")
 	}
 
+	// The profile flag takes precedence over the environment, and the
+	// environment also reaches configuration reloads
+	if profile != "" {
+		if err := os.Setenv(cron.EnvProfile, profile); err != nil {
+			fmt.Printf("Warning: failed to select profile %s: %v\n", profile, err)
+		}
+	}
+
 	// Config file will be used in the individual commands
 	// The actual config handling is implemented in pkg/config
 }
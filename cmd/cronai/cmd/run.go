@@ -1,17 +1,27 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/rshade/cronai/internal/budget"
+	"github.com/rshade/cronai/internal/cron"
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/history"
 	"github.com/rshade/cronai/internal/models"
 	"github.com/rshade/cronai/internal/processor"
 	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/internal/variable"
 	"github.com/spf13/cobra"
 )
 
 var (
+	taskName      string
 	modelName     string
 	promptName    string
 	processorName string
@@ -28,10 +38,14 @@ var runCmd = &cobra.Command{
 This command allows you to run any prompt with your chosen AI model and processor
 without scheduling. Perfect for testing new prompts or running ad-hoc tasks.
 
+Use --task to run a task defined in the configuration file by its name= field,
+with its variables, model parameters and template, exactly as the scheduler
+would run it.
+
 Features:
   • Variable substitution in prompts
   • Conditional logic with Go templates
-  • Special variables like {{CURRENT_DATE}}
+  • Dynamic variables like {{CURRENT_DATE}} or {{date -7d}}
   • Model parameter customization
   • Response templating
 
@@ -39,7 +53,10 @@ Prompt Template Syntax:
   {{.Variables.name}}              - Variable substitution
   {{if eq .condition "value"}}...{{end}} - Conditional logic
   {{include "header.md"}}          - Include other files`,
-	Example: `  # Basic execution
+	Example: `  # Run a configured task by name
+  cronai run --task=daily_pm
+
+  # Basic execution
   cronai run --model=openai --prompt=daily_summary --processor=file
 
   # With variables
@@ -50,10 +67,19 @@ Prompt Template Syntax:
   cronai run --model=gemini --prompt=creative --processor=file \
     --model-params="temperature=0.9,max_tokens=2000"
 
-  # With special variables and template
+  # With dynamic variables and template
   cronai run --model=openai --prompt=status --processor=slack \
-    --vars="date={{CURRENT_DATE}}" --template=alert`,
+    --vars='date={{CURRENT_DATE}},since={{date -7d}}' --template=alert`,
 	Run: func(_ *cobra.Command, _ []string) {
+		// Interrupting the command cancels the model call in flight
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if taskName != "" {
+			runConfiguredTask(ctx, taskName)
+			return
+		}
+
 		// Parse variables if provided
 		variables := make(map[string]string)
 		if varsString != "" {
@@ -62,22 +88,18 @@ Prompt Template Syntax:
 				if len(keyValue) == 2 {
 					key := strings.TrimSpace(keyValue[0])
 					value := strings.TrimSpace(keyValue[1])
-
-					// Handle special variables
-					switch value {
-					case "{{CURRENT_DATE}}":
-						value = time.Now().Format("2006-01-02")
-					case "{{CURRENT_TIME}}":
-						value = time.Now().Format("15:04:05")
-					case "{{CURRENT_DATETIME}}":
-						value = time.Now().Format("2006-01-02 15:04:05")
-					}
-
 					variables[key] = value
 				}
 			}
 		}
 
+		// Evaluate dynamic variables such as {{date -7d}}
+		variables, err := variable.New(time.Now()).ExpandAll(variables)
+		if err != nil {
+			fmt.Printf("Error evaluating variables: %v\n", err)
+			return
+		}
+
 		fmt.Printf("Running task with model: %s, prompt: %s, processor: %s\n", modelName, promptName, processorName)
 		if len(variables) > 0 {
 			fmt.Println("Variables:")
@@ -90,9 +112,21 @@ Prompt Template Syntax:
 			fmt.Printf("Model parameters: %s\n", modelParams)
 		}
 
+		// Record the execution in the history store
+		record := history.New(history.SourceRun)
+		record.Prompt = promptName
+		record.Model = modelName
+		record.Processor = processorName
+		record.Variables = variables
+		defer func() {
+			record.Finish()
+			if err := history.Save(record); err != nil {
+				fmt.Printf("Warning: failed to record execution history: %v\n", err)
+			}
+		}()
+
 		// Load the prompt with variables if provided
 		var promptContent string
-		var err error
 
 		if len(variables) > 0 {
 			// Load the prompt with variables, which will use template processing if needed
@@ -105,6 +139,7 @@ Prompt Template Syntax:
 		}
 
 		if err != nil {
+			record.Fail(history.StagePrompt, errors.Wrap(errors.CategoryConfiguration, err, "error loading prompt"))
 			// Provide more specific error messages for template-related errors
 			if strings.Contains(err.Error(), "template") {
 				fmt.Printf("Error processing prompt template: %v\n", err)
@@ -114,17 +149,46 @@ Prompt Template Syntax:
 			}
 			return
 		}
+		record.SetPrompt(promptContent)
+
+		// Check the model call against the budgets of the configuration file
+		configPath := cfgFile
+		if configPath == "" {
+			configPath = "./cronai.config"
+		}
+		guard, err := cron.LoadBudgetGuard(configPath)
+		if err != nil {
+			record.Fail(history.StageModel, err)
+			fmt.Printf("Error loading budgets: %v\n", err)
+			return
+		}
+		if guard != nil {
+			ctx = budget.WithGuard(ctx, guard, budget.Request{Category: prompt.Category(promptName)})
+		}
 
 		// Execute the model with model parameters
-		response, err := models.ExecuteModel(modelName, promptContent, variables, modelParams)
+		response, err := models.ExecuteModel(ctx, modelName, promptContent, variables, modelParams)
+		var exceeded *budget.ExceededError
+		if errors.As(err, &exceeded) {
+			record.Skip(err.Error())
+			fmt.Printf("Skipped: %v\n", err)
+			return
+		}
 		if err != nil {
+			record.Fail(history.StageModel, errors.Wrap(errors.CategoryExternal, err, "error executing model"))
 			fmt.Printf("Error executing model: %v\n", err)
 			return
 		}
+		record.ModelUsed = response.Provider
+		record.ModelVersion = response.Model
+		record.SetUsage(response.Usage.PromptTokens, response.Usage.CompletionTokens, response.Usage.CachedTokens, response.Cost)
+		record.Response = response.Content
+		response.ExecutionID = record.ID
 
 		// Process the response
-		err = processor.ProcessResponse(processorName, response, templateName)
+		err = processor.ProcessResponse(ctx, processorName, response, templateName)
 		if err != nil {
+			record.Fail(history.StageProcessor, err)
 			fmt.Printf("Error processing response: %v\n", err)
 			return
 		}
@@ -133,20 +197,38 @@ Prompt Template Syntax:
 	},
 }
 
+// runConfiguredTask runs a named task from the configuration file through the scheduler's code path
+func runConfiguredTask(ctx context.Context, name string) {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "./cronai.config"
+	}
+
+	fmt.Printf("Running task '%s' from config: %s\n", name, configPath)
+	if err := cron.NewCronService(configPath).RunNamedTask(ctx, name); err != nil {
+		fmt.Printf("Error running task: %v\n", err)
+		return
+	}
+	fmt.Println("Task completed successfully")
+}
+
 func init() {
 	rootCmd.AddCommand(runCmd)
 
-	runCmd.Flags().StringVar(&modelName, "model", "", "AI model to use (openai, claude, gemini)")
+	runCmd.Flags().StringVar(&taskName, "task", "", "Name of a task in the configuration file to run")
+	runCmd.Flags().StringVar(&modelName, "model", "", "AI model to use (openai, claude, gemini, ollama)")
 	runCmd.Flags().StringVar(&promptName, "prompt", "", "Name of prompt file in cron_prompts directory")
 	runCmd.Flags().StringVar(&processorName, "processor", "", "Response processor to use")
 	runCmd.Flags().StringVar(&templateName, "template", "", "Optional template name to use for formatting the response")
 	runCmd.Flags().StringVar(&varsString, "vars", "", "Variables in format key1=value1,key2=value2")
 	runCmd.Flags().StringVar(&modelParams, "model-params", "", "Model parameters in format temperature=0.7,max_tokens=1024")
 
-	// Fail fast if we can't mark flags as required - this indicates a serious configuration issue
-	markFlagRequiredOrFail(runCmd, "model")
-	markFlagRequiredOrFail(runCmd, "prompt")
-	markFlagRequiredOrFail(runCmd, "processor")
+	// Either run a configured task, or describe the task with model, prompt and processor
+	runCmd.MarkFlagsOneRequired("task", "model")
+	runCmd.MarkFlagsRequiredTogether("model", "prompt", "processor")
+	for _, flagName := range []string{"model", "prompt", "processor", "template", "vars", "model-params"} {
+		runCmd.MarkFlagsMutuallyExclusive("task", flagName)
+	}
 }
 
 // markFlagRequiredOrFail marks a flag as required and fails early if there's an issue
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func TestRunCommand(t *testing.T) {
@@ -18,16 +19,11 @@ func TestRunCommand(t *testing.T) {
 		t.Errorf("Unexpected short description: %s", runCmd.Short)
 	}
 
-	// Verify required flags
-	requiredFlags := []string{"model", "prompt", "processor"}
-	for _, flagName := range requiredFlags {
-		flag := runCmd.Flags().Lookup(flagName)
-		if flag == nil {
+	// Verify flags describing the task; they are required unless --task is used
+	taskFlags := []string{"task", "model", "prompt", "processor"}
+	for _, flagName := range taskFlags {
+		if runCmd.Flags().Lookup(flagName) == nil {
 			t.Errorf("Expected flag '%s' to exist", flagName)
-			continue
-		}
-		if !isRequired(runCmd, flagName) {
-			t.Errorf("Expected flag '%s' to be required", flagName)
 		}
 	}
 
@@ -45,6 +41,56 @@ func TestRunCommand(t *testing.T) {
 	}
 }
 
+func TestRunCommandFlagGroups(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr string
+	}{
+		{"configured task", []string{"--task", "daily_pm"}, ""},
+		{"explicit task", []string{"--model", "openai", "--prompt", "report", "--processor", "console"}, ""},
+		{"no task", []string{}, "at least one of the flags"},
+		{"missing processor", []string{"--model", "openai", "--prompt", "report"}, "must all be set"},
+		{"task with model", []string{"--task", "daily_pm", "--model", "openai", "--prompt", "report", "--processor", "console"}, "none of the others can be"},
+		{"task with vars", []string{"--task", "daily_pm", "--vars", "a=b"}, "none of the others can be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Validate against a copy of the run command's flags so nothing is executed
+			testCmd := &cobra.Command{
+				Use:           "test",
+				Run:           func(_ *cobra.Command, _ []string) {},
+				SilenceUsage:  true,
+				SilenceErrors: true,
+			}
+			flags := runCmd.Flags()
+			flags.VisitAll(func(f *pflag.Flag) {
+				f.Changed = false
+			})
+			testCmd.Flags().AddFlagSet(flags)
+			defer flags.VisitAll(func(f *pflag.Flag) {
+				f.Changed = false
+				if err := f.Value.Set(f.DefValue); err != nil {
+					t.Errorf("Failed to reset flag %s: %v", f.Name, err)
+				}
+			})
+
+			testCmd.SetArgs(tt.args)
+			err := testCmd.Execute()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
 func TestMarkFlagRequiredOrFail(t *testing.T) {
 	// Test successful case
 	cmd := &cobra.Command{}
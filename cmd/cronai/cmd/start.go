@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/rshade/cronai/internal/bot"
+	"github.com/rshade/cronai/internal/budget"
 	"github.com/rshade/cronai/internal/cron"
 	"github.com/rshade/cronai/internal/queue"
 	"github.com/rshade/cronai/internal/queue/consumers"
@@ -66,6 +67,10 @@ Examples:
 				fmt.Printf("Error starting cron service: %v\n", err)
 			}
 		case "bot":
+			if err := useBudgets(configPath); err != nil {
+				fmt.Printf("Error loading budgets: %v\n", err)
+				return
+			}
 			if err := bot.StartService(configPath); err != nil {
 				fmt.Printf("Error starting bot service: %v\n", err)
 			}
@@ -75,6 +80,10 @@ Examples:
 				fmt.Printf("Error registering consumers: %v\n", err)
 				return
 			}
+			if err := useBudgets(configPath); err != nil {
+				fmt.Printf("Error loading budgets: %v\n", err)
+				return
+			}
 			if err := queue.StartService(configPath); err != nil {
 				fmt.Printf("Error starting queue service: %v\n", err)
 			}
@@ -84,6 +93,18 @@ Examples:
 	},
 }
 
+// useBudgets checks the model calls of the bot and queue modes against the
+// budgets of the configuration file, counting their spend in the ledger the
+// cron service uses
+func useBudgets(configPath string) error {
+	guard, err := cron.LoadBudgetGuard(configPath)
+	if err != nil {
+		return err
+	}
+	budget.SetDefault(guard)
+	return nil
+}
+
 // validateMode validates the operation mode flag
 func validateMode(mode string) error {
 	switch mode {
@@ -2,11 +2,14 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 
+	"github.com/rshade/cronai/internal/budget"
 	"github.com/rshade/cronai/internal/logger"
 	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/pool"
 	"github.com/rshade/cronai/internal/processor"
 )
 
@@ -41,12 +44,33 @@ Please provide:
 }
 
 // processWithAI sends the prompt to the AI model and processes the response
-func (h *baseHandler) processWithAI(prompt string, metadata map[string]string) error {
-	// Execute the model
-	response, err := h.model.Execute(prompt)
+func (h *baseHandler) processWithAI(ctx context.Context, prompt string, metadata map[string]string) error {
+	// Check the model call against the budgets of the configuration. The
+	// client can't switch to a fallback model, so downgrading budgets refuse it.
+	provider := models.ProviderOf(h.model)
+	if guard := budget.Default(); guard != nil {
+		ctx = budget.WithGuard(ctx, guard, budget.Request{})
+	}
+	if err := models.BudgetAllows(ctx, provider); err != nil {
+		return fmt.Errorf("model call refused: %w", err)
+	}
+
+	// Share the execution pool with the cron and queue modes while the model runs
+	release, err := pool.Default().Acquire(ctx, provider)
+	if err != nil {
+		return fmt.Errorf("no execution slot available: %w", err)
+	}
+
+	// Execute the model and count its cost against the budgets
+	response, err := h.model.Execute(ctx, prompt)
+	release()
 	if err != nil {
 		return fmt.Errorf("model execution failed: %w", err)
 	}
+	if response.Provider == "" {
+		response.Provider = provider
+	}
+	models.RecordSpend(ctx, response)
 
 	// Add metadata to response
 	if response.Variables == nil {
@@ -60,7 +84,7 @@ func (h *baseHandler) processWithAI(prompt string, metadata map[string]string) e
 	if h.processor != nil {
 		// Use a default template name based on event type
 		templateName := metadata["event_type"] + "_template"
-		if err := h.processor.Process(response, templateName); err != nil {
+		if err := h.processor.Process(ctx, response, templateName); err != nil {
 			return fmt.Errorf("processor failed: %w", err)
 		}
 	}
@@ -85,7 +109,7 @@ func NewIssuesHandler(model models.ModelClient, processor processor.Processor) *
 }
 
 // Handle processes an issues event
-func (h *IssuesHandler) Handle(event Event) error {
+func (h *IssuesHandler) Handle(ctx context.Context, event Event) error {
 	h.logger.Info("Handling issues event", logger.Fields{"action": event.Action})
 
 	// Parse the payload
@@ -123,7 +147,7 @@ func (h *IssuesHandler) Handle(event Event) error {
 		"repository":   fmt.Sprintf("%s/%s", data.Repository.Owner.Login, data.Repository.Name),
 	}
 
-	return h.processWithAI(prompt, metadata)
+	return h.processWithAI(ctx, prompt, metadata)
 }
 
 // PullRequestHandler handles GitHub pull request events
@@ -143,7 +167,7 @@ func NewPullRequestHandler(model models.ModelClient, processor processor.Process
 }
 
 // Handle processes a pull request event
-func (h *PullRequestHandler) Handle(event Event) error {
+func (h *PullRequestHandler) Handle(ctx context.Context, event Event) error {
 	h.logger.Info("Handling pull request event", logger.Fields{"action": event.Action})
 
 	// Parse the payload
@@ -187,7 +211,7 @@ func (h *PullRequestHandler) Handle(event Event) error {
 		"repository": fmt.Sprintf("%s/%s", data.Repository.Owner.Login, data.Repository.Name),
 	}
 
-	return h.processWithAI(prompt, metadata)
+	return h.processWithAI(ctx, prompt, metadata)
 }
 
 // PushHandler handles GitHub push events
@@ -207,7 +231,7 @@ func NewPushHandler(model models.ModelClient, processor processor.Processor) *Pu
 }
 
 // Handle processes a push event
-func (h *PushHandler) Handle(event Event) error {
+func (h *PushHandler) Handle(ctx context.Context, event Event) error {
 	h.logger.Info("Handling push event", logger.Fields{})
 
 	// Parse the payload
@@ -246,7 +270,7 @@ func (h *PushHandler) Handle(event Event) error {
 		"repository":   fmt.Sprintf("%s/%s", data.Repository.Owner.Login, data.Repository.Name),
 	}
 
-	return h.processWithAI(prompt, metadata)
+	return h.processWithAI(ctx, prompt, metadata)
 }
 
 // ReleaseHandler handles GitHub release events
@@ -266,7 +290,7 @@ func NewReleaseHandler(model models.ModelClient, processor processor.Processor)
 }
 
 // Handle processes a release event
-func (h *ReleaseHandler) Handle(event Event) error {
+func (h *ReleaseHandler) Handle(ctx context.Context, event Event) error {
 	h.logger.Info("Handling release event", logger.Fields{"action": event.Action})
 
 	// Parse the payload
@@ -305,5 +329,5 @@ func (h *ReleaseHandler) Handle(event Event) error {
 		"repository": fmt.Sprintf("%s/%s", data.Repository.Owner.Login, data.Repository.Name),
 	}
 
-	return h.processWithAI(prompt, metadata)
+	return h.processWithAI(ctx, prompt, metadata)
 }
@@ -2,10 +2,14 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/rshade/cronai/internal/budget"
 	"github.com/rshade/cronai/internal/models"
 	"github.com/rshade/cronai/internal/processor"
 )
@@ -18,7 +22,7 @@ type mockProcessor struct {
 	err          error
 }
 
-func (m *mockProcessor) Process(response *models.ModelResponse, templateName string) error {
+func (m *mockProcessor) Process(_ context.Context, response *models.ModelResponse, templateName string) error {
 	m.processed = true
 	m.lastResponse = response
 	m.lastTemplate = templateName
@@ -43,7 +47,7 @@ type mockModel struct {
 	err      error
 }
 
-func (m *mockModel) Execute(_ string) (*models.ModelResponse, error) {
+func (m *mockModel) Execute(_ context.Context, _ string) (*models.ModelResponse, error) {
 	if m.err != nil {
 		return nil, m.err
 	}
@@ -130,7 +134,7 @@ func TestIssuesHandler(t *testing.T) {
 				Payload: json.RawMessage(tt.payload),
 			}
 
-			err := handler.Handle(event)
+			err := handler.Handle(context.Background(), event)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
@@ -220,7 +224,7 @@ func TestPullRequestHandler(t *testing.T) {
 				Payload: json.RawMessage(tt.payload),
 			}
 
-			err := handler.Handle(event)
+			err := handler.Handle(context.Background(), event)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
@@ -269,7 +273,7 @@ func TestPushHandler(t *testing.T) {
 		Payload: json.RawMessage(payload),
 	}
 
-	err := handler.Handle(event)
+	err := handler.Handle(context.Background(), event)
 	if err != nil {
 		t.Fatalf("Handle() error = %v", err)
 	}
@@ -351,7 +355,7 @@ func TestReleaseHandler(t *testing.T) {
 				Payload: json.RawMessage(tt.payload),
 			}
 
-			err := handler.Handle(event)
+			err := handler.Handle(context.Background(), event)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Handle() error = %v, wantErr %v", err, tt.wantErr)
@@ -440,7 +444,7 @@ func TestHandlerProcessorError(t *testing.T) {
 				Payload: json.RawMessage(tt.payload),
 			}
 
-			err := handler.Handle(event)
+			err := handler.Handle(context.Background(), event)
 			if err == nil {
 				t.Error("Handle() expected error, got nil")
 			}
@@ -451,3 +455,55 @@ func TestHandlerProcessorError(t *testing.T) {
 		})
 	}
 }
+
+// pricedModel is a model client of a provider whose responses cost money
+type pricedModel struct {
+	calls int
+}
+
+func (m *pricedModel) Execute(_ context.Context, _ string) (*models.ModelResponse, error) {
+	m.calls++
+	return &models.ModelResponse{Content: "AI response", Model: "gpt-4o", Cost: 3}, nil
+}
+
+func (m *pricedModel) ProviderName() string {
+	return "openai"
+}
+
+func TestHandlerBudget(t *testing.T) {
+	ledger := budget.NewLedger("")
+	budget.SetDefault(&budget.Guard{
+		Budgets: []*budget.Budget{{Scope: budget.ScopeProvider, Name: "openai", Limit: 5, Period: budget.PeriodDay, Policy: budget.PolicyDowngrade, Fallback: "ollama"}},
+		Ledger:  ledger,
+	})
+	defer budget.SetDefault(nil)
+
+	model := &pricedModel{}
+	processor := &mockProcessor{}
+	handler := NewReleaseHandler(model, processor)
+	event := Event{
+		Type:    "release",
+		Action:  "created",
+		Payload: json.RawMessage(`{"action": "created", "release": {"tag_name": "v1.0.0"}, "repository": {"name": "test", "owner": {"login": "owner"}}}`),
+	}
+
+	// Events are counted against the provider's budget until it is exhausted
+	for i := 0; i < 2; i++ {
+		if err := handler.Handle(context.Background(), event); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+	if spent := ledger.Spent("provider:openai", budget.PeriodDay, time.Now()); spent != 6 {
+		t.Errorf("spent = %v, want 6", spent)
+	}
+
+	// The client can't be downgraded, so the exhausted budget refuses the event
+	err := handler.Handle(context.Background(), event)
+	var exceeded *budget.ExceededError
+	if !errors.As(err, &exceeded) {
+		t.Fatalf("Handle() error = %v, want a budget refusal", err)
+	}
+	if model.calls != 2 {
+		t.Errorf("model calls = %d, want 2", model.calls)
+	}
+}
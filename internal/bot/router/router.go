@@ -2,6 +2,7 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
@@ -11,7 +12,7 @@ import (
 
 // EventHandler processes a specific type of GitHub event
 type EventHandler interface {
-	Handle(event Event) error
+	Handle(ctx context.Context, event Event) error
 }
 
 // Event represents a parsed GitHub webhook event
@@ -58,8 +59,9 @@ func (r *Router) AddFilter(filter EventFilter) {
 	r.filters = append(r.filters, filter)
 }
 
-// Route processes an incoming webhook event
-func (r *Router) Route(eventType string, payload []byte) error {
+// Route processes an incoming webhook event. Cancelling ctx stops the
+// handler's model call and processor.
+func (r *Router) Route(ctx context.Context, eventType string, payload []byte) error {
 	event := Event{
 		Type:    eventType,
 		Payload: json.RawMessage(payload),
@@ -97,7 +99,7 @@ func (r *Router) Route(eventType string, payload []byte) error {
 
 	r.logger.Info("Routing event to handler", logger.Fields{"eventType": eventType, "action": event.Action})
 
-	if err := handler.Handle(event); err != nil {
+	if err := handler.Handle(ctx, event); err != nil {
 		return fmt.Errorf("handler error for %s event: %w", eventType, err)
 	}
 
@@ -2,6 +2,7 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -13,7 +14,7 @@ type mockHandler struct {
 	err     error
 }
 
-func (m *mockHandler) Handle(event Event) error {
+func (m *mockHandler) Handle(_ context.Context, event Event) error {
 	m.handled = append(m.handled, event)
 	return m.err
 }
@@ -150,7 +151,7 @@ func TestRoute(t *testing.T) {
 			}
 
 			// Route event
-			err := r.Route(tt.eventType, []byte(tt.payload))
+			err := r.Route(context.Background(), tt.eventType, []byte(tt.payload))
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Route() error = %v, wantErr %v", err, tt.wantErr)
@@ -244,7 +245,7 @@ func TestAddFilter(t *testing.T) {
 	r.RegisterHandler("test", handler)
 
 	// Route event
-	_ = r.Route("test", []byte(`{}`)) //nolint:errcheck // Test doesn't need error handling
+	_ = r.Route(context.Background(), "test", []byte(`{}`)) //nolint:errcheck // Test doesn't need error handling
 
 	// Check filters were called
 	if !filter1Called {
@@ -279,7 +280,7 @@ func TestConcurrentAccess(_ *testing.T) {
 	go func() {
 		for i := 0; i < 100; i++ {
 			r.GetRegisteredTypes()
-			_ = r.Route("event0", []byte(`{}`)) //nolint:errcheck // Test doesn't need error handling
+			_ = r.Route(context.Background(), "event0", []byte(`{}`)) //nolint:errcheck // Test doesn't need error handling
 		}
 		done <- true
 	}()
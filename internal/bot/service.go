@@ -2,6 +2,7 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"github.com/rshade/cronai/internal/logger"
 	"github.com/rshade/cronai/internal/models"
 	"github.com/rshade/cronai/internal/processor"
+	"github.com/rshade/cronai/pkg/config"
 )
 
 // MockModelClient is a basic model client for testing/development
@@ -20,13 +22,18 @@ type MockModelClient struct {
 }
 
 // Execute implements the ModelClient interface
-func (m *MockModelClient) Execute(promptContent string) (*models.ModelResponse, error) {
+func (m *MockModelClient) Execute(_ context.Context, promptContent string) (*models.ModelResponse, error) {
 	return &models.ModelResponse{
 		Content: fmt.Sprintf("Mock response from %s model for prompt: %s", m.modelName, promptContent[:minInt(50, len(promptContent))]),
 		Model:   m.modelName,
 	}, nil
 }
 
+// ProviderName returns the provider the mock stands in for
+func (m *MockModelClient) ProviderName() string {
+	return m.modelName
+}
+
 // minInt returns the minimum of two integers
 func minInt(a, b int) int {
 	if a < b {
@@ -50,6 +57,30 @@ type Config struct {
 	Secret    string
 	Model     string
 	Processor string
+	Rules     []Rule // Events to handle; when empty, every supported event is handled
+}
+
+// Rule routes a GitHub event type to a model. An empty model falls back to
+// the service's. Responses go to the service's processor.
+type Rule struct {
+	Event string
+	Model string
+}
+
+// eventHandlers creates the handler for each supported GitHub event type
+var eventHandlers = map[string]func(models.ModelClient, processor.Processor) router.EventHandler{
+	"issues": func(m models.ModelClient, p processor.Processor) router.EventHandler {
+		return router.NewIssuesHandler(m, p)
+	},
+	"pull_request": func(m models.ModelClient, p processor.Processor) router.EventHandler {
+		return router.NewPullRequestHandler(m, p)
+	},
+	"push": func(m models.ModelClient, p processor.Processor) router.EventHandler {
+		return router.NewPushHandler(m, p)
+	},
+	"release": func(m models.ModelClient, p processor.Processor) router.EventHandler {
+		return router.NewReleaseHandler(m, p)
+	},
 }
 
 // NewService creates a new bot service
@@ -85,11 +116,23 @@ func NewService(cfg Config) (*Service, error) {
 		r.AddFilter(filter)
 	}
 
-	// Register handlers for common events
-	r.RegisterHandler("issues", router.NewIssuesHandler(modelClient, proc))
-	r.RegisterHandler("pull_request", router.NewPullRequestHandler(modelClient, proc))
-	r.RegisterHandler("push", router.NewPushHandler(modelClient, proc))
-	r.RegisterHandler("release", router.NewReleaseHandler(modelClient, proc))
+	// Register handlers for common events, or only the events that have rules
+	if len(cfg.Rules) == 0 {
+		for eventType, newHandler := range eventHandlers {
+			r.RegisterHandler(eventType, newHandler(modelClient, proc))
+		}
+	}
+	for _, rule := range cfg.Rules {
+		newHandler, ok := eventHandlers[rule.Event]
+		if !ok {
+			return nil, fmt.Errorf("unsupported event type '%s' in bot rule", rule.Event)
+		}
+		ruleModel := modelClient
+		if rule.Model != "" && rule.Model != modelName {
+			ruleModel = &MockModelClient{modelName: rule.Model}
+		}
+		r.RegisterHandler(rule.Event, newHandler(ruleModel, proc))
+	}
 
 	// Create rate limiter (100 requests per minute)
 	rateLimiter := NewRateLimiter(100, time.Minute)
@@ -129,10 +172,23 @@ func (s *Service) Stop() error {
 	return s.server.Stop()
 }
 
-// StartService starts the bot service with the given configuration
-func StartService(_ string) error {
+// StartService starts the bot service with the given configuration. A structured
+// YAML configuration file can provide the bot settings and rules; environment
+// variables take precedence over the file.
+func StartService(configPath string) error {
+	fileConfig := &config.BotConfig{}
+	if config.IsYAMLFile(configPath) {
+		file, err := config.LoadFile(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load configuration: %w", err)
+		}
+		if file.Bot != nil {
+			fileConfig = file.Bot
+		}
+	}
+
 	// Get and validate configuration from environment variables
-	port := os.Getenv("CRONAI_BOT_PORT")
+	port := envOr("CRONAI_BOT_PORT", fileConfig.Port)
 	if port == "" {
 		port = "8080"
 	}
@@ -147,7 +203,7 @@ func StartService(_ string) error {
 	}
 
 	// Validate model if specified
-	model := os.Getenv("CRONAI_DEFAULT_MODEL")
+	model := envOr("CRONAI_DEFAULT_MODEL", fileConfig.Model)
 	if model == "" {
 		model = "openai"
 	}
@@ -156,17 +212,23 @@ func StartService(_ string) error {
 	}
 
 	// Validate processor if specified
-	processor := os.Getenv("CRONAI_BOT_PROCESSOR")
+	processor := envOr("CRONAI_BOT_PROCESSOR", fileConfig.Processor)
 	if err := ValidateProcessor(processor); err != nil {
 		return fmt.Errorf("invalid processor configuration: %w", err)
 	}
 
+	rules, err := rulesFromConfig(fileConfig.Rules)
+	if err != nil {
+		return fmt.Errorf("invalid bot rules: %w", err)
+	}
+
 	// Create and start service
 	service, err := NewService(Config{
 		Port:      port,
 		Secret:    secret,
 		Model:     model,
 		Processor: processor,
+		Rules:     rules,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create bot service: %w", err)
@@ -174,3 +236,32 @@ func StartService(_ string) error {
 
 	return service.Start()
 }
+
+// rulesFromConfig validates the bot rules of a structured configuration file
+func rulesFromConfig(ruleConfigs []config.BotRule) ([]Rule, error) {
+	rules := make([]Rule, 0, len(ruleConfigs))
+	for _, ruleConfig := range ruleConfigs {
+		if _, ok := eventHandlers[ruleConfig.Event]; !ok {
+			return nil, &ValidationError{
+				Field:   "event",
+				Value:   ruleConfig.Event,
+				Message: "unsupported event type, must be one of: issues, pull_request, push, release",
+			}
+		}
+		if ruleConfig.Model != "" {
+			if err := ValidateModel(ruleConfig.Model); err != nil {
+				return nil, err
+			}
+		}
+		rules = append(rules, Rule(ruleConfig))
+	}
+	return rules, nil
+}
+
+// envOr returns the value of the environment variable, or fallback when it is unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -4,6 +4,8 @@ package bot
 import (
 	"net"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
 )
@@ -154,6 +156,47 @@ func TestStartService(t *testing.T) {
 	}
 }
 
+func TestNewServiceWithRules(t *testing.T) {
+	service, err := NewService(Config{
+		Port:  "0",
+		Model: "openai",
+		Rules: []Rule{
+			{Event: "issues", Model: "claude"},
+			{Event: "release"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewService() error = %v", err)
+	}
+
+	// Only the events with rules are handled
+	types := service.router.GetRegisteredTypes()
+	sort.Strings(types)
+	if strings.Join(types, ",") != "issues,release" {
+		t.Errorf("Expected handlers for issues and release, got %v", types)
+	}
+
+	if _, err := NewService(Config{Rules: []Rule{{Event: "deployment"}}}); err == nil {
+		t.Error("NewService() expected an error for an unsupported event")
+	}
+}
+
+func TestStartServiceInvalidRules(t *testing.T) {
+	t.Setenv("CRONAI_BOT_PORT", "0")
+	t.Setenv("GITHUB_WEBHOOK_SECRET", "valid-secret-123")
+
+	configPath := filepath.Join(t.TempDir(), "cronai.yaml")
+	content := "bot:\n  rules:\n    - event: issues\n      model: llama\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	err := StartService(configPath)
+	if err == nil || !strings.Contains(err.Error(), "invalid bot rules") {
+		t.Errorf("StartService() error = %v, want invalid bot rules", err)
+	}
+}
+
 func TestServiceStartStop(t *testing.T) {
 	service, err := NewService(Config{
 		Port:      "0", // Use port 0 for testing
@@ -13,6 +13,7 @@ var SupportedModels = map[string]bool{
 	"openai": true,
 	"claude": true,
 	"gemini": true,
+	"ollama": true,
 }
 
 // SupportedProcessors defines the allowed processor types
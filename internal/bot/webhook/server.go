@@ -27,6 +27,11 @@ type Server struct {
 	httpServer  *http.Server
 	logger      *logger.Logger
 	rateLimiter RateLimiter
+
+	// ctx is handed to routed events and cancelled when the server shuts
+	// down, so in-flight model calls stop with it
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 // RateLimiter interface for rate limiting
@@ -36,7 +41,7 @@ type RateLimiter interface {
 
 // Router defines the interface for event routing
 type Router interface {
-	Route(eventType string, payload []byte) error
+	Route(ctx context.Context, eventType string, payload []byte) error
 }
 
 // Config holds the server configuration
@@ -53,12 +58,15 @@ func New(cfg Config) *Server {
 		cfg.Port = "8080"
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Server{
 		port:        cfg.Port,
 		secret:      cfg.Secret,
 		router:      cfg.Router,
 		logger:      logger.GetLogger(),
 		rateLimiter: cfg.RateLimiter,
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 }
 
@@ -111,6 +119,8 @@ func (s *Server) Start() error {
 func (s *Server) gracefulShutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	// Events still being processed when the timeout expires are cancelled
+	defer s.cancel()
 
 	if err := s.httpServer.Shutdown(ctx); err != nil {
 		return fmt.Errorf("server shutdown error: %w", err)
@@ -202,9 +212,10 @@ func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	s.logger.Info("Received event", logger.Fields{"eventType": eventType})
 
-	// Route the event
+	// Route the event. The request context is not used: GitHub closing the
+	// connection should not abort the event, only shutting down the server.
 	if s.router != nil {
-		if err := s.router.Route(eventType, body); err != nil {
+		if err := s.router.Route(s.ctx, eventType, body); err != nil {
 			s.logger.Error("Failed to route event", logger.Fields{"error": err})
 			http.Error(w, "Failed to process event", http.StatusInternalServerError)
 			return
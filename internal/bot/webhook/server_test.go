@@ -3,6 +3,7 @@ package webhook
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -22,7 +23,7 @@ type mockRouter struct {
 	err           error
 }
 
-func (m *mockRouter) Route(eventType string, payload []byte) error {
+func (m *mockRouter) Route(_ context.Context, eventType string, payload []byte) error {
 	m.lastEventType = eventType
 	m.lastPayload = payload
 	return m.err
@@ -0,0 +1,301 @@
+// Package budget limits the estimated spend on model calls. Budgets cap the
+// spend of a task, a prompt category or a provider over a day or a month, and
+// their policy decides whether a call that would exceed one is refused,
+// downgraded to a cheaper model or only warned about.
+package budget
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope is what a budget limits the spend of
+type Scope string
+
+// Budget scopes
+const (
+	ScopeTask     Scope = "task"     // every model call of a task
+	ScopeCategory Scope = "category" // the calls of the prompts of a category
+	ScopeProvider Scope = "provider" // every call to a provider
+)
+
+// Period is the time over which a budget's spend adds up
+type Period string
+
+// Budget periods, in local time
+const (
+	PeriodDay   Period = "day"
+	PeriodMonth Period = "month"
+)
+
+// Policy decides what happens to a model call once a budget is exhausted
+type Policy string
+
+// Budget policies, from the least to the most strict
+const (
+	PolicyWarn      Policy = "warn"      // call the model anyway and log a warning
+	PolicyDowngrade Policy = "downgrade" // call the budget's cheaper fallback model instead
+	PolicyRefuse    Policy = "refuse"    // don't call the model
+)
+
+// Budget limits the spend on the model calls of a scope
+type Budget struct {
+	Scope    Scope
+	Name     string  // task, category or provider limited
+	Limit    float64 // USD per period
+	Period   Period
+	Policy   Policy
+	Fallback string // downgrade: model called instead, as provider or provider:model
+	Notify   string // processor told when the budget is exhausted
+}
+
+// Key identifies the spend the budget limits, such as provider:openai
+func (b *Budget) Key() string {
+	return string(b.Scope) + ":" + b.Name
+}
+
+// String describes the budget, such as provider:openai $20.00/day
+func (b *Budget) String() string {
+	return fmt.Sprintf("%s $%.2f/%s", b.Key(), b.Limit, b.Period)
+}
+
+// FallbackModel returns the provider and, when one is named, the model a
+// downgraded call uses
+func (b *Budget) FallbackModel() (provider, model string) {
+	provider, model, _ = strings.Cut(b.Fallback, ":")
+	return provider, model
+}
+
+// applies reports whether the budget limits a model call
+func (b *Budget) applies(request Request) bool {
+	switch b.Scope {
+	case ScopeTask:
+		return b.Name == request.Task
+	case ScopeCategory:
+		return b.Name == request.Category
+	default:
+		return b.Name == request.Provider
+	}
+}
+
+// strictness orders policies so that the strictest exhausted budget decides
+func (p Policy) strictness() int {
+	switch p {
+	case PolicyWarn:
+		return 1
+	case PolicyDowngrade:
+		return 2
+	case PolicyRefuse:
+		return 3
+	default:
+		return 0
+	}
+}
+
+// ParseScope parses the scope and name of a budget, such as provider:openai
+func ParseScope(value string) (Scope, string, error) {
+	scope, name, ok := strings.Cut(value, ":")
+	if !ok || name == "" {
+		return "", "", fmt.Errorf("invalid budget scope '%s' (use task:<name>, category:<name> or provider:<name>)", value)
+	}
+	switch Scope(scope) {
+	case ScopeTask, ScopeCategory, ScopeProvider:
+		return Scope(scope), name, nil
+	default:
+		return "", "", fmt.Errorf("unknown budget scope '%s' (supported: task, category, provider)", scope)
+	}
+}
+
+// ParseLimit parses a spend limit such as $20/day or 500/month
+func ParseLimit(value string) (float64, Period, error) {
+	amount, period, ok := strings.Cut(strings.TrimSpace(value), "/")
+	limit, err := strconv.ParseFloat(strings.TrimPrefix(strings.TrimSpace(amount), "$"), 64)
+	if !ok || err != nil || limit <= 0 {
+		return 0, "", fmt.Errorf("invalid budget limit '%s' (use an amount in USD per day or month, such as $20/day)", value)
+	}
+	switch Period(strings.TrimSpace(period)) {
+	case PeriodDay:
+		return limit, PeriodDay, nil
+	case PeriodMonth:
+		return limit, PeriodMonth, nil
+	default:
+		return 0, "", fmt.Errorf("invalid budget period '%s' (supported: day, month)", period)
+	}
+}
+
+// ParsePolicy parses a budget policy, refuse when empty
+func ParsePolicy(value string) (Policy, error) {
+	switch Policy(value) {
+	case "":
+		return PolicyRefuse, nil
+	case PolicyRefuse, PolicyDowngrade, PolicyWarn:
+		return Policy(value), nil
+	default:
+		return "", fmt.Errorf("invalid budget policy '%s' (supported: refuse, downgrade, warn)", value)
+	}
+}
+
+// Request is a model call the budgets are checked for
+type Request struct {
+	Task     string
+	Category string
+	Provider string
+}
+
+// keys returns the spend keys a call adds to
+func (r Request) keys() []string {
+	var keys []string
+	if r.Task != "" {
+		keys = append(keys, string(ScopeTask)+":"+r.Task)
+	}
+	if r.Category != "" {
+		keys = append(keys, string(ScopeCategory)+":"+r.Category)
+	}
+	if r.Provider != "" {
+		keys = append(keys, string(ScopeProvider)+":"+r.Provider)
+	}
+	return keys
+}
+
+// Decision is the outcome of checking a model call against the budgets
+type Decision struct {
+	Policy Policy  // policy of the strictest exhausted budget, empty within every budget
+	Budget *Budget // the strictest exhausted budget
+	Spent  float64 // spend of that budget in its current period
+}
+
+// Err describes the exhausted budget as an ExceededError
+func (d Decision) Err() error {
+	if d.Budget == nil {
+		return nil
+	}
+	return &ExceededError{Budget: d.Budget, Spent: d.Spent}
+}
+
+// ExceededError is returned for model calls an exhausted budget refuses
+type ExceededError struct {
+	Budget *Budget
+	Spent  float64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("budget %s exhausted: spent $%.2f this %s", e.Budget, e.Spent, e.Budget.Period)
+}
+
+// Exhaustion reports that a call found a budget exhausted
+type Exhaustion struct {
+	Budget  *Budget
+	Spent   float64
+	Request Request
+}
+
+// Guard checks model calls against budgets and records their spend
+type Guard struct {
+	Budgets []*Budget
+	Ledger  *Ledger
+
+	// Notify is told once per period when a budget is exhausted
+	Notify func(ctx context.Context, exhaustion Exhaustion)
+}
+
+// now returns the time spend is recorded at, replaced by tests
+var now = time.Now
+
+// Check returns the decision of the strictest budget of the call that is
+// exhausted. Budgets found exhausted are reported to Notify.
+func (g *Guard) Check(ctx context.Context, request Request) Decision {
+	at := now()
+	var decision Decision
+	for _, b := range g.Budgets {
+		if !b.applies(request) {
+			continue
+		}
+		spent := g.Ledger.Spent(b.Key(), b.Period, at)
+		if spent < b.Limit {
+			continue
+		}
+		g.notify(ctx, b, spent, request, at)
+		if b.Policy.strictness() > decision.Policy.strictness() {
+			decision = Decision{Policy: b.Policy, Budget: b, Spent: spent}
+		}
+	}
+	return decision
+}
+
+// Record adds the cost of a call to the spend of its task, category and
+// provider, and reports the budgets it exhausts to Notify
+func (g *Guard) Record(ctx context.Context, request Request, cost float64) error {
+	if cost <= 0 {
+		return nil
+	}
+	at := now()
+	if err := g.Ledger.Add(request.keys(), cost, at); err != nil {
+		return err
+	}
+	for _, b := range g.Budgets {
+		if !b.applies(request) {
+			continue
+		}
+		if spent := g.Ledger.Spent(b.Key(), b.Period, at); spent >= b.Limit {
+			g.notify(ctx, b, spent, request, at)
+		}
+	}
+	return nil
+}
+
+// notify reports an exhausted budget unless it was already reported this period
+func (g *Guard) notify(ctx context.Context, b *Budget, spent float64, request Request, at time.Time) {
+	if g.Notify == nil || !g.Ledger.markNotified(b, at) {
+		return
+	}
+	g.Notify(ctx, Exhaustion{Budget: b, Spent: spent, Request: request})
+}
+
+// guardKey is the context key of the guard of an execution
+type guardKey struct{}
+
+// guarded is the guard of an execution and the call it makes
+type guarded struct {
+	guard   *Guard
+	request Request
+}
+
+// WithGuard returns a context whose model calls are checked against the
+// guard's budgets. The request names the task and category of the calls;
+// the provider is filled in for each call.
+func WithGuard(ctx context.Context, guard *Guard, request Request) context.Context {
+	return context.WithValue(ctx, guardKey{}, guarded{guard: guard, request: request})
+}
+
+// FromContext returns the guard of an execution and the call it makes
+func FromContext(ctx context.Context) (*Guard, Request, bool) {
+	g, ok := ctx.Value(guardKey{}).(guarded)
+	if !ok || g.guard == nil {
+		return nil, Request{}, false
+	}
+	return g.guard, g.request, true
+}
+
+var (
+	defaultGuard *Guard
+	defaultMu    sync.RWMutex
+)
+
+// Default returns the guard of the model calls the queue and bot modes make,
+// nil when the configuration has no budgets
+func Default() *Guard {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultGuard
+}
+
+// SetDefault replaces the guard returned by Default
+func SetDefault(g *Guard) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultGuard = g
+}
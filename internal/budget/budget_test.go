@@ -0,0 +1,178 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setNow fixes the time spend is recorded at
+func setNow(t *testing.T, at time.Time) {
+	t.Helper()
+	oldNow := now
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = oldNow })
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value  string
+		limit  float64
+		period Period
+		err    string
+	}{
+		{value: "$20/day", limit: 20, period: PeriodDay},
+		{value: "500/month", limit: 500, period: PeriodMonth},
+		{value: "$2.50/day", limit: 2.5, period: PeriodDay},
+		{value: "$20", err: "invalid budget limit"},
+		{value: "$0/day", err: "invalid budget limit"},
+		{value: "twenty/day", err: "invalid budget limit"},
+		{value: "$20/week", err: "invalid budget period 'week'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			limit, period, err := ParseLimit(tt.value)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.limit, limit)
+			assert.Equal(t, tt.period, period)
+		})
+	}
+}
+
+func TestParseScopeAndPolicy(t *testing.T) {
+	scope, name, err := ParseScope("provider:openai")
+	require.NoError(t, err)
+	assert.Equal(t, ScopeProvider, scope)
+	assert.Equal(t, "openai", name)
+
+	_, _, err = ParseScope("team:ops")
+	assert.ErrorContains(t, err, "unknown budget scope 'team'")
+	_, _, err = ParseScope("openai")
+	assert.ErrorContains(t, err, "invalid budget scope")
+
+	policy, err := ParsePolicy("")
+	require.NoError(t, err)
+	assert.Equal(t, PolicyRefuse, policy)
+	_, err = ParsePolicy("block")
+	assert.ErrorContains(t, err, "invalid budget policy 'block'")
+}
+
+func TestGuard(t *testing.T) {
+	setNow(t, time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local))
+	openai := &Budget{Scope: ScopeProvider, Name: "openai", Limit: 20, Period: PeriodDay, Policy: PolicyDowngrade, Fallback: "openai:gpt-4o-mini"}
+	reports := &Budget{Scope: ScopeCategory, Name: "reports", Limit: 100, Period: PeriodMonth, Policy: PolicyWarn}
+	weekly := &Budget{Scope: ScopeTask, Name: "weekly", Limit: 5, Period: PeriodDay, Policy: PolicyRefuse}
+
+	var notified []string
+	guard := &Guard{
+		Budgets: []*Budget{openai, reports, weekly},
+		Ledger:  NewLedger(""),
+		Notify: func(_ context.Context, exhaustion Exhaustion) {
+			notified = append(notified, exhaustion.Budget.String())
+		},
+	}
+	ctx := context.Background()
+	request := Request{Task: "daily", Category: "reports", Provider: "openai"}
+
+	// Within every budget
+	assert.Equal(t, Decision{}, guard.Check(ctx, request))
+
+	// Spending the provider's budget downgrades its calls and reports it once
+	require.NoError(t, guard.Record(ctx, request, 20))
+	assert.Equal(t, []string{"provider:openai $20.00/day"}, notified)
+	decision := guard.Check(ctx, request)
+	assert.Equal(t, PolicyDowngrade, decision.Policy)
+	assert.Equal(t, openai, decision.Budget)
+	assert.Len(t, notified, 1)
+
+	// Other providers aren't limited by it
+	assert.Equal(t, Decision{}, guard.Check(ctx, Request{Task: "daily", Category: "reports", Provider: "claude"}))
+
+	// The strictest exhausted budget decides
+	weeklyRequest := Request{Task: "weekly", Category: "reports", Provider: "openai"}
+	decision = guard.Check(ctx, weeklyRequest)
+	assert.Equal(t, PolicyDowngrade, decision.Policy)
+	require.NoError(t, guard.Record(ctx, Request{Task: "weekly", Provider: "claude"}, 5))
+	decision = guard.Check(ctx, weeklyRequest)
+	assert.Equal(t, PolicyRefuse, decision.Policy)
+
+	var exceeded *ExceededError
+	require.True(t, errors.As(decision.Err(), &exceeded))
+	assert.Equal(t, "budget task:weekly $5.00/day exhausted: spent $5.00 this day", exceeded.Error())
+
+	// Spend counts again the next day, a monthly budget keeps adding up
+	setNow(t, time.Date(2026, 10, 17, 9, 0, 0, 0, time.Local))
+	assert.Equal(t, Decision{}, guard.Check(ctx, weeklyRequest))
+	assert.InDelta(t, 20, guard.Ledger.Spent("category:reports", PeriodMonth, now()), 1e-9)
+	require.NoError(t, guard.Record(ctx, request, 80))
+	assert.Equal(t, PolicyDowngrade, guard.Check(ctx, request).Policy)
+	assert.Equal(t, []string{
+		"provider:openai $20.00/day",
+		"task:weekly $5.00/day",
+		"provider:openai $20.00/day",
+		"category:reports $100.00/month",
+	}, notified)
+}
+
+func TestLedgerPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "budget", "ledger.json")
+	day := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+	ledger, err := LoadLedger(path)
+	require.NoError(t, err)
+	require.NoError(t, ledger.Add([]string{"provider:openai", "task:weekly"}, 1.5, day))
+	require.NoError(t, ledger.Add([]string{"provider:openai"}, 2, day))
+	assert.True(t, ledger.markNotified(&Budget{Scope: ScopeProvider, Name: "openai", Period: PeriodDay}, day))
+
+	// Spend and reports survive a restart
+	ledger, err = LoadLedger(path)
+	require.NoError(t, err)
+	assert.InDelta(t, 3.5, ledger.Spent("provider:openai", PeriodDay, day), 1e-9)
+	assert.InDelta(t, 1.5, ledger.Spent("task:weekly", PeriodMonth, day), 1e-9)
+	assert.False(t, ledger.markNotified(&Budget{Scope: ScopeProvider, Name: "openai", Period: PeriodDay}, day))
+
+	// Earlier periods are dropped
+	nextMonth := time.Date(2026, 11, 1, 8, 0, 0, 0, time.Local)
+	require.NoError(t, ledger.Add([]string{"provider:openai"}, 1, nextMonth))
+	assert.Equal(t, map[string]float64{"2026-11-01": 1, "2026-11": 1}, ledger.Spend["provider:openai"])
+	assert.Zero(t, ledger.Spent("provider:openai", PeriodMonth, day))
+}
+
+func TestLedgerSharedBetweenProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+	day := time.Date(2026, 10, 16, 12, 0, 0, 0, time.Local)
+
+	cron, err := LoadLedger(path)
+	require.NoError(t, err)
+	queue, err := LoadLedger(path)
+	require.NoError(t, err)
+
+	// Each ledger sees the spend the other wrote and adds to it
+	require.NoError(t, cron.Add([]string{"provider:openai"}, 2, day))
+	assert.InDelta(t, 2, queue.Spent("provider:openai", PeriodDay, day), 1e-9)
+	require.NoError(t, queue.Add([]string{"provider:openai"}, 1, day))
+	assert.InDelta(t, 3, cron.Spent("provider:openai", PeriodDay, day), 1e-9)
+
+	// A budget reported by one isn't reported again by the other
+	b := &Budget{Scope: ScopeProvider, Name: "openai", Period: PeriodDay}
+	assert.True(t, cron.markNotified(b, day))
+	assert.False(t, queue.markNotified(b, day))
+}
+
+func TestDefaultGuard(t *testing.T) {
+	defer SetDefault(nil)
+	assert.Nil(t, Default())
+
+	guard := &Guard{Ledger: NewLedger("")}
+	SetDefault(guard)
+	assert.Same(t, guard, Default())
+}
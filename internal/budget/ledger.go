@@ -0,0 +1,188 @@
+package budget
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// EnvLedgerPath overrides where the spend counted against budgets is persisted
+const EnvLedgerPath = "CRONAI_BUDGET_PATH"
+
+// DefaultLedgerPath is the default location of the persisted spend
+const DefaultLedgerPath = ".cronai/budget.json"
+
+// LedgerPathFromEnv returns the ledger location configured in the environment
+func LedgerPathFromEnv() string {
+	if path := os.Getenv(EnvLedgerPath); path != "" {
+		return path
+	}
+	return DefaultLedgerPath
+}
+
+// Ledger keeps the spend of each task, category and provider in the current
+// day and month, and which budgets were reported exhausted, in a JSON file.
+// The cron, queue and bot modes share the file: a ledger re-reads it when
+// another process has written it since.
+type Ledger struct {
+	path string
+	mu   sync.Mutex
+	file os.FileInfo // the file when last read or written
+
+	// Spend key, such as provider:openai -> period, such as 2026-10-16 or 2026-10 -> USD
+	Spend map[string]map[string]float64 `json:"spend"`
+	// Budget key and period, such as provider:openai/day -> period it was reported exhausted in
+	Notified map[string]string `json:"notified,omitempty"`
+}
+
+// NewLedger returns an empty ledger persisted at path, or kept in memory when
+// path is empty
+func NewLedger(path string) *Ledger {
+	return &Ledger{path: path, Spend: make(map[string]map[string]float64), Notified: make(map[string]string)}
+}
+
+// LoadLedger reads the ledger at path. A missing file yields an empty ledger.
+func LoadLedger(path string) (*Ledger, error) {
+	ledger := NewLedger(path)
+	if err := ledger.read(); err != nil {
+		return nil, err
+	}
+	return ledger, nil
+}
+
+// read replaces the ledger's contents with the file's, unless the file is
+// missing or unchanged since it was last read or written. The caller must
+// hold l.mu or own the ledger.
+func (l *Ledger) read() error {
+	info, err := os.Stat(l.path)
+	if os.IsNotExist(err) || err == nil && sameFile(info, l.file) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read budget ledger: %w", err)
+	}
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return fmt.Errorf("failed to read budget ledger: %w", err)
+	}
+	var contents struct {
+		Spend    map[string]map[string]float64 `json:"spend"`
+		Notified map[string]string             `json:"notified,omitempty"`
+	}
+	if err := json.Unmarshal(data, &contents); err != nil {
+		return fmt.Errorf("failed to parse budget ledger %s: %w", l.path, err)
+	}
+	l.Spend, l.Notified = contents.Spend, contents.Notified
+	if l.Spend == nil {
+		l.Spend = make(map[string]map[string]float64)
+	}
+	if l.Notified == nil {
+		l.Notified = make(map[string]string)
+	}
+	l.file = info
+	return nil
+}
+
+// sameFile reports whether the ledger file is unchanged. Every save replaces
+// the file, so a file that was written since is another file.
+func sameFile(info, last os.FileInfo) bool {
+	return last != nil && os.SameFile(info, last) && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()
+}
+
+// refresh picks up the spend other processes wrote to the file. A file that
+// can't be read leaves the ledger as it is. The caller must hold l.mu.
+func (l *Ledger) refresh() {
+	if l.path != "" {
+		_ = l.read()
+	}
+}
+
+// periodOf returns the period containing at, such as 2026-10-16 for a day
+func periodOf(period Period, at time.Time) string {
+	if period == PeriodMonth {
+		return at.Format("2006-01")
+	}
+	return at.Format("2006-01-02")
+}
+
+// Spent returns the spend of a key in the period containing at
+func (l *Ledger) Spent(key string, period Period, at time.Time) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refresh()
+	return l.Spend[key][periodOf(period, at)]
+}
+
+// Add adds cost to the current day and month of each key and persists the
+// ledger. Earlier periods are dropped.
+func (l *Ledger) Add(keys []string, cost float64, at time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refresh()
+
+	day, month := periodOf(PeriodDay, at), periodOf(PeriodMonth, at)
+	for _, key := range keys {
+		spend := l.Spend[key]
+		if spend == nil {
+			spend = make(map[string]float64)
+			l.Spend[key] = spend
+		}
+		for period := range spend {
+			if period != day && period != month {
+				delete(spend, period)
+			}
+		}
+		spend[day] += cost
+		spend[month] += cost
+	}
+	return l.save()
+}
+
+// markNotified records that a budget was reported exhausted in the period
+// containing at. It returns false when it already was. A ledger that can't be
+// saved only risks a repeated report after a restart.
+func (l *Ledger) markNotified(b *Budget, at time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refresh()
+
+	key := b.Key() + "/" + string(b.Period)
+	period := periodOf(b.Period, at)
+	if l.Notified[key] == period {
+		return false
+	}
+	l.Notified[key] = period
+	_ = l.save()
+	return true
+}
+
+// save writes the ledger atomically. The caller must hold l.mu.
+func (l *Ledger) save() error {
+	if l.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode budget ledger: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create budget ledger directory: %w", err)
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write budget ledger: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to replace budget ledger: %w", err)
+	}
+	if info, err := os.Stat(l.path); err == nil {
+		l.file = info
+	}
+	return nil
+}
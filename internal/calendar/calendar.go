@@ -0,0 +1,313 @@
+// Package calendar reads holiday and blackout calendars, from ICS files or
+// plain date lists, and matches fire times against them.
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// Default logger for the calendar package
+var log = logger.DefaultLogger()
+
+// SetLogger sets the logger for the calendar package
+func SetLogger(l *logger.Logger) {
+	log = l
+}
+
+// dateLayout is the layout of dates in date list files and freeze windows
+const dateLayout = "2006-01-02"
+
+// Entry is a range of days or a range of time in a calendar
+type Entry struct {
+	Summary string
+	// AllDay entries cover the dates from Start up to End, exclusive, in the
+	// timezone of the time they are matched against. Start and End are
+	// midnight UTC of their date.
+	AllDay bool
+	Start  time.Time
+	End    time.Time // exclusive
+}
+
+// Contains reports whether the entry covers t
+func (e Entry) Contains(t time.Time) bool {
+	if e.AllDay {
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return !t.Before(e.Start) && t.Before(e.End)
+}
+
+// String describes the entry for skip reasons and logs
+func (e Entry) String() string {
+	var when string
+	switch {
+	case e.AllDay && e.End.Sub(e.Start) <= 24*time.Hour:
+		when = e.Start.Format(dateLayout)
+	case e.AllDay:
+		when = fmt.Sprintf("%s to %s", e.Start.Format(dateLayout), e.End.AddDate(0, 0, -1).Format(dateLayout))
+	default:
+		when = fmt.Sprintf("%s to %s", e.Start.Format(time.RFC3339), e.End.Format(time.RFC3339))
+	}
+	if e.Summary == "" {
+		return when
+	}
+	return fmt.Sprintf("%s (%s)", e.Summary, when)
+}
+
+// Calendar is a named set of entries
+type Calendar struct {
+	Name    string
+	Entries []Entry
+}
+
+// Match returns the first entry covering t
+func (c *Calendar) Match(t time.Time) (Entry, bool) {
+	for _, entry := range c.Entries {
+		if entry.Contains(t) {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Day returns an all-day entry covering the dates from first to last, inclusive
+func Day(summary string, first, last time.Time) Entry {
+	return Entry{
+		Summary: summary,
+		AllDay:  true,
+		Start:   time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC),
+		End:     time.Date(last.Year(), last.Month(), last.Day()+1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// Load reads a calendar file. Files ending in .ics are read as iCalendar,
+// anything else as a date list.
+func Load(name, path string) (calendar *Calendar, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close calendar file: %w", closeErr)
+		}
+	}()
+
+	if strings.EqualFold(filepath.Ext(path), ".ics") {
+		calendar, err = ParseICS(name, f)
+	} else {
+		calendar, err = ParseDateList(name, f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return calendar, nil
+}
+
+// ParseDateList reads a date list: one date or date range per line, followed
+// by an optional description. Blank lines and lines starting with # are ignored.
+//
+//	2026-12-25 Christmas Day
+//	2026-12-24..2026-12-31 Year-end freeze
+func ParseDateList(name string, r io.Reader) (*Calendar, error) {
+	calendar := &Calendar{Name: name}
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		dates, summary, _ := strings.Cut(line, " ")
+		firstValue, lastValue, isRange := strings.Cut(dates, "..")
+		first, err := time.Parse(dateLayout, firstValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid date '%s' (use YYYY-MM-DD)", lineNum, firstValue)
+		}
+		last := first
+		if isRange {
+			if last, err = time.Parse(dateLayout, lastValue); err != nil {
+				return nil, fmt.Errorf("line %d: invalid date '%s' (use YYYY-MM-DD)", lineNum, lastValue)
+			}
+			if last.Before(first) {
+				return nil, fmt.Errorf("line %d: range ends before it starts", lineNum)
+			}
+		}
+		calendar.Entries = append(calendar.Entries, Day(strings.TrimSpace(summary), first, last))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return calendar, nil
+}
+
+// ParseICS reads the events of an iCalendar file. All-day events cover their
+// dates in the timezone of the task they apply to, timed events the exact
+// time range. Recurring events only contribute their first occurrence.
+func ParseICS(name string, r io.Reader) (*Calendar, error) {
+	lines, err := unfoldICS(r)
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &Calendar{Name: name}
+	var event map[string]icsProperty
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = make(map[string]icsProperty)
+		case line == "END:VEVENT":
+			if event == nil {
+				continue
+			}
+			entry, err := eventEntry(event)
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := event["RRULE"]; ok {
+				log.Warn("Recurring calendar event, only its first occurrence is used", logger.Fields{
+					"calendar": name,
+					"event":    entry.Summary,
+				})
+			}
+			calendar.Entries = append(calendar.Entries, entry)
+			event = nil
+		case event != nil:
+			property := parseICSProperty(line)
+			event[property.name] = property
+		}
+	}
+	return calendar, nil
+}
+
+// icsProperty is a content line of an iCalendar file
+type icsProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfoldICS reads the content lines of an iCalendar file, joining lines that
+// continue on the next line
+func unfoldICS(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseICSProperty splits a content line such as DTSTART;TZID=Europe/Berlin:20261224T120000
+func parseICSProperty(line string) icsProperty {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	property := icsProperty{name: strings.ToUpper(parts[0]), params: make(map[string]string), value: value}
+	for _, param := range parts[1:] {
+		if key, paramValue, ok := strings.Cut(param, "="); ok {
+			property.params[strings.ToUpper(key)] = strings.Trim(paramValue, `"`)
+		}
+	}
+	return property
+}
+
+// eventEntry converts a VEVENT into a calendar entry
+func eventEntry(event map[string]icsProperty) (Entry, error) {
+	summary := strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\\`, `\`).Replace(event["SUMMARY"].value)
+
+	dtstart, ok := event["DTSTART"]
+	if !ok {
+		return Entry{}, fmt.Errorf("event '%s' has no DTSTART", summary)
+	}
+	start, allDay, err := parseICSTime(dtstart)
+	if err != nil {
+		return Entry{}, fmt.Errorf("event '%s': %w", summary, err)
+	}
+
+	entry := Entry{Summary: summary, AllDay: allDay, Start: start}
+	if dtend, ok := event["DTEND"]; ok {
+		if entry.End, _, err = parseICSTime(dtend); err != nil {
+			return Entry{}, fmt.Errorf("event '%s': %w", summary, err)
+		}
+	} else if allDay {
+		// An all-day event without an end lasts one day
+		entry.End = start.AddDate(0, 0, 1)
+	} else {
+		entry.End = start
+	}
+	return entry, nil
+}
+
+// parseICSTime parses a DATE or DATE-TIME value. Dates are returned as
+// midnight UTC, times without a zone are taken as local time.
+func parseICSTime(property icsProperty) (t time.Time, allDay bool, err error) {
+	value := property.value
+	if property.params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err = time.Parse("20060102", value)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	loc := time.Local
+	if tzid := property.params["TZID"]; tzid != "" {
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return t, false, fmt.Errorf("unknown TZID '%s': %w", tzid, err)
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// Window returns the entry for a freeze window from start to end. When both
+// are dates (YYYY-MM-DD) the window covers whole days and end is inclusive,
+// otherwise they are RFC3339 or local YYYY-MM-DDTHH:MM times and end is exclusive.
+func Window(summary, start, end string) (Entry, error) {
+	first, firstErr := time.Parse(dateLayout, start)
+	last, lastErr := time.Parse(dateLayout, end)
+	if firstErr == nil && lastErr == nil {
+		if last.Before(first) {
+			return Entry{}, fmt.Errorf("window ends before it starts")
+		}
+		return Day(summary, first, last), nil
+	}
+
+	from, err := parseWindowTime(start)
+	if err != nil {
+		return Entry{}, err
+	}
+	until, err := parseWindowTime(end)
+	if err != nil {
+		return Entry{}, err
+	}
+	if !until.After(from) {
+		return Entry{}, fmt.Errorf("window ends before it starts")
+	}
+	return Entry{Summary: summary, Start: from, End: until}, nil
+}
+
+// parseWindowTime parses a window boundary
+func parseWindowTime(value string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04", dateLayout} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (use YYYY-MM-DD, YYYY-MM-DDTHH:MM or RFC3339)", value)
+}
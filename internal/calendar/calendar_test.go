@@ -0,0 +1,122 @@
+package calendar
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testICS = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20261126\r\n" +
+	"DTEND;VALUE=DATE:20261127\r\n" +
+	"SUMMARY:Thanksgiving\r\n" +
+	"  Day\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART:20261201T170000Z\r\n" +
+	"DTEND:20261201T190000Z\r\n" +
+	"SUMMARY:Release window\\, EU\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	calendar, err := ParseICS("us-holidays", strings.NewReader(testICS))
+	require.NoError(t, err)
+	require.Len(t, calendar.Entries, 2)
+
+	thanksgiving := calendar.Entries[0]
+	assert.Equal(t, "Thanksgiving Day", thanksgiving.Summary)
+	assert.True(t, thanksgiving.AllDay)
+
+	// All-day events match the date wherever the time is
+	tokyo := time.FixedZone("JST", 9*3600)
+	assert.True(t, thanksgiving.Contains(time.Date(2026, 11, 26, 0, 30, 0, 0, tokyo)))
+	assert.True(t, thanksgiving.Contains(time.Date(2026, 11, 26, 23, 59, 0, 0, time.UTC)))
+	assert.False(t, thanksgiving.Contains(time.Date(2026, 11, 27, 0, 0, 0, 0, time.UTC)))
+
+	release := calendar.Entries[1]
+	assert.Equal(t, "Release window, EU", release.Summary)
+	assert.True(t, release.Contains(time.Date(2026, 12, 1, 18, 0, 0, 0, time.UTC)))
+	assert.False(t, release.Contains(time.Date(2026, 12, 1, 19, 0, 0, 0, time.UTC)))
+
+	entry, ok := calendar.Match(time.Date(2026, 11, 26, 9, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, "Thanksgiving Day (2026-11-26)", entry.String())
+}
+
+func TestParseICSErrors(t *testing.T) {
+	_, err := ParseICS("broken", strings.NewReader("BEGIN:VEVENT\nSUMMARY:No start\nEND:VEVENT\n"))
+	assert.ErrorContains(t, err, "has no DTSTART")
+
+	_, err = ParseICS("broken", strings.NewReader("BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\n"))
+	assert.Error(t, err)
+}
+
+func TestParseDateList(t *testing.T) {
+	content := `# Public holidays
+2026-12-25 Christmas Day
+
+2026-12-28..2026-12-31 Year-end freeze
+`
+	calendar, err := ParseDateList("holidays", strings.NewReader(content))
+	require.NoError(t, err)
+	require.Len(t, calendar.Entries, 2)
+
+	_, ok := calendar.Match(time.Date(2026, 12, 25, 9, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	entry, ok := calendar.Match(time.Date(2026, 12, 31, 23, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, "Year-end freeze (2026-12-28 to 2026-12-31)", entry.String())
+	_, ok = calendar.Match(time.Date(2027, 1, 1, 9, 0, 0, 0, time.UTC))
+	assert.False(t, ok)
+
+	for _, content := range []string{"25.12.2026 Christmas\n", "2026-12-31..2026-12-28\n"} {
+		_, err := ParseDateList("broken", strings.NewReader(content))
+		assert.ErrorContains(t, err, "line 1", content)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	icsPath := filepath.Join(dir, "holidays.ics")
+	listPath := filepath.Join(dir, "release-days.txt")
+	require.NoError(t, os.WriteFile(icsPath, []byte(testICS), 0644))
+	require.NoError(t, os.WriteFile(listPath, []byte("2026-12-01\n"), 0644))
+
+	calendar, err := Load("holidays", icsPath)
+	require.NoError(t, err)
+	assert.Len(t, calendar.Entries, 2)
+
+	calendar, err = Load("release-days", listPath)
+	require.NoError(t, err)
+	assert.Equal(t, "release-days", calendar.Name)
+	assert.Len(t, calendar.Entries, 1)
+
+	_, err = Load("missing", filepath.Join(dir, "missing.ics"))
+	assert.Error(t, err)
+}
+
+func TestWindow(t *testing.T) {
+	days, err := Window("year-end", "2026-12-20", "2027-01-03")
+	require.NoError(t, err)
+	assert.True(t, days.AllDay)
+	assert.True(t, days.Contains(time.Date(2027, 1, 3, 23, 0, 0, 0, time.UTC)))
+	assert.False(t, days.Contains(time.Date(2027, 1, 4, 0, 0, 0, 0, time.UTC)))
+
+	timed, err := Window("deploy", "2026-12-01T17:00:00Z", "2026-12-01T19:00:00Z")
+	require.NoError(t, err)
+	assert.False(t, timed.AllDay)
+	assert.True(t, timed.Contains(time.Date(2026, 12, 1, 18, 0, 0, 0, time.UTC)))
+
+	_, err = Window("backwards", "2026-12-20", "2026-12-01")
+	assert.Error(t, err)
+	_, err = Window("invalid", "soon", "later")
+	assert.Error(t, err)
+}
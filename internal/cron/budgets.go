@@ -0,0 +1,308 @@
+package cron
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rshade/cronai/internal/budget"
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/logger"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// Budgets holds the spend limits of a configuration file
+type Budgets struct {
+	list   []*budget.Budget
+	digest string // fingerprint of every budget, so a reload notices budget changes
+}
+
+// newBudget validates a budget definition. Budgets can limit and downgrade
+// to named providers as well as built-in ones.
+func newBudget(budgetConfig config.BudgetConfig, providers *Providers) (*budget.Budget, error) {
+	b := &budget.Budget{Fallback: budgetConfig.Fallback, Notify: budgetConfig.Notify}
+	scopes := 0
+	for _, scope := range []struct {
+		scope budget.Scope
+		name  string
+	}{
+		{budget.ScopeTask, budgetConfig.Task},
+		{budget.ScopeCategory, budgetConfig.Category},
+		{budget.ScopeProvider, budgetConfig.Provider},
+	} {
+		if scope.name != "" {
+			b.Scope, b.Name = scope.scope, scope.name
+			scopes++
+		}
+	}
+	if scopes != 1 {
+		return nil, fmt.Errorf("a budget limits exactly one of a task, a category or a provider")
+	}
+	if b.Scope == budget.ScopeProvider && !providers.isModel(b.Name) {
+		return nil, fmt.Errorf("unknown provider '%s'", b.Name)
+	}
+
+	var err error
+	if b.Limit, b.Period, err = budget.ParseLimit(budgetConfig.Limit); err != nil {
+		return nil, err
+	}
+	if b.Policy, err = budget.ParsePolicy(budgetConfig.Policy); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b.Policy == budget.PolicyDowngrade && b.Fallback == "":
+		return nil, fmt.Errorf("a downgrade budget needs a fallback model")
+	case b.Policy != budget.PolicyDowngrade && b.Fallback != "":
+		return nil, fmt.Errorf("fallback only applies to downgrade budgets")
+	}
+	if b.Fallback != "" {
+		if provider, model := b.FallbackModel(); !providers.isModel(provider) || strings.Contains(b.Fallback, ":") && model == "" {
+			return nil, fmt.Errorf("invalid fallback '%s' (use a provider or provider:model)", b.Fallback)
+		}
+	}
+	if b.Notify != "" && !isValidProcessor(b.Notify) {
+		return nil, fmt.Errorf("invalid notify processor '%s'", b.Notify)
+	}
+	return b, nil
+}
+
+// loadBudgets validates the budgets of a configuration file
+func loadBudgets(budgetConfigs []config.BudgetConfig, providers *Providers) (*Budgets, error) {
+	budgets := &Budgets{}
+	var loadErrors *multierror.Error
+
+	defined := make(map[string]bool)
+	for _, budgetConfig := range budgetConfigs {
+		at := location{budgetConfig.File, budgetConfig.Line}
+		b, err := newBudget(budgetConfig, providers)
+		if err != nil {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: budget: %v", at, err))
+			continue
+		}
+		key := b.Key() + "/" + string(b.Period)
+		if defined[key] {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: duplicate %s budget of %s", at, b.Period, b.Key()))
+			continue
+		}
+		defined[key] = true
+		budgets.list = append(budgets.list, b)
+	}
+
+	budgets.digest = budgets.fingerprint()
+	return budgets, loadErrors.ErrorOrNil()
+}
+
+// resolveBudgets validates the budgets of a configuration file and, when it
+// defines any, gives every task access to them. Task budgets must name a
+// task of the file.
+func resolveBudgets(budgetConfigs []config.BudgetConfig, providers *Providers, tasks []Task) error {
+	if len(budgetConfigs) == 0 {
+		return nil
+	}
+	budgets, err := loadBudgets(budgetConfigs, providers)
+	var resolveErrors *multierror.Error
+	if err != nil {
+		resolveErrors = multierror.Append(resolveErrors, err)
+	}
+
+	names := make(map[string]bool, len(tasks))
+	for _, task := range tasks {
+		names[task.Name] = true
+	}
+	for _, budgetConfig := range budgetConfigs {
+		if budgetConfig.Task != "" && !names[budgetConfig.Task] {
+			resolveErrors = multierror.Append(resolveErrors, fmt.Errorf("%s: budget: unknown task '%s'", location{budgetConfig.File, budgetConfig.Line}, budgetConfig.Task))
+		}
+	}
+
+	for i := range tasks {
+		tasks[i].budgets = budgets
+	}
+	return resolveErrors.ErrorOrNil()
+}
+
+// fingerprint hashes every budget definition
+func (b *Budgets) fingerprint() string {
+	var sb strings.Builder
+	for _, bg := range b.list {
+		fmt.Fprintf(&sb, "%s:%g:%s:%s:%s:%s\n", bg.Key(), bg.Limit, bg.Period, bg.Policy, bg.Fallback, bg.Notify)
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// budgetGuard returns the guard that checks the task's model calls against
+// the budgets of its configuration file, nil when the file has none. The
+// ledger is loaded on first use, so tasks run outside the scheduler count
+// their spend in it too.
+func (s *Service) budgetGuard(task Task) (*budget.Guard, error) {
+	if task.budgets == nil {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ledger == nil {
+		ledger, err := budget.LoadLedger(s.budgetPath)
+		if err != nil {
+			return nil, errors.Wrap(errors.CategorySystem, err, "failed to load budget ledger")
+		}
+		s.ledger = ledger
+	}
+	return &budget.Guard{Budgets: task.budgets.list, Ledger: s.ledger, Notify: notifyBudget}, nil
+}
+
+// LoadBudgetGuard returns the guard that checks the model calls of the queue
+// and bot modes, and of tasks run from the command line, against the budgets
+// of the configuration file at configPath. It is nil when the file has no
+// budgets or doesn't exist. Spend is counted in the ledger the cron service
+// uses.
+func LoadBudgetGuard(configPath string) (*budget.Guard, error) {
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	var budgetConfigs []config.BudgetConfig
+	var providerConfigs []config.ProviderConfig
+	if config.IsYAMLFile(configPath) {
+		file, err := config.LoadFile(configPath)
+		if err != nil {
+			return nil, errors.Wrap(errors.CategoryConfiguration, err, "failed to load config file")
+		}
+		budgetConfigs, providerConfigs = file.Budgets, file.Providers
+	} else {
+		c := newLineConfig()
+		if err := c.readFile(configPath); err != nil {
+			return nil, errors.Wrap(errors.CategoryConfiguration, err, "failed to read config file")
+		}
+		budgetConfigs, providerConfigs = c.budgets, c.providers
+	}
+	if len(budgetConfigs) == 0 {
+		return nil, nil
+	}
+
+	providers, err := resolveProviders(providerConfigs, nil)
+	if err != nil {
+		return nil, errors.Wrap(errors.CategoryConfiguration, err, "invalid providers")
+	}
+	budgets, err := loadBudgets(budgetConfigs, providers)
+	if err != nil {
+		return nil, errors.Wrap(errors.CategoryConfiguration, err, "invalid budgets")
+	}
+	ledger, err := budget.LoadLedger(budget.LedgerPathFromEnv())
+	if err != nil {
+		return nil, errors.Wrap(errors.CategorySystem, err, "failed to load budget ledger")
+	}
+	return &budget.Guard{Budgets: budgets.list, Ledger: ledger, Notify: notifyBudget}, nil
+}
+
+// notifyBudget reports an exhausted budget to the log and to its processor
+func notifyBudget(ctx context.Context, exhaustion budget.Exhaustion) {
+	b := exhaustion.Budget
+	log.Warn("Budget exhausted", logger.Fields{
+		"budget": b.Key(),
+		"limit":  b.Limit,
+		"period": string(b.Period),
+		"spent":  exhaustion.Spent,
+		"policy": string(b.Policy),
+		"task":   exhaustion.Request.Task,
+	})
+	if b.Notify == "" {
+		return
+	}
+
+	var consequence string
+	switch b.Policy {
+	case budget.PolicyRefuse:
+		consequence = "are refused"
+	case budget.PolicyDowngrade:
+		consequence = "are downgraded to " + b.Fallback
+	default:
+		consequence = "go ahead with a warning"
+	}
+	response := &models.ModelResponse{
+		Content: fmt.Sprintf("Budget %s is exhausted: $%.2f spent this %s. Model calls %s until the %s is over.",
+			b, exhaustion.Spent, b.Period, consequence, b.Period),
+		Model:      "budget",
+		PromptName: "budget_exhausted",
+		Variables: map[string]string{
+			"budget": b.Key(),
+			"limit":  fmt.Sprintf("%.2f", b.Limit),
+			"period": string(b.Period),
+			"spent":  fmt.Sprintf("%.2f", exhaustion.Spent),
+			"policy": string(b.Policy),
+			"task":   exhaustion.Request.Task,
+		},
+		Timestamp:   time.Now(),
+		ExecutionID: fmt.Sprintf("budget-%s-%s", strings.ReplaceAll(b.Key(), ":", "-"), time.Now().Format("20060102150405")),
+		Provider:    exhaustion.Request.Provider,
+	}
+	if err := deliverResponse(ctx, config.ProcessorConfig{Name: b.Notify}, response); err != nil {
+		log.Warn("Failed to report exhausted budget", logger.Fields{"budget": b.Key(), "processor": b.Notify, "error": err.Error()})
+	}
+}
+
+// ParseBudgetLine parses a budget definition of the line format:
+//
+//	budget task|category|provider:<name> <limit> [policy=<policy>,fallback=<model>,notify=<processor>]
+//
+// such as budget provider:openai $20/day policy=downgrade,fallback=openai:gpt-4o-mini.
+// It returns nil for any other line.
+func ParseBudgetLine(line string) (*config.BudgetConfig, error) {
+	if !strings.HasPrefix(strings.TrimSpace(line), "budget ") {
+		return nil, nil
+	}
+	fields, err := splitFields(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 3 || len(fields) > 4 {
+		return nil, errorAt(line, len(strings.TrimRight(line, " \t")),
+			fmt.Errorf("invalid budget: use budget task|category|provider:<name> <limit> [policy=..,fallback=..,notify=..]"))
+	}
+
+	budgetConfig := &config.BudgetConfig{Limit: fields[2].text}
+	scope, name, err := budget.ParseScope(fields[1].text)
+	if err != nil {
+		return nil, errorAt(line, fields[1].offset, err)
+	}
+	switch scope {
+	case budget.ScopeTask:
+		budgetConfig.Task = name
+	case budget.ScopeCategory:
+		budgetConfig.Category = name
+	default:
+		budgetConfig.Provider = name
+	}
+	if _, _, err := budget.ParseLimit(budgetConfig.Limit); err != nil {
+		return nil, errorAt(line, fields[2].offset, err)
+	}
+	if len(fields) == 3 {
+		return budgetConfig, nil
+	}
+
+	pairs, err := splitVariablePairs(line, fields[3].offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		switch pair.key {
+		case "policy":
+			budgetConfig.Policy = pair.value
+		case "fallback":
+			budgetConfig.Fallback = pair.value
+		case "notify":
+			budgetConfig.Notify = pair.value
+		default:
+			return nil, errorAt(line, pair.offset, fmt.Errorf("unknown budget option '%s' (supported: policy, fallback, notify)", pair.key))
+		}
+	}
+	return budgetConfig, nil
+}
@@ -0,0 +1,258 @@
+package cron
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/budget"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBudgetLine(t *testing.T) {
+	budgetConfig, err := ParseBudgetLine("budget provider:openai $20/day policy=downgrade,fallback=openai:gpt-4o-mini,notify=slack-#ops")
+	require.NoError(t, err)
+	assert.Equal(t, config.BudgetConfig{
+		Provider: "openai", Limit: "$20/day", Policy: "downgrade", Fallback: "openai:gpt-4o-mini", Notify: "slack-#ops",
+	}, *budgetConfig)
+
+	budgetConfig, err = ParseBudgetLine("budget category:reports 150/month")
+	require.NoError(t, err)
+	assert.Equal(t, config.BudgetConfig{Category: "reports", Limit: "150/month"}, *budgetConfig)
+
+	budgetConfig, err = ParseBudgetLine("0 8 * * * openai budget console")
+	assert.NoError(t, err)
+	assert.Nil(t, budgetConfig)
+
+	for line, message := range map[string]string{
+		"budget provider:openai":                          "invalid budget",
+		"budget team:ops $5/day":                          "column 8: unknown budget scope 'team'",
+		"budget task:weekly $5/week":                      "column 20: invalid budget period 'week'",
+		"budget task:weekly $5/day policy=warn,limit=$10": "unknown budget option 'limit'",
+	} {
+		_, err := ParseBudgetLine(line)
+		assert.ErrorContains(t, err, message, line)
+	}
+}
+
+func TestLoadBudgetsValidation(t *testing.T) {
+	_, err := loadBudgets([]config.BudgetConfig{
+		{Provider: "openai", Limit: "$20/day"},
+		{Provider: "openai", Limit: "$30/day", Line: 2},
+		{Provider: "openai", Limit: "$300/month", Line: 3},
+		{Task: "weekly", Provider: "openai", Limit: "$5/day", Line: 4},
+		{Provider: "mistral", Limit: "$5/day", Line: 5},
+		{Category: "reports", Limit: "$5/day", Policy: "downgrade", Line: 6},
+		{Category: "reports", Limit: "$5/month", Policy: "warn", Fallback: "gemini", Line: 7},
+		{Task: "weekly", Limit: "$5/day", Policy: "downgrade", Fallback: "mistral", Line: 8},
+		{Task: "daily", Limit: "$5/day", Notify: "pager", Line: 9},
+	}, nil)
+	require.Error(t, err)
+	for _, message := range []string{
+		"line 2: duplicate day budget of provider:openai",
+		"line 4: budget: a budget limits exactly one of a task, a category or a provider",
+		"line 5: budget: unknown provider 'mistral'",
+		"line 6: budget: a downgrade budget needs a fallback model",
+		"line 7: budget: fallback only applies to downgrade budgets",
+		"line 8: budget: invalid fallback 'mistral'",
+		"line 9: budget: invalid notify processor 'pager'",
+	} {
+		assert.Contains(t, err.Error(), message)
+	}
+	assert.NotContains(t, err.Error(), "line 3")
+}
+
+func TestParseConfigFileBudgets(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	tasks, err := parseConfigFile(writeCalendarConfig(t, `budget provider:openai $20/day policy=downgrade,fallback=openai:gpt-4o-mini
+budget task:report 50/month notify=console
+0 9 * * * openai test_prompt console name=report
+0 9 * * * claude test_prompt console
+`))
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	require.NotNil(t, tasks[0].budgets)
+	assert.Same(t, tasks[0].budgets, tasks[1].budgets, "budgets apply to every task of the file")
+	require.Len(t, tasks[0].budgets.list, 2)
+	assert.Equal(t, "provider:openai $20.00/day", tasks[0].budgets.list[0].String())
+	assert.Equal(t, budget.PolicyRefuse, tasks[0].budgets.list[1].Policy)
+
+	_, err = parseConfigFile(writeCalendarConfig(t, "budget task:missing $5/day\n0 9 * * * openai test_prompt console\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1: budget: unknown task 'missing'")
+
+	tasks, err = parseConfigFile(writeYAMLConfig(t, `budgets:
+  - category: reports
+    limit: $100/month
+    policy: warn
+tasks:
+  - name: report
+    schedule: "0 9 * * *"
+    model: openai
+    prompt: test_prompt
+    processors: [console]
+`))
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.NotNil(t, tasks[0].budgets)
+	assert.Equal(t, "category:reports $100.00/month", tasks[0].budgets.list[0].String())
+
+	// Files without budgets don't check them
+	tasks, err = parseConfigFile(writeCalendarConfig(t, "0 9 * * * openai test_prompt console\n"))
+	require.NoError(t, err)
+	assert.Nil(t, tasks[0].budgets)
+}
+
+func TestRunTaskBudget(t *testing.T) {
+	proc := &flakyProcessor{}
+	store, _ := setupRetryTest(t, proc)
+
+	// The model call reports a refusal of the budget guard it is given
+	var requests []budget.Request
+	oldExecuteModel := executeModel
+	executeModel = func(ctx context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		guard, request, ok := budget.FromContext(ctx)
+		require.True(t, ok)
+		requests = append(requests, request)
+		request.Provider = model
+		if decision := guard.Check(ctx, request); decision.Policy == budget.PolicyRefuse {
+			return nil, decision.Err()
+		}
+		return &models.ModelResponse{Content: "ok", Model: model, Cost: 3}, guard.Record(ctx, request, 3)
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	budgets, err := loadBudgets([]config.BudgetConfig{{Task: "report", Limit: "$5/day", Notify: "console"}}, nil)
+	require.NoError(t, err)
+	task := Task{Name: "report", Model: "openai", Prompt: "test", Processor: "console", budgets: budgets}
+	service := NewCronService("test.config")
+	service.ledger, err = budget.LoadLedger(filepath.Join(t.TempDir(), "budget.json"))
+	require.NoError(t, err)
+
+	record, err := service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.NoError(t, err)
+	assert.Equal(t, history.StatusSuccess, record.Status)
+	assert.Equal(t, []budget.Request{{Task: "report"}}, requests)
+
+	// The second call exhausts the budget, which is reported to its processor
+	record, err = service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.NoError(t, err)
+	assert.Equal(t, history.StatusSuccess, record.Status)
+	assert.Equal(t, []string{
+		"ok",
+		"Budget task:report $5.00/day is exhausted: $6.00 spent this day. Model calls are refused until the day is over.",
+		"ok",
+	}, proc.responses)
+
+	// Refused runs are skipped, and the budget isn't reported again
+	record, err = service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.NoError(t, err)
+	assert.Equal(t, history.StatusSkipped, record.Status)
+	assert.Equal(t, "budget task:report $5.00/day exhausted: spent $6.00 this day", record.Reason)
+	assert.Len(t, proc.responses, 3)
+
+	records, err := store.List(history.Filter{Task: "report"})
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+
+	// Tasks of files without budgets aren't guarded
+	task.budgets = nil
+	executeModel = func(ctx context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		_, _, ok := budget.FromContext(ctx)
+		assert.False(t, ok)
+		return &models.ModelResponse{Content: "ok", Model: model}, nil
+	}
+	_, err = service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.NoError(t, err)
+}
+
+func TestRunNamedTaskBudget(t *testing.T) {
+	proc := &flakyProcessor{}
+	store, calls := setupRetryTest(t, proc)
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+	prompt.PM.(*MockPromptManager).SetPrompt("test_prompt", "This is a test prompt")
+	oldExecuteModel := executeModel
+	executeModel = func(ctx context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		guard, request, ok := budget.FromContext(ctx)
+		require.True(t, ok)
+		if decision := guard.Check(ctx, request); decision.Policy == budget.PolicyRefuse {
+			return nil, decision.Err()
+		}
+		calls.Add(1)
+		return &models.ModelResponse{Content: "ok", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	// The spend of earlier runs is saved in the ledger
+	ledgerPath := filepath.Join(t.TempDir(), "budget.json")
+	t.Setenv(budget.EnvLedgerPath, ledgerPath)
+	ledger, err := budget.LoadLedger(ledgerPath)
+	require.NoError(t, err)
+	require.NoError(t, ledger.Add([]string{"task:report"}, 6, time.Now()))
+
+	configPath := writeCalendarConfig(t, "budget task:report $5/day\n0 9 * * * openai test_prompt console name=report\n")
+	require.NoError(t, NewCronService(configPath).RunNamedTask(context.Background(), "report"))
+	assert.Zero(t, calls.Load())
+
+	records, err := store.List(history.Filter{Task: "report"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StatusSkipped, records[0].Status)
+	assert.Equal(t, "budget task:report $5.00/day exhausted: spent $6.00 this day", records[0].Reason)
+}
+
+func TestLoadBudgetGuard(t *testing.T) {
+	ledgerPath := filepath.Join(t.TempDir(), "budget.json")
+	t.Setenv(budget.EnvLedgerPath, ledgerPath)
+	ledger, err := budget.LoadLedger(ledgerPath)
+	require.NoError(t, err)
+	require.NoError(t, ledger.Add([]string{"provider:openai"}, 25, time.Now()))
+
+	// Budgets apply without any task, using the cron service's ledger
+	guard, err := LoadBudgetGuard(writeYAMLConfig(t, `budgets:
+  - provider: openai
+    limit: $20/day
+`))
+	require.NoError(t, err)
+	require.NotNil(t, guard)
+	decision := guard.Check(context.Background(), budget.Request{Provider: "openai"})
+	assert.Equal(t, budget.PolicyRefuse, decision.Policy)
+
+	guard, err = LoadBudgetGuard(writeCalendarConfig(t, "budget category:reports $100/month policy=warn\n"))
+	require.NoError(t, err)
+	require.NotNil(t, guard)
+	assert.Equal(t, "category:reports $100.00/month", guard.Budgets[0].String())
+
+	guard, err = LoadBudgetGuard(writeCalendarConfig(t, "0 9 * * * openai test_prompt console\n"))
+	require.NoError(t, err)
+	assert.Nil(t, guard)
+
+	guard, err = LoadBudgetGuard(filepath.Join(t.TempDir(), "missing.config"))
+	require.NoError(t, err)
+	assert.Nil(t, guard)
+
+	_, err = LoadBudgetGuard(writeCalendarConfig(t, "budget provider:mistral $5/day\n"))
+	assert.ErrorContains(t, err, "unknown provider 'mistral'")
+}
+
+func TestTaskKeyChangesWithBudgets(t *testing.T) {
+	task := Task{Name: "report", Schedule: "* * * * *"}
+	first, err := loadBudgets([]config.BudgetConfig{{Provider: "openai", Limit: "$20/day"}}, nil)
+	require.NoError(t, err)
+	second, err := loadBudgets([]config.BudgetConfig{{Provider: "openai", Limit: "$25/day"}}, nil)
+	require.NoError(t, err)
+
+	withFirst, withSecond := task, task
+	withFirst.budgets, withSecond.budgets = first, second
+	assert.NotEqual(t, taskKey(withFirst), taskKey(withSecond))
+	assert.Equal(t, taskDefinition(withFirst), taskDefinition(withSecond))
+}
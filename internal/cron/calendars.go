@@ -0,0 +1,266 @@
+package cron
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rshade/cronai/internal/calendar"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// Calendars holds the calendars and freeze windows of a configuration file
+type Calendars struct {
+	byName  map[string]*calendar.Calendar
+	freezes []freeze
+	digest  string // fingerprint of every entry, so a reload notices calendar changes
+}
+
+// freeze pauses the tasks of some groups, or every task, during its windows
+type freeze struct {
+	name    string
+	windows *calendar.Calendar
+	groups  []string
+}
+
+// appliesTo reports whether the freeze pauses tasks of the given group
+func (f freeze) appliesTo(group string) bool {
+	if len(f.groups) == 0 {
+		return true
+	}
+	for _, g := range f.groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// loadCalendars reads the calendars and builds the freeze windows of a
+// configuration file. Relative calendar paths are relative to dir.
+func loadCalendars(dir string, calendarConfigs []config.CalendarConfig, freezeConfigs []config.FreezeConfig) (*Calendars, error) {
+	calendars := &Calendars{byName: make(map[string]*calendar.Calendar)}
+	var loadErrors *multierror.Error
+
+	for _, calendarConfig := range calendarConfigs {
+		if err := validateTaskName(calendarConfig.Name); err != nil {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: invalid calendar name: %v", location{calendarConfig.File, calendarConfig.Line}, err))
+			continue
+		}
+		if _, exists := calendars.byName[calendarConfig.Name]; exists {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: duplicate calendar '%s'", location{calendarConfig.File, calendarConfig.Line}, calendarConfig.Name))
+			continue
+		}
+		path := calendarConfig.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		cal, err := calendar.Load(calendarConfig.Name, path)
+		if err != nil {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: calendar %s: %v", location{calendarConfig.File, calendarConfig.Line}, calendarConfig.Name, err))
+			continue
+		}
+		calendars.byName[cal.Name] = cal
+	}
+
+	for _, freezeConfig := range freezeConfigs {
+		f, err := calendars.newFreeze(freezeConfig)
+		if err != nil {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: freeze %s: %v", location{freezeConfig.File, freezeConfig.Line}, freezeConfig.Name, err))
+			continue
+		}
+		calendars.freezes = append(calendars.freezes, f)
+	}
+
+	calendars.digest = calendars.fingerprint()
+	return calendars, loadErrors.ErrorOrNil()
+}
+
+// resolveCalendars loads the calendars and freeze windows of a configuration
+// file and attaches them to its tasks. lines holds where each task is
+// defined.
+func resolveCalendars(dir string, calendarConfigs []config.CalendarConfig, freezeConfigs []config.FreezeConfig, tasks []Task, lines []location) error {
+	calendars, err := loadCalendars(dir, calendarConfigs, freezeConfigs)
+	var resolveErrors *multierror.Error
+	if err != nil {
+		resolveErrors = multierror.Append(resolveErrors, err)
+	}
+	for i := range tasks {
+		if err := calendars.attach(&tasks[i]); err != nil {
+			resolveErrors = multierror.Append(resolveErrors, fmt.Errorf("%s: %v", lines[i], err))
+		}
+	}
+	return resolveErrors.ErrorOrNil()
+}
+
+// newFreeze builds a freeze window from its definition
+func (c *Calendars) newFreeze(freezeConfig config.FreezeConfig) (freeze, error) {
+	f := freeze{name: freezeConfig.Name, groups: freezeConfig.Groups}
+	switch {
+	case freezeConfig.Calendar != "" && (freezeConfig.Start != "" || freezeConfig.End != ""):
+		return f, fmt.Errorf("use either calendar or start and end")
+	case freezeConfig.Calendar != "":
+		cal, ok := c.byName[freezeConfig.Calendar]
+		if !ok {
+			return f, fmt.Errorf("unknown calendar '%s'", freezeConfig.Calendar)
+		}
+		f.windows = cal
+	case freezeConfig.Start == "" || freezeConfig.End == "":
+		return f, fmt.Errorf("start and end are required")
+	default:
+		window, err := calendar.Window("", freezeConfig.Start, freezeConfig.End)
+		if err != nil {
+			return f, err
+		}
+		f.windows = &calendar.Calendar{Name: freezeConfig.Name, Entries: []calendar.Entry{window}}
+	}
+	return f, nil
+}
+
+// fingerprint hashes every calendar entry and freeze window
+func (c *Calendars) fingerprint() string {
+	names := make([]string, 0, len(c.byName))
+	for name := range c.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		for _, entry := range c.byName[name].Entries {
+			fmt.Fprintf(&b, "%s:%v:%d:%d\n", name, entry.AllDay, entry.Start.UnixNano(), entry.End.UnixNano())
+		}
+	}
+	for _, f := range c.freezes {
+		fmt.Fprintf(&b, "freeze:%s:%s:%s\n", f.name, f.windows.Name, strings.Join(f.groups, ","))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// empty reports whether the configuration defines no calendars or freezes
+func (c *Calendars) empty() bool {
+	return len(c.byName) == 0 && len(c.freezes) == 0
+}
+
+// attach checks the calendars a task refers to and gives the task access to
+// the calendars and freeze windows
+func (c *Calendars) attach(task *Task) error {
+	for _, name := range []string{task.SkipCalendar, task.OnlyCalendar} {
+		if name == "" {
+			continue
+		}
+		if _, ok := c.byName[name]; !ok {
+			return fmt.Errorf("unknown calendar '%s'", name)
+		}
+	}
+	if !c.empty() {
+		task.calendars = c
+	}
+	return nil
+}
+
+// SkipReason returns why the task must not run at the given time because of a
+// freeze window or its calendars, or an empty string when it may run. Dates
+// are compared in the task's timezone.
+func (t Task) SkipReason(at time.Time) string {
+	c := t.calendars
+	if c == nil {
+		return ""
+	}
+	if loc, err := t.location(); err == nil && loc != nil {
+		at = at.In(loc)
+	}
+
+	for _, f := range c.freezes {
+		if !f.appliesTo(t.Group) {
+			continue
+		}
+		if entry, ok := f.windows.Match(at); ok {
+			return fmt.Sprintf("freeze window %s: %s", f.name, entry)
+		}
+	}
+	if t.SkipCalendar != "" {
+		if entry, ok := c.byName[t.SkipCalendar].Match(at); ok {
+			return fmt.Sprintf("skip_calendar %s: %s", t.SkipCalendar, entry)
+		}
+	}
+	if t.OnlyCalendar != "" {
+		if _, ok := c.byName[t.OnlyCalendar].Match(at); !ok {
+			return fmt.Sprintf("only_calendar %s: not a listed day", t.OnlyCalendar)
+		}
+	}
+	return ""
+}
+
+// skipBlackout records a skipped execution and returns true when a freeze
+// window or calendar keeps the task from running at the given time
+func (s *Service) skipBlackout(task Task, source string, at time.Time) bool {
+	reason := task.SkipReason(at)
+	if reason == "" {
+		return false
+	}
+	s.recordSkip(task, source, reason)
+	return true
+}
+
+// ParseCalendarLine parses a calendar definition of the line format:
+//
+//	calendar <name> <path>
+//
+// It returns nil for any other line.
+func ParseCalendarLine(line string) (*config.CalendarConfig, error) {
+	parts := strings.Fields(line)
+	if len(parts) == 0 || parts[0] != "calendar" {
+		return nil, nil
+	}
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid calendar definition (use: calendar <name> <path>)")
+	}
+	return &config.CalendarConfig{Name: parts[1], Path: parts[2]}, nil
+}
+
+// ParseFreezeLine parses a freeze window definition of the line format:
+//
+//	freeze <name> <start> <end> [groups=a,b]
+//	freeze <name> calendar=<calendar> [groups=a,b]
+//
+// It returns nil for any other line.
+func ParseFreezeLine(line string) (*config.FreezeConfig, error) {
+	parts := strings.Fields(line)
+	if len(parts) == 0 || parts[0] != "freeze" {
+		return nil, nil
+	}
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid freeze definition (use: freeze <name> <start> <end> [groups=a,b] or freeze <name> calendar=<calendar> [groups=a,b])")
+	}
+
+	freezeConfig := &config.FreezeConfig{Name: parts[1]}
+	var positional []string
+	for _, part := range parts[2:] {
+		key, value, ok := strings.Cut(part, "=")
+		switch {
+		case !ok:
+			positional = append(positional, part)
+		case key == "calendar":
+			freezeConfig.Calendar = value
+		case key == "groups":
+			freezeConfig.Groups = strings.Split(value, ",")
+		default:
+			return nil, fmt.Errorf("unknown freeze option '%s'", key)
+		}
+	}
+	switch len(positional) {
+	case 0:
+	case 2:
+		freezeConfig.Start, freezeConfig.End = positional[0], positional[1]
+	default:
+		return nil, fmt.Errorf("a freeze window needs a start and an end")
+	}
+	return freezeConfig, nil
+}
@@ -0,0 +1,205 @@
+package cron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCalendarConfig writes a line configuration and a holiday list next to it
+func writeCalendarConfig(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	holidays := "2026-12-25 Christmas Day\n2026-12-31..2027-01-01 New Year\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "holidays.txt"), []byte(holidays), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "release-days.txt"), []byte("2026-12-02\n2026-12-16\n"), 0644))
+	configPath := filepath.Join(dir, "cronai.config")
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+	return configPath
+}
+
+const calendarConfig = `calendar holidays holidays.txt
+calendar release-days release-days.txt
+freeze year-end 2026-12-21 2026-12-23 groups=standups
+
+0 9 * * 1-5 openai test_prompt console name=standup,group=standups,skip_calendar=holidays
+0 12 * * * openai test_prompt console name=release_notes,only_calendar=release-days
+0 6 * * * openai test_prompt console name=health
+`
+
+func TestParseConfigFileCalendars(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	tasks, err := parseConfigFile(writeCalendarConfig(t, calendarConfig))
+	require.NoError(t, err)
+	require.Len(t, tasks, 3)
+
+	standup, releaseNotes, health := tasks[0], tasks[1], tasks[2]
+	assert.Equal(t, "standups", standup.Group)
+	assert.Equal(t, "holidays", standup.SkipCalendar)
+	assert.Equal(t, "release-days", releaseNotes.OnlyCalendar)
+	assert.Empty(t, standup.Variables)
+
+	at := func(day, hour int) time.Time {
+		return time.Date(2026, 12, day, hour, 0, 0, 0, time.Local)
+	}
+	assert.Equal(t, "", standup.SkipReason(at(18, 9)))
+	assert.Equal(t, "freeze window year-end: 2026-12-21 to 2026-12-23", standup.SkipReason(at(22, 9)))
+	assert.Equal(t, "skip_calendar holidays: Christmas Day (2026-12-25)", standup.SkipReason(at(25, 9)))
+	assert.Equal(t, "", releaseNotes.SkipReason(at(16, 12)))
+	assert.Equal(t, "only_calendar release-days: not a listed day", releaseNotes.SkipReason(at(17, 12)))
+
+	// The freeze only pauses its groups
+	assert.Equal(t, "", health.SkipReason(at(22, 6)))
+}
+
+func TestParseConfigFileCalendarErrors(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	content := `calendar holidays missing.txt
+freeze broken 2026-12-23 2026-12-21
+freeze unknown calendar=none
+0 9 * * * openai test_prompt console skip_calendar=vacations
+`
+	_, err := parseConfigFile(writeCalendarConfig(t, content))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1: calendar holidays")
+	assert.Contains(t, err.Error(), "line 2: freeze broken: window ends before it starts")
+	assert.Contains(t, err.Error(), "line 3: freeze unknown: unknown calendar 'none'")
+	assert.Contains(t, err.Error(), "line 4: unknown calendar 'vacations'")
+}
+
+func TestParseFreezeLine(t *testing.T) {
+	freeze, err := ParseFreezeLine("freeze deploys calendar=change-freezes groups=reports,standups")
+	require.NoError(t, err)
+	assert.Equal(t, "change-freezes", freeze.Calendar)
+	assert.Equal(t, []string{"reports", "standups"}, freeze.Groups)
+
+	freeze, err = ParseFreezeLine("freeze maintenance 2026-12-01T22:00 2026-12-02T02:00")
+	require.NoError(t, err)
+	assert.Equal(t, "2026-12-01T22:00", freeze.Start)
+	assert.Empty(t, freeze.Groups)
+
+	freeze, err = ParseFreezeLine("0 8 * * * openai report console")
+	assert.NoError(t, err)
+	assert.Nil(t, freeze)
+
+	for _, line := range []string{"freeze", "freeze name 2026-12-01", "freeze name 2026-12-01 2026-12-02 team=ops"} {
+		_, err := ParseFreezeLine(line)
+		assert.Error(t, err, line)
+	}
+
+	_, err = ParseCalendarLine("calendar holidays")
+	assert.Error(t, err)
+}
+
+func TestPreviewSkips(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	tasks, err := parseConfigFile(writeCalendarConfig(t, calendarConfig))
+	require.NoError(t, err)
+
+	from := time.Date(2026, 12, 24, 0, 0, 0, 0, time.Local)
+	previews, err := NextFires(tasks[:1], from, 2)
+	require.NoError(t, err)
+	require.Len(t, previews[0].Fires, 2)
+	assert.Equal(t, "", previews[0].SkipReason(0))
+	assert.Contains(t, previews[0].SkipReason(1), "Christmas Day")
+
+	// Skipped fires don't collide with other tasks
+	assert.Empty(t, Collisions([]Preview{
+		{Task: Task{Name: "a"}, Fires: []time.Time{from}},
+		{Task: Task{Name: "b"}, Fires: []time.Time{from}, Skips: []string{"freeze window year-end"}},
+	}))
+}
+
+func TestSkipBlackoutRecordsHistory(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	tasks, err := parseConfigFile(writeCalendarConfig(t, calendarConfig))
+	require.NoError(t, err)
+
+	service := NewCronService("test.config")
+	christmas := time.Date(2026, 12, 25, 9, 0, 0, 0, time.Local)
+	assert.True(t, service.skipBlackout(tasks[0], history.SourceCron, christmas))
+	assert.False(t, service.skipBlackout(tasks[2], history.SourceCron, christmas))
+
+	records, err := store.List(history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StatusSkipped, records[0].Status)
+	assert.Equal(t, "standup", records[0].Task)
+	assert.Equal(t, "skip_calendar holidays: Christmas Day (2026-12-25)", records[0].Reason)
+	assert.Equal(t, 1, service.SkippedRuns()["standup"])
+}
+
+func TestTaskKeyCalendars(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	configPath := writeCalendarConfig(t, calendarConfig)
+	before, err := parseConfigFile(configPath)
+	require.NoError(t, err)
+
+	// Changing a calendar replaces the scheduler entry but keeps the task's identity
+	holidays := filepath.Join(filepath.Dir(configPath), "holidays.txt")
+	require.NoError(t, os.WriteFile(holidays, []byte("2026-12-24 Christmas Eve\n"), 0644))
+	after, err := parseConfigFile(configPath)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, taskKey(before[0]), taskKey(after[0]))
+	assert.Equal(t, before[0].ID(), after[0].ID())
+	assert.Equal(t, taskDefinition(before[2]), taskDefinition(after[2]))
+}
+
+func TestYAMLCalendars(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	configPath := writeCalendarConfig(t, "")
+	yamlPath := filepath.Join(filepath.Dir(configPath), "cronai.yaml")
+	content := `defaults:
+  model: openai
+  processors: [console]
+calendars:
+  - name: holidays
+    path: holidays.txt
+freezes:
+  - name: holiday-freeze
+    calendar: holidays
+    groups: [reports]
+tasks:
+  - name: weekly
+    schedule: "0 9 * * *"
+    prompt: test_prompt
+    group: reports
+`
+	require.NoError(t, os.WriteFile(yamlPath, []byte(content), 0644))
+
+	tasks, err := parseConfigFile(yamlPath)
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "freeze window holiday-freeze: New Year (2026-12-31 to 2027-01-01)",
+		tasks[0].SkipReason(time.Date(2027, 1, 1, 9, 0, 0, 0, time.Local)))
+
+	taskConfig, err := tasks[0].TaskConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "reports", taskConfig.Group)
+
+	require.NoError(t, os.WriteFile(yamlPath, []byte("calendars:\n  - name: holidays\n    path: missing.ics\n"), 0644))
+	_, err = parseConfigFile(yamlPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2: calendar holidays")
+}
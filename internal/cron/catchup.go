@@ -0,0 +1,213 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// CatchupMode determines which runs missed during downtime are executed on startup
+type CatchupMode string
+
+// Catch-up modes
+const (
+	// CatchupNone ignores missed runs (default)
+	CatchupNone CatchupMode = "none"
+	// CatchupLast runs only the most recent missed fire time
+	CatchupLast CatchupMode = "last"
+	// CatchupAll runs every missed fire time, up to a limit
+	CatchupAll CatchupMode = "all"
+)
+
+// DefaultCatchupLimit is the maximum number of missed runs executed by catchup=all
+const DefaultCatchupLimit = 10
+
+// Catchup is a task's policy for runs missed while the service was down
+type Catchup struct {
+	Mode  CatchupMode
+	Limit int // Maximum number of runs for CatchupAll
+}
+
+// String returns the policy in configuration syntax
+func (c Catchup) String() string {
+	switch c.Mode {
+	case CatchupAll:
+		return fmt.Sprintf("%s:%d", c.Mode, c.Limit)
+	case "":
+		return string(CatchupNone)
+	default:
+		return string(c.Mode)
+	}
+}
+
+// Enabled reports whether missed runs should be executed
+func (c Catchup) Enabled() bool {
+	return c.Mode == CatchupLast || c.Mode == CatchupAll
+}
+
+// ParseCatchup parses a catch-up policy: none, last, all or all:N.
+// An empty value selects CatchupNone.
+func ParseCatchup(value string) (Catchup, error) {
+	mode, limit, hasLimit := strings.Cut(strings.ToLower(strings.TrimSpace(value)), ":")
+	switch CatchupMode(mode) {
+	case "", CatchupNone:
+		if !hasLimit {
+			return Catchup{Mode: CatchupNone}, nil
+		}
+	case CatchupLast:
+		if !hasLimit {
+			return Catchup{Mode: CatchupLast, Limit: 1}, nil
+		}
+	case CatchupAll:
+		if !hasLimit {
+			return Catchup{Mode: CatchupAll, Limit: DefaultCatchupLimit}, nil
+		}
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return Catchup{}, fmt.Errorf("invalid catchup limit '%s' (must be a positive number)", limit)
+		}
+		return Catchup{Mode: CatchupAll, Limit: n}, nil
+	}
+	return Catchup{}, fmt.Errorf("invalid catchup policy '%s' (supported: none, last, all, all:N)", value)
+}
+
+// missedRuns returns the fire times of schedule after last and up to now that
+// the policy wants executed, oldest first
+func missedRuns(schedule cron.Schedule, last, now time.Time, policy Catchup) []time.Time {
+	if !policy.Enabled() || policy.Limit < 1 {
+		return nil
+	}
+
+	// Keep only the most recent fire times, the oldest are the most stale
+	var missed []time.Time
+	for t := schedule.Next(last); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+		missed = append(missed, t)
+		if len(missed) > policy.Limit {
+			missed = missed[1:]
+		}
+	}
+	return missed
+}
+
+// catchUp executes the runs each scheduled task missed since it last fired.
+// It is called once the scheduler has started: each task catches up in its
+// own goroutine, its missed runs one after the other through the entry's
+// overlap policy, so slow runs don't hold up the schedule or other tasks and
+// downstream tasks are triggered as usual. Tasks that have never fired get
+// the current time as their baseline so that later downtime can be detected.
+// The returned channel is closed once every missed run has finished.
+func (s *Service) catchUp(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	if s.state == nil {
+		close(done)
+		return done
+	}
+
+	s.mu.Lock()
+	entries := make([]EntryMetadata, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	s.mu.Unlock()
+
+	now := time.Now()
+	planned := make(map[string]bool) // identical lines share their task ID and state
+	var wg sync.WaitGroup
+	for _, entry := range entries {
+		task := entry.Task
+		if planned[task.ID()] {
+			continue
+		}
+		planned[task.ID()] = true
+
+		last, ok := s.state.LastRun(task.ID())
+		if !ok {
+			if task.Catchup.Enabled() {
+				s.setLastRun(task, now)
+			}
+			continue
+		}
+
+		schedule, err := parseSchedule(task.Schedule)
+		if err != nil {
+			continue
+		}
+
+		missed := missedRuns(schedule, last, now, task.Catchup)
+		if len(missed) == 0 {
+			continue
+		}
+
+		log.Info("Catching up on missed runs", logger.Fields{
+			"task":      task.ID(),
+			"prompt":    task.Prompt,
+			"catchup":   task.Catchup.String(),
+			"last_run":  last.Format(time.RFC3339),
+			"run_count": len(missed),
+		})
+
+		wg.Add(1)
+		go func(wrap overlapWrapper) {
+			defer wg.Done()
+			for _, fireTime := range missed {
+				if ctx.Err() != nil {
+					return
+				}
+				wrap(history.SourceCatchup, func(runCtx context.Context) {
+					if !s.claimRun(task, history.SourceCatchup, fireTime) {
+						return
+					}
+					log.Info("Running missed task execution", logger.Fields{
+						"task":      task.ID(),
+						"prompt":    task.Prompt,
+						"fire_time": fireTime.Format(time.RFC3339),
+					})
+					if !s.skipPaused(task, history.SourceCatchup) && !s.skipBlackout(task, history.SourceCatchup, fireTime) {
+						_ = s.executeTask(runCtx, task, history.SourceCatchup, nil) //nolint:errcheck // failures are logged and recorded in history
+					}
+				}).Run()
+				s.advanceLastRun(task, fireTime)
+			}
+		}(entry.wrap)
+	}
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// advanceLastRun persists that a task fired at a missed fire time, unless
+// the schedule has fired it since
+func (s *Service) advanceLastRun(task Task, at time.Time) {
+	if s.state == nil {
+		return
+	}
+	if err := s.state.AdvanceLastRun(task.ID(), at); err != nil {
+		log.Warn("Failed to persist task state", logger.Fields{
+			"task":  task.ID(),
+			"error": err.Error(),
+		})
+	}
+}
+
+// setLastRun persists when a task last fired, logging failures
+func (s *Service) setLastRun(task Task, at time.Time) {
+	if s.state == nil {
+		return
+	}
+	if err := s.state.SetLastRun(task.ID(), at); err != nil {
+		log.Warn("Failed to persist task state", logger.Fields{
+			"task":  task.ID(),
+			"error": err.Error(),
+		})
+	}
+}
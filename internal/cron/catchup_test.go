@@ -0,0 +1,216 @@
+package cron
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/processor"
+	"github.com/rshade/cronai/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCatchup(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Catchup
+		wantErr  bool
+	}{
+		{"", Catchup{Mode: CatchupNone}, false},
+		{"none", Catchup{Mode: CatchupNone}, false},
+		{"last", Catchup{Mode: CatchupLast, Limit: 1}, false},
+		{"all", Catchup{Mode: CatchupAll, Limit: DefaultCatchupLimit}, false},
+		{"all:3", Catchup{Mode: CatchupAll, Limit: 3}, false},
+		{"all:0", Catchup{}, true},
+		{"all:many", Catchup{}, true},
+		{"last:2", Catchup{}, true},
+		{"first", Catchup{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			policy, err := ParseCatchup(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy)
+		})
+	}
+}
+
+func TestParseConfigLineCatchup(t *testing.T) {
+	task, err := parseConfigLine("0 8 * * * openai product_manager console catchup=all:5,overlap=skip")
+	require.NoError(t, err)
+	assert.Equal(t, Catchup{Mode: CatchupAll, Limit: 5}, task.Task.Catchup)
+	assert.Equal(t, OverlapSkip, task.Task.Overlap)
+	assert.Nil(t, task.Task.Variables)
+
+	_, err = parseConfigLine("0 8 * * * openai product_manager console catchup=sometimes")
+	assert.Error(t, err)
+}
+
+func TestMissedRuns(t *testing.T) {
+	schedule, err := cron.ParseStandard("0 8 * * *")
+	require.NoError(t, err)
+
+	last := time.Date(2026, 10, 10, 8, 0, 5, 0, time.Local)
+	now := time.Date(2026, 10, 13, 9, 0, 0, 0, time.Local)
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 8, 0, 0, 0, time.Local) }
+
+	assert.Empty(t, missedRuns(schedule, last, now, Catchup{Mode: CatchupNone}))
+	assert.Equal(t, []time.Time{day(13)}, missedRuns(schedule, last, now, Catchup{Mode: CatchupLast, Limit: 1}))
+	assert.Equal(t, []time.Time{day(11), day(12), day(13)}, missedRuns(schedule, last, now, Catchup{Mode: CatchupAll, Limit: 10}))
+	assert.Equal(t, []time.Time{day(12), day(13)}, missedRuns(schedule, last, now, Catchup{Mode: CatchupAll, Limit: 2}))
+
+	// Nothing was missed when the last run is the latest fire time
+	assert.Empty(t, missedRuns(schedule, day(13), now, Catchup{Mode: CatchupAll, Limit: 10}))
+}
+
+func TestStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+
+	state, err := loadState(path)
+	require.NoError(t, err)
+	_, ok := state.LastRun("task")
+	assert.False(t, ok)
+
+	at := time.Date(2026, 10, 13, 8, 0, 0, 0, time.UTC)
+	require.NoError(t, state.SetLastRun("task", at))
+
+	reloaded, err := loadState(path)
+	require.NoError(t, err)
+	last, ok := reloaded.LastRun("task")
+	require.True(t, ok)
+	assert.True(t, at.Equal(last))
+}
+
+func TestCatchUp(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("product_manager", "Write the report")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	processor.GetRegistry().RegisterFactory("console", func(_ processor.Config) (processor.Processor, error) {
+		return &mockProcessor{}, nil
+	})
+
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		return &models.ModelResponse{Content: "report", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	service := NewCronService("test.config", WithStatePath(filepath.Join(t.TempDir(), "state.json")))
+	var err error
+	service.state, err = loadState(service.statePath)
+	require.NoError(t, err)
+
+	daily := Task{Schedule: "0 8 * * *", Model: "openai", Prompt: "product_manager", Processor: "console",
+		Catchup: Catchup{Mode: CatchupAll, Limit: 3}}
+	neverCatchup := Task{Schedule: "0 9 * * *", Model: "openai", Prompt: "product_manager", Processor: "console"}
+	fresh := Task{Schedule: "0 10 * * *", Model: "openai", Prompt: "product_manager", Processor: "console",
+		Catchup: Catchup{Mode: CatchupLast, Limit: 1}}
+
+	// Both tasks last fired five days ago
+	last := time.Now().AddDate(0, 0, -5)
+	require.NoError(t, service.state.SetLastRun(daily.ID(), last))
+	require.NoError(t, service.state.SetLastRun(neverCatchup.ID(), last))
+
+	service.scheduler = newScheduler()
+	service.applyTasks([]Task{daily, neverCatchup, fresh})
+	<-service.catchUp(context.Background())
+
+	// Only the three most recent missed runs of the opted-in task are executed
+	records, err := store.List(history.Filter{Source: history.SourceCatchup})
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+	for _, r := range records {
+		assert.Equal(t, daily.ID(), r.Task)
+	}
+
+	// The last run advances to the latest missed fire time
+	newLast, ok := service.state.LastRun(daily.ID())
+	require.True(t, ok)
+	assert.True(t, newLast.After(last))
+	assert.Equal(t, 8, newLast.Hour())
+
+	// A task that never fired gets a baseline instead of running
+	_, ok = service.state.LastRun(fresh.ID())
+	assert.True(t, ok)
+}
+
+func TestCatchUpThroughEntries(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("collect", "Collect")
+	mockPM.SetPrompt("summary", "Summarize")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	processor.GetRegistry().RegisterFactory("console", func(_ processor.Config) (processor.Processor, error) {
+		return &mockProcessor{}, nil
+	})
+
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		return &models.ModelResponse{Content: "collected", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	service := NewCronService("test.config", WithStatePath(filepath.Join(t.TempDir(), "state.json")))
+	var err error
+	service.state, err = loadState(service.statePath)
+	require.NoError(t, err)
+
+	collect := Task{Name: "collect", Schedule: "0 8 * * *", Model: "openai", Prompt: "collect", Processor: "console",
+		Overlap: OverlapSkip, Catchup: Catchup{Mode: CatchupLast, Limit: 1}}
+	summary := Task{Schedule: ScheduleAfter, Model: "claude", Prompt: "summary", Processor: "console", After: "collect"}
+	service.scheduler = newScheduler()
+	service.applyTasks([]Task{collect, summary})
+
+	// A caught-up run triggers the tasks that run after it
+	last := time.Now().AddDate(0, 0, -2)
+	require.NoError(t, service.state.SetLastRun(collect.ID(), last))
+	<-service.catchUp(context.Background())
+	require.Eventually(t, func() bool {
+		records, err := store.List(history.Filter{Source: history.SourceDependency})
+		require.NoError(t, err)
+		return len(records) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// A missed run is skipped while a scheduled run is in progress
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	var runs, cancelled int32
+	go service.entries[taskKey(collect)].wrap(history.SourceCron, blockingJob(started, release, &runs, &cancelled)).Run()
+	<-started
+
+	require.NoError(t, service.state.SetLastRun(collect.ID(), last))
+	<-service.catchUp(context.Background())
+	records, err := store.List(history.Filter{Source: history.SourceCatchup})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, history.StatusSkipped, records[0].Status)
+
+	// The last run never moves back to a missed fire time
+	now := time.Now()
+	require.NoError(t, service.state.SetLastRun(collect.ID(), now))
+	require.NoError(t, service.state.AdvanceLastRun(collect.ID(), last))
+	newLast, _ := service.state.LastRun(collect.ID())
+	assert.True(t, newLast.Equal(now))
+}
@@ -0,0 +1,490 @@
+package cron
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/kballard/go-shellquote"
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// Check types
+const (
+	CheckCommand = "command" // passes when a command exits with one of the expected codes
+	CheckHTTP    = "http"    // passes when a URL answers with an expected status and body
+	CheckChanged = "changed" // passes when a file changed since the task's last run
+)
+
+// EnvCheckCommands lists, comma-separated, the programs command checks may
+// run. It comes from the environment rather than the configuration so that
+// included files and environment references can't widen it.
+const EnvCheckCommands = "CRONAI_CHECK_COMMANDS"
+
+// DefaultCheckTimeout limits checks that don't set their own timeout
+const DefaultCheckTimeout = 30 * time.Second
+
+// maxCheckOutput caps the probe output kept for the prompt
+const maxCheckOutput = 16 * 1024
+
+// checkClient sends the requests of http checks
+var checkClient = &http.Client{}
+
+// Checks holds the preconditions of a configuration file
+type Checks struct {
+	byName map[string]*check
+	digest string // fingerprint of every check, so a reload notices check changes
+}
+
+// check is a precondition evaluated before a task calls its model
+type check struct {
+	config.CheckConfig
+	exit    codeRanges
+	status  codeRanges
+	match   *regexp.Regexp
+	timeout time.Duration
+}
+
+// checkResult is the outcome of running a check
+type checkResult struct {
+	output string // probe output, exposed to the prompt as check_<name>
+	reason string // why the check didn't pass, empty when it passed
+	stamp  string // file stamp of a changed check, stored once every check passed
+}
+
+// codeRanges is a set of exit or status codes such as 0 or 500-599
+type codeRanges [][2]int
+
+// parseCodes parses comma-separated codes and ranges
+func parseCodes(value string) (codeRanges, error) {
+	var codes codeRanges
+	for _, part := range strings.Split(value, ",") {
+		low, high, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		first, err := strconv.Atoi(low)
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(high)
+		}
+		if err != nil || first < 0 || last < first {
+			return nil, fmt.Errorf("invalid codes '%s' (use codes and ranges such as 0 or 500-599)", value)
+		}
+		codes = append(codes, [2]int{first, last})
+	}
+	return codes, nil
+}
+
+// contains reports whether code is in the set
+func (c codeRanges) contains(code int) bool {
+	for _, r := range c {
+		if code >= r[0] && code <= r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// newCheck validates a check definition. Relative paths of changed checks are
+// relative to dir.
+func newCheck(dir string, checkConfig config.CheckConfig) (*check, error) {
+	c := &check{CheckConfig: checkConfig, timeout: DefaultCheckTimeout}
+	if err := validateTaskName(c.Name); err != nil {
+		return nil, fmt.Errorf("invalid check name: %v", err)
+	}
+
+	var err error
+	unused := func(options map[string]string) error {
+		for key, value := range options {
+			if value != "" {
+				return fmt.Errorf("%s doesn't apply to %s checks", key, c.Type)
+			}
+		}
+		return nil
+	}
+	switch c.Type {
+	case CheckCommand:
+		if len(c.Command) == 0 {
+			return nil, fmt.Errorf("command is required")
+		}
+		if err = unused(map[string]string{"url": c.URL, "status": c.Status, "match": c.Match, "path": c.Path}); err != nil {
+			return nil, err
+		}
+		if !commandAllowed(c.Command[0]) {
+			return nil, fmt.Errorf("program '%s' isn't allowed to run (add it to %s)", c.Command[0], EnvCheckCommands)
+		}
+		if c.exit, err = parseCodes(firstNonEmpty(c.Exit, "0")); err != nil {
+			return nil, err
+		}
+	case CheckHTTP:
+		if err = unused(map[string]string{"command": strings.Join(c.Command, " "), "exit": c.Exit, "path": c.Path}); err != nil {
+			return nil, err
+		}
+		if u, parseErr := url.Parse(c.URL); parseErr != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid url '%s'", c.URL)
+		}
+		if c.status, err = parseCodes(firstNonEmpty(c.Status, "200-299")); err != nil {
+			return nil, err
+		}
+		if c.Match != "" {
+			if c.match, err = regexp.Compile(c.Match); err != nil {
+				return nil, fmt.Errorf("invalid match: %v", err)
+			}
+		}
+	case CheckChanged:
+		if c.Path == "" {
+			return nil, fmt.Errorf("path is required")
+		}
+		if err = unused(map[string]string{"command": strings.Join(c.Command, " "), "exit": c.Exit, "url": c.URL, "status": c.Status, "match": c.Match}); err != nil {
+			return nil, err
+		}
+		if !filepath.IsAbs(c.Path) {
+			c.Path = filepath.Join(dir, c.Path)
+		}
+	default:
+		return nil, fmt.Errorf("unknown check type '%s' (supported: command, http, changed)", c.Type)
+	}
+
+	if c.Timeout != "" {
+		if c.timeout, err = ParseTimeout(c.Timeout); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// commandAllowed reports whether program is listed in EnvCheckCommands.
+// Programs match as written, so list absolute paths to keep PATH from
+// choosing what runs.
+func commandAllowed(program string) bool {
+	for _, allowed := range strings.Split(os.Getenv(EnvCheckCommands), ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && filepath.Clean(allowed) == filepath.Clean(program) {
+			return true
+		}
+	}
+	return false
+}
+
+// programFromEnv reports whether the program of a command check defined on
+// configLine comes from an environment reference, comparing the check with
+// the one the line defines as written
+func programFromEnv(configLine ConfigLine, checkConfig *config.CheckConfig) bool {
+	if checkConfig.Type != CheckCommand || configLine.expansions == nil {
+		return false
+	}
+	written, err := ParseCheckLine(configLine.raw)
+	return err != nil || written == nil || len(written.Command) == 0 || written.Command[0] != checkConfig.Command[0]
+}
+
+// loadChecks validates the checks of a configuration file
+func loadChecks(dir string, checkConfigs []config.CheckConfig) (*Checks, error) {
+	checks := &Checks{byName: make(map[string]*check)}
+	var loadErrors *multierror.Error
+
+	for _, checkConfig := range checkConfigs {
+		if _, exists := checks.byName[checkConfig.Name]; exists {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: duplicate check '%s'", location{checkConfig.File, checkConfig.Line}, checkConfig.Name))
+			continue
+		}
+		c, err := newCheck(dir, checkConfig)
+		if err != nil {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: check %s: %v", location{checkConfig.File, checkConfig.Line}, checkConfig.Name, err))
+			continue
+		}
+		checks.byName[c.Name] = c
+	}
+
+	checks.digest = checks.fingerprint()
+	return checks, loadErrors.ErrorOrNil()
+}
+
+// resolveChecks validates the checks of a configuration file and attaches
+// them to the tasks that use them. lines holds where each task is defined.
+func resolveChecks(dir string, checkConfigs []config.CheckConfig, tasks []Task, lines []location) error {
+	checks, err := loadChecks(dir, checkConfigs)
+	var resolveErrors *multierror.Error
+	if err != nil {
+		resolveErrors = multierror.Append(resolveErrors, err)
+	}
+	for i := range tasks {
+		if err := checks.attach(&tasks[i]); err != nil {
+			resolveErrors = multierror.Append(resolveErrors, fmt.Errorf("%s: %v", lines[i], err))
+		}
+	}
+	return resolveErrors.ErrorOrNil()
+}
+
+// fingerprint hashes every check definition
+func (c *Checks) fingerprint() string {
+	names := make([]string, 0, len(c.byName))
+	for name := range c.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		ch := c.byName[name]
+		fmt.Fprintf(&b, "%s:%s:%q:%s:%s:%s:%s:%s:%s\n", name, ch.Type, ch.Command, ch.Exit, ch.URL, ch.Status, ch.Match, ch.Path, ch.timeout)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// attach checks the checks a task refers to and gives the task access to them
+func (c *Checks) attach(task *Task) error {
+	for _, name := range task.When {
+		if _, ok := c.byName[name]; !ok {
+			return fmt.Errorf("unknown check '%s'", name)
+		}
+	}
+	if len(task.When) > 0 {
+		task.checks = c
+	}
+	return nil
+}
+
+// evaluateChecks runs the task's checks in order. It returns the output of
+// each probe as a check_<name> variable, the file stamps the changed checks
+// saw and, when a check doesn't pass, the reason the model must not be
+// called. The stamps are only saved, with saveCheckStamps, once the run
+// succeeds, so a change isn't lost to a run another check stopped or that
+// failed later. Without persisted state changed checks always pass.
+func (s *Service) evaluateChecks(ctx context.Context, task Task) (variables, stamps map[string]string, reason string, err error) {
+	if task.checks == nil {
+		return nil, nil, "", nil
+	}
+
+	variables = make(map[string]string, len(task.When))
+	stamps = make(map[string]string)
+	for _, name := range task.When {
+		c := task.checks.byName[name]
+		previous := ""
+		if s.state != nil {
+			previous = s.state.CheckStamp(task.ID(), name)
+		}
+		result, err := c.run(ctx, previous)
+		if err != nil {
+			return nil, nil, "", fmt.Errorf("check %s: %w", name, err)
+		}
+		if result.reason != "" && (c.Type != CheckChanged || s.state != nil) {
+			return nil, nil, fmt.Sprintf("check %s not met: %s", name, result.reason), nil
+		}
+		variables["check_"+name] = result.output
+		if c.Type == CheckChanged {
+			stamps[name] = result.stamp
+		}
+	}
+	return variables, stamps, "", nil
+}
+
+// saveCheckStamps remembers the file stamps the task's changed checks saw,
+// so its next run only happens once the files change again
+func (s *Service) saveCheckStamps(task Task, stamps map[string]string) error {
+	if s.state == nil {
+		return nil
+	}
+	for name, stamp := range stamps {
+		if err := s.state.SetCheckStamp(task.ID(), name, stamp); err != nil {
+			return errors.Wrap(errors.CategorySystem, err, "failed to persist task state")
+		}
+	}
+	return nil
+}
+
+// run evaluates the check. previous is the file stamp a changed check saw on
+// the task's last run. Errors mean the check itself couldn't run.
+func (c *check) run(ctx context.Context, previous string) (checkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	switch c.Type {
+	case CheckCommand:
+		return c.runCommand(ctx)
+	case CheckHTTP:
+		return c.runHTTP(ctx)
+	default:
+		return c.runChanged(previous)
+	}
+}
+
+// runCommand runs the command without a shell and compares its exit code
+func (c *check) runCommand(ctx context.Context) (checkResult, error) {
+	var output cappedBuffer
+	cmd := exec.CommandContext(ctx, c.Command[0], c.Command[1:]...)
+	cmd.Stdout, cmd.Stderr = &output, &output
+
+	err := cmd.Run()
+	if ctx.Err() != nil {
+		return checkResult{}, fmt.Errorf("command didn't finish within %s: %w", c.timeout, ctx.Err())
+	}
+	code := 0
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	case err != nil:
+		return checkResult{}, err
+	}
+
+	result := checkResult{output: strings.TrimSpace(output.String())}
+	if !c.exit.contains(code) {
+		result.reason = fmt.Sprintf("exit code %d", code)
+	}
+	return result, nil
+}
+
+// runHTTP requests the URL and compares the status and body. A server that
+// can't be reached answers with status 0.
+func (c *check) runHTTP(ctx context.Context) (checkResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return checkResult{}, err
+	}
+
+	var result checkResult
+	status := 0
+	resp, err := checkClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return checkResult{}, fmt.Errorf("no response within %s: %w", c.timeout, ctx.Err())
+		}
+		result.output = err.Error()
+	} else {
+		defer func() { _ = resp.Body.Close() }() //nolint:errcheck // the body has been read
+		body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxCheckOutput))
+		if readErr != nil {
+			return checkResult{}, fmt.Errorf("failed to read response: %w", readErr)
+		}
+		status = resp.StatusCode
+		result.output = strings.TrimSpace(string(body))
+	}
+
+	switch {
+	case !c.status.contains(status):
+		result.reason = fmt.Sprintf("status %d", status)
+	case c.match != nil && !c.match.MatchString(result.output):
+		result.reason = fmt.Sprintf("response doesn't match '%s'", c.Match)
+	}
+	return result, nil
+}
+
+// runChanged compares the file's modification time and size with the stamp
+// of the last run. A file that doesn't exist has an empty stamp, so it counts
+// as changed when it appears or disappears.
+func (c *check) runChanged(previous string) (checkResult, error) {
+	result := checkResult{output: c.Path + " doesn't exist"}
+	info, err := os.Stat(c.Path)
+	switch {
+	case err == nil:
+		result.stamp = fmt.Sprintf("%d:%d", info.ModTime().UnixNano(), info.Size())
+		result.output = fmt.Sprintf("%s modified %s, %d bytes", c.Path, info.ModTime().Format(time.RFC3339), info.Size())
+	case !os.IsNotExist(err):
+		return checkResult{}, err
+	}
+
+	if result.stamp == previous {
+		result.reason = c.Path + " unchanged since the last run"
+	}
+	return result, nil
+}
+
+// cappedBuffer keeps the first maxCheckOutput bytes written to it
+type cappedBuffer struct {
+	strings.Builder
+}
+
+// Write implements io.Writer, dropping what exceeds the cap
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := maxCheckOutput - b.Len(); room > 0 {
+		if len(p) > room {
+			_, _ = b.Builder.Write(p[:room])
+		} else {
+			_, _ = b.Builder.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// ParseCheckLine parses a check definition of the line format:
+//
+//	check <name> command [exit=<codes>] [timeout=<duration>] <program> [args...]
+//	check <name> http <url> [status=<codes>] [match=<regexp>] [timeout=<duration>]
+//	check <name> changed <path>
+//
+// Values are split like a shell would, so quote arguments and patterns that
+// contain spaces. Commands run without a shell. It returns nil for any other line.
+func ParseCheckLine(line string) (*config.CheckConfig, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "check" {
+		return nil, nil
+	}
+	parts, err := shellquote.Split(line)
+	if err != nil {
+		return nil, fmt.Errorf("invalid check definition: %v", err)
+	}
+	if len(parts) < 4 {
+		return nil, fmt.Errorf("invalid check definition (use: check <name> command|http|changed <target> [options])")
+	}
+
+	checkConfig := &config.CheckConfig{Name: parts[1], Type: parts[2]}
+	options := map[string]*string{"timeout": &checkConfig.Timeout}
+	switch checkConfig.Type {
+	case CheckCommand:
+		options["exit"] = &checkConfig.Exit
+	case CheckHTTP:
+		options["status"] = &checkConfig.Status
+		options["match"] = &checkConfig.Match
+	case CheckChanged:
+		delete(options, "timeout")
+	default:
+		return nil, fmt.Errorf("unknown check type '%s' (supported: command, http, changed)", checkConfig.Type)
+	}
+
+	var positional []string
+	for i, part := range parts[3:] {
+		key, value, _ := strings.Cut(part, "=")
+		option, ok := options[key]
+		if !ok {
+			if checkConfig.Type == CheckCommand {
+				// Options come before the program, everything after it is an argument
+				positional = parts[3+i:]
+				break
+			}
+			positional = append(positional, part)
+			continue
+		}
+		*option = value
+	}
+
+	switch checkConfig.Type {
+	case CheckCommand:
+		if len(positional) == 0 {
+			return nil, fmt.Errorf("a command check needs a program to run")
+		}
+		checkConfig.Command = positional
+	case CheckHTTP:
+		if len(positional) != 1 {
+			return nil, fmt.Errorf("an http check needs a single url")
+		}
+		checkConfig.URL = positional[0]
+	default:
+		if len(positional) != 1 {
+			return nil, fmt.Errorf("a changed check needs a single path")
+		}
+		checkConfig.Path = positional[0]
+	}
+	return checkConfig, nil
+}
@@ -0,0 +1,358 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCheckLine(t *testing.T) {
+	checkConfig, err := ParseCheckLine(`check disk_full command exit=1,2 timeout=5s /usr/bin/check_disk -w "10 %" exit=3`)
+	require.NoError(t, err)
+	assert.Equal(t, config.CheckConfig{
+		Name: "disk_full", Type: CheckCommand, Exit: "1,2", Timeout: "5s",
+		Command: []string{"/usr/bin/check_disk", "-w", "10 %", "exit=3"},
+	}, *checkConfig)
+
+	checkConfig, err = ParseCheckLine(`check api http status=0,500-599 https://example.com/health?verbose=1 "match=degraded|down"`)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/health?verbose=1", checkConfig.URL)
+	assert.Equal(t, "0,500-599", checkConfig.Status)
+	assert.Equal(t, "degraded|down", checkConfig.Match)
+
+	checkConfig, err = ParseCheckLine("check errors changed /var/log/app/errors.log")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/log/app/errors.log", checkConfig.Path)
+
+	checkConfig, err = ParseCheckLine("0 8 * * * openai check console")
+	assert.NoError(t, err)
+	assert.Nil(t, checkConfig)
+
+	for _, line := range []string{
+		"check api",
+		"check api ping example.com",
+		"check api http https://a https://b",
+		"check api command exit=1",
+		`check api command "unterminated`,
+	} {
+		_, err := ParseCheckLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestParseCodes(t *testing.T) {
+	codes, err := parseCodes("0, 500-599")
+	require.NoError(t, err)
+	assert.True(t, codes.contains(0))
+	assert.True(t, codes.contains(503))
+	assert.False(t, codes.contains(200))
+
+	for _, value := range []string{"", "x", "5-1", "-1"} {
+		_, err := parseCodes(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestLoadChecksValidation(t *testing.T) {
+	t.Setenv(EnvCheckCommands, "true")
+	_, err := loadChecks(".", []config.CheckConfig{
+		{Name: "ok", Type: CheckCommand, Command: []string{"true"}},
+		{Name: "ok", Type: CheckCommand, Command: []string{"true"}, Line: 2},
+		{Name: "url", Type: CheckHTTP, URL: "example.com", Line: 3},
+		{Name: "mixed", Type: CheckChanged, Path: "a.log", Exit: "1", Line: 4},
+		{Name: "pattern", Type: CheckHTTP, URL: "http://example.com", Match: "(", Line: 5},
+		{Name: "kind", Type: "ping", Line: 6},
+		{Name: "shell", Type: CheckCommand, Command: []string{"sh", "-c", "true"}, Line: 7},
+	})
+	require.Error(t, err)
+	for _, message := range []string{
+		"line 2: duplicate check 'ok'",
+		"line 3: check url: invalid url",
+		"line 4: check mixed: exit doesn't apply to changed checks",
+		"line 5: check pattern: invalid match",
+		"line 6: check kind: unknown check type",
+		"line 7: check shell: program 'sh' isn't allowed to run (add it to CRONAI_CHECK_COMMANDS)",
+	} {
+		assert.Contains(t, err.Error(), message)
+	}
+}
+
+func TestParseConfigFileChecks(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	configPath := writeCalendarConfig(t, `check down http http://localhost:9/health status=0,500-599
+check errors changed errors.log
+0 9 * * * openai test_prompt console name=health,when=down+errors,team=ops
+0 9 * * * openai test_prompt console name=report
+`)
+	tasks, err := parseConfigFile(configPath)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, []string{"down", "errors"}, tasks[0].When)
+	assert.Equal(t, map[string]string{"team": "ops"}, tasks[0].Variables)
+	require.NotNil(t, tasks[0].checks)
+	assert.Equal(t, filepath.Join(filepath.Dir(configPath), "errors.log"), tasks[0].checks.byName["errors"].Path)
+	assert.Nil(t, tasks[1].checks)
+
+	_, err = parseConfigFile(writeCalendarConfig(t, "0 9 * * * openai test_prompt console when=missing\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1: unknown check 'missing'")
+
+	t.Setenv(EnvCheckCommands, "/usr/bin/check_disk, /usr/lib/nagios/plugins/check_load")
+
+	tasks, err = parseConfigFile(writeYAMLConfig(t, `checks:
+  - name: disk_full
+    type: command
+    command: [/usr/bin/check_disk, -w, 10%]
+    exit: 1
+tasks:
+  - name: health
+    schedule: "*/10 * * * *"
+    model: openai
+    prompt: test_prompt
+    processors: [console]
+    when: [disk_full]
+`))
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, []string{"disk_full"}, tasks[0].When)
+	require.NotNil(t, tasks[0].checks)
+	assert.Equal(t, []string{"/usr/bin/check_disk", "-w", "10%"}, tasks[0].checks.byName["disk_full"].Command)
+}
+
+func TestCheckCommandAllowlist(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+	t.Setenv(EnvCheckCommands, "/usr/bin/check_disk")
+	t.Setenv("CHECK_PROGRAM", "/usr/bin/check_disk")
+	t.Setenv("CHECK_LIMIT", "10%")
+
+	// Listed programs run, and their arguments may come from the environment
+	tasks, err := parseConfigFile(writeCalendarConfig(t, `check disk_full command exit=1 /usr/bin/check_disk -w ${CHECK_LIMIT}
+0 9 * * * openai test_prompt console when=disk_full
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/usr/bin/check_disk", "-w", "10%"}, tasks[0].checks.byName["disk_full"].Command)
+
+	// Other programs are rejected, in included files too
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "checks.config"), []byte("check shell command sh -c true\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cronai.config"), []byte("include checks.config\n0 9 * * * openai test_prompt console when=shell\n"), 0644))
+	_, err = parseConfigFile(filepath.Join(dir, "cronai.config"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checks.config: line 1: check shell: program 'sh' isn't allowed to run (add it to CRONAI_CHECK_COMMANDS)")
+
+	_, err = parseConfigFile(writeYAMLConfig(t, `checks:
+  - name: load
+    type: command
+    command: [/usr/bin/uptime]
+tasks:
+  - schedule: "0 9 * * *"
+    model: openai
+    prompt: test_prompt
+    processors: [console]
+    when: [load]
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "check load: program '/usr/bin/uptime' isn't allowed to run")
+
+	// The program can't come from the environment, even when it is listed
+	_, err = parseConfigFile(writeCalendarConfig(t, `check disk_full command exit=1 ${CHECK_PROGRAM} -w 10%
+0 9 * * * openai test_prompt console when=disk_full
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1: check disk_full: the program of a command check can't come from an environment variable")
+}
+
+func TestCommandCheck(t *testing.T) {
+	t.Setenv(EnvCheckCommands, "sh")
+	c, err := newCheck(".", config.CheckConfig{Name: "disk", Type: CheckCommand, Exit: "1", Command: []string{"sh", "-c", "echo 91% used; exit 1"}})
+	require.NoError(t, err)
+	result, err := c.run(context.Background(), "")
+	require.NoError(t, err)
+	assert.Empty(t, result.reason)
+	assert.Equal(t, "91% used", result.output)
+
+	c.Command = []string{"sh", "-c", "exit 0"}
+	result, err = c.run(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "exit code 0", result.reason)
+
+	c.Command = []string{"sleep", "5"}
+	c.timeout = 50 * time.Millisecond
+	_, err = c.run(context.Background(), "")
+	assert.Error(t, err)
+
+	c.Command = []string{filepath.Join(t.TempDir(), "missing")}
+	_, err = c.run(context.Background(), "")
+	assert.Error(t, err)
+}
+
+func TestHTTPCheck(t *testing.T) {
+	status, body := http.StatusServiceUnavailable, "queue degraded"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(status)
+		_, _ = fmt.Fprint(w, body)
+	}))
+	defer server.Close()
+
+	c, err := newCheck(".", config.CheckConfig{Name: "api", Type: CheckHTTP, URL: server.URL, Status: "0,500-599", Match: "degraded"})
+	require.NoError(t, err)
+	result, err := c.run(context.Background(), "")
+	require.NoError(t, err)
+	assert.Empty(t, result.reason)
+	assert.Equal(t, "queue degraded", result.output)
+
+	body = "queue down"
+	result, err = c.run(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "response doesn't match 'degraded'", result.reason)
+
+	status = http.StatusOK
+	result, err = c.run(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, "status 200", result.reason)
+
+	// An unreachable server answers with status 0
+	server.Close()
+	c.match = nil
+	result, err = c.run(context.Background(), "")
+	require.NoError(t, err)
+	assert.Empty(t, result.reason)
+	assert.NotEmpty(t, result.output)
+}
+
+func TestRunTaskChecks(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("health", "Investigate")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	var received []map[string]string
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+		received = append(received, variables)
+		return &models.ModelResponse{Content: "ok", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	t.Setenv(EnvCheckCommands, "sh")
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "errors.log")
+	require.NoError(t, os.WriteFile(logPath, []byte("error\n"), 0644))
+	failing := filepath.Join(dir, "failing")
+
+	service := NewCronService("test.config", WithStatePath(filepath.Join(dir, "state.json")))
+	var err error
+	service.state, err = loadState(service.statePath)
+	require.NoError(t, err)
+
+	task := Task{Name: "health", Schedule: "* * * * *", Model: "openai", Prompt: "health", Processor: "console",
+		When: []string{"failing", "errors"}}
+	checks, err := loadChecks(dir, []config.CheckConfig{
+		{Name: "failing", Type: CheckCommand, Command: []string{"sh", "-c", "cat " + failing + " && exit 1 || exit 0"}, Exit: "1"},
+		{Name: "errors", Type: CheckChanged, Path: "errors.log"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, checks.attach(&task))
+	run := func() *history.Record {
+		record, err := service.runTask(context.Background(), task, history.SourceCron, nil)
+		require.NoError(t, err)
+		return record
+	}
+
+	// The command check stops the run, so the log change isn't used up
+	record := run()
+	assert.Equal(t, history.StatusSkipped, record.Status)
+	assert.Equal(t, "check failing not met: exit code 0", record.Reason)
+	assert.Empty(t, received)
+
+	require.NoError(t, os.WriteFile(failing, []byte("disk full"), 0644))
+	record = run()
+	assert.Equal(t, history.StatusSuccess, record.Status)
+	require.Len(t, received, 1)
+	assert.Equal(t, "disk full", received[0]["check_failing"])
+	assert.Contains(t, received[0]["check_errors"], "errors.log modified")
+
+	// The log didn't change since the last run
+	record = run()
+	assert.Equal(t, history.StatusSkipped, record.Status)
+	assert.Contains(t, record.Reason, "check errors not met: ")
+	assert.Len(t, received, 1)
+
+	// A check that can't run fails the execution
+	task.checks.byName["failing"].Command = []string{filepath.Join(dir, "missing")}
+	record, err = service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.Error(t, err)
+	assert.Equal(t, history.StatusFailed, record.Status)
+	assert.Equal(t, history.StageCheck, record.Stage)
+
+	records, err := store.List(history.Filter{Task: "health"})
+	require.NoError(t, err)
+	assert.Len(t, records, 4)
+}
+
+func TestRunTaskChecksFailedRun(t *testing.T) {
+	proc := &flakyProcessor{failures: 1}
+	_, calls := setupRetryTest(t, proc)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "errors.log"), []byte("error\n"), 0644))
+	service := NewCronService("test.config", WithStatePath(filepath.Join(dir, "state.json")))
+	var err error
+	service.state, err = loadState(service.statePath)
+	require.NoError(t, err)
+
+	task := Task{Name: "health", Schedule: "* * * * *", Model: "openai", Prompt: "test", Processor: "console",
+		When: []string{"errors"}}
+	checks, err := loadChecks(dir, []config.CheckConfig{{Name: "errors", Type: CheckChanged, Path: "errors.log"}})
+	require.NoError(t, err)
+	require.NoError(t, checks.attach(&task))
+
+	// The delivery fails, so the change is still new to the next run
+	record, err := service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.Error(t, err)
+	assert.Equal(t, history.StageProcessor, record.Stage)
+	assert.Empty(t, service.state.CheckStamp("health", "errors"))
+
+	record, err = service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.NoError(t, err)
+	assert.Equal(t, history.StatusSuccess, record.Status)
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Once delivered the change counts as seen
+	record, err = service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.NoError(t, err)
+	assert.Equal(t, history.StatusSkipped, record.Status)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestTaskKeyChangesWithChecks(t *testing.T) {
+	task := Task{Name: "health", Schedule: "* * * * *", When: []string{"api"}}
+	first, err := loadChecks(".", []config.CheckConfig{{Name: "api", Type: CheckHTTP, URL: "http://localhost/health"}})
+	require.NoError(t, err)
+	second, err := loadChecks(".", []config.CheckConfig{{Name: "api", Type: CheckHTTP, URL: "http://localhost/status"}})
+	require.NoError(t, err)
+
+	withFirst, withSecond := task, task
+	require.NoError(t, first.attach(&withFirst))
+	require.NoError(t, second.attach(&withSecond))
+	assert.NotEqual(t, taskKey(withFirst), taskKey(withSecond))
+	assert.NotEqual(t, taskDefinition(task), taskDefinition(Task{Name: "health", Schedule: "* * * * *"}))
+}
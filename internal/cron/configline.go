@@ -0,0 +1,428 @@
+package cron
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rshade/cronai/internal/errors"
+)
+
+// escapable are the characters a backslash escapes in a configuration line.
+// A backslash before any other character is kept, so paths such as
+// C:\reports read as written.
+const escapable = `,=\"' `
+
+// ConfigLine is a logical line of a line configuration file. A physical line
+// that ends in a backslash continues on the next one, and the backslash and
+// line break are removed as in a shell.
+type ConfigLine struct {
+	Text string // Text of the line with continuations joined
+	File string // File the line was read from, empty when unknown
+	Line int    // Number of the first physical line
+
+	starts     []lineStart
+	raw        string      // Text before environment references were replaced
+	expansions []expansion // Replaced environment references, in order
+}
+
+// lineStart is where a physical line begins within a logical line
+type lineStart struct {
+	offset int
+	line   int
+}
+
+// ReadConfigLines reads the logical lines of a line configuration file.
+// Comment lines never continue, and a continuation on the last line is
+// dropped.
+func ReadConfigLines(r io.Reader) ([]ConfigLine, error) {
+	var lines []ConfigLine
+	var current *ConfigLine
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		text := scanner.Text()
+		if current == nil {
+			lines = append(lines, ConfigLine{Line: lineNum})
+			current = &lines[len(lines)-1]
+		}
+		current.starts = append(current.starts, lineStart{offset: len(current.Text), line: lineNum})
+
+		continued := false
+		if !strings.HasPrefix(strings.TrimSpace(current.Text+text), "#") {
+			text, continued = trimContinuation(text)
+		}
+		current.Text += text
+		if !continued {
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// envName matches the name of an environment variable
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// expansion is a replaced environment reference, at start:end of a line's
+// text and rawStart:rawEnd of its text as written
+type expansion struct {
+	start, end       int
+	rawStart, rawEnd int
+}
+
+// interpolate replaces references to environment variables, ${NAME} or
+// ${NAME:-default}, with their values. The default is used when the variable
+// is unset or empty, and $${ writes a literal ${. Values are inserted as
+// they are, so a value with commas or spaces must be quoted like any other.
+func (l *ConfigLine) interpolate(lookup func(string) (string, bool)) error {
+	if !strings.Contains(l.Text, "${") || strings.HasPrefix(strings.TrimSpace(l.Text), "#") {
+		return nil
+	}
+
+	raw := l.Text
+	var text strings.Builder
+	var expansions []expansion
+	i := 0
+	for {
+		j := strings.Index(raw[i:], "${")
+		if j < 0 {
+			text.WriteString(raw[i:])
+			break
+		}
+		j += i
+		if j > i && raw[j-1] == '$' {
+			text.WriteString(raw[i : j-1])
+			expansions = append(expansions, expansion{start: text.Len(), end: text.Len() + 2, rawStart: j - 1, rawEnd: j + 2})
+			text.WriteString("${")
+			i = j + 2
+			continue
+		}
+		text.WriteString(raw[i:j])
+
+		end := strings.IndexByte(raw[j:], '}')
+		if end < 0 {
+			return errorAt(raw, j, fmt.Errorf("unterminated ${ reference"))
+		}
+		end += j + 1
+		name, fallback, hasDefault := strings.Cut(raw[j+2:end-1], ":-")
+		if !envName.MatchString(name) {
+			return errorAt(raw, j, fmt.Errorf("invalid environment reference '%s'", raw[j:end]))
+		}
+		value, ok := lookup(name)
+		if hasDefault && value == "" {
+			value, ok = fallback, true
+		}
+		if !ok {
+			return errorAt(raw, j, fmt.Errorf("environment variable %s is not set (use ${%s:-default} to give a default)", name, name))
+		}
+
+		expansions = append(expansions, expansion{start: text.Len(), end: text.Len() + len(value), rawStart: j, rawEnd: end})
+		text.WriteString(value)
+		i = end
+	}
+
+	l.raw, l.Text, l.expansions = raw, text.String(), expansions
+	return nil
+}
+
+// origin returns the offset of the line as written that offset of its text
+// comes from. Offsets within a replaced reference point at the reference.
+func (l ConfigLine) origin(offset int) int {
+	shift := 0
+	for _, e := range l.expansions {
+		if offset < e.start {
+			break
+		}
+		if offset < e.end {
+			return e.rawStart
+		}
+		shift = e.rawEnd - e.end
+	}
+	return offset + shift
+}
+
+// trimContinuation removes the backslash that continues a line on the next
+// one. Trailing whitespace after the backslash is ignored, and an escaped
+// backslash doesn't continue the line.
+func trimContinuation(text string) (string, bool) {
+	trimmed := strings.TrimRight(text, " \t")
+	backslashes := len(trimmed) - len(strings.TrimRight(trimmed, `\`))
+	if backslashes%2 == 0 {
+		return text, false
+	}
+	return trimmed[:len(trimmed)-1], true
+}
+
+// Wrap prefixes err with the file and physical line it refers to, and the
+// column when err points at one
+func (l ConfigLine) Wrap(err error) error {
+	var columnErr *columnError
+	if !errors.As(err, &columnErr) {
+		return fmt.Errorf("%s: %v", location{l.File, l.Line}, err)
+	}
+
+	text, offset := l.Text, columnErr.offset
+	if l.expansions != nil {
+		text, offset = l.raw, l.origin(offset)
+	}
+	start := lineStart{line: l.Line}
+	for _, s := range l.starts {
+		if s.offset > offset {
+			break
+		}
+		start = s
+	}
+	column := utf8.RuneCountInString(text[start.offset:offset]) + 1
+	return fmt.Errorf("%s, column %d: %v", location{l.File, start.line}, column, columnErr.err)
+}
+
+// location is where a definition was read from. Definitions of YAML files,
+// which can't include others, only record their line.
+type location struct {
+	file string
+	line int
+}
+
+func (l location) String() string {
+	if l.file == "" {
+		return fmt.Sprintf("line %d", l.line)
+	}
+	return fmt.Sprintf("%s: line %d", l.file, l.line)
+}
+
+// relativeTo describes l, leaving out the file when it is the same as other's
+func (l location) relativeTo(other location) string {
+	if l.file == other.file {
+		return fmt.Sprintf("line %d", l.line)
+	}
+	return l.String()
+}
+
+// columnError is an error at a byte offset of a configuration line
+type columnError struct {
+	offset int
+	column int
+	err    error
+}
+
+// errorAt returns err located at offset of line
+func errorAt(line string, offset int, err error) *columnError {
+	return &columnError{offset: offset, column: utf8.RuneCountInString(line[:offset]) + 1, err: err}
+}
+
+func (e *columnError) Error() string {
+	return fmt.Sprintf("column %d: %v", e.column, e.err)
+}
+
+func (e *columnError) Unwrap() error {
+	return e.err
+}
+
+// variableError is an error in the value of a variable or task option
+type variableError struct {
+	key string
+	err error
+}
+
+func (e *variableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *variableError) Unwrap() error {
+	return e.err
+}
+
+// lineField is a whitespace-separated field of a configuration line
+type lineField struct {
+	text   string
+	offset int
+}
+
+// splitFields splits a configuration line on whitespace outside quotes,
+// escapes and {{ }} expressions. The fields are returned as written.
+func splitFields(line string) ([]lineField, error) {
+	var fields []lineField
+	i := 0
+	for {
+		for i < len(line) && isBlank(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return fields, nil
+		}
+
+		start := i
+		valueStart := true
+		for i < len(line) && !isBlank(line[i]) {
+			c := line[i]
+			switch {
+			case c == '\\' && i+1 < len(line):
+				i += 2
+			case (c == '"' || c == '\'') && valueStart:
+				_, end, err := unquote(line, i)
+				if err != nil {
+					return nil, err
+				}
+				i = end
+			case strings.HasPrefix(line[i:], "{{"):
+				i = expressionEnd(line, i)
+			default:
+				i++
+			}
+			valueStart = c == '=' || c == ','
+		}
+		fields = append(fields, lineField{text: line[start:i], offset: start})
+	}
+}
+
+// variablePair is a key=value pair of a configuration line's variables
+type variablePair struct {
+	key    string
+	value  string
+	offset int
+}
+
+// splitVariablePairs splits the variables section of a configuration line,
+// which starts at offset of line, into its pairs. Pairs are separated by
+// commas. Values may be quoted with single or double quotes to keep commas
+// and whitespace, and \, \= \\ \" \' and "\ " escape a single character.
+// Unquoted runs of whitespace read as a single space.
+func splitVariablePairs(line string, offset int) ([]variablePair, error) {
+	var pairs []variablePair
+	i := offset
+	for i < len(line) {
+		for i < len(line) && isBlank(line[i]) {
+			i++
+		}
+		start := i
+
+		var key strings.Builder
+		for i < len(line) && line[i] != '=' && line[i] != ',' {
+			if line[i] == '\\' && i+1 < len(line) && strings.IndexByte(escapable, line[i+1]) >= 0 {
+				i++
+			}
+			key.WriteByte(line[i])
+			i++
+		}
+		name := strings.TrimSpace(key.String())
+		if i == len(line) || line[i] == ',' {
+			if name != "" {
+				return nil, errorAt(line, start, fmt.Errorf("invalid variable format '%s' (expected key=value)", name))
+			}
+			i++ // A trailing or doubled comma
+			continue
+		}
+		if name == "" {
+			return nil, errorAt(line, start, fmt.Errorf("invalid variable format: missing name before '='"))
+		}
+		if strings.ContainsAny(name, " \t") {
+			return nil, errorAt(line, start, fmt.Errorf("invalid variable name '%s' (quote or escape values that contain commas)", name))
+		}
+
+		value, end, err := scanValue(line, i+1)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, variablePair{key: name, value: value, offset: start})
+		i = end + 1 // Past the comma
+	}
+	return pairs, nil
+}
+
+// scanValue reads a variable value starting at i. It returns the value and
+// the offset of the comma or line end that follows it.
+func scanValue(line string, i int) (string, int, error) {
+	for i < len(line) && isBlank(line[i]) {
+		i++
+	}
+	if i < len(line) && (line[i] == '"' || line[i] == '\'') {
+		value, end, err := unquote(line, i)
+		if err != nil {
+			return "", 0, err
+		}
+		for end < len(line) && isBlank(line[end]) {
+			end++
+		}
+		if end < len(line) && line[end] != ',' {
+			return "", 0, errorAt(line, end, fmt.Errorf("unexpected text after closing quote"))
+		}
+		return value, end, nil
+	}
+
+	var value strings.Builder
+	space := false
+	for i < len(line) && line[i] != ',' {
+		c := line[i]
+		if isBlank(c) {
+			space = true
+			i++
+			continue
+		}
+		if space && value.Len() > 0 {
+			value.WriteByte(' ')
+		}
+		space = false
+
+		switch {
+		case c == '\\' && i+1 < len(line) && strings.IndexByte(escapable, line[i+1]) >= 0:
+			value.WriteByte(line[i+1])
+			i += 2
+		case strings.HasPrefix(line[i:], "{{"):
+			end := expressionEnd(line, i)
+			value.WriteString(line[i:end])
+			i = end
+		default:
+			value.WriteByte(c)
+			i++
+		}
+	}
+	return value.String(), i, nil
+}
+
+// unquote reads the quoted string that starts at i. Double quoted strings
+// may escape \" and \\, single quoted strings are read as written. {{ }}
+// expressions are copied whole, so they can quote their own arguments.
+func unquote(line string, i int) (string, int, error) {
+	quote := line[i]
+	var value strings.Builder
+	j := i + 1
+	for j < len(line) {
+		c := line[j]
+		switch {
+		case c == quote:
+			return value.String(), j + 1, nil
+		case quote == '"' && c == '\\' && j+1 < len(line) && (line[j+1] == '"' || line[j+1] == '\\'):
+			value.WriteByte(line[j+1])
+			j += 2
+		case strings.HasPrefix(line[j:], "{{"):
+			end := expressionEnd(line, j)
+			value.WriteString(line[j:end])
+			j = end
+		default:
+			value.WriteByte(c)
+			j++
+		}
+	}
+	return "", 0, errorAt(line, i, fmt.Errorf("unterminated %c quote", quote))
+}
+
+// expressionEnd returns the offset after the {{ }} expression that starts at
+// i, or after the opening braces when the expression isn't closed
+func expressionEnd(line string, i int) int {
+	if end := strings.Index(line[i+2:], "}}"); end >= 0 {
+		return i + 2 + end + 2
+	}
+	return i + 2
+}
+
+// isBlank reports whether c separates fields
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
@@ -0,0 +1,118 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLineQuoting(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want map[string]string
+	}{
+		{"simple", "0 9 * * 1 openai report console team=ops,region=eu", map[string]string{"team": "ops", "region": "eu"}},
+		{"spaces around separators", "0 9 * * 1 openai report console team = ops , region=eu", map[string]string{"team": "ops", "region": "eu"}},
+		{"unquoted spaces collapse", "0 9 * * 1 openai report console title=Weekly   Report", map[string]string{"title": "Weekly Report"}},
+		{"double quotes", `0 9 * * 1 openai report console title="Weekly Ops Report, EMEA",team=ops`,
+			map[string]string{"title": "Weekly Ops Report, EMEA", "team": "ops"}},
+		{"single quotes", `0 9 * * 1 openai report console title='  say "hi", a=b'`, map[string]string{"title": `  say "hi", a=b`}},
+		{"escaped quote", `0 9 * * 1 openai report console title="the \"ops\" team"`, map[string]string{"title": `the "ops" team`}},
+		{"escapes", `0 9 * * 1 openai report console filter=region\=eu\,us,path=C:\reports\\`,
+			map[string]string{"filter": "region=eu,us", "path": `C:\reports\`}},
+		{"apostrophe", "0 9 * * 1 openai report console team=Bob's", map[string]string{"team": "Bob's"}},
+		{"expression", `0 9 * * 1 openai report console week={{week_start -1w "Jan 2, 2006"}},team=ops`,
+			map[string]string{"week": `{{week_start -1w "Jan 2, 2006"}}`, "team": "ops"}},
+		{"quoted expression", `0 9 * * 1 openai report console title="Report, {{date "Jan 2"}}"`,
+			map[string]string{"title": `Report, {{date "Jan 2"}}`}},
+		{"trailing comma", "0 9 * * 1 openai report console team=ops,", map[string]string{"team": "ops"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := parseLine(tt.line)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, task.Variables)
+		})
+	}
+}
+
+func TestParseLineColumns(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"0 9 * * 1 gpt report console", "column 11: invalid model 'gpt'"},
+		{"0 9 * * 1 openai report pigeon", "column 25: invalid processor format 'pigeon'"},
+		{"0 9 * * 1 openai report", "column 24: invalid format: insufficient fields"},
+		{`0 9 * * 1 openai report console title="Weekly, team=ops`, `column 39: unterminated " quote`},
+		{`0 9 * * 1 openai report console title="Weekly" Report`, "column 48: unexpected text after closing quote"},
+		{"0 9 * * 1 openai report console team=ops, extra", "column 43: invalid variable format 'extra'"},
+		{"0 9 * * 1 openai report console team=ops,overlap=sometimes", "column 42: invalid overlap"},
+		{"0 9 * * 1 openai report console team=ops, since={{date -7q}}", "column 43: invalid variable since"},
+		{"@after openai report console", "column 1: schedule @after requires an after=<task> option"},
+	}
+	for _, tt := range tests {
+		_, err := parseLine(tt.line)
+		require.Error(t, err, tt.line)
+		assert.Contains(t, err.Error(), tt.want, tt.line)
+	}
+}
+
+func TestParseLineOptionClash(t *testing.T) {
+	require.NoError(t, createTestDirectory("cron_prompts"))
+	require.NoError(t, createTestFile("cron_prompts/option_clash.md", "Report for {{group}}{{if .Variables.team}} and {{team}}{{end}}"))
+	defer func() { _ = removeTestFile("cron_prompts/option_clash.md") }()
+
+	// A prompt variable named like a task option would lose its value
+	_, err := parseLine("0 9 * * 1 openai option_clash console team=ops,group=sre")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "column 48: group is a task option and isn't passed to the prompt, but prompt 'option_clash' uses it as a variable")
+
+	// Options the prompt doesn't use are fine
+	task, err := parseLine("0 9 * * 1 openai option_clash console team=ops,name=daily_sre")
+	require.NoError(t, err)
+	assert.Equal(t, "daily_sre", task.Task.Name)
+	assert.Equal(t, map[string]string{"team": "ops"}, task.Variables)
+}
+
+func TestReadConfigLines(t *testing.T) {
+	lines, err := ReadConfigLines(strings.NewReader("# a comment \\\n" +
+		"0 9 * * 1 openai report console \\\n" +
+		"  title=\"Weekly, EMEA\", \\  \n" +
+		"  team=ops\n" +
+		"path=C:\\dir\\\\\n" +
+		"0 8 * * * openai last console \\"))
+	require.NoError(t, err)
+	require.Len(t, lines, 4)
+	assert.Equal(t, 1, lines[0].Line)
+	assert.Equal(t, "0 9 * * 1 openai report console   title=\"Weekly, EMEA\",   team=ops", lines[1].Text)
+	assert.Equal(t, 2, lines[1].Line)
+	assert.Equal(t, `path=C:\dir\\`, lines[2].Text)
+	assert.Equal(t, "0 8 * * * openai last console ", lines[3].Text)
+}
+
+func TestParseConfigFileContinuation(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	tasks, err := parseConfigFile(writeCalendarConfig(t, `0 9 * * 1 openai test_prompt console \
+    title="Weekly Ops Report, EMEA", \
+    team=ops
+0 10 * * 1 openai test_prompt console name=second
+`))
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, map[string]string{"title": "Weekly Ops Report, EMEA", "team": "ops"}, tasks[0].Variables)
+	assert.Equal(t, "second", tasks[1].Name)
+
+	_, err = parseConfigFile(writeCalendarConfig(t, `# header
+0 9 * * 1 openai test_prompt console \
+    title="Weekly, \
+    team=ops
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `line 3, column 11: unterminated " quote`)
+}
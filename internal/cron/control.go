@@ -0,0 +1,313 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// EnvControlSocket overrides where the cron service listens for control
+// requests; "none" disables the control socket
+const EnvControlSocket = "CRONAI_CONTROL_SOCKET"
+
+// DefaultControlSocket is the default location of the control socket
+const DefaultControlSocket = ".cronai/control.sock"
+
+// controlSocketFromEnv returns the control socket location configured in the
+// environment, or an empty string when it is disabled
+func controlSocketFromEnv() string {
+	switch path := os.Getenv(EnvControlSocket); path {
+	case "":
+		return DefaultControlSocket
+	case "none":
+		return ""
+	default:
+		return path
+	}
+}
+
+// WithControlSocket sets where the service listens for control requests. An
+// empty path disables the control socket.
+func WithControlSocket(path string) ServiceOption {
+	return func(s *Service) {
+		s.controlSocket = path
+	}
+}
+
+// TaskStatus is the live state of a scheduled task on a running service
+type TaskStatus struct {
+	ID       string    `json:"id"`
+	Schedule string    `json:"schedule"`
+	Model    string    `json:"model"`
+	Prompt   string    `json:"prompt"`
+	Paused   bool      `json:"paused"`
+	Running  int       `json:"running"` // Executions in progress
+	LastRun  time.Time `json:"last_run,omitempty"`
+	NextRun  time.Time `json:"next_run,omitempty"` // Zero for tasks that only run after another task
+}
+
+// TaskStatuses returns the live state of every scheduled task, ordered by ID
+func (s *Service) TaskStatuses() []TaskStatus {
+	s.mu.Lock()
+	entries := make([]EntryMetadata, 0, len(s.entries))
+	for _, entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	running := make(map[string]int, len(s.active))
+	for id, count := range s.active {
+		running[id] = count
+	}
+	s.mu.Unlock()
+
+	statuses := make([]TaskStatus, 0, len(entries))
+	for _, entry := range entries {
+		id := entry.Task.ID()
+		status := TaskStatus{
+			ID:       id,
+			Schedule: entry.Schedule,
+			Model:    entry.Model,
+			Prompt:   entry.Prompt,
+			Paused:   s.isPaused(entry.Task),
+			Running:  running[id],
+		}
+		if s.state != nil {
+			status.LastRun, _ = s.state.LastRun(id)
+		}
+		if s.scheduler != nil && entry.EntryID != 0 {
+			// The scheduler only knows the next fire time once it has started
+			scheduled := s.scheduler.Entry(entry.EntryID)
+			status.NextRun = scheduled.Next
+			if status.NextRun.IsZero() && scheduled.Schedule != nil {
+				status.NextRun = scheduled.Schedule.Next(time.Now())
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// PauseTask stops the scheduled runs of the task with the given ID until it is
+// resumed. The paused state is persisted and survives restarts. Instances
+// sharing a lock backend only accept it on the leader, see controlsTasks.
+func (s *Service) PauseTask(id string) error {
+	return s.setPaused(id, true)
+}
+
+// ResumeTask lets a paused task run on its schedule again
+func (s *Service) ResumeTask(id string) error {
+	return s.setPaused(id, false)
+}
+
+// setPaused records whether the task with the given ID is paused
+func (s *Service) setPaused(id string, paused bool) error {
+	if _, err := s.findEntry(id); err != nil {
+		return err
+	}
+	if s.locker != nil && s.lockMode == LockModePerRun {
+		// Every instance runs the schedule but only this one would know of the pause
+		return errors.Wrap(errors.CategoryConfiguration, errors.ErrUnavailable,
+			"tasks can't be paused or resumed in per-run lock mode, each instance keeps its own pause state")
+	}
+	if err := s.controlsTasks(); err != nil {
+		return err
+	}
+	if s.state == nil {
+		return errors.New(errors.CategorySystem, "task state not loaded")
+	}
+	if err := s.state.SetPaused(id, paused); err != nil {
+		return errors.Wrap(errors.CategorySystem, err, "failed to persist task state")
+	}
+
+	message := "Resumed task"
+	if paused {
+		message = "Paused task"
+	}
+	log.Info(message, logger.Fields{"task": id})
+	return nil
+}
+
+// TriggerTask starts a run of the task with the given ID right away, whether
+// or not it is paused. The run honors the task's overlap policy and triggers
+// its downstream tasks; TriggerTask returns without waiting for it. Instances
+// sharing a lock backend in leader mode only accept it on the leader.
+func (s *Service) TriggerTask(id string) error {
+	entry, err := s.findEntry(id)
+	if err != nil {
+		return err
+	}
+	if err := s.controlsTasks(); err != nil {
+		return err
+	}
+
+	log.Info("Triggering task", logger.Fields{"task": id})
+	task := entry.Task
+	job := entry.wrap(history.SourceTrigger, func(ctx context.Context) {
+		_ = s.executeTask(ctx, task, history.SourceTrigger, nil) //nolint:errcheck // failures are logged and recorded in history
+	})
+	go job.Run()
+	return nil
+}
+
+// Reload re-reads the configuration file and applies the changes to the
+// running scheduler, see reloadConfig
+func (s *Service) Reload() error {
+	return s.reloadConfig()
+}
+
+// controlsTasks returns an error when another instance runs the scheduled
+// tasks, so that pausing or triggering them here would have no effect on, or
+// overlap with, the leader's runs
+func (s *Service) controlsTasks() error {
+	if s.locker == nil || s.lockMode != LockModeLeader || s.isLeader() {
+		return nil
+	}
+	return errors.Wrap(errors.CategoryConfiguration, errors.ErrUnavailable,
+		"instance '"+s.instanceID+"' is not the leader, send control requests to the leading instance")
+}
+
+// findEntry returns the scheduler entry of the task with the given ID
+func (s *Service) findEntry(id string) (EntryMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if entry.Task.ID() == id {
+			return entry, nil
+		}
+	}
+	return EntryMetadata{}, errors.Wrap(errors.CategoryValidation, errors.ErrNotFound, "task '"+id+"'")
+}
+
+// isPaused reports whether the task is paused
+func (s *Service) isPaused(task Task) bool {
+	return s.state != nil && s.state.Paused(task.ID())
+}
+
+// skipPaused records a skipped execution and returns true when the task is paused
+func (s *Service) skipPaused(task Task, source string) bool {
+	if !s.isPaused(task) {
+		return false
+	}
+	s.recordSkip(task, source, "task paused")
+	return true
+}
+
+// trackRunning adjusts the number of executions of the task in progress
+func (s *Service) trackRunning(task Task, delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		s.active = make(map[string]int)
+	}
+	s.active[task.ID()] += delta
+	if s.active[task.ID()] <= 0 {
+		delete(s.active, task.ID())
+	}
+}
+
+// controlResponse is the body of every control API response
+type controlResponse struct {
+	Tasks []TaskStatus `json:"tasks,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+// controlHandler serves the control API:
+//
+//	GET  /tasks               live state of every task
+//	POST /tasks/{id}/pause    pause a task
+//	POST /tasks/{id}/resume   resume a paused task
+//	POST /tasks/{id}/trigger  run a task now
+//	POST /reload              reload the configuration file
+func (s *Service) controlHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, _ *http.Request) {
+		writeControlResponse(w, http.StatusOK, controlResponse{Tasks: s.TaskStatuses()})
+	})
+	taskAction := func(action func(id string) error) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			writeControlResult(w, action(r.PathValue("id")))
+		}
+	}
+	mux.HandleFunc("POST /tasks/{id}/pause", taskAction(s.PauseTask))
+	mux.HandleFunc("POST /tasks/{id}/resume", taskAction(s.ResumeTask))
+	mux.HandleFunc("POST /tasks/{id}/trigger", taskAction(s.TriggerTask))
+	mux.HandleFunc("POST /reload", func(w http.ResponseWriter, _ *http.Request) {
+		writeControlResult(w, s.Reload())
+	})
+	return mux
+}
+
+// writeControlResult writes the outcome of a control action
+func writeControlResult(w http.ResponseWriter, err error) {
+	switch {
+	case err == nil:
+		writeControlResponse(w, http.StatusOK, controlResponse{})
+	case errors.Is(err, errors.ErrNotFound):
+		writeControlResponse(w, http.StatusNotFound, controlResponse{Error: err.Error()})
+	case errors.Is(err, errors.ErrUnavailable):
+		writeControlResponse(w, http.StatusConflict, controlResponse{Error: err.Error()})
+	default:
+		writeControlResponse(w, http.StatusInternalServerError, controlResponse{Error: err.Error()})
+	}
+}
+
+// writeControlResponse writes a JSON control response
+func writeControlResponse(w http.ResponseWriter, status int, response controlResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Debug("Failed to write control response", logger.Fields{"error": err.Error()})
+	}
+}
+
+// serveControl listens on the control socket and serves the control API until
+// ctx is cancelled. A socket left behind by a previous run is replaced, one in
+// use by another running service is not.
+func (s *Service) serveControl(ctx context.Context) error {
+	path := s.controlSocket
+	if conn, err := net.Dial("unix", path); err == nil {
+		_ = conn.Close() //nolint:errcheck // only probing
+		return errors.New(errors.CategorySystem, "control socket "+path+" is in use by another service")
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return errors.Wrap(errors.CategorySystem, err, "failed to remove stale control socket")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(errors.CategorySystem, err, "failed to create control socket directory")
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return errors.Wrap(errors.CategorySystem, err, "failed to listen on control socket")
+	}
+	// Only the owner of the service may control it
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = listener.Close() //nolint:errcheck // the chmod error is more relevant
+		return errors.Wrap(errors.CategorySystem, err, "failed to restrict control socket")
+	}
+
+	server := &http.Server{Handler: s.controlHandler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		_ = server.Close() //nolint:errcheck // the service is stopping
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Error("Control socket stopped", logger.Fields{"path": path, "error": err.Error()})
+		}
+	}()
+
+	log.Info("Listening for control requests", logger.Fields{"path": path})
+	return nil
+}
@@ -0,0 +1,102 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ControlClient sends control requests to a running cron service over its
+// control socket
+type ControlClient struct {
+	socket string
+	http   *http.Client
+}
+
+// NewControlClient creates a client for the service listening on the control
+// socket at path. An empty path selects the socket configured in the environment.
+func NewControlClient(path string) *ControlClient {
+	if path == "" {
+		path = controlSocketFromEnv()
+	}
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	return &ControlClient{
+		socket: path,
+		http: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", path)
+				},
+			},
+		},
+	}
+}
+
+// Socket returns the path of the control socket
+func (c *ControlClient) Socket() string {
+	return c.socket
+}
+
+// Tasks returns the live state of every scheduled task
+func (c *ControlClient) Tasks(ctx context.Context) ([]TaskStatus, error) {
+	response, err := c.do(ctx, http.MethodGet, "/tasks")
+	if err != nil {
+		return nil, err
+	}
+	return response.Tasks, nil
+}
+
+// Pause pauses the task with the given ID
+func (c *ControlClient) Pause(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodPost, "/tasks/"+url.PathEscape(id)+"/pause")
+	return err
+}
+
+// Resume resumes the paused task with the given ID
+func (c *ControlClient) Resume(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodPost, "/tasks/"+url.PathEscape(id)+"/resume")
+	return err
+}
+
+// Trigger starts a run of the task with the given ID
+func (c *ControlClient) Trigger(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodPost, "/tasks/"+url.PathEscape(id)+"/trigger")
+	return err
+}
+
+// Reload makes the service reload its configuration file
+func (c *ControlClient) Reload(ctx context.Context) error {
+	_, err := c.do(ctx, http.MethodPost, "/reload")
+	return err
+}
+
+// do sends a control request and decodes the response
+func (c *ControlClient) do(ctx context.Context, method, path string) (*controlResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "http://cronai"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach the cron service at %s (is it running?): %w", c.socket, err)
+	}
+	defer func() { _ = resp.Body.Close() }() //nolint:errcheck // the body has been read
+
+	var response controlResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("invalid response from the cron service: %w", err)
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("%s", response.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cron service returned %s", resp.Status)
+	}
+	return &response, nil
+}
@@ -0,0 +1,173 @@
+package cron
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/lock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taskStatus returns the live state of the task with the given ID
+func taskStatus(t *testing.T, client *ControlClient, id string) TaskStatus {
+	t.Helper()
+	statuses, err := client.Tasks(context.Background())
+	require.NoError(t, err)
+	for _, status := range statuses {
+		if status.ID == id {
+			return status
+		}
+	}
+	t.Fatalf("task %s not listed", id)
+	return TaskStatus{}
+}
+
+func TestControlSocket(t *testing.T) {
+	store, started, release := startDrainTest(t)
+	if err := setupTestPromptFile(t); err != nil {
+		t.Fatalf("Failed to setup test prompt file: %v", err)
+	}
+	defer cleanupTestPromptFile(t)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "cronai.config")
+	statePath := filepath.Join(dir, "state.json")
+	socket := filepath.Join(dir, "control.sock")
+	service := NewCronService(configPath, WithStatePath(statePath), WithControlSocket(socket))
+
+	var err error
+	service.state, err = loadState(statePath)
+	require.NoError(t, err)
+	service.scheduler = newScheduler()
+	task := Task{Name: "report", Schedule: "0 8 * * *", Model: "openai", Prompt: "test", Processor: "console"}
+	service.applyTasks([]Task{task})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, service.serveControl(ctx))
+	client := NewControlClient(socket)
+	ctl := context.Background()
+
+	status := taskStatus(t, client, "report")
+	assert.False(t, status.Paused)
+	assert.Zero(t, status.Running)
+	assert.Equal(t, 8, status.NextRun.Hour())
+
+	// Paused tasks skip their runs, also after a restart
+	require.NoError(t, client.Pause(ctl, "report"))
+	assert.True(t, taskStatus(t, client, "report").Paused)
+	restarted, err := loadState(statePath)
+	require.NoError(t, err)
+	assert.True(t, restarted.Paused("report"))
+	assert.True(t, service.skipPaused(task, history.SourceCron))
+
+	err = client.Pause(ctl, "unknown")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+
+	// Triggered runs ignore the pause
+	require.NoError(t, client.Trigger(ctl, "report"))
+	<-started
+	assert.Equal(t, 1, taskStatus(t, client, "report").Running)
+	close(release)
+	assert.Eventually(t, func() bool { return taskStatus(t, client, "report").Running == 0 }, 2*time.Second, 10*time.Millisecond)
+
+	records, err := store.List(history.Filter{Source: history.SourceTrigger})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StatusSuccess, records[0].Status)
+
+	require.NoError(t, client.Resume(ctl, "report"))
+	assert.False(t, taskStatus(t, client, "report").Paused)
+	assert.False(t, service.skipPaused(task, history.SourceCron))
+
+	// Reload replaces the schedule with the configuration file
+	require.NoError(t, os.WriteFile(configPath, []byte("0 9 * * * openai test_prompt console name=digest\n"), 0644))
+	require.NoError(t, client.Reload(ctl))
+	statuses, err := client.Tasks(ctl)
+	require.NoError(t, err)
+	require.Len(t, statuses, 1)
+	assert.Equal(t, "digest", statuses[0].ID)
+
+	// The socket is removed when the service stops
+	cancel()
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(socket)
+		return os.IsNotExist(err)
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestControlSocketInUse(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "control.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first := NewCronService("test.config", WithControlSocket(socket))
+	require.NoError(t, first.serveControl(ctx))
+	second := NewCronService("test.config", WithControlSocket(socket))
+	assert.Error(t, second.serveControl(ctx))
+}
+
+func TestControlClientWithoutService(t *testing.T) {
+	client := NewControlClient(filepath.Join(t.TempDir(), "control.sock"))
+	_, err := client.Tasks(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is it running?")
+}
+
+func TestControlSocketFromEnv(t *testing.T) {
+	t.Setenv(EnvControlSocket, "")
+	assert.Equal(t, DefaultControlSocket, controlSocketFromEnv())
+
+	t.Setenv(EnvControlSocket, "/run/cronai.sock")
+	assert.Equal(t, "/run/cronai.sock", controlSocketFromEnv())
+
+	t.Setenv(EnvControlSocket, "none")
+	assert.Empty(t, controlSocketFromEnv())
+}
+
+func TestControlOnStandbyInstance(t *testing.T) {
+	locker := lock.NewKVLocker(lock.NewMemoryKV(), "cronai:")
+	newService := func(id string, mode LockMode) *Service {
+		service := NewCronService("test.config", WithLocker(locker, mode), WithInstanceID(id),
+			WithStatePath(filepath.Join(t.TempDir(), "state.json")))
+		var err error
+		service.state, err = loadState(service.statePath)
+		require.NoError(t, err)
+		service.scheduler = newScheduler()
+		service.applyTasks([]Task{{Name: "report", Schedule: "0 8 * * *", Model: "openai", Prompt: "test"}})
+		return service
+	}
+
+	leader, standby := newService("first", LockModeLeader), newService("second", LockModeLeader)
+	leader.leaseUntil.Store(time.Now().Add(time.Minute).UnixNano())
+
+	// Only the leader runs the schedule, so only it takes control requests
+	for _, action := range []func(string) error{standby.PauseTask, standby.ResumeTask, standby.TriggerTask} {
+		err := action("report")
+		require.Error(t, err)
+		assert.ErrorIs(t, err, errors.ErrUnavailable)
+		assert.Contains(t, err.Error(), "instance 'second' is not the leader")
+	}
+	assert.False(t, standby.state.Paused("report"))
+	require.NoError(t, leader.PauseTask("report"))
+	assert.True(t, leader.state.Paused("report"))
+
+	// Per-run instances would each keep their own pause state
+	perRun := newService("third", LockModePerRun)
+	err := perRun.PauseTask("report")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "per-run lock mode")
+
+	recorder := httptest.NewRecorder()
+	writeControlResult(recorder, standby.TriggerTask("report"))
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+}
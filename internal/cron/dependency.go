@@ -0,0 +1,155 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// ScheduleAfter is the schedule of a task that only runs when its upstream task completes
+const ScheduleAfter = "@after"
+
+// TriggerOn determines which upstream outcomes fire a downstream task
+type TriggerOn string
+
+// Trigger conditions
+const (
+	// TriggerOnSuccess fires when the upstream task succeeded (default)
+	TriggerOnSuccess TriggerOn = "success"
+	// TriggerOnFailure fires when the upstream task failed
+	TriggerOnFailure TriggerOn = "failure"
+	// TriggerOnAlways fires whenever the upstream task finished
+	TriggerOnAlways TriggerOn = "always"
+)
+
+// namePattern restricts task names to characters that are safe in config lines and file names
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// validateTaskName checks that a task name is usable as an identifier
+func validateTaskName(name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid task name '%s' (use letters, digits, '.', '_' and '-')", name)
+	}
+	return nil
+}
+
+// ParseTriggerOn parses a trigger condition. An empty value selects TriggerOnSuccess.
+func ParseTriggerOn(value string) (TriggerOn, error) {
+	switch on := TriggerOn(strings.ToLower(strings.TrimSpace(value))); on {
+	case "":
+		return TriggerOnSuccess, nil
+	case TriggerOnSuccess, TriggerOnFailure, TriggerOnAlways:
+		return on, nil
+	default:
+		return "", fmt.Errorf("invalid trigger condition '%s' (supported: success, failure, always)", value)
+	}
+}
+
+// Matches reports whether an upstream execution with the given status fires the trigger
+func (o TriggerOn) Matches(status history.Status) bool {
+	switch o {
+	case TriggerOnAlways:
+		return status == history.StatusSuccess || status == history.StatusFailed
+	case TriggerOnFailure:
+		return status == history.StatusFailed
+	default:
+		return status == history.StatusSuccess
+	}
+}
+
+// validateDependencies checks that every upstream task exists and that the
+// dependencies contain no cycles
+func validateDependencies(tasks []Task) error {
+	var validateErrors *multierror.Error
+
+	upstream := make(map[string]string, len(tasks))
+	for _, task := range tasks {
+		if task.Name != "" {
+			upstream[task.Name] = task.After
+		}
+	}
+
+	for _, task := range tasks {
+		if task.After == "" {
+			continue
+		}
+		if _, ok := upstream[task.After]; !ok {
+			validateErrors = multierror.Append(validateErrors,
+				fmt.Errorf("task '%s': upstream task '%s' not found", task.ID(), task.After))
+		}
+	}
+
+	// Follow each chain upstream; revisiting a task means there is a cycle
+	reported := make(map[string]bool)
+	for name := range upstream {
+		visited := map[string]bool{name: true}
+		for current := upstream[name]; current != ""; current = upstream[current] {
+			if visited[current] {
+				if !reported[current] {
+					reported[current] = true
+					validateErrors = multierror.Append(validateErrors,
+						fmt.Errorf("task '%s': dependency cycle detected", current))
+				}
+				break
+			}
+			visited[current] = true
+		}
+	}
+
+	return validateErrors.ErrorOrNil()
+}
+
+// upstreamVariables exposes an upstream execution to the downstream prompt
+func upstreamVariables(task Task, record *history.Record) map[string]string {
+	return map[string]string{
+		"upstream_task":         task.ID(),
+		"upstream_prompt":       task.Prompt,
+		"upstream_status":       string(record.Status),
+		"upstream_content":      record.Response,
+		"upstream_model":        record.ModelUsed,
+		"upstream_execution_id": record.ID,
+		"upstream_error":        record.Error,
+		"upstream_time":         record.StartedAt.Format(time.RFC3339),
+	}
+}
+
+// triggerDependents starts the tasks that run after the given upstream execution
+func (s *Service) triggerDependents(task Task, record *history.Record) {
+	if task.Name == "" {
+		return
+	}
+
+	s.mu.Lock()
+	var dependents []EntryMetadata
+	for _, entry := range s.entries {
+		if entry.Task.After == task.Name && entry.Task.On.Matches(record.Status) {
+			dependents = append(dependents, entry)
+		}
+	}
+	s.mu.Unlock()
+
+	upstream := upstreamVariables(task, record)
+	for _, entry := range dependents {
+		downstream := entry.Task
+		log.Info("Triggering downstream task", logger.Fields{
+			"task":               downstream.ID(),
+			"upstream":           task.ID(),
+			"upstream_status":    string(record.Status),
+			"upstream_execution": record.ID,
+		})
+
+		job := entry.wrap(history.SourceDependency, func(ctx context.Context) {
+			if s.skipPaused(downstream, history.SourceDependency) || s.skipBlackout(downstream, history.SourceDependency, time.Now()) {
+				return
+			}
+			_ = s.executeTask(ctx, downstream, history.SourceDependency, upstream) //nolint:errcheck // failures are logged and recorded in history
+		})
+		go job.Run()
+	}
+}
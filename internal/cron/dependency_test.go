@@ -0,0 +1,156 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/processor"
+	"github.com/rshade/cronai/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigLineDependency(t *testing.T) {
+	task, err := parseConfigLine("@after claude summary slack-ops after=collect,on=always,team=ops")
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleAfter, task.Task.Schedule)
+	assert.Equal(t, "claude", task.Task.Model)
+	assert.Equal(t, "summary", task.Task.Prompt)
+	assert.Equal(t, "slack-ops", task.Task.Processor)
+	assert.Equal(t, "collect", task.Task.After)
+	assert.Equal(t, TriggerOnAlways, task.Task.On)
+	assert.Equal(t, map[string]string{"team": "ops"}, task.Task.Variables)
+
+	task, err = parseConfigLine("0 8 * * * openai collect console name=collect")
+	require.NoError(t, err)
+	assert.Equal(t, "collect", task.Task.Name)
+	assert.Equal(t, "collect", task.Task.ID())
+	assert.Nil(t, task.Task.Variables)
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"after without upstream", "@after claude summary console"},
+		{"on without after", "0 8 * * * openai collect console on=failure"},
+		{"invalid condition", "@after claude summary console after=collect,on=sometimes"},
+		{"invalid name", "0 8 * * * openai collect console name=my/task"},
+		{"insufficient fields", "@after claude summary"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfigLine(tt.line)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	collect := Task{Name: "collect", Schedule: "0 8 * * *"}
+	summary := Task{Name: "summary", Schedule: ScheduleAfter, After: "collect"}
+	assert.NoError(t, validateDependencies([]Task{collect, summary}))
+
+	missing := Task{Schedule: ScheduleAfter, Prompt: "report", After: "nowhere"}
+	err := validateDependencies([]Task{collect, missing})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "upstream task 'nowhere' not found")
+
+	a := Task{Name: "a", Schedule: ScheduleAfter, After: "b"}
+	b := Task{Name: "b", Schedule: ScheduleAfter, After: "a"}
+	err = validateDependencies([]Task{a, b})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "dependency cycle")
+
+	self := Task{Name: "self", Schedule: "0 8 * * *", After: "self"}
+	assert.Error(t, validateDependencies([]Task{self}))
+}
+
+func TestTriggerOnMatches(t *testing.T) {
+	assert.True(t, TriggerOn("").Matches(history.StatusSuccess))
+	assert.False(t, TriggerOnSuccess.Matches(history.StatusFailed))
+	assert.True(t, TriggerOnFailure.Matches(history.StatusFailed))
+	assert.False(t, TriggerOnFailure.Matches(history.StatusSuccess))
+	assert.True(t, TriggerOnAlways.Matches(history.StatusSuccess))
+	assert.True(t, TriggerOnAlways.Matches(history.StatusFailed))
+	assert.False(t, TriggerOnAlways.Matches(history.StatusSkipped))
+}
+
+func TestTriggerDependents(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("collect", "Collect the data")
+	mockPM.SetPrompt("summary", "Summarize {{upstream_content}}")
+	mockPM.SetPrompt("alert", "Collection failed: {{upstream_error}}")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	processor.GetRegistry().RegisterFactory("console", func(_ processor.Config) (processor.Processor, error) {
+		return &mockProcessor{}, nil
+	})
+
+	var mu sync.Mutex
+	received := make(map[string]map[string]string)
+	failCollect := false
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		received[variables["promptName"]] = variables
+		if variables["promptName"] == "collect" && failCollect {
+			return nil, fmt.Errorf("rate limited")
+		}
+		return &models.ModelResponse{Content: "collected data", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	collect := Task{Name: "collect", Schedule: "0 8 * * *", Model: "openai", Prompt: "collect", Processor: "console"}
+	summary := Task{Schedule: ScheduleAfter, Model: "claude", Prompt: "summary", Processor: "console", After: "collect"}
+	alert := Task{Schedule: ScheduleAfter, Model: "claude", Prompt: "alert", Processor: "console",
+		After: "collect", On: TriggerOnFailure}
+
+	service := NewCronService("test.config")
+	service.scheduler = cron.New()
+	service.applyTasks([]Task{collect, summary, alert})
+
+	// @after tasks are known to the service but not scheduled
+	assert.Len(t, service.ListTasks(), 3)
+	assert.Len(t, service.scheduler.Entries(), 1)
+
+	dependencyRuns := func() []*history.Record {
+		records, err := store.List(history.Filter{Source: history.SourceDependency})
+		require.NoError(t, err)
+		return records
+	}
+
+	// A successful upstream run triggers the on=success task with its response
+	require.NoError(t, service.executeTask(context.Background(), collect, history.SourceCron, nil))
+	require.Eventually(t, func() bool { return len(dependencyRuns()) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "summary", dependencyRuns()[0].Prompt)
+
+	mu.Lock()
+	assert.Equal(t, "collected data", received["summary"]["upstream_content"])
+	assert.Equal(t, "collect", received["summary"]["upstream_task"])
+	assert.Equal(t, "success", received["summary"]["upstream_status"])
+	mu.Unlock()
+
+	// A failed upstream run triggers the on=failure task
+	mu.Lock()
+	failCollect = true
+	mu.Unlock()
+	require.Error(t, service.executeTask(context.Background(), collect, history.SourceCron, nil))
+	require.Eventually(t, func() bool { return len(dependencyRuns()) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "alert", dependencyRuns()[0].Prompt)
+
+	mu.Lock()
+	assert.Contains(t, received["alert"]["upstream_error"], "rate limited")
+	mu.Unlock()
+}
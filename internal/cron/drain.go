@@ -0,0 +1,117 @@
+package cron
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// EnvShutdownGrace overrides how long shutdown waits for running tasks
+const EnvShutdownGrace = "CRONAI_SHUTDOWN_GRACE"
+
+// DefaultShutdownGrace is how long shutdown waits for running tasks to finish
+// before cancelling them
+const DefaultShutdownGrace = 30 * time.Second
+
+// errShutdown is the cancellation cause of runs still in progress when the
+// shutdown grace period expires
+var errShutdown = errors.New(errors.CategorySystem, "service shutting down")
+
+// WithShutdownGrace sets how long shutdown waits for running tasks to finish
+// before cancelling them. Zero cancels them right away.
+func WithShutdownGrace(grace time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.shutdownGrace = grace
+	}
+}
+
+// shutdownGraceFromEnv returns the shutdown grace period configured in the environment
+func shutdownGraceFromEnv() time.Duration {
+	value := os.Getenv(EnvShutdownGrace)
+	if value == "" {
+		return DefaultShutdownGrace
+	}
+
+	grace, err := time.ParseDuration(value)
+	if err != nil || grace < 0 {
+		log.Warn("Invalid shutdown grace period, using default", logger.Fields{
+			"value":   value,
+			"default": DefaultShutdownGrace.String(),
+		})
+		return DefaultShutdownGrace
+	}
+	return grace
+}
+
+// beginRun registers a task execution so shutdown waits for it. It returns
+// false once the service is draining, the execution must not start then.
+func (s *Service) beginRun() (finish func(), ok bool) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if s.draining {
+		return nil, false
+	}
+	s.running.Add(1)
+	s.inFlight.Add(1)
+	return func() {
+		s.inFlight.Add(-1)
+		s.running.Done()
+	}, true
+}
+
+// drain stops new executions from starting and waits for the running ones
+// for up to the grace period. Executions still running after that are
+// cancelled and recorded as interrupted; drain returns once they have stopped.
+func (s *Service) drain() {
+	s.runMu.Lock()
+	s.draining = true
+	s.runMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	if running := s.inFlight.Load(); running > 0 {
+		log.Info("Waiting for running tasks to finish", logger.Fields{
+			"running": running,
+			"grace":   s.shutdownGrace.String(),
+		})
+	}
+
+	timer := time.NewTimer(s.shutdownGrace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return
+	case <-timer.C:
+	}
+
+	log.Warn("Shutdown grace period expired, cancelling running tasks", logger.Fields{
+		"running": s.inFlight.Load(),
+		"grace":   s.shutdownGrace.String(),
+	})
+	if s.cancelRuns != nil {
+		s.cancelRuns(errShutdown)
+	}
+	<-done
+}
+
+// markInterrupted turns the failure of a run cancelled by shutdown into an
+// interruption
+func markInterrupted(ctx context.Context, record *history.Record) {
+	if record.Status != history.StatusFailed || !errors.Is(context.Cause(ctx), errShutdown) {
+		return
+	}
+	record.Interrupt(errShutdown.Error())
+	log.Warn("Task interrupted by shutdown", logger.Fields{
+		"execution_id": record.ID,
+		"task":         record.Task,
+		"stage":        record.Stage,
+	})
+}
@@ -0,0 +1,120 @@
+package cron
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startDrainTest installs a prompt and a model call that reports when it
+// starts and returns once released or cancelled
+func startDrainTest(t *testing.T) (store *history.MemoryStore, started <-chan struct{}, release chan<- struct{}) {
+	t.Helper()
+	store = history.NewMemoryStore()
+	history.SetStore(store)
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("test", "This is a test prompt")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	t.Cleanup(func() { prompt.PM = oldManager })
+
+	startedCh := make(chan struct{}, 1)
+	releaseCh := make(chan struct{})
+	oldExecuteModel := executeModel
+	executeModel = func(ctx context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		startedCh <- struct{}{}
+		select {
+		case <-releaseCh:
+			return &models.ModelResponse{Content: "done", Model: model}, nil
+		case <-ctx.Done():
+			return nil, context.Cause(ctx)
+		}
+	}
+	t.Cleanup(func() { executeModel = oldExecuteModel })
+	return store, startedCh, releaseCh
+}
+
+// newDrainService returns a service whose runs are cancelled like a started service's
+func newDrainService(grace time.Duration) *Service {
+	service := NewCronService("test.config", WithShutdownGrace(grace))
+	service.ctx, service.cancelRuns = context.WithCancelCause(context.Background())
+	return service
+}
+
+func TestDrainWaitsForRunningTasks(t *testing.T) {
+	store, started, release := startDrainTest(t)
+	service := newDrainService(time.Minute)
+	task := Task{Name: "report", Model: "openai", Prompt: "test", Processor: "console"}
+
+	errs := make(chan error, 1)
+	go func() { errs <- service.executeTask(service.runContext(), task, history.SourceCron, nil) }()
+	<-started
+
+	drained := make(chan struct{})
+	go func() {
+		service.drain()
+		close(drained)
+	}()
+
+	// The running task finishes within the grace period
+	select {
+	case <-drained:
+		t.Fatal("drain returned while a task was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-drained
+	require.NoError(t, <-errs)
+
+	records, err := store.List(history.Filter{Task: "report"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StatusSuccess, records[0].Status)
+
+	// Nothing starts once the service is draining
+	assert.ErrorIs(t, service.executeTask(service.runContext(), task, history.SourceCron, nil), errShutdown)
+	records, err = store.List(history.Filter{Status: history.StatusSkipped})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "service shutting down", records[0].Reason)
+}
+
+func TestDrainCancelsAfterGracePeriod(t *testing.T) {
+	store, started, _ := startDrainTest(t)
+	service := newDrainService(20 * time.Millisecond)
+	task := Task{Name: "report", Model: "openai", Prompt: "test", Processor: "console"}
+
+	errs := make(chan error, 1)
+	go func() { errs <- service.executeTask(service.runContext(), task, history.SourceCron, nil) }()
+	<-started
+
+	service.drain()
+	err := <-errs
+	require.Error(t, err)
+	assert.ErrorIs(t, err, errShutdown)
+
+	records, err := store.List(history.Filter{Task: "report"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StatusInterrupted, records[0].Status)
+	assert.Equal(t, history.StageModel, records[0].Stage)
+	assert.Equal(t, "service shutting down", records[0].Reason)
+}
+
+func TestShutdownGraceFromEnv(t *testing.T) {
+	t.Setenv(EnvShutdownGrace, "")
+	assert.Equal(t, DefaultShutdownGrace, shutdownGraceFromEnv())
+
+	t.Setenv(EnvShutdownGrace, "2m")
+	assert.Equal(t, 2*time.Minute, shutdownGraceFromEnv())
+
+	t.Setenv(EnvShutdownGrace, "-1s")
+	assert.Equal(t, DefaultShutdownGrace, shutdownGraceFromEnv())
+}
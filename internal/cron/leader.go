@@ -0,0 +1,244 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rshade/cronai/internal/lock"
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// Environment variables configuring coordination between instances
+const (
+	// EnvLockDir enables coordination through lease files in a shared directory
+	EnvLockDir = "CRONAI_LOCK_DIR"
+	// EnvLockMode selects how instances share the work: leader or per-run
+	EnvLockMode = "CRONAI_LOCK_MODE"
+	// EnvLockTTL overrides how long a lease lasts without renewal
+	EnvLockTTL = "CRONAI_LOCK_TTL"
+)
+
+// DefaultLockTTL is how long a lease lasts without renewal. The leader renews
+// its lease every third of it.
+const DefaultLockTTL = 30 * time.Second
+
+// leaderKey is the lease held by the leader
+const leaderKey = "leader"
+
+// LockMode determines how instances sharing a lock backend split the work
+type LockMode string
+
+// Lock modes
+const (
+	// LockModeLeader lets only the instance holding the leader lease run scheduled tasks (default)
+	LockModeLeader LockMode = "leader"
+	// LockModePerRun lets the first instance to take the lease of a fire time run it
+	LockModePerRun LockMode = "per-run"
+)
+
+// ParseLockMode parses a lock mode name. An empty value selects LockModeLeader.
+func ParseLockMode(value string) (LockMode, error) {
+	switch mode := LockMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return LockModeLeader, nil
+	case LockModeLeader, LockModePerRun:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid lock mode '%s' (supported: leader, per-run)", value)
+	}
+}
+
+// WithLocker coordinates the service with other instances sharing locker, so
+// that every scheduled run happens on a single instance
+func WithLocker(locker lock.Locker, mode LockMode) ServiceOption {
+	return func(s *Service) {
+		s.locker = locker
+		s.lockMode = mode
+	}
+}
+
+// WithLockTTL sets how long a lease lasts without renewal
+func WithLockTTL(ttl time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.lockTTL = ttl
+	}
+}
+
+// WithInstanceID sets the name the service holds leases under
+func WithInstanceID(id string) ServiceOption {
+	return func(s *Service) {
+		s.instanceID = id
+	}
+}
+
+// lockerFromEnv returns the lock backend and mode configured in the
+// environment. The locker is nil when no backend is configured.
+func lockerFromEnv() (lock.Locker, LockMode) {
+	dir := os.Getenv(EnvLockDir)
+	if dir == "" {
+		return nil, LockModeLeader
+	}
+
+	mode, err := ParseLockMode(os.Getenv(EnvLockMode))
+	if err != nil {
+		log.Warn("Invalid lock mode, using leader election", logger.Fields{
+			"value": os.Getenv(EnvLockMode),
+			"error": err.Error(),
+		})
+		mode = LockModeLeader
+	}
+	return lock.NewFileLocker(dir), mode
+}
+
+// lockTTLFromEnv returns the lease time to live configured in the environment
+func lockTTLFromEnv() time.Duration {
+	value := os.Getenv(EnvLockTTL)
+	if value == "" {
+		return DefaultLockTTL
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < time.Second {
+		log.Warn("Invalid lock TTL, using default", logger.Fields{
+			"value":   value,
+			"default": DefaultLockTTL.String(),
+		})
+		return DefaultLockTTL
+	}
+	return ttl
+}
+
+// startLeading takes part in the leader election until ctx is cancelled. It
+// tries to lead right away, then renews its lease or tries to take over every
+// third of the lease. The returned function waits for the election to stop
+// and gives up the lease.
+func (s *Service) startLeading(ctx context.Context) (stop func()) {
+	if s.locker == nil || s.lockMode != LockModeLeader {
+		return func() {}
+	}
+
+	s.renewLeadership(ctx)
+	if !s.isLeader() {
+		log.Info("Another instance is leading, standing by", logger.Fields{"instance": s.instanceID})
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(s.lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.renewLeadership(ctx)
+			}
+		}
+	}()
+
+	return func() {
+		<-done
+		if !s.isLeader() {
+			return
+		}
+		s.leaseUntil.Store(0)
+		if err := s.locker.Release(context.Background(), leaderKey, s.instanceID); err != nil {
+			log.Warn("Failed to release leadership", logger.Fields{"instance": s.instanceID, "error": err.Error()})
+			return
+		}
+		log.Info("Released leadership", logger.Fields{"instance": s.instanceID})
+	}
+}
+
+// renewLeadership takes or extends the leader lease. When the lock backend
+// fails, a leader keeps leading until its current lease runs out.
+func (s *Service) renewLeadership(ctx context.Context) {
+	wasLeader := s.isLeader()
+	start := time.Now()
+
+	ok, err := s.locker.Acquire(ctx, leaderKey, s.instanceID, s.lockTTL)
+	switch {
+	case err != nil:
+		if ctx.Err() != nil {
+			return
+		}
+		log.Error("Failed to renew leadership", logger.Fields{
+			"instance": s.instanceID,
+			"leader":   wasLeader,
+			"error":    err.Error(),
+		})
+	case ok:
+		s.leaseUntil.Store(start.Add(s.lockTTL).UnixNano())
+		if !wasLeader {
+			log.Info("Became leader, running scheduled tasks", logger.Fields{"instance": s.instanceID})
+		}
+	default:
+		s.leaseUntil.Store(0)
+		if wasLeader {
+			log.Warn("Lost leadership, standing by", logger.Fields{"instance": s.instanceID})
+		}
+	}
+}
+
+// isLeader reports whether this instance holds an unexpired leader lease
+func (s *Service) isLeader() bool {
+	return time.Now().UnixNano() < s.leaseUntil.Load()
+}
+
+// claimRun reports whether this instance runs the execution of task, triggered
+// by source, for the fire time at. Without a lock backend every instance runs
+// every execution. In leader mode only the leader does; in per-run mode the
+// instance that takes the lease of the fire time does. A failing lock backend
+// is recorded as a skipped execution.
+func (s *Service) claimRun(task Task, source string, at time.Time) bool {
+	if s.locker == nil {
+		return true
+	}
+
+	if s.lockMode == LockModeLeader {
+		if s.isLeader() {
+			return true
+		}
+		log.Debug("Not the leader, leaving task execution to the leader", logger.Fields{
+			"task":      task.ID(),
+			"fire_time": at.Format(time.RFC3339),
+		})
+		return false
+	}
+
+	slot, length := runSlot(task, at)
+	key := fmt.Sprintf("run:%s:%s", task.ID(), slot.UTC().Format(time.RFC3339))
+	ok, err := s.locker.Acquire(s.runContext(), key, s.instanceID, s.lockTTL+length)
+	if err != nil {
+		s.recordSkip(task, source, fmt.Sprintf("lock unavailable: %v", err))
+		return false
+	}
+	if !ok {
+		log.Debug("Task execution claimed by another instance", logger.Fields{
+			"task":      task.ID(),
+			"fire_time": slot.Format(time.RFC3339),
+		})
+	}
+	return ok
+}
+
+// runSlot returns the start and length of the period that identifies a fire
+// time across instances: its minute, or its second for schedules that fire
+// more than once a minute
+func runSlot(task Task, at time.Time) (time.Time, time.Duration) {
+	minute := at.Truncate(time.Minute)
+	schedule, err := parseSchedule(task.Schedule)
+	if err != nil {
+		return minute, time.Minute
+	}
+
+	first := schedule.Next(minute.Add(-time.Nanosecond))
+	if second := schedule.Next(first); !second.IsZero() && second.Before(minute.Add(time.Minute)) {
+		return at.Truncate(time.Second), time.Second
+	}
+	return minute, time.Minute
+}
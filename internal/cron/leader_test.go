@@ -0,0 +1,144 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/lock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingLocker is a lock backend that can't be reached
+type failingLocker struct{}
+
+func (failingLocker) Acquire(context.Context, string, string, time.Duration) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func (failingLocker) Release(context.Context, string, string) error {
+	return errors.New("connection refused")
+}
+
+func TestParseLockMode(t *testing.T) {
+	mode, err := ParseLockMode("")
+	require.NoError(t, err)
+	assert.Equal(t, LockModeLeader, mode)
+
+	mode, err = ParseLockMode("Per-Run")
+	require.NoError(t, err)
+	assert.Equal(t, LockModePerRun, mode)
+
+	_, err = ParseLockMode("everyone")
+	assert.Error(t, err)
+}
+
+func TestRunSlot(t *testing.T) {
+	at := time.Date(2026, 10, 16, 9, 0, 10, 3000000, time.UTC)
+
+	slot, length := runSlot(Task{Schedule: "0 9 * * *"}, at)
+	assert.Equal(t, time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), slot)
+	assert.Equal(t, time.Minute, length)
+
+	slot, length = runSlot(Task{Schedule: "*/10 * * * * *"}, at)
+	assert.Equal(t, time.Date(2026, 10, 16, 9, 0, 10, 0, time.UTC), slot)
+	assert.Equal(t, time.Second, length)
+}
+
+func TestClaimRunPerRun(t *testing.T) {
+	locker := lock.NewKVLocker(lock.NewMemoryKV(), "cronai:")
+	first := NewCronService("test.config", WithLocker(locker, LockModePerRun), WithInstanceID("first"))
+	second := NewCronService("test.config", WithLocker(locker, LockModePerRun), WithInstanceID("second"))
+	task := Task{Name: "report", Schedule: "0 * * * *"}
+	fire := time.Date(2026, 10, 16, 9, 0, 0, 2000000, time.UTC)
+
+	assert.True(t, first.claimRun(task, history.SourceCron, fire))
+	assert.False(t, second.claimRun(task, history.SourceCron, fire.Add(800*time.Millisecond)))
+
+	// The next fire time and other tasks are claimed separately
+	assert.True(t, second.claimRun(task, history.SourceCron, fire.Add(time.Hour)))
+	assert.True(t, second.claimRun(Task{Name: "digest", Schedule: "0 * * * *"}, history.SourceCron, fire))
+}
+
+func TestClaimRunWithoutLocker(t *testing.T) {
+	service := NewCronService("test.config")
+	assert.True(t, service.claimRun(Task{Name: "report", Schedule: "0 * * * *"}, history.SourceCron, time.Now()))
+}
+
+func TestClaimRunLockUnavailable(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	service := NewCronService("test.config", WithLocker(failingLocker{}, LockModePerRun))
+	assert.False(t, service.claimRun(Task{Name: "report", Schedule: "0 * * * *"}, history.SourceCron, time.Now()))
+
+	records, err := store.List(history.Filter{Task: "report"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StatusSkipped, records[0].Status)
+	assert.Contains(t, records[0].Reason, "lock unavailable")
+}
+
+func TestLeaderElection(t *testing.T) {
+	locker := lock.NewKVLocker(lock.NewMemoryKV(), "cronai:")
+	newService := func(id string) *Service {
+		return NewCronService("test.config", WithLocker(locker, LockModeLeader), WithInstanceID(id), WithLockTTL(time.Minute))
+	}
+	first, second := newService("first"), newService("second")
+	task := Task{Name: "report", Schedule: "0 * * * *"}
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	stopFirstLeading := first.startLeading(firstCtx)
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	stopSecondLeading := second.startLeading(secondCtx)
+	defer func() {
+		stopSecond()
+		stopSecondLeading()
+	}()
+
+	assert.True(t, first.isLeader())
+	assert.False(t, second.isLeader())
+	assert.True(t, first.claimRun(task, history.SourceCron, time.Now()))
+	assert.False(t, second.claimRun(task, history.SourceCron, time.Now()))
+
+	// The leader steps down on shutdown and the standby takes over
+	stopFirst()
+	stopFirstLeading()
+	assert.False(t, first.isLeader())
+	second.renewLeadership(secondCtx)
+	assert.True(t, second.isLeader())
+	assert.True(t, second.claimRun(task, history.SourceCron, time.Now()))
+}
+
+func TestLeaderKeepsLeadingUntilLeaseExpires(t *testing.T) {
+	service := NewCronService("test.config", WithLocker(failingLocker{}, LockModeLeader), WithLockTTL(time.Minute))
+
+	service.leaseUntil.Store(time.Now().Add(time.Minute).UnixNano())
+	service.renewLeadership(context.Background())
+	assert.True(t, service.isLeader())
+
+	service.leaseUntil.Store(time.Now().Add(-time.Second).UnixNano())
+	assert.False(t, service.isLeader())
+}
+
+func TestLockerFromEnv(t *testing.T) {
+	t.Setenv(EnvLockDir, "")
+	locker, _ := lockerFromEnv()
+	assert.Nil(t, locker)
+
+	dir := t.TempDir()
+	t.Setenv(EnvLockDir, dir)
+	t.Setenv(EnvLockMode, "per-run")
+	locker, mode := lockerFromEnv()
+	require.IsType(t, &lock.FileLocker{}, locker)
+	assert.Equal(t, dir, locker.(*lock.FileLocker).Dir())
+	assert.Equal(t, LockModePerRun, mode)
+
+	t.Setenv(EnvLockTTL, "2m")
+	assert.Equal(t, 2*time.Minute, lockTTLFromEnv())
+	t.Setenv(EnvLockTTL, "10ms")
+	assert.Equal(t, DefaultLockTTL, lockTTLFromEnv())
+}
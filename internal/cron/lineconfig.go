@@ -0,0 +1,290 @@
+package cron
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rshade/cronai/internal/logger"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// lineConfig collects the definitions of a line configuration file and of
+// the files it includes
+type lineConfig struct {
+	tasks     []Task
+	taskLines []location
+	calendars []config.CalendarConfig
+	freezes   []config.FreezeConfig
+	checks    []config.CheckConfig
+	budgets   []config.BudgetConfig
+	profiles  []config.ProfileConfig
+	providers []config.ProviderConfig
+	errors    *multierror.Error
+
+	declared map[string]bool // Named providers read so far, tasks after them can use them
+
+	including []string // Files being read, outermost first
+	lookupEnv func(string) (string, bool)
+}
+
+// newLineConfig returns a collector that interpolates the process environment
+func newLineConfig() *lineConfig {
+	return &lineConfig{lookupEnv: os.LookupEnv}
+}
+
+// readFile reads the definitions of a file and the files it includes. It
+// returns an error when the file can't be read; errors in its contents are
+// collected in c.errors.
+func (c *lineConfig) readFile(path string) (err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil {
+			log.Warn("Failed to close config file", logger.Fields{"path": path, "error": closeErr.Error()})
+			if err == nil {
+				err = fmt.Errorf("failed to close config file: %w", closeErr)
+			}
+		}
+	}()
+
+	lines, err := ReadConfigLines(file)
+	if err != nil {
+		return err
+	}
+
+	c.including = append(c.including, path)
+	defer func() { c.including = c.including[:len(c.including)-1] }()
+	dir := filepath.Dir(path)
+	included := len(c.including) > 1
+
+	for _, configLine := range lines {
+		configLine.File = path
+		if err := configLine.interpolate(c.lookupEnv); err != nil {
+			c.errors = multierror.Append(c.errors, configLine.Wrap(err))
+			continue
+		}
+		line := configLine.Text
+		at := location{path, configLine.Line}
+
+		if pattern, err := parseIncludeLine(line); err != nil || pattern != "" {
+			if err == nil {
+				err = c.include(dir, pattern)
+			}
+			if err != nil {
+				c.errors = multierror.Append(c.errors, configLine.Wrap(err))
+			}
+			continue
+		}
+
+		// Calendars, freeze windows, checks, budgets, profiles and providers apply to the tasks once every file is read
+		calendarConfig, err := ParseCalendarLine(line)
+		if err == nil && calendarConfig != nil {
+			calendarConfig.Line, calendarConfig.File = at.line, at.file
+			if included {
+				calendarConfig.Path = relativeTo(dir, calendarConfig.Path)
+			}
+			c.calendars = append(c.calendars, *calendarConfig)
+			continue
+		}
+		freezeConfig, freezeErr := ParseFreezeLine(line)
+		if err == nil {
+			err = freezeErr
+		}
+		if err == nil && freezeConfig != nil {
+			freezeConfig.Line, freezeConfig.File = at.line, at.file
+			c.freezes = append(c.freezes, *freezeConfig)
+			continue
+		}
+		checkConfig, checkErr := ParseCheckLine(line)
+		if err == nil {
+			err = checkErr
+		}
+		if err == nil && checkConfig != nil {
+			if programFromEnv(configLine, checkConfig) {
+				c.errors = multierror.Append(c.errors,
+					configLine.Wrap(fmt.Errorf("check %s: the program of a command check can't come from an environment variable", checkConfig.Name)))
+				continue
+			}
+			checkConfig.Line, checkConfig.File = at.line, at.file
+			if included && checkConfig.Path != "" {
+				checkConfig.Path = relativeTo(dir, checkConfig.Path)
+			}
+			c.checks = append(c.checks, *checkConfig)
+			continue
+		}
+		budgetConfig, budgetErr := ParseBudgetLine(line)
+		if err == nil {
+			err = budgetErr
+		}
+		if err == nil && budgetConfig != nil {
+			budgetConfig.Line, budgetConfig.File = at.line, at.file
+			c.budgets = append(c.budgets, *budgetConfig)
+			continue
+		}
+		profileConfig, profileErr := ParseProfileLine(line)
+		if err == nil {
+			err = profileErr
+		}
+		if err == nil && profileConfig != nil {
+			profileConfig.Line, profileConfig.File = at.line, at.file
+			c.profiles = append(c.profiles, *profileConfig)
+			continue
+		}
+		providerConfig, providerErr := ParseProviderLine(line)
+		if err == nil {
+			err = providerErr
+		}
+		if err == nil && providerConfig != nil {
+			providerConfig.Line, providerConfig.File = at.line, at.file
+			c.providers = append(c.providers, *providerConfig)
+			if c.declared == nil {
+				c.declared = make(map[string]bool)
+			}
+			c.declared[providerConfig.Name] = true
+			continue
+		}
+		if err != nil {
+			c.errors = multierror.Append(c.errors, configLine.Wrap(err))
+			continue
+		}
+
+		task, err := parseTaskLine(line, c.declared)
+		if err != nil {
+			c.errors = multierror.Append(c.errors, configLine.Wrap(err))
+			continue
+		}
+		// Empty and comment lines have no task
+		if task != nil {
+			c.tasks = append(c.tasks, task.Task)
+			c.taskLines = append(c.taskLines, at)
+		}
+	}
+	return nil
+}
+
+// include reads the files matching a pattern, relative to dir, in name
+// order. A pattern without wildcards must name an existing file.
+func (c *lineConfig) include(dir, pattern string) error {
+	pattern = relativeTo(dir, pattern)
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+	}
+	if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+		return fmt.Errorf("include %s: file not found", pattern)
+	}
+	sort.Strings(matches)
+
+	for _, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			continue
+		}
+		for _, reading := range c.including {
+			if sameFile(reading, match) {
+				return fmt.Errorf("include cycle: %s -> %s", strings.Join(c.including, " -> "), match)
+			}
+		}
+		if err := c.readFile(match); err != nil {
+			return fmt.Errorf("include %s: %w", match, err)
+		}
+	}
+	return nil
+}
+
+// parseIncludeLine returns the pattern of an include directive:
+//
+//	include <pattern>
+//
+// The pattern may be quoted. It returns an empty pattern for other lines.
+func parseIncludeLine(line string) (string, error) {
+	trimmed := strings.TrimSpace(line)
+	if !strings.HasPrefix(trimmed, "include ") && !strings.HasPrefix(trimmed, "include\t") {
+		return "", nil
+	}
+
+	start := strings.Index(line, "include") + len("include")
+	for start < len(line) && isBlank(line[start]) {
+		start++
+	}
+	pattern := strings.TrimRight(line[start:], " \t")
+	if strings.HasPrefix(pattern, `"`) || strings.HasPrefix(pattern, "'") {
+		value, end, err := unquote(line, start)
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(line[end:]) != "" {
+			return "", errorAt(line, end, fmt.Errorf("unexpected text after closing quote"))
+		}
+		pattern = value
+	}
+	if pattern == "" {
+		return "", errorAt(line, start, fmt.Errorf("include needs a file or pattern"))
+	}
+	return pattern, nil
+}
+
+// includedFiles returns the files a line configuration file includes,
+// directly or through other included files. Files that can't be read are
+// left out; parsing the configuration reports them.
+func includedFiles(path string) []string {
+	read := []string{path}
+	var walk func(path string)
+	walk = func(path string) {
+		file, err := os.Open(path)
+		if err != nil {
+			return
+		}
+		lines, err := ReadConfigLines(file)
+		_ = file.Close()
+		if err != nil {
+			return
+		}
+		for _, configLine := range lines {
+			if configLine.interpolate(os.LookupEnv) != nil {
+				continue
+			}
+			pattern, err := parseIncludeLine(configLine.Text)
+			if err != nil || pattern == "" {
+				continue
+			}
+			matches, _ := filepath.Glob(relativeTo(filepath.Dir(path), pattern))
+			sort.Strings(matches)
+		next:
+			for _, match := range matches {
+				for _, file := range read {
+					if sameFile(file, match) {
+						continue next
+					}
+				}
+				read = append(read, match)
+				walk(match)
+			}
+		}
+	}
+	walk(path)
+	return read[1:]
+}
+
+// relativeTo resolves a relative path against dir
+func relativeTo(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// sameFile reports whether two paths name the same file
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return absA == absB
+}
@@ -0,0 +1,148 @@
+package cron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigFiles writes files, by path relative to a temporary directory,
+// and returns the directory
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		switch name {
+		case "REGION":
+			return "emea", true
+		case "EMPTY":
+			return "", true
+		}
+		return "", false
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"team=${REGION}", "team=emea"},
+		{"team=${ZONE:-a},b=${EMPTY:-fallback},c=${EMPTY}", "team=a,b=fallback,c="},
+		{"price=$${REGION} and $5", "price=${REGION} and $5"},
+		{"# ${UNSET}", "# ${UNSET}"},
+	}
+	for _, tt := range tests {
+		line := ConfigLine{Text: tt.text, Line: 1}
+		require.NoError(t, line.interpolate(lookup), tt.text)
+		assert.Equal(t, tt.want, line.Text, tt.text)
+	}
+
+	line := ConfigLine{Text: "0 9 * * * openai p console a=${REGION},b=${UNSET}", Line: 4, File: "cronai.config"}
+	err := line.interpolate(lookup)
+	require.Error(t, err)
+	assert.Equal(t, "cronai.config: line 4, column 42: environment variable UNSET is not set (use ${UNSET:-default} to give a default)",
+		line.Wrap(err).Error())
+
+	// Errors in the interpolated line point at the text as written
+	line = ConfigLine{Text: "0 9 * * * ${MODEL} p console", Line: 2}
+	require.NoError(t, line.interpolate(func(string) (string, bool) { return "gpt-x", true }))
+	_, err = parseLine(line.Text)
+	require.Error(t, err)
+	assert.Equal(t, "line 2, column 11: invalid model 'gpt-x'", line.Wrap(err).Error())
+
+	line = ConfigLine{Text: "0 9 * * ${DAY} ${MODEL} p pigeon", Line: 2}
+	require.NoError(t, line.interpolate(func(name string) (string, bool) {
+		return map[string]string{"DAY": "1-5", "MODEL": "openai"}[name], true
+	}))
+	_, err = parseLine(line.Text)
+	require.Error(t, err)
+	assert.Equal(t, "line 2, column 27: invalid processor format 'pigeon'", line.Wrap(err).Error())
+
+	for _, text := range []string{"a=${REGION", "a=${1X}", "a=${}"} {
+		line := ConfigLine{Text: text}
+		assert.Error(t, line.interpolate(lookup), text)
+	}
+}
+
+func TestParseConfigFileIncludes(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+	t.Setenv("CRONAI_TEST_ENV", "prod")
+
+	dir := writeConfigFiles(t, map[string]string{
+		"cronai.config": `include tasks/*.config
+include "${CRONAI_TEST_ENV}.config"
+0 8 * * * openai test_prompt console name=main
+`,
+		"tasks/a.config":     "0 9 * * * openai test_prompt console name=a,region=${CRONAI_TEST_REGION:-us}\n",
+		"tasks/b.config":     "calendar holidays holidays.txt\n0 10 * * * openai test_prompt console name=b,skip_calendar=holidays\n",
+		"tasks/holidays.txt": "2026-12-25 Christmas Day\n",
+		"prod.config":        "0 11 * * * openai test_prompt console name=prod\n",
+	})
+	configPath := filepath.Join(dir, "cronai.config")
+
+	tasks, err := parseConfigFile(configPath)
+	require.NoError(t, err)
+	names := make([]string, len(tasks))
+	for i, task := range tasks {
+		names[i] = task.Name
+	}
+	assert.Equal(t, []string{"a", "b", "prod", "main"}, names)
+	assert.Equal(t, "us", tasks[0].Variables["region"])
+	require.NotNil(t, tasks[1].calendars, "calendar paths are relative to the file that defines them")
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "tasks/a.config"), filepath.Join(dir, "tasks/b.config"), filepath.Join(dir, "prod.config"),
+	}, includedFiles(configPath))
+
+	// Errors name the file they were found in
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "tasks/c.config"),
+		[]byte("# broken\n0 12 * * * openai test_prompt pigeon\n0 13 * * * openai test_prompt console name=main\n"), 0644))
+	_, err = parseConfigFile(configPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), filepath.Join(dir, "tasks/c.config")+": line 2, column 31: invalid processor format 'pigeon'")
+	assert.Contains(t, err.Error(), configPath+": line 3: duplicate task name 'main' (first defined on "+
+		filepath.Join(dir, "tasks/c.config")+": line 3)")
+}
+
+func TestParseConfigFileIncludeErrors(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"cronai.config": "include missing.config\ninclude loop.config\n",
+		"loop.config":   "include cronai.config\n",
+	})
+	configPath := filepath.Join(dir, "cronai.config")
+
+	_, err := parseConfigFile(configPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), configPath+": line 1: include "+filepath.Join(dir, "missing.config")+": file not found")
+	assert.Contains(t, err.Error(), "include cycle: "+configPath+" -> "+filepath.Join(dir, "loop.config")+" -> "+configPath)
+
+	// A pattern that matches nothing is fine
+	require.NoError(t, os.WriteFile(configPath, []byte("include conf.d/*.config\n"), 0644))
+	tasks, err := parseConfigFile(configPath)
+	require.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestStatConfigIncludes(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"cronai.config": "include conf.d/*.config\n",
+	})
+	configPath := filepath.Join(dir, "cronai.config")
+
+	before := statConfig(configPath)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "a.config"), []byte("# new\n"), 0644))
+	assert.NotEqual(t, before, statConfig(configPath), "a new included file changes the stamp")
+}
@@ -0,0 +1,31 @@
+package cron
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rshade/cronai/internal/history"
+)
+
+// TestMain keeps execution history in memory, task state in a temporary
+// directory and the control socket closed so tests don't write to the working
+// directory
+func TestMain(m *testing.M) {
+	history.SetStore(history.NewMemoryStore())
+
+	stateDir, err := os.MkdirTemp("", "cronai-state")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Setenv(EnvStatePath, filepath.Join(stateDir, "state.json")); err != nil {
+		panic(err)
+	}
+	if err := os.Setenv(EnvControlSocket, "none"); err != nil {
+		panic(err)
+	}
+
+	code := m.Run()
+	_ = os.RemoveAll(stateDir) //nolint:errcheck // best-effort cleanup
+	os.Exit(code)
+}
@@ -0,0 +1,224 @@
+package cron
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taskOptions holds settings given in the variables section of a config line
+// that configure how the task runs rather than the prompt itself
+type taskOptions struct {
+	Name    string
+	After   string
+	On      TriggerOn
+	Overlap Overlap
+	Catchup Catchup
+	Timeout time.Duration
+
+	Group        string
+	SkipCalendar string
+	OnlyCalendar string
+	When         []string
+	Tags         []string
+
+	Retry  Retry
+	Stream bool
+}
+
+// extractTaskOptions removes task options from variables and parses them.
+// Errors are variableErrors naming the option at fault.
+func extractTaskOptions(variables map[string]string) (taskOptions, error) {
+	var options taskOptions
+	var err error
+
+	if value, ok := variables["name"]; ok {
+		if err = validateTaskName(value); err != nil {
+			return options, &variableError{key: "name", err: err}
+		}
+		options.Name = value
+		delete(variables, "name")
+	}
+
+	if value, ok := variables["after"]; ok {
+		if err = validateTaskName(value); err != nil {
+			return options, &variableError{key: "after", err: fmt.Errorf("invalid after: %w", err)}
+		}
+		options.After = value
+		delete(variables, "after")
+	}
+
+	if value, ok := variables["on"]; ok {
+		if options.After == "" {
+			return options, &variableError{key: "on", err: fmt.Errorf("on=%s requires an after=<task> option", value)}
+		}
+		if options.On, err = ParseTriggerOn(value); err != nil {
+			return options, &variableError{key: "on", err: err}
+		}
+		delete(variables, "on")
+	}
+
+	if value, ok := variables["overlap"]; ok {
+		if options.Overlap, err = ParseOverlap(value); err != nil {
+			return options, &variableError{key: "overlap", err: err}
+		}
+		delete(variables, "overlap")
+	}
+
+	if value, ok := variables["catchup"]; ok {
+		if options.Catchup, err = ParseCatchup(value); err != nil {
+			return options, &variableError{key: "catchup", err: err}
+		}
+		delete(variables, "catchup")
+	}
+
+	if value, ok := variables["timeout"]; ok {
+		if options.Timeout, err = ParseTimeout(value); err != nil {
+			return options, &variableError{key: "timeout", err: err}
+		}
+		delete(variables, "timeout")
+	}
+
+	if value, ok := variables["group"]; ok {
+		if err = validateTaskName(value); err != nil {
+			return options, &variableError{key: "group", err: fmt.Errorf("invalid group: %w", err)}
+		}
+		options.Group = value
+		delete(variables, "group")
+	}
+
+	if value, ok := variables["skip_calendar"]; ok {
+		if err = validateTaskName(value); err != nil {
+			return options, &variableError{key: "skip_calendar", err: fmt.Errorf("invalid skip_calendar: %w", err)}
+		}
+		options.SkipCalendar = value
+		delete(variables, "skip_calendar")
+	}
+
+	if value, ok := variables["only_calendar"]; ok {
+		if err = validateTaskName(value); err != nil {
+			return options, &variableError{key: "only_calendar", err: fmt.Errorf("invalid only_calendar: %w", err)}
+		}
+		options.OnlyCalendar = value
+		delete(variables, "only_calendar")
+	}
+
+	if value, ok := variables["when"]; ok {
+		for _, name := range strings.Split(value, "+") {
+			if err = validateTaskName(name); err != nil {
+				return options, &variableError{key: "when", err: fmt.Errorf("invalid when: %w", err)}
+			}
+			options.When = append(options.When, name)
+		}
+		delete(variables, "when")
+	}
+
+	if value, ok := variables["tags"]; ok {
+		for _, tag := range strings.Split(value, "+") {
+			if err = validateTaskName(tag); err != nil {
+				return options, &variableError{key: "tags", err: fmt.Errorf("invalid tags: %w", err)}
+			}
+			options.Tags = append(options.Tags, tag)
+		}
+		delete(variables, "tags")
+	}
+
+	if options.Retry, err = extractRetry(variables); err != nil {
+		return options, err
+	}
+
+	if value, ok := variables["stream"]; ok {
+		if options.Stream, err = strconv.ParseBool(value); err != nil {
+			return options, &variableError{key: "stream", err: fmt.Errorf("invalid stream '%s' (use true or false)", value)}
+		}
+		delete(variables, "stream")
+	}
+
+	return options, nil
+}
+
+// checkOptionClash returns a variableError for the first of the task options
+// given on a line that the task's prompt uses as a variable. Options aren't
+// passed to the prompt, so it would silently render without the value. A
+// prompt that can't be read is reported by validateTask instead.
+func checkOptionClash(promptName string, optionKeys []string) error {
+	if len(optionKeys) == 0 {
+		return nil
+	}
+	promptPath := promptName
+	if !strings.HasSuffix(promptPath, ".md") {
+		promptPath += ".md"
+	}
+	content, err := os.ReadFile(filepath.Join("cron_prompts", promptPath))
+	if err != nil {
+		return nil
+	}
+
+	for _, key := range optionKeys {
+		quoted := regexp.QuoteMeta(key)
+		usage := regexp.MustCompile(`\{\{\s*` + quoted + `\s*\}\}|\.Variables\.` + quoted + `\b`)
+		if usage.Match(content) {
+			return &variableError{key: key, err: fmt.Errorf(
+				"%s is a task option and isn't passed to the prompt, but prompt '%s' uses it as a variable (rename the variable in the prompt)",
+				key, promptName)}
+		}
+	}
+	return nil
+}
+
+// extractRetry removes the delivery retry options from variables and parses
+// them. The other retry options require retry_limit.
+func extractRetry(variables map[string]string) (Retry, error) {
+	var retry Retry
+	var err error
+
+	value, ok := variables["retry_limit"]
+	if ok {
+		if retry.Limit, err = ParseRetryLimit(value); err != nil {
+			return retry, &variableError{key: "retry_limit", err: err}
+		}
+		retry.Backoff = BackoffExponential
+		delete(variables, "retry_limit")
+	}
+	for _, key := range []string{"retry_backoff", "retry_delay", "retry_max_delay"} {
+		if _, set := variables[key]; set && !ok {
+			return retry, &variableError{key: key, err: fmt.Errorf("%s requires a retry_limit option", key)}
+		}
+	}
+
+	if value, ok := variables["retry_backoff"]; ok {
+		if retry.Backoff, err = ParseBackoff(value); err != nil {
+			return retry, &variableError{key: "retry_backoff", err: err}
+		}
+		delete(variables, "retry_backoff")
+	}
+	if value, ok := variables["retry_delay"]; ok {
+		if retry.Delay, err = parseRetryDelay("retry_delay", value); err != nil {
+			return retry, &variableError{key: "retry_delay", err: err}
+		}
+		delete(variables, "retry_delay")
+	}
+	if value, ok := variables["retry_max_delay"]; ok {
+		if retry.MaxDelay, err = parseRetryDelay("retry_max_delay", value); err != nil {
+			return retry, &variableError{key: "retry_max_delay", err: err}
+		}
+		delete(variables, "retry_max_delay")
+		if err = retry.validate(); err != nil {
+			return retry, &variableError{key: "retry_max_delay", err: err}
+		}
+	}
+	return retry, nil
+}
+
+// ParseTimeout parses a task timeout such as 90s or 5m
+func ParseTimeout(value string) (time.Duration, error) {
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout '%s' (use a positive duration such as 90s or 5m)", value)
+	}
+	return timeout, nil
+}
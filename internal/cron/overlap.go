@@ -0,0 +1,201 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// Overlap determines what happens when a task fires while its previous run is still in progress
+type Overlap string
+
+// Overlap policies
+const (
+	// OverlapAllow starts the new run alongside the running one (default)
+	OverlapAllow Overlap = "allow"
+	// OverlapSkip drops the new run
+	OverlapSkip Overlap = "skip"
+	// OverlapQueue starts the new run once the running one finishes
+	OverlapQueue Overlap = "queue"
+	// OverlapCancelPrevious cancels the running one and starts the new run
+	OverlapCancelPrevious Overlap = "cancel-previous"
+)
+
+// ParseOverlap parses an overlap policy name. An empty value selects OverlapAllow.
+func ParseOverlap(value string) (Overlap, error) {
+	switch policy := Overlap(strings.ToLower(strings.TrimSpace(value))); policy {
+	case "":
+		return OverlapAllow, nil
+	case OverlapAllow, OverlapSkip, OverlapQueue, OverlapCancelPrevious:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid overlap policy '%s' (supported: skip, queue, allow, cancel-previous)", value)
+	}
+}
+
+// maxQueuedRuns is how many executions of a task with the queue policy may
+// wait for the running one
+const maxQueuedRuns = 5
+
+// taskJob is a task execution that stops early when its context is cancelled
+type taskJob func(ctx context.Context)
+
+// overlapWrapper adapts a task execution triggered by source into a cron job
+// that enforces an overlap policy
+type overlapWrapper func(source string, job taskJob) cron.Job
+
+// overlapWrapperFor returns the wrapper enforcing the task's overlap policy.
+// Each call returns a wrapper with its own state, so every scheduler entry
+// tracks only its own runs. Runs are cancelled when the service stops.
+func (s *Service) overlapWrapperFor(task Task) overlapWrapper {
+	switch task.Overlap {
+	case OverlapSkip:
+		return s.skipIfRunning(task)
+	case OverlapQueue:
+		return s.queueIfRunning(task)
+	case OverlapCancelPrevious:
+		return s.cancelPrevious()
+	default:
+		return s.allowOverlap()
+	}
+}
+
+// allowOverlap runs every execution regardless of the ones in progress
+func (s *Service) allowOverlap() overlapWrapper {
+	return func(_ string, job taskJob) cron.Job {
+		return cron.FuncJob(func() {
+			job(s.runContext())
+		})
+	}
+}
+
+// skipIfRunning drops executions that fire while a previous one is still running
+func (s *Service) skipIfRunning(task Task) overlapWrapper {
+	var running atomic.Bool
+	return func(source string, job taskJob) cron.Job {
+		return cron.FuncJob(func() {
+			if !running.CompareAndSwap(false, true) {
+				s.recordSkip(task, source, "previous run still in progress")
+				return
+			}
+			defer running.Store(false)
+			job(s.runContext())
+		})
+	}
+}
+
+// queueIfRunning delays executions until the previous one has finished. Up to
+// maxQueuedRuns executions wait in the order they fired and run one after the
+// other; executions that fire while the queue is full are skipped.
+func (s *Service) queueIfRunning(task Task) overlapWrapper {
+	var (
+		mu      sync.Mutex
+		running bool
+		queued  []taskJob
+	)
+	return func(source string, job taskJob) cron.Job {
+		return cron.FuncJob(func() {
+			mu.Lock()
+			if running {
+				if len(queued) >= maxQueuedRuns {
+					mu.Unlock()
+					s.recordSkip(task, source, "run queue full")
+					return
+				}
+				queued = append(queued, job)
+				mu.Unlock()
+				return
+			}
+			running = true
+			mu.Unlock()
+
+			// Run the queued executions after this one
+			for job != nil {
+				job(s.runContext())
+
+				mu.Lock()
+				job = nil
+				if len(queued) > 0 {
+					job, queued = queued[0], queued[1:]
+				} else {
+					running = false
+				}
+				mu.Unlock()
+			}
+		})
+	}
+}
+
+// cancelPrevious cancels the running execution, waits for it to stop and then
+// starts the new one
+func (s *Service) cancelPrevious() overlapWrapper {
+	var (
+		mu     sync.Mutex
+		cancel context.CancelFunc
+		done   chan struct{}
+	)
+	return func(_ string, job taskJob) cron.Job {
+		return cron.FuncJob(func() {
+			mu.Lock()
+			if cancel != nil {
+				cancel()
+				<-done
+			}
+			ctx, runCancel := context.WithCancel(s.runContext())
+			runDone := make(chan struct{})
+			cancel, done = runCancel, runDone
+			mu.Unlock()
+
+			defer close(runDone)
+			defer runCancel()
+			job(ctx)
+		})
+	}
+}
+
+// recordSkip logs, counts and records an execution triggered by source that
+// was not started
+func (s *Service) recordSkip(task Task, source, reason string) {
+	s.mu.Lock()
+	if s.skipped == nil {
+		s.skipped = make(map[string]int)
+	}
+	s.skipped[task.ID()]++
+	count := s.skipped[task.ID()]
+	s.mu.Unlock()
+
+	log.Warn("Skipped task execution", logger.Fields{
+		"task":          task.ID(),
+		"prompt":        task.Prompt,
+		"source":        source,
+		"reason":        reason,
+		"skipped_total": count,
+	})
+
+	record := newTaskRecord(task, source)
+	record.Skip(reason)
+	if err := history.Save(record); err != nil {
+		log.Warn("Failed to record execution history", logger.Fields{
+			"execution_id": record.ID,
+			"error":        err.Error(),
+		})
+	}
+}
+
+// SkippedRuns returns the number of skipped executions per task ID
+func (s *Service) SkippedRuns() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int, len(s.skipped))
+	for id, count := range s.skipped {
+		counts[id] = count
+	}
+	return counts
+}
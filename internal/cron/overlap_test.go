@@ -0,0 +1,328 @@
+package cron
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOverlap(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Overlap
+		wantErr  bool
+	}{
+		{"", OverlapAllow, false},
+		{"allow", OverlapAllow, false},
+		{"skip", OverlapSkip, false},
+		{"Queue", OverlapQueue, false},
+		{"cancel-previous", OverlapCancelPrevious, false},
+		{"sometimes", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			policy, err := ParseOverlap(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, policy)
+		})
+	}
+}
+
+func TestParseConfigLineOverlap(t *testing.T) {
+	task, err := parseConfigLine("*/5 * * * * openai test slack-test overlap=skip")
+	require.NoError(t, err)
+	assert.Equal(t, OverlapSkip, task.Task.Overlap)
+	assert.Nil(t, task.Task.Variables, "overlap is a task option, not a prompt variable")
+
+	task, err = parseConfigLine("*/5 * * * * openai test slack-test overlap=queue,team=ops")
+	require.NoError(t, err)
+	assert.Equal(t, OverlapQueue, task.Task.Overlap)
+	assert.Equal(t, map[string]string{"team": "ops"}, task.Task.Variables)
+
+	_, err = parseConfigLine("*/5 * * * * openai test slack-test overlap=never")
+	assert.Error(t, err)
+}
+
+// blockingJob returns a task job that reports when it starts and blocks until
+// released or cancelled
+func blockingJob(started chan<- struct{}, release <-chan struct{}, runs, cancelled *int32) taskJob {
+	return func(ctx context.Context) {
+		atomic.AddInt32(runs, 1)
+		started <- struct{}{}
+		select {
+		case <-release:
+		case <-ctx.Done():
+			atomic.AddInt32(cancelled, 1)
+		}
+	}
+}
+
+func TestOverlapSkip(t *testing.T) {
+	history.SetStore(history.NewMemoryStore())
+
+	service := NewCronService("test.config")
+	task := Task{Prompt: "slow", Overlap: OverlapSkip}
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var runs, cancelled int32
+	job := service.overlapWrapperFor(task)(history.SourceCron, blockingJob(started, release, &runs, &cancelled))
+
+	go job.Run()
+	<-started
+
+	// Fires while the first run is in progress are dropped
+	job.Run()
+	job.Run()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	assert.Equal(t, 2, service.SkippedRuns()[task.ID()])
+
+	records, err := history.GetStore().List(history.Filter{Status: history.StatusSkipped})
+	require.NoError(t, err)
+	assert.Len(t, records, 2)
+
+	// Once finished the task runs again
+	close(release)
+	assert.Eventually(t, func() bool {
+		job.Run()
+		return atomic.LoadInt32(&runs) == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestOverlapSkipRecordsSource(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	service := NewCronService("test.config")
+	task := Task{Prompt: "slow", Overlap: OverlapSkip}
+	wrap := service.overlapWrapperFor(task)
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	defer close(release)
+	var runs, cancelled int32
+	go wrap(history.SourceCron, blockingJob(started, release, &runs, &cancelled)).Run()
+	<-started
+
+	// A trigger dropped while the scheduled run is in progress is recorded as a trigger
+	wrap(history.SourceTrigger, blockingJob(started, release, &runs, &cancelled)).Run()
+	records, err := store.List(history.Filter{Status: history.StatusSkipped})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.SourceTrigger, records[0].Source)
+}
+
+func TestOverlapQueue(t *testing.T) {
+	service := NewCronService("test.config")
+	task := Task{Prompt: "slow", Overlap: OverlapQueue}
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var runs, cancelled int32
+	job := service.overlapWrapperFor(task)(history.SourceCron, blockingJob(started, release, &runs, &cancelled))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); job.Run() }()
+	<-started
+	go func() { defer wg.Done(); job.Run() }()
+
+	// The second run waits for the first
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	assert.Empty(t, service.SkippedRuns())
+}
+
+func TestOverlapQueueOrderAndLimit(t *testing.T) {
+	history.SetStore(history.NewMemoryStore())
+
+	service := NewCronService("test.config")
+	task := Task{Prompt: "slow", Overlap: OverlapQueue}
+	wrap := service.overlapWrapperFor(task)
+
+	var mu sync.Mutex
+	var order []int
+	release := make(chan struct{})
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		wrap(history.SourceCron, func(_ context.Context) {
+			close(started)
+			<-release
+		}).Run()
+	}()
+	<-started
+
+	// Fires during the run return right away, the ones over the limit are skipped
+	for i := 1; i <= maxQueuedRuns+2; i++ {
+		wrap(history.SourceCron, func(_ context.Context) {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, i)
+		}).Run()
+	}
+	assert.Equal(t, 2, service.SkippedRuns()[task.ID()])
+
+	// Queued runs start in the order they fired once the running one finishes
+	close(release)
+	<-finished
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, order)
+
+	records, err := history.GetStore().List(history.Filter{Status: history.StatusSkipped})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "run queue full", records[0].Reason)
+}
+
+func TestOverlapAllow(t *testing.T) {
+	service := NewCronService("test.config")
+	task := Task{Prompt: "slow"}
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var runs, cancelled int32
+	job := service.overlapWrapperFor(task)(history.SourceCron, blockingJob(started, release, &runs, &cancelled))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); job.Run() }()
+	go func() { defer wg.Done(); job.Run() }()
+
+	// Both runs are in progress at the same time
+	<-started
+	<-started
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestOverlapCancelPrevious(t *testing.T) {
+	service := NewCronService("test.config")
+	task := Task{Prompt: "slow", Overlap: OverlapCancelPrevious}
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var runs, cancelled int32
+	job := service.overlapWrapperFor(task)(history.SourceCron, blockingJob(started, release, &runs, &cancelled))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); job.Run() }()
+	<-started
+	go func() { defer wg.Done(); job.Run() }()
+
+	// The new run cancels the one in progress before starting
+	<-started
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cancelled))
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cancelled))
+}
+
+func TestRunTaskCancelled(t *testing.T) {
+	history.SetStore(history.NewMemoryStore())
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("test", "This is a test prompt")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	modelCalled := false
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, _, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		modelCalled = true
+		return &models.ModelResponse{Content: "stale"}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	service := NewCronService("test.config")
+	record, err := service.runTask(ctx, Task{Model: "openai", Prompt: "test", Processor: "console"}, history.SourceCron, nil)
+	require.Error(t, err)
+	assert.False(t, modelCalled, "a cancelled run must not call the model")
+	assert.Equal(t, history.StatusFailed, record.Status)
+}
+
+func TestParseConfigLineTimeout(t *testing.T) {
+	task, err := parseConfigLine("*/5 * * * * openai test slack-test timeout=90s,team=ops")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, task.Task.Timeout)
+	assert.Equal(t, map[string]string{"team": "ops"}, task.Task.Variables)
+
+	for _, value := range []string{"soon", "0s", "-5m"} {
+		_, err = parseConfigLine("*/5 * * * * openai test slack-test timeout=" + value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRunTaskTimeout(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("test", "This is a test prompt")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	// The model call only returns when its context ends
+	oldExecuteModel := executeModel
+	executeModel = func(ctx context.Context, _, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		<-ctx.Done()
+		return nil, context.Cause(ctx)
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	service := NewCronService("test.config")
+	task := Task{Model: "openai", Prompt: "test", Processor: "console", Timeout: 20 * time.Millisecond}
+	record, err := service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "task timed out after 20ms")
+	assert.Equal(t, history.StatusFailed, record.Status)
+	assert.Equal(t, history.StageModel, record.Stage)
+}
+
+func TestOverlapServiceShutdown(t *testing.T) {
+	service := NewCronService("test.config")
+	ctx, cancel := context.WithCancel(context.Background())
+	service.ctx = ctx
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	var runs, cancelled int32
+	job := service.overlapWrapperFor(Task{Prompt: "slow"})(history.SourceCron, blockingJob(started, release, &runs, &cancelled))
+
+	done := make(chan struct{})
+	go func() { defer close(done); job.Run() }()
+	<-started
+
+	// Stopping the service cancels the runs in progress
+	cancel()
+	<-done
+	assert.Equal(t, int32(1), atomic.LoadInt32(&cancelled))
+}
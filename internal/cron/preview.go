@@ -0,0 +1,125 @@
+package cron
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxPreviewFires caps the number of fire times computed for a single task
+// when previewing a time range
+const MaxPreviewFires = 1000
+
+// Preview lists the upcoming fire times of a task
+type Preview struct {
+	Task  Task
+	Fires []time.Time
+	Skips []string // why each fire is skipped by a calendar or freeze window, "" when it runs
+	Never bool     // the schedule has no fire time at all
+}
+
+// SkipReason returns why the i-th fire is skipped, or an empty string when it runs
+func (p Preview) SkipReason(i int) string {
+	if i < len(p.Skips) {
+		return p.Skips[i]
+	}
+	return ""
+}
+
+// Collision is a minute in which several tasks fire
+type Collision struct {
+	Time  time.Time
+	Tasks []string
+}
+
+// NextFires returns the next n fire times of each task after from. Tasks that
+// only run after an upstream task have no fire times of their own.
+func NextFires(tasks []Task, from time.Time, n int) ([]Preview, error) {
+	return previewTasks(tasks, from, func(fires []time.Time, _ time.Time) bool {
+		return len(fires) < n
+	})
+}
+
+// FiresBetween returns the fire times of each task in [start, end), at most
+// MaxPreviewFires per task
+func FiresBetween(tasks []Task, start, end time.Time) ([]Preview, error) {
+	if !end.After(start) {
+		return nil, fmt.Errorf("end %s is not after start %s", end.Format(time.RFC3339), start.Format(time.RFC3339))
+	}
+	// Schedules return the first fire time strictly after the given time
+	from := start.Add(-time.Second)
+	return previewTasks(tasks, from, func(fires []time.Time, next time.Time) bool {
+		return next.Before(end) && len(fires) < MaxPreviewFires
+	})
+}
+
+// previewTasks walks each task's schedule from from while keep returns true
+func previewTasks(tasks []Task, from time.Time, keep func(fires []time.Time, next time.Time) bool) ([]Preview, error) {
+	previews := make([]Preview, 0, len(tasks))
+	for _, task := range tasks {
+		preview := Preview{Task: task}
+		if task.Schedule == ScheduleAfter {
+			previews = append(previews, preview)
+			continue
+		}
+
+		schedule, err := parseSchedule(task.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("task %s: invalid schedule '%s': %w", task.ID(), task.Schedule, err)
+		}
+
+		// Show fire times in the schedule's own timezone
+		start := from
+		if loc, err := task.location(); err == nil && loc != nil {
+			start = from.In(loc)
+		}
+
+		next := schedule.Next(start)
+		// robfig/cron gives up after five years without a match
+		preview.Never = next.IsZero()
+		for !next.IsZero() && keep(preview.Fires, next) {
+			preview.Fires = append(preview.Fires, next)
+			preview.Skips = append(preview.Skips, task.SkipReason(next))
+			next = schedule.Next(next)
+		}
+		previews = append(previews, preview)
+	}
+	return previews, nil
+}
+
+// Collisions returns the minutes in which more than one task fires, in time
+// order. Skipped fires don't collide.
+func Collisions(previews []Preview) []Collision {
+	byMinute := make(map[int64]*Collision)
+	for _, preview := range previews {
+		seen := make(map[int64]bool)
+		for i, fire := range preview.Fires {
+			if preview.SkipReason(i) != "" {
+				continue
+			}
+			minute := fire.Truncate(time.Minute)
+			if seen[minute.Unix()] {
+				continue
+			}
+			seen[minute.Unix()] = true
+
+			collision, ok := byMinute[minute.Unix()]
+			if !ok {
+				collision = &Collision{Time: minute}
+				byMinute[minute.Unix()] = collision
+			}
+			collision.Tasks = append(collision.Tasks, preview.Task.ID())
+		}
+	}
+
+	var collisions []Collision
+	for _, collision := range byMinute {
+		if len(collision.Tasks) > 1 {
+			collisions = append(collisions, *collision)
+		}
+	}
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Time.Before(collisions[j].Time)
+	})
+	return collisions
+}
@@ -0,0 +1,95 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextFires(t *testing.T) {
+	from := time.Date(2026, 10, 16, 8, 30, 0, 0, time.Local)
+	tasks := []Task{
+		{Name: "hourly", Schedule: "0 * * * *"},
+		{Name: "summary", Schedule: ScheduleAfter, After: "hourly"},
+		{Name: "feb30", Schedule: "0 0 30 2 *"},
+	}
+
+	previews, err := NextFires(tasks, from, 3)
+	require.NoError(t, err)
+	require.Len(t, previews, 3)
+
+	assert.Equal(t, []time.Time{
+		time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local),
+		time.Date(2026, 10, 16, 10, 0, 0, 0, time.Local),
+		time.Date(2026, 10, 16, 11, 0, 0, 0, time.Local),
+	}, previews[0].Fires)
+	assert.False(t, previews[0].Never)
+
+	// Dependent tasks have no schedule of their own
+	assert.Empty(t, previews[1].Fires)
+	assert.False(t, previews[1].Never)
+
+	assert.Empty(t, previews[2].Fires)
+	assert.True(t, previews[2].Never)
+
+	_, err = NextFires([]Task{{Schedule: "not a schedule"}}, from, 3)
+	assert.Error(t, err)
+}
+
+func TestFiresBetween(t *testing.T) {
+	start := time.Date(2026, 10, 16, 0, 0, 0, 0, time.Local)
+	end := time.Date(2026, 10, 18, 0, 0, 0, 0, time.Local)
+
+	previews, err := FiresBetween([]Task{{Name: "daily", Schedule: "0 0 * * *"}}, start, end)
+	require.NoError(t, err)
+
+	// The start is included, the end is not
+	assert.Equal(t, []time.Time{start, start.AddDate(0, 0, 1)}, previews[0].Fires)
+
+	_, err = FiresBetween(nil, end, start)
+	assert.Error(t, err)
+
+	// Frequent schedules over long ranges are capped
+	previews, err = FiresBetween([]Task{{Schedule: "* * * * *"}}, start, end.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	assert.Len(t, previews[0].Fires, MaxPreviewFires)
+}
+
+func TestFiresBetweenDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+
+	// Clocks go back from 02:00 to 01:00 on 2026-11-01
+	start := time.Date(2026, 10, 31, 0, 0, 0, 0, newYork)
+	end := time.Date(2026, 11, 2, 0, 0, 0, 0, newYork)
+	previews, err := FiresBetween([]Task{{Schedule: "CRON_TZ=America/New_York 30 8 * * *"}}, start, end)
+	require.NoError(t, err)
+	require.Len(t, previews[0].Fires, 2)
+
+	first, second := previews[0].Fires[0], previews[0].Fires[1]
+	assert.Equal(t, 8, first.Hour())
+	assert.Equal(t, 8, second.Hour())
+	assert.Equal(t, 25*time.Hour, second.Sub(first))
+}
+
+func TestCollisions(t *testing.T) {
+	at := func(hour, minute, second int) time.Time {
+		return time.Date(2026, 10, 16, hour, minute, second, 0, time.UTC)
+	}
+	previews := []Preview{
+		{Task: Task{Name: "a"}, Fires: []time.Time{at(8, 0, 0), at(9, 0, 0)}},
+		{Task: Task{Name: "b"}, Fires: []time.Time{at(8, 0, 30), at(9, 30, 0)}},
+		{Task: Task{Name: "c"}, Fires: []time.Time{at(9, 30, 0)}},
+	}
+
+	assert.Equal(t, []Collision{
+		{Time: at(8, 0, 0), Tasks: []string{"a", "b"}},
+		{Time: at(9, 30, 0), Tasks: []string{"b", "c"}},
+	}, Collisions(previews))
+
+	assert.Empty(t, Collisions(previews[:1]))
+}
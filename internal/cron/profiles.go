@@ -0,0 +1,216 @@
+package cron
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rshade/cronai/internal/logger"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// EnvProfile selects the profile whose overrides apply to the configuration,
+// as the --profile flag does
+const EnvProfile = "CRONAI_PROFILE"
+
+// activeProfile returns the name of the profile selected in the environment
+func activeProfile() string {
+	return strings.TrimSpace(os.Getenv(EnvProfile))
+}
+
+// profileOptions are the task options a profile entry can't override, since
+// profiles only change what a task calls and where its response goes
+var profileOptions = []string{
+	"name", "after", "on", "overlap", "catchup", "timeout", "group",
+	"skip_calendar", "only_calendar", "when", "tags", "template",
+	"retry_limit", "retry_backoff", "retry_delay", "retry_max_delay", "stream",
+}
+
+// ParseProfileLine parses a profile entry of the line format:
+//
+//	profile <name> task=<pattern>,tag=<tag>,model=<model>[:<params>],processor=<processor>,<key>=<value>,...
+//
+// Pairs are separated by commas and quoted like task variables. task and tag
+// select the tasks, and pairs other than model and processor override
+// variables. It returns nil for other lines.
+func ParseProfileLine(line string) (*config.ProfileConfig, error) {
+	if !strings.HasPrefix(strings.TrimSpace(line), "profile ") {
+		return nil, nil
+	}
+	fields, err := splitFields(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 3 {
+		return nil, errorAt(line, len(strings.TrimRight(line, " \t")),
+			fmt.Errorf("invalid profile: use profile <name> task=<pattern> or tag=<tag> followed by overrides"))
+	}
+
+	profileConfig := &config.ProfileConfig{Name: fields[1].text}
+	pairs, err := splitVariablePairs(line, fields[2].offset)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		switch pair.key {
+		case "task":
+			profileConfig.Task = pair.value
+		case "tag":
+			profileConfig.Tag = pair.value
+		case "model":
+			model, params, _ := strings.Cut(pair.value, ":")
+			profileConfig.Model = model
+			if params != "" {
+				if profileConfig.ModelParams, err = config.ParseModelParams(params); err != nil {
+					return nil, errorAt(line, pair.offset, fmt.Errorf("invalid model parameters: %w", err))
+				}
+			}
+		case "processor":
+			profileConfig.Processors = append(profileConfig.Processors, config.ProcessorConfig{Name: pair.value})
+		default:
+			for _, option := range profileOptions {
+				if pair.key == option {
+					return nil, errorAt(line, pair.offset,
+						fmt.Errorf("profiles can't set %s, they override model, processor and variables", option))
+				}
+			}
+			if profileConfig.Variables == nil {
+				profileConfig.Variables = make(map[string]string)
+			}
+			profileConfig.Variables[pair.key] = pair.value
+		}
+	}
+	return profileConfig, nil
+}
+
+// validateProfiles checks the entries of every profile, active or not, so a
+// mistake doesn't wait for the profile to be used. YAML entries have their
+// model profiles resolved already.
+func validateProfiles(profiles []config.ProfileConfig, providers *Providers) error {
+	var validateErrors *multierror.Error
+	for _, profileConfig := range profiles {
+		if err := validateProfile(profileConfig, providers); err != nil {
+			validateErrors = multierror.Append(validateErrors,
+				fmt.Errorf("%s: profile %s: %v", location{profileConfig.File, profileConfig.Line}, profileConfig.Name, err))
+		}
+	}
+	return validateErrors.ErrorOrNil()
+}
+
+// validateProfile checks a single profile entry
+func validateProfile(profileConfig config.ProfileConfig, providers *Providers) error {
+	if err := validateTaskName(profileConfig.Name); err != nil {
+		return fmt.Errorf("invalid name: %w", err)
+	}
+	if profileConfig.Task == "" && profileConfig.Tag == "" {
+		return fmt.Errorf("select tasks with task=<pattern> or tag=<tag>")
+	}
+	if _, err := path.Match(profileConfig.Task, ""); err != nil || strings.ContainsAny(profileConfig.Task, " \t") {
+		return fmt.Errorf("invalid task pattern '%s' (use a name or a pattern such as weekly_*, and separate pairs with commas)", profileConfig.Task)
+	}
+	if profileConfig.Tag != "" {
+		if err := validateTaskName(profileConfig.Tag); err != nil {
+			return fmt.Errorf("invalid tag: %w", err)
+		}
+	}
+	if profileConfig.Model == "" && len(profileConfig.ModelParams) == 0 && len(profileConfig.Processors) == 0 && len(profileConfig.Variables) == 0 {
+		return fmt.Errorf("nothing to override (set model, processor or variables)")
+	}
+	if profileConfig.Model != "" && !providers.isModel(profileConfig.Model) {
+		return fmt.Errorf("invalid model '%s'", profileConfig.Model)
+	}
+	for _, procConfig := range profileConfig.Processors {
+		if !isValidProcessor(procConfig.Name) {
+			return fmt.Errorf("invalid processor format '%s'", procConfig.Name)
+		}
+	}
+	return validateVariables(profileConfig.Variables)
+}
+
+// applyProfile applies the entries of the named profile to the tasks they
+// select, in the order the entries are defined. An empty name applies none.
+func applyProfile(name string, profiles []config.ProfileConfig, tasks []Task) error {
+	if name == "" {
+		return nil
+	}
+
+	found := false
+	var applyErrors *multierror.Error
+	for _, profileConfig := range profiles {
+		if profileConfig.Name != name {
+			continue
+		}
+		found = true
+
+		selected := 0
+		for i := range tasks {
+			if !profileSelects(profileConfig, tasks[i]) {
+				continue
+			}
+			selected++
+			if err := applyProfileEntry(profileConfig, &tasks[i]); err != nil {
+				applyErrors = multierror.Append(applyErrors,
+					fmt.Errorf("%s: profile %s: task %s: %v", location{profileConfig.File, profileConfig.Line}, name, tasks[i].ID(), err))
+			}
+		}
+		if selected == 0 {
+			log.Warn("Profile entry selects no tasks", logger.Fields{
+				"profile": name,
+				"task":    profileConfig.Task,
+				"tag":     profileConfig.Tag,
+				"at":      location{profileConfig.File, profileConfig.Line}.String(),
+			})
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown profile '%s'", name)
+	}
+	return applyErrors.ErrorOrNil()
+}
+
+// profileSelects reports whether a profile entry applies to a task. Entries
+// that give both a pattern and a tag select the tasks that match both.
+func profileSelects(profileConfig config.ProfileConfig, task Task) bool {
+	if profileConfig.Task != "" {
+		if matched, _ := path.Match(profileConfig.Task, task.Name); task.Name == "" || !matched {
+			return false
+		}
+	}
+	if profileConfig.Tag != "" {
+		for _, tag := range task.Tags {
+			if tag == profileConfig.Tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// applyProfileEntry overrides a task's model, parameters, processors and
+// variables with those a profile entry sets. Parameters and variables are
+// merged with the task's own.
+func applyProfileEntry(profileConfig config.ProfileConfig, task *Task) error {
+	if profileConfig.Model != "" {
+		task.Model = profileConfig.Model
+	}
+	if len(profileConfig.ModelParams) > 0 {
+		params, err := config.ParseModelParams(task.ModelParams)
+		if err != nil {
+			return err
+		}
+		if task.ModelParams, err = config.FormatModelParams(mergeMaps(params, profileConfig.ModelParams)); err != nil {
+			return err
+		}
+	}
+	if len(profileConfig.Processors) > 0 {
+		task.Processors = append([]config.ProcessorConfig(nil), profileConfig.Processors...)
+		task.Processor = task.Processors[0].Name
+	}
+	if len(profileConfig.Variables) > 0 {
+		task.Variables = mergeMaps(mergeMaps(nil, task.Variables), profileConfig.Variables)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package cron
+
+import (
+	"testing"
+
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProfileLine(t *testing.T) {
+	profileConfig, err := ParseProfileLine(`profile prod task=weekly_*,model=claude:temperature=0.2,processor=slack-prod-ops,title="Weekly, EMEA"`)
+	require.NoError(t, err)
+	assert.Equal(t, config.ProfileConfig{
+		Name: "prod", Task: "weekly_*", Model: "claude",
+		ModelParams: map[string]string{"temperature": "0.2"},
+		Processors:  []config.ProcessorConfig{{Name: "slack-prod-ops"}},
+		Variables:   map[string]string{"title": "Weekly, EMEA"},
+	}, *profileConfig)
+
+	profileConfig, err = ParseProfileLine("0 9 * * * openai profile console")
+	assert.NoError(t, err)
+	assert.Nil(t, profileConfig)
+
+	for _, line := range []string{
+		"profile prod",
+		"profile prod tag=reports,timeout=5m",
+		`profile prod tag=reports,title="unterminated`,
+		"profile prod tag=reports,model=claude:temperature",
+	} {
+		_, err := ParseProfileLine(line)
+		assert.Error(t, err, line)
+	}
+}
+
+func TestValidateProfiles(t *testing.T) {
+	err := validateProfiles([]config.ProfileConfig{
+		{Name: "prod", Tag: "reports", Model: "openai", Line: 1},
+		{Name: "prod", Model: "openai", Line: 2},
+		{Name: "prod", Task: "weekly_* model=claude", Model: "openai", Line: 3},
+		{Name: "prod", Tag: "reports", Line: 4},
+		{Name: "prod", Tag: "reports", Model: "gpt", Line: 5},
+		{Name: "prod", Tag: "reports", Variables: map[string]string{"since": "{{date -1q}}"}, Line: 6},
+	}, nil)
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "line 1:")
+	for _, message := range []string{
+		"line 2: profile prod: select tasks with task=<pattern> or tag=<tag>",
+		"line 3: profile prod: invalid task pattern",
+		"line 4: profile prod: nothing to override",
+		"line 5: profile prod: invalid model 'gpt'",
+		"line 6: profile prod: invalid variable since",
+	} {
+		assert.Contains(t, err.Error(), message)
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	profiles := []config.ProfileConfig{
+		{Name: "prod", Tag: "reports", Processors: []config.ProcessorConfig{{Name: "slack-prod"}}, Variables: map[string]string{"env": "prod"}},
+		{Name: "prod", Task: "weekly_*", Model: "claude", ModelParams: map[string]string{"temperature": "0.2"}},
+		{Name: "staging", Task: "*", Model: "gemini"},
+	}
+	newTasks := func() []Task {
+		return []Task{
+			{Name: "weekly_ops", Model: "openai", ModelParams: "max_tokens=500", Processor: "console",
+				Tags: []string{"reports"}, Variables: map[string]string{"env": "dev", "team": "ops"}},
+			{Name: "daily", Model: "openai", Processor: "console"},
+			{Model: "openai", Processor: "console", Tags: []string{"reports"}},
+		}
+	}
+
+	tasks := newTasks()
+	require.NoError(t, applyProfile("", profiles, tasks))
+	assert.Equal(t, newTasks(), tasks)
+
+	original := newTasks()
+	tasks = newTasks()
+	variables := tasks[0].Variables
+	require.NoError(t, applyProfile("prod", profiles, tasks))
+	assert.Equal(t, "claude", tasks[0].Model)
+	assert.Equal(t, "max_tokens=500,temperature=0.2", tasks[0].ModelParams)
+	assert.Equal(t, "slack-prod", tasks[0].Processor)
+	assert.Equal(t, []config.ProcessorConfig{{Name: "slack-prod"}}, tasks[0].Processors)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "ops"}, tasks[0].Variables)
+	assert.Equal(t, original[0].Variables, variables, "the task's own map is left alone")
+	assert.Equal(t, original[1], tasks[1])
+	assert.Equal(t, "slack-prod", tasks[2].Processor, "unnamed tasks are selected by tag")
+	assert.Equal(t, "openai", tasks[2].Model, "unnamed tasks never match a name pattern")
+
+	err := applyProfile("production", profiles, newTasks())
+	require.Error(t, err)
+	assert.Equal(t, "unknown profile 'production'", err.Error())
+}
+
+func TestParseConfigFileProfiles(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	configPath := writeCalendarConfig(t, `profile prod tag=reports,model=claude,processor=slack-prod-ops,env=prod
+profile prod task=daily,region=emea
+0 9 * * 1 openai test_prompt console name=weekly_report,tags=reports+ops,env=dev
+0 8 * * * openai test_prompt console name=daily
+`)
+	tasks, err := parseConfigFile(configPath)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "openai", tasks[0].Model)
+	assert.Equal(t, []string{"reports", "ops"}, tasks[0].Tags)
+
+	t.Setenv(EnvProfile, "prod")
+	tasks, err = parseConfigFile(configPath)
+	require.NoError(t, err)
+	assert.Equal(t, "claude", tasks[0].Model)
+	assert.Equal(t, "slack-prod-ops", tasks[0].Processor)
+	assert.Equal(t, map[string]string{"env": "prod"}, tasks[0].Variables)
+	assert.Equal(t, map[string]string{"region": "emea"}, tasks[1].Variables)
+
+	t.Setenv(EnvProfile, "qa")
+	_, err = parseConfigFile(configPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown profile 'qa'")
+
+	t.Setenv(EnvProfile, "prod")
+	tasks, err = parseConfigFile(writeYAMLConfig(t, `models:
+  precise:
+    provider: claude
+    params:
+      temperature: 0.1
+profiles:
+  - name: prod
+    tag: reports
+    model: precise
+    processors: [slack-prod-ops]
+tasks:
+  - name: weekly_report
+    schedule: "0 9 * * 1"
+    model: openai
+    prompt: test_prompt
+    processors: [console]
+    tags: [reports]
+`))
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "claude", tasks[0].Model)
+	assert.Equal(t, "temperature=0.1", tasks[0].ModelParams)
+	assert.Equal(t, "slack-prod-ops", tasks[0].Processor)
+}
@@ -0,0 +1,195 @@
+package cron
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// builtinModels are the providers every configuration can use
+var builtinModels = []string{"openai", "claude", "gemini", "ollama"}
+
+// envNamePattern matches the names of environment variables
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Providers holds the named providers of a configuration file
+type Providers struct {
+	byName map[string]config.ProviderConfig
+	digest string // fingerprint of every provider, so a reload notices provider changes
+}
+
+// isModel reports whether name is a built-in or a named provider. Nil
+// Providers only know the built-in ones.
+func (p *Providers) isModel(name string) bool {
+	if isValidModel(name) {
+		return true
+	}
+	if p == nil {
+		return false
+	}
+	_, ok := p.byName[name]
+	return ok
+}
+
+// supported lists the providers tasks can use, for error messages
+func (p *Providers) supported() string {
+	names := append([]string(nil), builtinModels...)
+	if p != nil {
+		named := make([]string, 0, len(p.byName))
+		for name := range p.byName {
+			named = append(named, name)
+		}
+		sort.Strings(named)
+		names = append(names, named...)
+	}
+	return strings.Join(names, ", ")
+}
+
+// validateProvider checks a named provider definition
+func validateProvider(providerConfig config.ProviderConfig) error {
+	if err := validateTaskName(providerConfig.Name); err != nil {
+		return fmt.Errorf("invalid name '%s' (use letters, digits, '.', '_' and '-')", providerConfig.Name)
+	}
+	if isValidModel(providerConfig.Name) {
+		return fmt.Errorf("'%s' is a built-in provider, choose another name", providerConfig.Name)
+	}
+
+	switch providerConfig.Type {
+	case config.ProviderOpenAICompatible:
+		if providerConfig.Deployment != "" || providerConfig.APIVersion != "" {
+			return fmt.Errorf("deployment and api_version only apply to %s providers", config.ProviderAzureOpenAI)
+		}
+	case config.ProviderAzureOpenAI:
+		if providerConfig.KeyEnv == "" {
+			return fmt.Errorf("%s providers need key_env", config.ProviderAzureOpenAI)
+		}
+	default:
+		return fmt.Errorf("unknown type '%s' (supported: %s, %s)", providerConfig.Type, config.ProviderOpenAICompatible, config.ProviderAzureOpenAI)
+	}
+
+	if baseURL, err := url.Parse(providerConfig.BaseURL); err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" {
+		return fmt.Errorf("invalid base_url '%s' (use an http or https URL)", providerConfig.BaseURL)
+	}
+	if providerConfig.KeyEnv != "" && !envNamePattern.MatchString(providerConfig.KeyEnv) {
+		return fmt.Errorf("invalid key_env '%s' (use the name of an environment variable)", providerConfig.KeyEnv)
+	}
+	return nil
+}
+
+// loadProviders validates the named providers of a configuration file
+func loadProviders(providerConfigs []config.ProviderConfig) (*Providers, error) {
+	providers := &Providers{byName: make(map[string]config.ProviderConfig, len(providerConfigs))}
+	var loadErrors *multierror.Error
+
+	defined := make(map[string]location)
+	for _, providerConfig := range providerConfigs {
+		at := location{providerConfig.File, providerConfig.Line}
+		if err := validateProvider(providerConfig); err != nil {
+			loadErrors = multierror.Append(loadErrors, fmt.Errorf("%s: provider %s: %v", at, providerConfig.Name, err))
+			continue
+		}
+		if first, ok := defined[providerConfig.Name]; ok {
+			loadErrors = multierror.Append(loadErrors,
+				fmt.Errorf("%s: duplicate provider '%s' (first defined on %s)", at, providerConfig.Name, first.relativeTo(at)))
+			continue
+		}
+		defined[providerConfig.Name] = at
+		providers.byName[providerConfig.Name] = providerConfig
+	}
+
+	providers.digest = providers.fingerprint()
+	return providers, loadErrors.ErrorOrNil()
+}
+
+// resolveProviders validates the named providers of a configuration file
+// and, when it defines any, lets every task call them. The providers are
+// returned for validating the models of profiles and budgets.
+func resolveProviders(providerConfigs []config.ProviderConfig, tasks []Task) (*Providers, error) {
+	if len(providerConfigs) == 0 {
+		return nil, nil
+	}
+	providers, err := loadProviders(providerConfigs)
+	for i := range tasks {
+		tasks[i].providers = providers
+	}
+	return providers, err
+}
+
+// fingerprint hashes every provider definition. Keys are referred to by
+// the variable holding them, so changing a key needs a restart.
+func (p *Providers) fingerprint() string {
+	names := make([]string, 0, len(p.byName))
+	for name := range p.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		pc := p.byName[name]
+		fmt.Fprintf(&sb, "%s:%s:%s:%s:%s:%s:%s\n", pc.Name, pc.Type, pc.BaseURL, pc.KeyEnv, pc.Model, pc.Deployment, pc.APIVersion)
+	}
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseProviderLine parses a named provider definition of the line format:
+//
+//	provider <name> openai-compatible|azure-openai base_url=<url>[,key_env=<variable>,model=<model>,deployment=<deployment>,api_version=<version>]
+//
+// such as provider gateway openai-compatible base_url=https://gateway.internal/v1,key_env=GATEWAY_KEY.
+// Options may also be separated by whitespace. It returns nil for any other line.
+func ParseProviderLine(line string) (*config.ProviderConfig, error) {
+	if !strings.HasPrefix(strings.TrimSpace(line), "provider ") {
+		return nil, nil
+	}
+	fields, err := splitFields(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 4 {
+		return nil, errorAt(line, len(strings.TrimRight(line, " \t")),
+			fmt.Errorf("invalid provider: use provider <name> openai-compatible|azure-openai base_url=<url>[,key_env=..,model=..]"))
+	}
+
+	providerConfig := &config.ProviderConfig{Name: fields[1].text, Type: fields[2].text}
+	if err := validateTaskName(providerConfig.Name); err != nil {
+		return nil, errorAt(line, fields[1].offset, fmt.Errorf("invalid provider name '%s' (use letters, digits, '.', '_' and '-')", providerConfig.Name))
+	}
+	if providerConfig.Type != config.ProviderOpenAICompatible && providerConfig.Type != config.ProviderAzureOpenAI {
+		return nil, errorAt(line, fields[2].offset, fmt.Errorf("unknown provider type '%s' (supported: %s, %s)",
+			providerConfig.Type, config.ProviderOpenAICompatible, config.ProviderAzureOpenAI))
+	}
+
+	for _, field := range fields[3:] {
+		pairs, err := splitVariablePairs(line[:field.offset+len(field.text)], field.offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, pair := range pairs {
+			switch pair.key {
+			case "base_url":
+				providerConfig.BaseURL = pair.value
+			case "key_env":
+				providerConfig.KeyEnv = pair.value
+			case "model":
+				providerConfig.Model = pair.value
+			case "deployment":
+				providerConfig.Deployment = pair.value
+			case "api_version":
+				providerConfig.APIVersion = pair.value
+			default:
+				return nil, errorAt(line, pair.offset,
+					fmt.Errorf("unknown provider option '%s' (supported: base_url, key_env, model, deployment, api_version)", pair.key))
+			}
+		}
+	}
+	return providerConfig, nil
+}
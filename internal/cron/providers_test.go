@@ -0,0 +1,184 @@
+package cron
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProviderLine(t *testing.T) {
+	providerConfig, err := ParseProviderLine("provider gateway openai-compatible base_url=https://gateway.internal/v1 key_env=GATEWAY_KEY")
+	require.NoError(t, err)
+	assert.Equal(t, config.ProviderConfig{
+		Name: "gateway", Type: "openai-compatible", BaseURL: "https://gateway.internal/v1", KeyEnv: "GATEWAY_KEY",
+	}, *providerConfig)
+
+	providerConfig, err = ParseProviderLine("provider azure azure-openai base_url=https://example.openai.azure.com,key_env=AZURE_KEY,deployment=gpt-4o-prod,api_version=2024-10-21")
+	require.NoError(t, err)
+	assert.Equal(t, config.ProviderConfig{
+		Name: "azure", Type: "azure-openai", BaseURL: "https://example.openai.azure.com", KeyEnv: "AZURE_KEY",
+		Deployment: "gpt-4o-prod", APIVersion: "2024-10-21",
+	}, *providerConfig)
+
+	providerConfig, err = ParseProviderLine("0 8 * * * openai report console")
+	assert.NoError(t, err)
+	assert.Nil(t, providerConfig)
+
+	for line, message := range map[string]string{
+		"provider gateway openai-compatible":                      "invalid provider",
+		"provider gateway vllm base_url=http://vllm:8000/v1":      "column 18: unknown provider type 'vllm'",
+		"provider gateway openai-compatible base_url=x,token=abc": "unknown provider option 'token'",
+		"provider 'my gateway' openai-compatible base_url=x":      "invalid provider name",
+	} {
+		_, err := ParseProviderLine(line)
+		assert.ErrorContains(t, err, message, line)
+	}
+}
+
+func TestLoadProvidersValidation(t *testing.T) {
+	_, err := loadProviders([]config.ProviderConfig{
+		{Name: "gateway", Type: "openai-compatible", BaseURL: "https://gateway.internal/v1", Line: 1},
+		{Name: "gateway", Type: "openai-compatible", BaseURL: "https://other.internal/v1", Line: 2},
+		{Name: "openai", Type: "openai-compatible", BaseURL: "https://proxy.internal/v1", Line: 3},
+		{Name: "lmstudio", Type: "openai-compatible", BaseURL: "localhost:1234", Line: 4},
+		{Name: "azure", Type: "azure-openai", BaseURL: "https://example.openai.azure.com", Line: 5},
+		{Name: "vllm", Type: "openai-compatible", BaseURL: "http://vllm:8000/v1", Deployment: "llama", Line: 6},
+		{Name: "proxy", Type: "openai-compatible", BaseURL: "https://proxy.internal/v1", KeyEnv: "PROXY-KEY", Line: 7},
+	})
+	require.Error(t, err)
+	for _, message := range []string{
+		"line 2: duplicate provider 'gateway' (first defined on line 1)",
+		"line 3: provider openai: 'openai' is a built-in provider",
+		"line 4: provider lmstudio: invalid base_url 'localhost:1234'",
+		"line 5: provider azure: azure-openai providers need key_env",
+		"line 6: provider vllm: deployment and api_version only apply to azure-openai providers",
+		"line 7: provider proxy: invalid key_env 'PROXY-KEY'",
+	} {
+		assert.Contains(t, err.Error(), message)
+	}
+	assert.NotContains(t, err.Error(), "line 1:")
+}
+
+func TestParseConfigFileProviders(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	// Tasks, profiles and budgets can use the providers declared before them
+	tasks, err := parseConfigFile(writeCalendarConfig(t, `provider gateway openai-compatible base_url=https://gateway.internal/v1,key_env=GATEWAY_KEY
+budget provider:gateway $5/day policy=downgrade,fallback=gateway:llama3-8b
+profile local task=report,model=gateway
+0 9 * * * gateway:model=llama3-70b test_prompt console name=digest
+0 9 * * * openai test_prompt console name=report
+`))
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	require.NotNil(t, tasks[0].providers)
+	assert.Same(t, tasks[0].providers, tasks[1].providers, "providers apply to every task of the file")
+	assert.Equal(t, "gateway", tasks[0].Model)
+	assert.Equal(t, "model=llama3-70b", tasks[0].ModelParams)
+	assert.Equal(t, "GATEWAY_KEY", tasks[0].providers.byName["gateway"].KeyEnv)
+
+	_, err = parseConfigFile(writeCalendarConfig(t, `0 9 * * * gateway test_prompt console
+provider gateway openai-compatible base_url=https://gateway.internal/v1
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 1, column 11: invalid model 'gateway'", "providers must be declared before use")
+
+	tasks, err = parseConfigFile(writeYAMLConfig(t, `providers:
+  - name: azure
+    type: azure-openai
+    base_url: https://example.openai.azure.com
+    key_env: AZURE_KEY
+    deployment: gpt-4o-prod
+models:
+  prod:
+    provider: azure
+    params:
+      temperature: 0.2
+tasks:
+  - name: report
+    schedule: "0 9 * * *"
+    model: prod
+    prompt: test_prompt
+    processors: [console]
+  - schedule: "0 9 * * *"
+    model: lmstudio
+    prompt: test_prompt
+    processors: [console]
+`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported model 'lmstudio' (supported: openai, claude, gemini, ollama, azure)")
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "azure", tasks[0].Model)
+	assert.Equal(t, "gpt-4o-prod", tasks[0].providers.byName["azure"].Deployment)
+	assert.NotContains(t, err.Error(), "line 13")
+
+	// Files without providers don't carry them
+	tasks, err = parseConfigFile(writeCalendarConfig(t, "0 9 * * * openai test_prompt console\n"))
+	require.NoError(t, err)
+	assert.Nil(t, tasks[0].providers)
+}
+
+func TestRunTaskProviders(t *testing.T) {
+	proc := &flakyProcessor{}
+	store, _ := setupRetryTest(t, proc)
+
+	// The gateway answers like any OpenAI-compatible server
+	var requests []map[string]interface{}
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		requests = append(requests, request)
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"llama3-70b","choices":[{"index":0,"message":{"role":"assistant","content":"Digest"}}],"usage":{"prompt_tokens":10,"completion_tokens":1}}`))
+	}))
+	defer server.Close()
+	t.Setenv("GATEWAY_KEY", "secret")
+
+	oldExecuteModel := executeModel
+	executeModel = models.ExecuteModel
+	defer func() { executeModel = oldExecuteModel }()
+
+	providers, err := loadProviders([]config.ProviderConfig{
+		{Name: "gateway", Type: "openai-compatible", BaseURL: server.URL + "/v1", KeyEnv: "GATEWAY_KEY"},
+	})
+	require.NoError(t, err)
+	task := Task{Name: "digest", Model: "gateway", ModelParams: "model=llama3-70b", Prompt: "test", Processor: "console", providers: providers}
+	service := NewCronService("test.config")
+
+	record, err := service.runTask(context.Background(), task, history.SourceCron, nil)
+	require.NoError(t, err)
+	assert.Equal(t, history.StatusSuccess, record.Status)
+	assert.Equal(t, "llama3-70b", record.ModelVersion)
+	assert.Equal(t, []string{"Digest"}, proc.responses)
+	require.Len(t, requests, 1)
+	assert.Equal(t, "llama3-70b", requests[0]["model"])
+	assert.Equal(t, "Bearer secret", authorization)
+
+	records, err := store.List(history.Filter{Task: "digest"})
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestTaskKeyChangesWithProviders(t *testing.T) {
+	task := Task{Name: "digest", Model: "gateway", Schedule: "* * * * *"}
+	first, err := loadProviders([]config.ProviderConfig{{Name: "gateway", Type: "openai-compatible", BaseURL: "https://a.internal/v1"}})
+	require.NoError(t, err)
+	second, err := loadProviders([]config.ProviderConfig{{Name: "gateway", Type: "openai-compatible", BaseURL: "https://b.internal/v1"}})
+	require.NoError(t, err)
+
+	withFirst, withSecond := task, task
+	withFirst.providers, withSecond.providers = first, second
+	assert.NotEqual(t, taskKey(withFirst), taskKey(withSecond))
+	assert.Equal(t, taskDefinition(withFirst), taskDefinition(withSecond))
+}
@@ -0,0 +1,291 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rshade/cronai/internal/logger"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// EnvConfigReloadInterval overrides how often the config file is polled for changes
+const EnvConfigReloadInterval = "CRONAI_CONFIG_RELOAD_INTERVAL"
+
+// DefaultReloadInterval is the default polling interval for configuration changes
+const DefaultReloadInterval = 30 * time.Second
+
+// reloadIntervalFromEnv returns the reload interval configured in the environment
+func reloadIntervalFromEnv() time.Duration {
+	value := os.Getenv(EnvConfigReloadInterval)
+	if value == "" {
+		return DefaultReloadInterval
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warn("Invalid config reload interval, using default", logger.Fields{
+			"value":   value,
+			"default": DefaultReloadInterval.String(),
+		})
+		return DefaultReloadInterval
+	}
+	return interval
+}
+
+// taskKey returns a fingerprint identifying a task by its full definition.
+// Two tasks with the same key are interchangeable, so a reload keeps the
+// existing scheduler entry instead of replacing it.
+func taskKey(task Task) string {
+	key := taskDefinition(task)
+	// Calendar, check, budget and provider contents don't change the task's identity but must
+	// replace its scheduler entry when they change
+	if task.calendars != nil {
+		key += "|calendars:" + task.calendars.digest
+	}
+	if task.checks != nil {
+		key += "|checks:" + task.checks.digest
+	}
+	if task.budgets != nil {
+		key += "|budgets:" + task.budgets.digest
+	}
+	if task.providers != nil {
+		key += "|providers:" + task.providers.digest
+	}
+	return key
+}
+
+// taskDefinition describes everything the task's configuration sets
+func taskDefinition(task Task) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s|%s|%s|%s|%s|%s|%s|%s|%s|%s|%s",
+		task.Name, task.Schedule, task.Model, task.ModelParams, task.Prompt, task.Processor, task.Template,
+		task.Overlap, task.Catchup, task.After, task.On)
+	if task.Group != "" || task.SkipCalendar != "" || task.OnlyCalendar != "" {
+		fmt.Fprintf(&b, "|group:%s|skip:%s|only:%s", task.Group, task.SkipCalendar, task.OnlyCalendar)
+	}
+	if task.Timeout > 0 {
+		fmt.Fprintf(&b, "|timeout:%s", task.Timeout)
+	}
+	if len(task.When) > 0 {
+		fmt.Fprintf(&b, "|when:%s", strings.Join(task.When, "+"))
+	}
+	if len(task.Tags) > 0 {
+		fmt.Fprintf(&b, "|tags:%s", strings.Join(task.Tags, "+"))
+	}
+	if retry := task.Retry.String(); retry != "" {
+		fmt.Fprintf(&b, "|retry:%s", retry)
+	}
+	if task.Stream {
+		b.WriteString("|stream")
+	}
+
+	for _, procConfig := range task.Processors {
+		fmt.Fprintf(&b, "|processor:%s:%s", procConfig.Name, procConfig.Template)
+		writeSorted(&b, ":", procConfig.Options)
+	}
+	writeSorted(&b, "|", task.Variables)
+
+	return b.String()
+}
+
+// writeSorted writes a map's entries to b in key order, each preceded by sep
+func writeSorted(b *strings.Builder, sep string, values map[string]string) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "%s%s=%s", sep, k, values[k])
+	}
+}
+
+// applyTasks reconciles the scheduler with the given task set. Entries whose
+// task is unchanged are left alone, entries that no longer exist are removed
+// and new or modified tasks are added. A modified task keeps its overlap state
+// when its ID and overlap policy are unchanged, so a run still in flight keeps
+// skipping or queueing the new entry's runs. It returns the number of added
+// and removed entries.
+func (s *Service) applyTasks(tasks []Task) (added, removed int) {
+	// Build the desired set, keeping identical lines as separate entries
+	desired := make(map[string]Task, len(tasks))
+	order := make([]string, 0, len(tasks))
+	seen := make(map[string]int)
+	for _, task := range tasks {
+		key := taskKey(task)
+		seen[key]++
+		if seen[key] > 1 {
+			key = fmt.Sprintf("%s#%d", key, seen[key])
+		}
+		desired[key] = task
+		order = append(order, key)
+	}
+
+	// Remove entries that are no longer configured
+	s.mu.Lock()
+	previous := make(map[string]EntryMetadata) // task ID -> removed entry
+	for key, entry := range s.entries {
+		if _, ok := desired[key]; ok {
+			continue
+		}
+		previous[entry.Task.ID()] = entry
+		if s.scheduler != nil {
+			s.scheduler.Remove(entry.EntryID)
+		}
+		delete(s.entries, key)
+		removed++
+		log.Info("Unscheduled task", logger.Fields{
+			"schedule":  entry.Schedule,
+			"model":     entry.Model,
+			"prompt":    entry.Prompt,
+			"processor": entry.Processor,
+		})
+	}
+	s.mu.Unlock()
+
+	// Add entries that are new or changed
+	for i, key := range order {
+		s.mu.Lock()
+		_, exists := s.entries[key]
+		s.mu.Unlock()
+		if exists {
+			continue
+		}
+
+		task := desired[key]
+		var wrap overlapWrapper
+		if entry, ok := previous[task.ID()]; ok && entry.Task.Overlap == task.Overlap {
+			wrap = entry.wrap
+		}
+		if err := s.addEntry(key, newScheduledTask(task), wrap); err != nil {
+			log.Error("Error scheduling task", logger.Fields{
+				"task_index": i,
+				"schedule":   task.Schedule,
+				"model":      task.Model,
+				"prompt":     task.Prompt,
+				"error":      err.Error(),
+			})
+			continue
+		}
+		added++
+		log.Info("Scheduled task", logger.Fields{
+			"task_index": i,
+			"schedule":   task.Schedule,
+			"model":      task.Model,
+			"prompt":     task.Prompt,
+			"processor":  task.Processor,
+		})
+	}
+
+	return added, removed
+}
+
+// reloadConfig re-reads the configuration file and applies the changes to the
+// running scheduler. If the new configuration fails to parse or validate, the
+// current schedule is kept and the error is returned. Concurrent reloads, from
+// the file watcher and the control socket, run one at a time.
+func (s *Service) reloadConfig() error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	log.Info("Reloading configuration file", logger.Fields{"config_path": s.configFile})
+
+	tasks, err := parseConfigFile(s.configFile)
+	if err != nil {
+		return err
+	}
+
+	added, removed := s.applyTasks(tasks)
+
+	s.mu.Lock()
+	total := len(s.entries)
+	s.mu.Unlock()
+
+	log.Info("Configuration reloaded", logger.Fields{
+		"config_path": s.configFile,
+		"added":       added,
+		"removed":     removed,
+		"task_count":  total,
+	})
+	return nil
+}
+
+// fileStamp captures the attributes used to detect a changed config file
+type fileStamp struct {
+	modTime  time.Time
+	size     int64
+	exists   bool
+	includes string // Stamps of the files a line configuration includes
+}
+
+// statConfig returns the current stamp of the config file and the files it
+// includes, so adding or editing an included file reloads the configuration
+func statConfig(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	stamp := fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+	if !config.IsYAMLFile(path) {
+		var b strings.Builder
+		for _, included := range includedFiles(path) {
+			if info, err := os.Stat(included); err == nil {
+				fmt.Fprintf(&b, "%s:%d:%d|", included, info.ModTime().UnixNano(), info.Size())
+			}
+		}
+		stamp.includes = b.String()
+	}
+	return stamp
+}
+
+// watchConfig reloads the configuration when the file changes on disk or when
+// the process receives SIGHUP. It returns when ctx is cancelled.
+func (s *Service) watchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var tick <-chan time.Time
+	if s.reloadInterval > 0 {
+		ticker := time.NewTicker(s.reloadInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	reload := func() {
+		if err := s.reloadConfig(); err != nil {
+			log.Error("Configuration reload failed, keeping current schedule", logger.Fields{
+				"config_path": s.configFile,
+				"error":       err.Error(),
+			})
+		}
+	}
+
+	last := statConfig(s.configFile)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-hup:
+			log.Info("Received SIGHUP", logger.Fields{"config_path": s.configFile})
+			last = statConfig(s.configFile)
+			reload()
+
+		case <-tick:
+			current := statConfig(s.configFile)
+			// A missing file is usually an editor mid-save; wait for it to return
+			if !current.exists || current == last {
+				continue
+			}
+			last = current
+			reload()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package cron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskKey(t *testing.T) {
+	base := Task{
+		Schedule:  "0 8 * * *",
+		Model:     "claude",
+		Prompt:    "test_prompt",
+		Processor: "console",
+		Variables: map[string]string{"a": "1", "b": "2"},
+	}
+
+	same := base
+	same.Variables = map[string]string{"b": "2", "a": "1"}
+	assert.Equal(t, taskKey(base), taskKey(same), "variable order must not affect the key")
+
+	changed := base
+	changed.Schedule = "0 9 * * *"
+	assert.NotEqual(t, taskKey(base), taskKey(changed))
+
+	changedVars := base
+	changedVars.Variables = map[string]string{"a": "1", "b": "3"}
+	assert.NotEqual(t, taskKey(base), taskKey(changedVars))
+}
+
+func TestApplyTasks(t *testing.T) {
+	service := NewCronService("test.config")
+	service.scheduler = cron.New()
+
+	taskA := Task{Schedule: "0 8 * * *", Model: "claude", Prompt: "a", Processor: "console"}
+	taskB := Task{Schedule: "0 9 * * *", Model: "openai", Prompt: "b", Processor: "console"}
+	taskC := Task{Schedule: "0 10 * * *", Model: "gemini", Prompt: "c", Processor: "console"}
+
+	added, removed := service.applyTasks([]Task{taskA, taskB})
+	assert.Equal(t, 2, added)
+	assert.Equal(t, 0, removed)
+	require.Len(t, service.entries, 2)
+	entryB := service.entries[taskKey(taskB)].EntryID
+
+	// Replace A with C; B must keep its scheduler entry
+	added, removed = service.applyTasks([]Task{taskB, taskC})
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 1, removed)
+	require.Len(t, service.entries, 2)
+	assert.Equal(t, entryB, service.entries[taskKey(taskB)].EntryID)
+	assert.NotContains(t, service.entries, taskKey(taskA))
+	assert.Len(t, service.scheduler.Entries(), 2)
+
+	// Identical lines are scheduled as separate entries
+	added, removed = service.applyTasks([]Task{taskB, taskB})
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 1, removed)
+	assert.Len(t, service.scheduler.Entries(), 2)
+}
+
+func TestApplyTasksKeepsOverlapState(t *testing.T) {
+	history.SetStore(history.NewMemoryStore())
+
+	service := NewCronService("test.config")
+	service.scheduler = cron.New()
+
+	task := Task{Name: "slow", Schedule: "0 8 * * *", Model: "claude", Prompt: "slow", Processor: "console", Overlap: OverlapSkip}
+	service.applyTasks([]Task{task})
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	defer close(release)
+	var runs, cancelled int32
+	go service.entries[taskKey(task)].wrap(history.SourceCron, blockingJob(started, release, &runs, &cancelled)).Run()
+	<-started
+
+	// The changed task is skipped while the run started before the reload is in flight
+	changed := task
+	changed.Schedule = "0 9 * * *"
+	added, removed := service.applyTasks([]Task{changed})
+	assert.Equal(t, 1, added)
+	assert.Equal(t, 1, removed)
+	service.entries[taskKey(changed)].wrap(history.SourceCron, blockingJob(started, release, &runs, &cancelled)).Run()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+	assert.Equal(t, 1, service.SkippedRuns()["slow"])
+
+	// A new overlap policy starts with fresh state
+	changed.Overlap = OverlapAllow
+	service.applyTasks([]Task{changed})
+	go service.entries[taskKey(changed)].wrap(history.SourceCron, blockingJob(started, release, &runs, &cancelled)).Run()
+	<-started
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+}
+
+func TestReloadConfig(t *testing.T) {
+	if err := setupTestPromptFile(t); err != nil {
+		t.Fatalf("Failed to setup test prompt file: %v", err)
+	}
+	defer cleanupTestPromptFile(t)
+
+	configPath := filepath.Join(t.TempDir(), "cronai.config")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+	}
+
+	writeConfig("0 8 * * * claude test_prompt console\n0 9 * * * openai test_prompt console\n")
+
+	service := NewCronService(configPath)
+	service.scheduler = cron.New()
+	require.NoError(t, service.reloadConfig())
+	require.Len(t, service.entries, 2)
+
+	kept := taskKey(Task{Schedule: "0 9 * * *", Model: "openai", Prompt: "test_prompt", Processor: "console"})
+	keptID := service.entries[kept].EntryID
+
+	// Change one task, keep the other
+	writeConfig("0 7 * * * claude test_prompt console\n0 9 * * * openai test_prompt console\n")
+	require.NoError(t, service.reloadConfig())
+	require.Len(t, service.entries, 2)
+	assert.Equal(t, keptID, service.entries[kept].EntryID)
+
+	// An invalid configuration must leave the running schedule untouched
+	before := len(service.scheduler.Entries())
+	writeConfig("0 7 * * * claude test_prompt console\ninvalid-cron * * * * claude test_prompt console\n")
+	assert.Error(t, service.reloadConfig())
+	assert.Len(t, service.entries, 2)
+	assert.Len(t, service.scheduler.Entries(), before)
+}
+
+func TestWatchConfigPolling(t *testing.T) {
+	if err := setupTestPromptFile(t); err != nil {
+		t.Fatalf("Failed to setup test prompt file: %v", err)
+	}
+	defer cleanupTestPromptFile(t)
+
+	configPath := filepath.Join(t.TempDir(), "cronai.config")
+	require.NoError(t, os.WriteFile(configPath, []byte("0 8 * * * claude test_prompt console\n"), 0644))
+
+	service := NewCronService(configPath, WithReloadInterval(10*time.Millisecond))
+	service.scheduler = cron.New()
+	require.NoError(t, service.reloadConfig())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go service.watchConfig(ctx)
+
+	// Give the watcher time to record the initial stamp before changing the file
+	time.Sleep(30 * time.Millisecond)
+	content := "0 8 * * * claude test_prompt console\n0 9 * * * openai test_prompt console\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+
+	assert.Eventually(t, func() bool {
+		service.mu.Lock()
+		defer service.mu.Unlock()
+		return len(service.entries) == 2
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestReloadIntervalFromEnv(t *testing.T) {
+	t.Setenv(EnvConfigReloadInterval, "")
+	assert.Equal(t, DefaultReloadInterval, reloadIntervalFromEnv())
+
+	t.Setenv(EnvConfigReloadInterval, "5s")
+	assert.Equal(t, 5*time.Second, reloadIntervalFromEnv())
+
+	t.Setenv(EnvConfigReloadInterval, "0")
+	assert.Equal(t, time.Duration(0), reloadIntervalFromEnv())
+
+	t.Setenv(EnvConfigReloadInterval, "soon")
+	assert.Equal(t, DefaultReloadInterval, reloadIntervalFromEnv())
+}
@@ -0,0 +1,213 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/logger"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/queue"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// Backoff determines how the delay between delivery retries grows
+type Backoff string
+
+// Backoff strategies
+const (
+	// BackoffExponential doubles the delay after every retry, up to a maximum (default)
+	BackoffExponential Backoff = "exponential"
+	// BackoffLinear waits the same delay before every retry
+	BackoffLinear Backoff = "linear"
+)
+
+// Default delivery retry settings
+const (
+	DefaultRetryDelay    = 5 * time.Second
+	DefaultRetryMaxDelay = 5 * time.Minute
+)
+
+// ParseBackoff parses a backoff strategy name. An empty value selects BackoffExponential.
+func ParseBackoff(value string) (Backoff, error) {
+	switch backoff := Backoff(strings.ToLower(strings.TrimSpace(value))); backoff {
+	case "":
+		return BackoffExponential, nil
+	case BackoffExponential, BackoffLinear:
+		return backoff, nil
+	default:
+		return "", fmt.Errorf("invalid retry_backoff '%s' (supported: exponential, linear)", value)
+	}
+}
+
+// Retry retries the delivery of a response to a processor that fails. The
+// response is delivered again as it is, the model isn't called again. The
+// zero value doesn't retry.
+type Retry struct {
+	Limit    int           // Retries after the first delivery fails
+	Backoff  Backoff       // How the delay grows between retries
+	Delay    time.Duration // Delay before the first retry
+	MaxDelay time.Duration // Longest delay of exponential backoff
+}
+
+// ParseRetryLimit parses the number of delivery retries
+func ParseRetryLimit(value string) (int, error) {
+	limit, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || limit < 0 {
+		return 0, fmt.Errorf("invalid retry_limit '%s' (use a number of retries such as 3)", value)
+	}
+	return limit, nil
+}
+
+// parseRetryDelay parses a retry delay such as 10s, naming the option in errors
+func parseRetryDelay(option, value string) (time.Duration, error) {
+	delay, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || delay <= 0 {
+		return 0, fmt.Errorf("invalid %s '%s' (use a positive duration such as 10s or 1m)", option, value)
+	}
+	return delay, nil
+}
+
+// RetryFromConfig returns the retry settings of a YAML task
+func RetryFromConfig(retryConfig *config.RetryConfig) (Retry, error) {
+	var retry Retry
+	if retryConfig == nil {
+		return retry, nil
+	}
+	if retryConfig.Limit < 0 {
+		return retry, fmt.Errorf("invalid retry limit %d (use a number of retries such as 3)", retryConfig.Limit)
+	}
+	retry.Limit = retryConfig.Limit
+
+	var err error
+	if retry.Backoff, err = ParseBackoff(retryConfig.Backoff); err != nil {
+		return retry, err
+	}
+	if retryConfig.Delay != "" {
+		if retry.Delay, err = parseRetryDelay("retry delay", retryConfig.Delay); err != nil {
+			return retry, err
+		}
+	}
+	if retryConfig.MaxDelay != "" {
+		if retry.MaxDelay, err = parseRetryDelay("retry max_delay", retryConfig.MaxDelay); err != nil {
+			return retry, err
+		}
+	}
+	return retry, retry.validate()
+}
+
+// RetryConfig returns the YAML definition of the retry settings, nil when
+// deliveries aren't retried
+func (r Retry) RetryConfig() *config.RetryConfig {
+	if r.Limit == 0 {
+		return nil
+	}
+	retryConfig := &config.RetryConfig{Limit: r.Limit}
+	if r.Backoff != "" && r.Backoff != BackoffExponential {
+		retryConfig.Backoff = string(r.Backoff)
+	}
+	if r.Delay > 0 {
+		retryConfig.Delay = r.Delay.String()
+	}
+	if r.MaxDelay > 0 {
+		retryConfig.MaxDelay = r.MaxDelay.String()
+	}
+	return retryConfig
+}
+
+// validate checks settings that only make sense together
+func (r Retry) validate() error {
+	if r.MaxDelay > 0 && r.Backoff == BackoffLinear {
+		return fmt.Errorf("retry_max_delay only applies to exponential backoff")
+	}
+	if r.MaxDelay > 0 && r.MaxDelay < r.delay() {
+		return fmt.Errorf("retry_max_delay %s is shorter than retry_delay %s", r.MaxDelay, r.delay())
+	}
+	return nil
+}
+
+// String describes the settings, empty when deliveries aren't retried
+func (r Retry) String() string {
+	if r.Limit == 0 {
+		return ""
+	}
+	if r.Backoff == BackoffLinear {
+		return fmt.Sprintf("%d:%s:%s", r.Limit, BackoffLinear, r.delay())
+	}
+	return fmt.Sprintf("%d:%s:%s:%s", r.Limit, BackoffExponential, r.delay(), r.maxDelay())
+}
+
+// delay returns the delay before the first retry
+func (r Retry) delay() time.Duration {
+	if r.Delay > 0 {
+		return r.Delay
+	}
+	return DefaultRetryDelay
+}
+
+// maxDelay returns the longest delay of exponential backoff
+func (r Retry) maxDelay() time.Duration {
+	if r.MaxDelay > 0 {
+		return r.MaxDelay
+	}
+	return max(DefaultRetryMaxDelay, r.delay())
+}
+
+// policy returns the queue retry policy implementing the settings
+func (r Retry) policy() queue.RetryPolicy {
+	switch {
+	case r.Limit == 0:
+		return queue.NewNoRetryPolicy()
+	case r.Backoff == BackoffLinear:
+		return queue.NewLinearRetryPolicy(r.Limit, r.delay())
+	default:
+		return queue.NewExponentialBackoffRetryPolicy(r.Limit, r.delay(), r.maxDelay())
+	}
+}
+
+// retryable reports whether a failed delivery may succeed when retried.
+// Configuration errors, such as an unknown processor, fail the same way
+// every time.
+func retryable(err error) bool {
+	return errors.GetCategory(err) != errors.CategoryConfiguration
+}
+
+// deliverWithRetry delivers a response to a processor, retrying failed
+// deliveries as the task's retry settings allow. It returns the number of
+// retries made and the error of the last attempt. Waiting between retries
+// stops when the context is cancelled.
+func deliverWithRetry(ctx context.Context, task Task, procConfig config.ProcessorConfig, response *models.ModelResponse) (int, error) {
+	policy := task.Retry.policy()
+	// The queue policies count attempts on a message
+	attempt := &queue.Message{ID: response.ExecutionID}
+	for {
+		err := deliverResponse(ctx, procConfig, response)
+		if err == nil || !retryable(err) || !policy.ShouldRetry(attempt, err) {
+			return attempt.RetryCount, err
+		}
+
+		delay := policy.NextRetryDelay(attempt)
+		attempt.RetryCount++
+		log.Warn("Delivery failed, retrying", logger.Fields{
+			"execution_id": response.ExecutionID,
+			"task":         task.ID(),
+			"processor":    procConfig.Name,
+			"retry":        attempt.RetryCount,
+			"retry_limit":  policy.MaxRetries(),
+			"delay":        delay.String(),
+			"error":        err.Error(),
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			// The announced retry wasn't made
+			timer.Stop()
+			return attempt.RetryCount - 1, err
+		case <-timer.C:
+		}
+	}
+}
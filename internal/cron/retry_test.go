@@ -0,0 +1,226 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/pool"
+	"github.com/rshade/cronai/internal/processor"
+	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyProcessor fails its first failures deliveries
+type flakyProcessor struct {
+	mockProcessor
+	failures  int32
+	delivered atomic.Int32
+	responses []string
+}
+
+func (p *flakyProcessor) Process(_ context.Context, response *models.ModelResponse, _ string) error {
+	p.responses = append(p.responses, response.Content)
+	if p.delivered.Add(1) <= p.failures {
+		return fmt.Errorf("502 bad gateway")
+	}
+	return nil
+}
+
+// setupRetryTest registers proc as the console processor and counts model calls
+func setupRetryTest(t *testing.T, proc processor.Processor) (*history.MemoryStore, *atomic.Int32) {
+	t.Helper()
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("test", "This is a test prompt")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	t.Cleanup(func() { prompt.PM = oldManager })
+
+	processor.GetRegistry().RegisterFactory("console", func(_ processor.Config) (processor.Processor, error) {
+		return proc, nil
+	})
+
+	var calls atomic.Int32
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		n := calls.Add(1)
+		return &models.ModelResponse{Content: fmt.Sprintf("response %d", n), Model: model}, nil
+	}
+	t.Cleanup(func() { executeModel = oldExecuteModel })
+
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+	return store, &calls
+}
+
+func TestDeliveryRetry(t *testing.T) {
+	proc := &flakyProcessor{failures: 2}
+	store, calls := setupRetryTest(t, proc)
+
+	service := &Service{entries: make(map[string]EntryMetadata)}
+	task := Task{Model: "openai", Prompt: "test", Processor: "console",
+		Retry: Retry{Limit: 3, Backoff: BackoffExponential, Delay: time.Millisecond}}
+	require.NoError(t, service.RunTask(task))
+
+	assert.Equal(t, int32(1), calls.Load(), "the model is called once")
+	assert.Equal(t, []string{"response 1", "response 1", "response 1"}, proc.responses)
+
+	records, err := store.List(history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StatusSuccess, records[0].Status)
+	assert.Equal(t, 2, records[0].DeliveryRetries)
+
+	// Deliveries fail once the retries run out
+	proc = &flakyProcessor{failures: 5}
+	store, calls = setupRetryTest(t, proc)
+	task.Retry = Retry{Limit: 2, Backoff: BackoffLinear, Delay: time.Millisecond}
+	err = service.RunTask(task)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "502 bad gateway")
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Len(t, proc.responses, 3)
+
+	records, err = store.List(history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StageProcessor, records[0].Stage)
+	assert.Equal(t, 2, records[0].DeliveryRetries)
+}
+
+func TestDeliveryRetryStops(t *testing.T) {
+	proc := &flakyProcessor{failures: 5}
+	setupRetryTest(t, proc)
+	task := Task{Name: "report", Retry: Retry{Limit: 3, Delay: time.Hour}}
+	response := &models.ModelResponse{Content: "response", ExecutionID: "exec-1"}
+
+	// Waiting for the next retry ends with the run
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	retries, err := deliverWithRetry(ctx, task, config.ProcessorConfig{Name: "console"}, response)
+	require.Error(t, err)
+	assert.Equal(t, 0, retries)
+	assert.Len(t, proc.responses, 1)
+
+	// Processors that can't be created fail the same way every time
+	processor.GetRegistry().RegisterFactory("console", func(_ processor.Config) (processor.Processor, error) {
+		return nil, fmt.Errorf("missing webhook URL")
+	})
+	retries, err = deliverWithRetry(context.Background(), task, config.ProcessorConfig{Name: "console"}, response)
+	require.Error(t, err)
+	assert.Equal(t, errors.CategoryConfiguration, errors.GetCategory(err))
+	assert.Equal(t, 0, retries)
+}
+
+// slotProcessor fails its deliveries and reports whether the execution
+// pool had a free slot while it ran
+type slotProcessor struct {
+	mockProcessor
+	free []bool
+}
+
+func (p *slotProcessor) Process(ctx context.Context, _ *models.ModelResponse, _ string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	release, err := pool.Default().AcquireGlobal(ctx)
+	if err == nil {
+		release()
+	}
+	p.free = append(p.free, err == nil)
+	return fmt.Errorf("502 bad gateway")
+}
+
+func TestDeliveryRetryReleasesSlot(t *testing.T) {
+	original := pool.Default()
+	pool.SetDefault(pool.New(pool.WithMaxConcurrency(1)))
+	defer pool.SetDefault(original)
+
+	proc := &slotProcessor{}
+	setupRetryTest(t, proc)
+
+	// Deliveries and their retries don't hold the task's execution slot
+	service := NewCronService("test.config")
+	task := Task{Model: "openai", Prompt: "test", Processor: "console",
+		Retry: Retry{Limit: 2, Backoff: BackoffLinear, Delay: time.Millisecond}}
+	require.Error(t, service.executeTask(context.Background(), task, history.SourceCron, nil))
+	assert.Equal(t, []bool{true, true, true}, proc.free)
+}
+
+func TestParseRetryOptions(t *testing.T) {
+	task, err := parseLine("0 9 * * 1 openai report console retry_limit=3,retry_delay=10s,retry_max_delay=2m,team=ops")
+	require.NoError(t, err)
+	assert.Equal(t, Retry{Limit: 3, Backoff: BackoffExponential, Delay: 10 * time.Second, MaxDelay: 2 * time.Minute}, task.Task.Retry)
+	assert.Equal(t, map[string]string{"team": "ops"}, task.Task.Variables)
+
+	task, err = parseLine("0 9 * * 1 openai report console retry_limit=2,retry_backoff=linear")
+	require.NoError(t, err)
+	assert.Equal(t, "2:linear:5s", task.Task.Retry.String())
+
+	for line, want := range map[string]string{
+		"0 9 * * 1 openai report console retry_limit=-1":                                                 "invalid retry_limit '-1'",
+		"0 9 * * 1 openai report console retry_delay=10s":                                                "retry_delay requires a retry_limit option",
+		"0 9 * * 1 openai report console retry_limit=3,retry_backoff=fibonacci":                          "invalid retry_backoff 'fibonacci'",
+		"0 9 * * 1 openai report console retry_limit=3,retry_delay=soon":                                 "invalid retry_delay 'soon'",
+		"0 9 * * 1 openai report console retry_limit=3,retry_backoff=linear,retry_max_delay=1m":          "retry_max_delay only applies to exponential backoff",
+		"0 9 * * 1 openai report console retry_limit=3,retry_delay=1m,retry_max_delay=10s":               "retry_max_delay 10s is shorter than retry_delay 1m0s",
+		"0 9 * * 1 openai report console team=ops,retry_limit=3,retry_backoff=linear,retry_max_delay=1m": "column 77:",
+	} {
+		_, err := parseLine(line)
+		require.Error(t, err, line)
+		assert.Contains(t, err.Error(), want, line)
+	}
+}
+
+func TestRetryConfig(t *testing.T) {
+	retry, err := RetryFromConfig(&config.RetryConfig{Limit: 4, Backoff: "linear", Delay: "30s"})
+	require.NoError(t, err)
+	assert.Equal(t, Retry{Limit: 4, Backoff: BackoffLinear, Delay: 30 * time.Second}, retry)
+	assert.Equal(t, &config.RetryConfig{Limit: 4, Backoff: "linear", Delay: "30s"}, retry.RetryConfig())
+
+	retry, err = RetryFromConfig(nil)
+	require.NoError(t, err)
+	assert.Nil(t, retry.RetryConfig())
+
+	_, err = RetryFromConfig(&config.RetryConfig{Limit: 2, Backoff: "random"})
+	assert.Error(t, err)
+
+	// The exponential defaults reach the queue policy
+	policy := Retry{Limit: 3}.policy()
+	assert.Equal(t, 3, policy.MaxRetries())
+	assert.Equal(t, DefaultRetryDelay, policy.NextRetryDelay(nil))
+}
+
+func TestParseYAMLRetry(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	tasks, err := parseConfigFile(writeYAMLConfig(t, `defaults:
+  model: openai
+  processors: [console]
+  retry:
+    limit: 3
+    max_delay: 1m
+tasks:
+  - name: inherits
+    schedule: "0 9 * * 1"
+    prompt: test_prompt
+  - name: linear
+    schedule: "0 10 * * 1"
+    prompt: test_prompt
+    retry:
+      limit: 5
+      backoff: linear
+      delay: 30s
+`))
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, Retry{Limit: 3, Backoff: BackoffExponential, MaxDelay: time.Minute}, tasks[0].Retry)
+	assert.Equal(t, Retry{Limit: 5, Backoff: BackoffLinear, Delay: 30 * time.Second}, tasks[1].Retry)
+}
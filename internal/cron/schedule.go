@@ -0,0 +1,103 @@
+package cron
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// scheduleParser accepts standard five-field cron expressions, an optional
+// leading seconds field, descriptors such as @daily and @every 90s, and a
+// CRON_TZ= or TZ= timezone prefix
+var scheduleParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// cronField matches a single field of a cron expression: numbers, *, ?,
+// month and weekday names, combined with ranges, steps and lists
+var cronField = regexp.MustCompile(`^(?i)(\*|\?|\d+|jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec|sun|mon|tue|wed|thu|fri|sat)` +
+	`([-,/](\*|\?|\d+|jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec|sun|mon|tue|wed|thu|fri|sat))*$`)
+
+// newScheduler creates a scheduler that accepts the same schedules as parseSchedule
+func newScheduler() *cron.Cron {
+	return cron.New(cron.WithParser(scheduleParser))
+}
+
+// parseSchedule parses a task's cron schedule
+func parseSchedule(spec string) (cron.Schedule, error) {
+	return scheduleParser.Parse(spec)
+}
+
+// scheduleFieldCount returns how many of the leading whitespace-separated
+// fields of a configuration line make up the schedule
+func scheduleFieldCount(parts []string) int {
+	count := 0
+	if len(parts) > 0 && isTimezonePrefix(parts[0]) {
+		count++
+	}
+	if len(parts) <= count {
+		return count
+	}
+
+	switch {
+	case parts[count] == "@every":
+		// @every takes a duration
+		return count + 2
+	case strings.HasPrefix(parts[count], "@"):
+		// @after, @daily, @hourly, ...
+		return count + 1
+	}
+
+	// Five fields, preceded by seconds when the sixth is a cron field as well
+	// (a model name never is)
+	count += 5
+	if len(parts) > count && cronField.MatchString(parts[count]) {
+		count++
+	}
+	return count
+}
+
+// isTimezonePrefix reports whether a schedule field selects a timezone
+func isTimezonePrefix(field string) bool {
+	return strings.HasPrefix(field, "CRON_TZ=") || strings.HasPrefix(field, "TZ=")
+}
+
+// SplitTimezone separates a CRON_TZ= or TZ= prefix from a schedule. The
+// timezone is empty when the schedule uses the local timezone.
+func SplitTimezone(spec string) (timezone, schedule string) {
+	spec = strings.TrimSpace(spec)
+	if !isTimezonePrefix(spec) {
+		return "", spec
+	}
+	prefix, schedule, _ := strings.Cut(spec, " ")
+	_, timezone, _ = strings.Cut(prefix, "=")
+	return timezone, strings.TrimSpace(schedule)
+}
+
+// withTimezone prefixes a schedule with CRON_TZ= unless the timezone is empty
+func withTimezone(timezone, schedule string) string {
+	if timezone == "" {
+		return schedule
+	}
+	return fmt.Sprintf("CRON_TZ=%s %s", timezone, schedule)
+}
+
+// Timezone returns the timezone the task's schedule is evaluated in, or an
+// empty string when it runs in the local timezone of the service
+func (t Task) Timezone() string {
+	timezone, _ := SplitTimezone(t.Schedule)
+	return timezone
+}
+
+// location returns the location the task's schedule is evaluated in, or nil
+// for the local timezone
+func (t Task) location() (*time.Location, error) {
+	timezone := t.Timezone()
+	if timezone == "" {
+		return nil, nil
+	}
+	return time.LoadLocation(timezone)
+}
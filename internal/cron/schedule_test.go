@@ -0,0 +1,150 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigLineSchedules(t *testing.T) {
+	tests := []struct {
+		line     string
+		schedule string
+		model    string
+	}{
+		{"0 8 * * * openai report console", "0 8 * * *", "openai"},
+		{"30 0 8 * * mon-fri openai report console", "30 0 8 * * mon-fri", "openai"},
+		{"*/15 * * * * * claude report console", "*/15 * * * * *", "claude"},
+		{"@daily gemini report console", "@daily", "gemini"},
+		{"@every 90s openai report console", "@every 90s", "openai"},
+		{"CRON_TZ=Europe/Berlin 0 8 * * * openai report console", "CRON_TZ=Europe/Berlin 0 8 * * *", "openai"},
+		{"TZ=America/New_York 0 0 9 * * 1-5 openai:temperature=0.2 report console", "TZ=America/New_York 0 0 9 * * 1-5", "openai"},
+		{"CRON_TZ=Asia/Tokyo @weekly openai report console", "CRON_TZ=Asia/Tokyo @weekly", "openai"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			task, err := ParseConfigLine(tt.line)
+			require.NoError(t, err)
+			require.NotNil(t, task)
+			assert.Equal(t, tt.schedule, task.Schedule)
+			assert.Equal(t, tt.model, task.Model)
+			assert.Equal(t, "report", task.Prompt)
+			assert.Equal(t, "console", task.Processor)
+		})
+	}
+}
+
+func TestValidateTaskSchedules(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	valid := []string{"0 8 * * *", "15 0 8 * * *", "@every 90s", "@daily", "CRON_TZ=Europe/Berlin 0 8 * * *"}
+	for _, schedule := range valid {
+		task := Task{Schedule: schedule, Model: "openai", Prompt: "test_prompt", Processor: "console"}
+		assert.NoError(t, validateTask(task, location{line: 1}), schedule)
+	}
+
+	invalid := []string{"CRON_TZ=Europe/Berln 0 8 * * *", "@every soon", "61 8 * * *", "0 0 0 8 * * *"}
+	for _, schedule := range invalid {
+		task := Task{Schedule: schedule, Model: "openai", Prompt: "test_prompt", Processor: "console"}
+		assert.Error(t, validateTask(task, location{line: 1}), schedule)
+	}
+}
+
+func TestParseScheduleTimezone(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("timezone data not available")
+	}
+
+	schedule, err := parseSchedule("CRON_TZ=Europe/Berlin 0 8 * * *")
+	require.NoError(t, err)
+
+	from := time.Date(2026, 3, 2, 6, 0, 0, 0, time.UTC)
+	assert.True(t, time.Date(2026, 3, 2, 8, 0, 0, 0, berlin).Equal(schedule.Next(from)))
+
+	// Previews show fire times in the task's timezone
+	previews, err := NextFires([]Task{{Schedule: "CRON_TZ=Europe/Berlin 0 8 * * *"}}, from, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", previews[0].Fires[0].Location().String())
+	assert.Equal(t, 8, previews[0].Fires[0].Hour())
+
+	// Seconds are optional, descriptors take a duration
+	schedule, err = parseSchedule("30 0 8 * * *")
+	require.NoError(t, err)
+	assert.Equal(t, 30, schedule.Next(from).Second())
+
+	schedule, err = parseSchedule("@every 90s")
+	require.NoError(t, err)
+	assert.Equal(t, from.Add(90*time.Second), schedule.Next(from))
+}
+
+func TestSplitTimezone(t *testing.T) {
+	tests := []struct {
+		spec, timezone, schedule string
+	}{
+		{"0 8 * * *", "", "0 8 * * *"},
+		{"CRON_TZ=Europe/Berlin 0 8 * * *", "Europe/Berlin", "0 8 * * *"},
+		{"TZ=UTC @daily", "UTC", "@daily"},
+	}
+	for _, tt := range tests {
+		timezone, schedule := SplitTimezone(tt.spec)
+		assert.Equal(t, tt.timezone, timezone, tt.spec)
+		assert.Equal(t, tt.schedule, schedule, tt.spec)
+		assert.Equal(t, tt.timezone, Task{Schedule: tt.spec}.Timezone())
+	}
+}
+
+func TestYAMLTimezone(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	content := `defaults:
+  model: openai
+  processors: [console]
+  timezone: America/New_York
+tasks:
+  - {name: ny, schedule: "0 9 * * 1-5", prompt: test_prompt}
+  - {name: berlin, schedule: "0 9 * * 1-5", timezone: Europe/Berlin, prompt: test_prompt}
+  - {name: tokyo, schedule: "CRON_TZ=Asia/Tokyo 0 9 * * 1-5", prompt: test_prompt}
+  - {name: after, after: ny, prompt: test_prompt}
+`
+	tasks, err := parseConfigFile(writeYAMLConfig(t, content))
+	if err != nil && strings.Contains(err.Error(), "unknown time zone") {
+		t.Skip("timezone data not available")
+	}
+	require.NoError(t, err)
+	require.Len(t, tasks, 4)
+	assert.Equal(t, "CRON_TZ=America/New_York 0 9 * * 1-5", tasks[0].Schedule)
+	assert.Equal(t, "CRON_TZ=Europe/Berlin 0 9 * * 1-5", tasks[1].Schedule)
+	assert.Equal(t, "CRON_TZ=Asia/Tokyo 0 9 * * 1-5", tasks[2].Schedule)
+	assert.Equal(t, ScheduleAfter, tasks[3].Schedule)
+
+	// The timezone is written as a field of its own when converting
+	taskConfig, err := tasks[1].TaskConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", taskConfig.Timezone)
+	assert.Equal(t, "0 9 * * 1-5", taskConfig.Schedule)
+
+	_, err = parseConfigFile(writeYAMLConfig(t, "tasks:\n  - schedule: 'CRON_TZ=UTC @daily'\n    timezone: UTC\n"+
+		"    model: openai\n    prompt: test_prompt\n    processors: [console]\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2: timezone conflicts")
+}
+
+func TestScheduleTaskSeconds(t *testing.T) {
+	service := NewCronService("test.config")
+	service.scheduler = newScheduler()
+
+	task := Task{Schedule: "*/10 * * * * *", Model: "openai", Prompt: "report", Processor: "console"}
+	require.NoError(t, service.scheduleTask(newScheduledTask(task)))
+
+	entries := service.scheduler.Entries()
+	require.Len(t, entries, 1)
+	from := time.Date(2026, 1, 1, 12, 0, 1, 0, time.UTC)
+	assert.Equal(t, from.Add(9*time.Second), entries[0].Schedule.Next(from))
+}
@@ -2,36 +2,79 @@
 package cron
 
 import (
-	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/robfig/cron/v3"
+	"github.com/rshade/cronai/internal/budget"
 	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/lock"
 	"github.com/rshade/cronai/internal/logger"
 	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/pool"
 	"github.com/rshade/cronai/internal/processor"
 	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/internal/variable"
 	"github.com/rshade/cronai/pkg/config"
 )
 
 // executeModel is a variable function for mocking in tests
 var executeModel = models.ExecuteModel
 
+// executeModelStream is a variable function for mocking in tests
+var executeModelStream = models.ExecuteModelStream
+
 // Task represents a scheduled task
 type Task struct {
-	Schedule    string
-	Model       string
-	Prompt      string
-	Processor   string
-	Template    string            // Optional template name
-	Variables   map[string]string // Variables for the prompt
-	ModelParams string            // Model-specific parameters (temperature, tokens, etc.)
+	Name         string // Optional unique name, used to refer to the task
+	Schedule     string
+	Model        string
+	Prompt       string
+	Processor    string
+	Processors   []config.ProcessorConfig // Processors to deliver to; when empty, Processor and Template are used
+	Template     string                   // Optional template name
+	Variables    map[string]string        // Variables for the prompt
+	ModelParams  string                   // Model-specific parameters (temperature, tokens, etc.)
+	Overlap      Overlap                  // What to do when the task fires while a previous run is in progress
+	Catchup      Catchup                  // Which runs missed during downtime to execute on startup
+	After        string                   // Name of the upstream task that triggers this task
+	On           TriggerOn                // Upstream outcomes that trigger this task
+	Timeout      time.Duration            // Optional limit for a single run, zero means no limit
+	Group        string                   // Optional group, freeze windows pause whole groups
+	SkipCalendar string                   // Calendar whose days the task doesn't run on
+	OnlyCalendar string                   // Calendar whose days are the only ones the task runs on
+	When         []string                 // Checks that must pass before the model is called
+	Tags         []string                 // Labels profiles select the task by
+	Retry        Retry                    // Retries of failed deliveries to processors
+	Stream       bool                     // Show the response on processors that can stream while the model produces it
+
+	calendars *Calendars // Calendars and freeze windows of the configuration file
+	checks    *Checks    // Checks of the configuration file, set when the task uses any
+	budgets   *Budgets   // Budgets of the configuration file, set when it defines any
+	providers *Providers // Named providers of the configuration file, set when it defines any
+}
+
+// ID returns the task's name or, for unnamed tasks, a stable identifier derived
+// from its definition
+func (t Task) ID() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	sum := sha256.Sum256([]byte(taskDefinition(t)))
+	return fmt.Sprintf("%s-%s", t.Prompt, hex.EncodeToString(sum[:4]))
 }
 
 // ScheduledTask represents a task with its schedule
@@ -48,19 +91,69 @@ type ScheduledTask struct {
 
 // EntryMetadata contains metadata about a scheduled task.
 type EntryMetadata struct {
+	EntryID   cron.EntryID
 	Model     string
 	Prompt    string
 	Processor string
 	Schedule  string
 	Variables map[string]string
+	Task      Task
+
+	wrap overlapWrapper // enforces the task's overlap policy for every run of this entry
 }
 
 // Service manages the scheduling and execution of AI tasks.
 type Service struct {
-	configFile string
-	scheduler  *cron.Cron
-	entries    map[string]EntryMetadata
-	mu         sync.Mutex
+	configFile     string
+	scheduler      *cron.Cron
+	entries        map[string]EntryMetadata // keyed by task key, see taskKey
+	mu             sync.Mutex
+	reloadInterval time.Duration
+	skipped        map[string]int // skipped executions by task ID
+	active         map[string]int // executions in progress by task ID
+	reloadMu       sync.Mutex     // serializes configuration reloads
+	controlSocket  string
+	statePath      string
+	state          *stateStore
+	budgetPath     string
+	ledger         *budget.Ledger // spend counted against the budgets of the configuration
+
+	// ctx is the parent of every scheduled execution, cancelling it stops
+	// in-flight model calls and processors
+	ctx        context.Context
+	cancelRuns context.CancelCauseFunc
+
+	// Executions in progress, waited for on shutdown, see drain
+	shutdownGrace time.Duration
+	runMu         sync.Mutex
+	draining      bool
+	running       sync.WaitGroup
+	inFlight      atomic.Int32
+
+	// Coordination with other instances sharing a lock backend, see claimRun
+	locker     lock.Locker
+	lockMode   LockMode
+	lockTTL    time.Duration
+	instanceID string
+	leaseUntil atomic.Int64 // leader mode: Unix nanoseconds until which this instance leads
+}
+
+// ServiceOption is a functional option for configuring the cron service
+type ServiceOption func(*Service)
+
+// WithReloadInterval sets how often the configuration file is checked for changes.
+// A zero or negative interval disables polling; SIGHUP still triggers a reload.
+func WithReloadInterval(interval time.Duration) ServiceOption {
+	return func(s *Service) {
+		s.reloadInterval = interval
+	}
+}
+
+// WithStatePath sets where per-task state such as the last run time is persisted
+func WithStatePath(path string) ServiceOption {
+	return func(s *Service) {
+		s.statePath = path
+	}
 }
 
 // Default logger for the cron package
@@ -78,17 +171,37 @@ func init() {
 }
 
 // NewCronService creates a new cron service
-func NewCronService(configFile string) *Service {
-	return &Service{
-		configFile: configFile,
-		entries:    make(map[string]EntryMetadata),
+func NewCronService(configFile string, opts ...ServiceOption) *Service {
+	s := &Service{
+		configFile:     configFile,
+		entries:        make(map[string]EntryMetadata),
+		reloadInterval: reloadIntervalFromEnv(),
+		statePath:      statePathFromEnv(),
+		budgetPath:     budget.LedgerPathFromEnv(),
+		shutdownGrace:  shutdownGraceFromEnv(),
+		lockTTL:        lockTTLFromEnv(),
+		instanceID:     lock.InstanceID(),
+		controlSocket:  controlSocketFromEnv(),
+	}
+	s.locker, s.lockMode = lockerFromEnv()
+
+	// Apply options
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	return s
 }
 
-// StartService starts the CronAI service
+// StartService starts the CronAI service. It runs until ctx is cancelled,
+// then stops scheduling new runs and drains the running ones, see drain.
 func (s *Service) StartService(ctx context.Context) error {
 	log.Info("Starting CronAI service", logger.Fields{"config_path": s.configFile})
 
+	// Executions outlive ctx for the shutdown grace period
+	s.ctx, s.cancelRuns = context.WithCancelCause(context.WithoutCancel(ctx))
+	defer s.cancelRuns(nil)
+
 	// Parse config file
 	tasks, err := parseConfigFile(s.configFile)
 	if err != nil {
@@ -98,84 +211,138 @@ func (s *Service) StartService(ctx context.Context) error {
 
 	log.Info("Parsed configuration file", logger.Fields{"task_count": len(tasks)})
 
+	// Load persisted task state
+	s.state, err = loadState(s.statePath)
+	if err != nil {
+		log.Error("Failed to load task state", logger.Fields{"path": s.statePath, "error": err.Error()})
+		return errors.Wrap(errors.CategorySystem, err, "failed to load task state")
+	}
+
+	// Load the spend counted against budgets before the restart
+	ledger, err := budget.LoadLedger(s.budgetPath)
+	if err != nil {
+		log.Error("Failed to load budget ledger", logger.Fields{"path": s.budgetPath, "error": err.Error()})
+		return errors.Wrap(errors.CategorySystem, err, "failed to load budget ledger")
+	}
+	s.mu.Lock()
+	s.ledger = ledger
+	s.mu.Unlock()
+
+	// Take part in the leader election before running anything
+	stopLeading := s.startLeading(ctx)
+	defer stopLeading()
+
+	drained := make(chan struct{})
+	context.AfterFunc(ctx, func() {
+		s.drain()
+		close(drained)
+	})
+
 	// Create a new cron scheduler
-	s.scheduler = cron.New()
+	s.scheduler = newScheduler()
 
 	// Add each task to the scheduler
-	for i, task := range tasks {
-		task := task // Create a copy of the task for the closure
+	s.applyTasks(tasks)
 
-		// Convert Task to ScheduledTask
-		scheduledTask := &ScheduledTask{
-			Schedule:    task.Schedule,
-			Model:       task.Model,
-			Prompt:      task.Prompt,
-			Processor:   task.Processor,
-			Variables:   task.Variables,
-			ModelParams: task.ModelParams,
-			Task: Task{
-				Model:     task.Model,
-				Prompt:    task.Prompt,
-				Processor: task.Processor,
-				Variables: task.Variables,
-			},
-		}
+	// Start the scheduler
+	s.scheduler.Start()
+	log.Info("Cron scheduler started")
 
-		err = s.scheduleTask(scheduledTask)
-		if err != nil {
-			log.Error("Error scheduling task", logger.Fields{
-				"task_index": i,
-				"schedule":   task.Schedule,
-				"model":      task.Model,
-				"prompt":     task.Prompt,
-				"error":      err.Error(),
-			})
-			continue
+	// Run what was missed while the service was down alongside the schedule
+	s.catchUp(ctx)
+
+	// Accept pause, resume, trigger and reload requests
+	if s.controlSocket != "" {
+		if err := s.serveControl(ctx); err != nil {
+			log.Error("Control socket unavailable", logger.Fields{"path": s.controlSocket, "error": err.Error()})
 		}
-		log.Info("Scheduled task", logger.Fields{
-			"task_index": i,
-			"schedule":   task.Schedule,
-			"model":      task.Model,
-			"prompt":     task.Prompt,
-			"processor":  task.Processor,
-		})
 	}
 
-	// Start the scheduler
-	s.scheduler.Start()
-	log.Info("Cron scheduler started")
+	// Pick up configuration changes while running
+	go s.watchConfig(ctx)
 
 	// Run until context is cancelled
 	<-ctx.Done()
 
-	// Stop the scheduler
-	s.scheduler.Stop()
+	// Stop scheduling new runs and wait for the running ones
+	stopped := s.scheduler.Stop()
+	<-drained
+	<-stopped.Done()
 	log.Info("Cron scheduler stopped")
 
 	return nil
 }
 
+// runContext returns the context scheduled executions run under
+func (s *Service) runContext() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// newScheduledTask converts a parsed task into a ScheduledTask
+func newScheduledTask(task Task) *ScheduledTask {
+	return &ScheduledTask{
+		Schedule:    task.Schedule,
+		Model:       task.Model,
+		Prompt:      task.Prompt,
+		Processor:   task.Processor,
+		Variables:   task.Variables,
+		ModelParams: task.ModelParams,
+		Template:    task.Template,
+		Task:        task,
+	}
+}
+
 // scheduleTask adds a task to the scheduler
 func (s *Service) scheduleTask(task *ScheduledTask) error {
+	return s.addEntry(taskKey(task.Task), task, nil)
+}
+
+// addEntry registers a task with the scheduler and records it under key. The
+// entry enforces its overlap policy with wrap, or with a new wrapper when wrap
+// is nil.
+func (s *Service) addEntry(key string, task *ScheduledTask, wrap overlapWrapper) error {
 	if s.scheduler == nil {
 		return fmt.Errorf("scheduler not initialized")
 	}
 
-	entryID, err := s.scheduler.AddFunc(task.Schedule, func() {
-		s.executeTask(task.Task)
-	})
-	if err != nil {
-		return err
+	if wrap == nil {
+		wrap = s.overlapWrapperFor(task.Task)
+	}
+
+	// Tasks scheduled @after only run when their upstream task completes
+	var entryID cron.EntryID
+	if task.Schedule != ScheduleAfter {
+		job := wrap(history.SourceCron, func(ctx context.Context) {
+			now := time.Now()
+			s.setLastRun(task.Task, now)
+			if !s.claimRun(task.Task, history.SourceCron, now) ||
+				s.skipPaused(task.Task, history.SourceCron) ||
+				s.skipBlackout(task.Task, history.SourceCron, now) {
+				return
+			}
+			_ = s.executeTask(ctx, task.Task, history.SourceCron, nil) //nolint:errcheck // failures are logged and recorded in history
+		})
+		schedule, err := parseSchedule(task.Schedule)
+		if err != nil {
+			return err
+		}
+		entryID = s.scheduler.Schedule(schedule, job)
 	}
 
 	// Store metadata
 	s.mu.Lock()
-	s.entries[fmt.Sprintf("%d", entryID)] = EntryMetadata{
+	s.entries[key] = EntryMetadata{
+		EntryID:   entryID,
 		Model:     task.Model,
 		Prompt:    task.Prompt,
 		Processor: task.Processor,
 		Schedule:  task.Schedule,
 		Variables: task.Variables,
+		Task:      task.Task,
+		wrap:      wrap,
 	}
 	s.mu.Unlock()
 
@@ -190,24 +357,31 @@ func (s *Service) ListTasks() []ScheduledTask {
 	tasks := make([]ScheduledTask, 0, len(s.entries))
 	for _, entry := range s.entries {
 		tasks = append(tasks, ScheduledTask{
-			Schedule:  entry.Schedule,
-			Model:     entry.Model,
-			Prompt:    entry.Prompt,
-			Processor: entry.Processor,
-			Variables: entry.Variables,
-			Task: Task{
-				Model:     entry.Model,
-				Prompt:    entry.Prompt,
-				Processor: entry.Processor,
-				Variables: entry.Variables,
-			},
+			Schedule:    entry.Schedule,
+			Model:       entry.Model,
+			Prompt:      entry.Prompt,
+			Processor:   entry.Processor,
+			Variables:   entry.Variables,
+			ModelParams: entry.Task.ModelParams,
+			Template:    entry.Task.Template,
+			Task:        entry.Task,
 		})
 	}
 	return tasks
 }
 
-// executeTask executes a single task
-func (s *Service) executeTask(task Task) {
+// executeTask executes a single task triggered by source, then triggers the
+// tasks that run after it. Upstream variables, if any, are passed to the prompt.
+func (s *Service) executeTask(ctx context.Context, task Task, source string, upstream map[string]string) error {
+	finish, ok := s.beginRun()
+	if !ok {
+		s.recordSkip(task, source, errShutdown.Error())
+		return errShutdown
+	}
+	defer finish()
+	s.trackRunning(task, 1)
+	defer s.trackRunning(task, -1)
+
 	startTime := time.Now()
 	log.Info("Executing task", logger.Fields{
 		"time":      startTime.Format(time.RFC3339),
@@ -216,133 +390,260 @@ func (s *Service) executeTask(task Task) {
 		"processor": task.Processor,
 	})
 
-	// Load the prompt with variables
-	var promptContent string
-	var err error
+	record, err := s.runTask(ctx, task, source, upstream)
+	defer s.triggerDependents(task, record)
+	if err != nil && record.Status == history.StatusInterrupted {
+		return err
+	}
+	if err == nil && record.Status == history.StatusSkipped {
+		log.Info("Task skipped", logger.Fields{
+			"execution_id": record.ID,
+			"task":         task.ID(),
+			"reason":       record.Reason,
+		})
+		return nil
+	}
+	if err != nil {
+		log.Error("Task failed", logger.Fields{
+			"execution_id": record.ID,
+			"stage":        record.Stage,
+			"model":        task.Model,
+			"prompt":       task.Prompt,
+			"error":        err.Error(),
+		})
+		return err
+	}
 
-	// Get the prompt manager
-	promptManager := prompt.GetPromptManager()
+	endTime := time.Now()
+	duration := endTime.Sub(startTime)
+	log.Info("Task completed successfully", logger.Fields{
+		"time":              endTime.Format(time.RFC3339),
+		"duration":          duration.String(),
+		"execution_id":      record.ID,
+		"model":             task.Model,
+		"model_used":        record.ModelUsed,
+		"prompt":            task.Prompt,
+		"processor":         task.Processor,
+		"prompt_tokens":     record.PromptTokens,
+		"completion_tokens": record.CompletionTokens,
+		"cached_tokens":     record.CachedTokens,
+		"cost":              record.Cost,
+	})
+	return nil
+}
 
-	if len(task.Variables) > 0 {
-		log.Debug("Loading prompt with variables", logger.Fields{"prompt": task.Prompt, "var_count": len(task.Variables)})
-		promptContent, err = promptManager.LoadPromptWithVariables(task.Prompt, task.Variables)
-	} else {
-		log.Debug("Loading prompt without variables", logger.Fields{"prompt": task.Prompt})
-		promptContent, err = promptManager.LoadPrompt(task.Prompt)
-	}
+// RunTask executes a single task immediately
+func (s *Service) RunTask(task Task) error {
+	_, err := s.runTask(s.runContext(), task, history.SourceRun, nil)
+	return err
+}
 
+// RunNamedTask executes the task with the given name from the configuration
+// file immediately, the same way the scheduler runs it
+func (s *Service) RunNamedTask(ctx context.Context, name string) error {
+	tasks, err := parseConfigFile(s.configFile)
 	if err != nil {
-		log.Error("Error loading prompt", logger.Fields{"prompt": task.Prompt, "error": err.Error()})
-		return
+		return errors.Wrap(errors.CategoryConfiguration, err, "failed to parse configuration file")
 	}
 
-	// Add the prompt name to the variables map for tracking execution
-	if task.Variables == nil {
-		task.Variables = make(map[string]string)
+	task, err := findTask(tasks, name)
+	if err != nil {
+		return err
 	}
-	task.Variables["promptName"] = task.Prompt
+	return s.executeTask(ctx, task, history.SourceRun, nil)
+}
 
-	// Execute the model with model parameters
-	log.Debug("Executing model", logger.Fields{"model": task.Model, "prompt_length": len(promptContent)})
-	response, err := executeModel(task.Model, promptContent, task.Variables, task.ModelParams)
-	if err != nil {
-		log.Error("Error executing model", logger.Fields{"model": task.Model, "error": err.Error()})
-		return
+// findTask returns the task with the given name
+func findTask(tasks []Task, name string) (Task, error) {
+	for _, task := range tasks {
+		if task.Name == name {
+			return task, nil
+		}
 	}
+	return Task{}, errors.Wrap(errors.CategoryValidation, errors.ErrNotFound, fmt.Sprintf("task '%s'", name))
+}
 
-	// Process the response
-	log.Debug("Processing response", logger.Fields{"processor": task.Processor})
+// newTaskRecord starts a history record describing an execution of task
+func newTaskRecord(task Task, source string) *history.Record {
+	record := history.New(source)
+	record.Task = task.ID()
+	record.Schedule = task.Schedule
+	record.Prompt = task.Prompt
+	record.Model = task.Model
+	record.Processor = task.Processor
+	record.Variables = task.Variables
+	return record
+}
 
-	// Create processor config
-	procConfig := processor.Config{
-		Type:   "unknown", // Will be set by ParseProcessor
-		Target: "",        // Will be set by ParseProcessor
+// runTask evaluates the task's checks, loads the prompt, executes the model
+// and processes the response, recording the execution in the history store.
+// A check that doesn't pass skips the execution before the model is called.
+// Check output and upstream variables are added to the task's variables,
+// which take precedence. A cancelled context, or the
+// task's timeout expiring, aborts the model call and processors in flight and
+// stops the execution before the next stage starts. The returned record is
+// never nil.
+func (s *Service) runTask(ctx context.Context, task Task, source string, upstream map[string]string) (record *history.Record, err error) {
+	record = newTaskRecord(task, source)
+
+	if task.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, task.Timeout,
+			fmt.Errorf("task timed out after %s: %w", task.Timeout, context.DeadlineExceeded))
+		defer cancel()
 	}
 
-	// Parse processor name to determine type and target
-	procType, target := parseProcessor(task.Processor)
-	procConfig.Type = procType
-	procConfig.Target = target
-
-	// Get the registry
-	registry := processor.GetRegistry()
+	defer func() {
+		markInterrupted(ctx, record)
+		record.Finish()
+		if saveErr := history.Save(record); saveErr != nil {
+			log.Warn("Failed to record execution history", logger.Fields{
+				"execution_id": record.ID,
+				"error":        saveErr.Error(),
+			})
+		}
+	}()
 
-	// Create the processor
-	proc, err := registry.CreateProcessor(procType, procConfig)
+	// Only call the model when every check of the task passes
+	checkVariables, checkStamps, reason, err := s.evaluateChecks(ctx, task)
 	if err != nil {
-		log.Error("Error getting processor", logger.Fields{"error": err.Error()})
-		return
+		err = errors.Wrap(errors.CategoryExternal, err, "error running checks")
+		record.Fail(history.StageCheck, err)
+		return record, err
 	}
-
-	// Create a models.ModelResponse
-	modelResponse := &models.ModelResponse{
-		Model:       task.Model,
-		PromptName:  task.Prompt,
-		Content:     response.Content,
-		Timestamp:   time.Now(),
-		ExecutionID: fmt.Sprintf("%d", time.Now().UnixNano()),
+	if reason != "" {
+		record.Skip(reason)
+		return record, nil
 	}
 
-	err = proc.Process(modelResponse, "")
+	// Get the prompt manager
+	promptManager := prompt.GetPromptManager()
+
+	// Evaluate dynamic expressions such as {{date -7d}} in the task's timezone
+	taskVariables, err := task.expandVariables(time.Now())
 	if err != nil {
-		log.Error("Error processing response", logger.Fields{"error": err.Error()})
-		return
+		err = errors.Wrap(errors.CategoryConfiguration, err, "error evaluating variables")
+		record.Fail(history.StagePrompt, err)
+		return record, err
+	}
+	record.Variables = taskVariables
+
+	// Merge check output and upstream variables, task variables take precedence
+	promptVariables := taskVariables
+	if len(upstream) > 0 || len(checkVariables) > 0 {
+		promptVariables = make(map[string]string, len(checkVariables)+len(upstream)+len(taskVariables))
+		for k, v := range checkVariables {
+			promptVariables[k] = v
+		}
+		for k, v := range upstream {
+			promptVariables[k] = v
+		}
+		for k, v := range taskVariables {
+			promptVariables[k] = v
+		}
 	}
 
-	endTime := time.Now()
-	duration := endTime.Sub(startTime)
-	log.Info("Task completed successfully", logger.Fields{
-		"time":      endTime.Format(time.RFC3339),
-		"duration":  duration.String(),
-		"model":     task.Model,
-		"prompt":    task.Prompt,
-		"processor": task.Processor,
-	})
-}
-
-// RunTask executes a single task immediately
-func (s *Service) RunTask(task Task) error {
-	// Load the prompt content
+	// Load the prompt with variables
 	var promptContent string
-	var err error
-
-	// Get the prompt manager
-	promptManager := prompt.GetPromptManager()
-
-	if len(task.Variables) > 0 {
-		promptContent, err = promptManager.LoadPromptWithVariables(task.Prompt, task.Variables)
+	if len(promptVariables) > 0 {
+		log.Debug("Loading prompt with variables", logger.Fields{"prompt": task.Prompt, "var_count": len(promptVariables)})
+		promptContent, err = promptManager.LoadPromptWithVariables(task.Prompt, promptVariables)
 	} else {
+		log.Debug("Loading prompt without variables", logger.Fields{"prompt": task.Prompt})
 		promptContent, err = promptManager.LoadPrompt(task.Prompt)
 	}
-
 	if err != nil {
-		return fmt.Errorf("error loading prompt: %w", err)
+		err = errors.Wrap(errors.CategoryConfiguration, err, "error loading prompt")
+		record.Fail(history.StagePrompt, err)
+		return record, err
 	}
+	record.SetPrompt(promptContent)
 
-	// Execute the model with model parameters
-	response, err := executeModel(task.Model, promptContent, task.Variables, task.ModelParams)
-	if err != nil {
-		return fmt.Errorf("error executing model: %w", err)
+	// Add the prompt name to a copy of the variables for tracking execution
+	variables := make(map[string]string, len(promptVariables)+1)
+	for k, v := range promptVariables {
+		variables[k] = v
 	}
+	variables["promptName"] = task.Prompt
 
-	// Process the response
-	procConfig := processor.Config{
-		Type:   "unknown", // Will be set by ParseProcessor
-		Target: "",        // Will be set by ParseProcessor
+	if ctx.Err() != nil {
+		err = errors.Wrap(errors.CategoryApplication, context.Cause(ctx), "execution cancelled before model call")
+		record.Fail(history.StageModel, err)
+		return record, err
 	}
 
-	// Parse processor name to determine type and target
-	procType, target := parseProcessor(task.Processor)
-	procConfig.Type = procType
-	procConfig.Target = target
+	// Check the model call against the budgets of the configuration
+	guard, err := s.budgetGuard(task)
+	if err != nil {
+		record.Fail(history.StageModel, err)
+		return record, err
+	}
+	if guard != nil {
+		ctx = budget.WithGuard(ctx, guard, budget.Request{Task: task.ID(), Category: prompt.Category(task.Prompt)})
+	}
+	if task.providers != nil {
+		ctx = models.WithProviders(ctx, task.providers.byName)
+	}
 
-	// Get the registry
-	registry := processor.GetRegistry()
+	// Wait for a slot in the execution pool shared with the queue and bot
+	// modes. It is only held for the model call, which takes the slot of each
+	// provider it tries; checks and deliveries don't need one.
+	release, err := pool.Default().AcquireGlobal(ctx)
+	if err != nil {
+		err = errors.Wrap(errors.CategorySystem, err, "no execution slot available")
+		record.Fail(history.StageModel, err)
+		return record, err
+	}
 
-	// Create the processor
-	proc, err := registry.CreateProcessor(procType, procConfig)
+	// Execute the model with model parameters, streaming the response to the
+	// processors that can show it as it is produced
+	log.Debug("Executing model", logger.Fields{"model": task.Model, "prompt_length": len(promptContent)})
+	timestamp := time.Now()
+	var streams *responseStreams
+	if task.Stream {
+		streams = beginStreams(ctx, task, &models.ModelResponse{
+			Model:       task.Model,
+			PromptName:  task.Prompt,
+			Variables:   variables,
+			Timestamp:   timestamp,
+			ExecutionID: record.ID,
+		})
+	}
+	var response *models.ModelResponse
+	if streams != nil {
+		response, err = executeModelStream(ctx, task.Model, promptContent, variables, task.ModelParams, streams)
+		streams.stop()
+	} else {
+		response, err = executeModel(ctx, task.Model, promptContent, variables, task.ModelParams)
+	}
+	release()
+	var exceeded *budget.ExceededError
+	if errors.As(err, &exceeded) {
+		// An exhausted budget refused the call, there is nothing to deliver
+		streams.abort(ctx, err)
+		record.Skip(err.Error())
+		return record, nil
+	}
 	if err != nil {
-		return fmt.Errorf("error getting processor: %w", err)
+		err = errors.Wrap(errors.CategoryExternal, err, "error executing model")
+		streams.abort(ctx, err)
+		record.Fail(history.StageModel, err)
+		return record, err
+	}
+	record.ModelUsed = response.Provider
+	if record.ModelUsed == "" {
+		record.ModelUsed = task.Model
+	}
+	record.ModelVersion = response.Model
+	record.SetUsage(response.Usage.PromptTokens, response.Usage.CompletionTokens, response.Usage.CachedTokens, response.Cost)
+	record.Response = response.Content
+
+	// Don't deliver a response that has been superseded by a newer run
+	if ctx.Err() != nil {
+		err = errors.Wrap(errors.CategoryApplication, context.Cause(ctx), "execution cancelled before processing")
+		streams.abort(ctx, err)
+		record.Fail(history.StageProcessor, err)
+		return record, err
 	}
 
 	// Create a models.ModelResponse
@@ -350,18 +651,112 @@ func (s *Service) RunTask(task Task) error {
 		Model:       task.Model,
 		PromptName:  task.Prompt,
 		Content:     response.Content,
-		Timestamp:   time.Now(),
-		ExecutionID: fmt.Sprintf("%d", time.Now().UnixNano()),
+		Variables:   variables,
+		Timestamp:   timestamp,
+		ExecutionID: record.ID,
+		Provider:    record.ModelUsed,
+		Usage:       response.Usage,
+		Cost:        response.Cost,
+	}
+
+	// Deliver the response to every processor, a failing processor doesn't
+	// stop delivery to the others. Failed deliveries are retried with the
+	// same response as the task's retry settings allow. Streamed deliveries
+	// are finished with the assembled response.
+	var processErrors []error
+	for i, procConfig := range task.processors() {
+		if streams.finish(ctx, i, procConfig, modelResponse) {
+			continue
+		}
+		retries, err := deliverWithRetry(ctx, task, procConfig, modelResponse)
+		record.DeliveryRetries += retries
+		if err != nil {
+			processErrors = append(processErrors, err)
+		}
+	}
+	switch len(processErrors) {
+	case 0:
+	case 1:
+		err = processErrors[0]
+	default:
+		err = errors.Wrap(errors.CategoryExternal, multierror.Append(nil, processErrors...).ErrorOrNil(),
+			fmt.Sprintf("error processing response with %d processors", len(processErrors)))
 	}
+	if err != nil {
+		record.Fail(history.StageProcessor, err)
+		return record, err
+	}
+
+	// The files changed checks looked at only count as seen once delivered
+	if err := s.saveCheckStamps(task, checkStamps); err != nil {
+		log.Warn("Failed to save check state", logger.Fields{
+			"task":  task.ID(),
+			"error": err.Error(),
+		})
+	}
+	return record, nil
+}
+
+// expandVariables evaluates the dynamic expressions in the task's variables at
+// now, in the task's timezone
+func (t Task) expandVariables(now time.Time) (map[string]string, error) {
+	if loc, err := t.location(); err == nil && loc != nil {
+		now = now.In(loc)
+	}
+	return variable.New(now).ExpandAll(t.Variables)
+}
 
-	err = proc.Process(modelResponse, "")
+// processors returns the processors the task delivers its response to
+func (t Task) processors() []config.ProcessorConfig {
+	if len(t.Processors) > 0 {
+		procs := make([]config.ProcessorConfig, len(t.Processors))
+		for i, procConfig := range t.Processors {
+			if procConfig.Template == "" {
+				procConfig.Template = t.Template
+			}
+			procs[i] = procConfig
+		}
+		return procs
+	}
+	return []config.ProcessorConfig{{Name: t.Processor, Template: t.Template}}
+}
+
+// deliverResponse sends a model response to a single processor
+func deliverResponse(ctx context.Context, procConfig config.ProcessorConfig, response *models.ModelResponse) error {
+	log.Debug("Processing response", logger.Fields{"processor": procConfig.Name})
+
+	proc, err := newProcessor(procConfig)
 	if err != nil {
-		return fmt.Errorf("error processing response: %w", err)
+		return err
 	}
 
+	if err := proc.Process(ctx, response, procConfig.Template); err != nil {
+		return errors.Wrap(errors.CategoryExternal, err, "error processing response")
+	}
 	return nil
 }
 
+// newProcessor creates the processor a task delivers to
+func newProcessor(procConfig config.ProcessorConfig) (processor.Processor, error) {
+	// Parse processor name to determine type and target
+	procType, target := parseProcessor(procConfig.Name)
+	options := make(map[string]interface{}, len(procConfig.Options))
+	for k, v := range procConfig.Options {
+		options[k] = v
+	}
+
+	proc, err := processor.GetRegistry().CreateProcessor(procType, processor.Config{
+		Type:         procType,
+		Target:       target,
+		Options:      options,
+		TemplateName: procConfig.Template,
+	})
+	if err != nil {
+		return nil, errors.Wrap(errors.CategoryConfiguration, err, "error getting processor")
+	}
+	return proc, nil
+}
+
 // Stop stops the cron service
 func (s *Service) Stop() error {
 	if s.scheduler == nil {
@@ -373,10 +768,13 @@ func (s *Service) Stop() error {
 
 // Package-level functions for backward compatibility
 
-// StartService starts the CronAI service with the given configuration file
+// StartService starts the CronAI service with the given configuration file. It
+// runs until the process receives SIGINT or SIGTERM, then waits for the
+// executions in progress for up to the shutdown grace period.
 func StartService(configPath string) error {
 	service := NewCronService(configPath)
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	return service.StartService(ctx)
 }
 
@@ -385,65 +783,56 @@ func ListTasks(configPath string) ([]Task, error) {
 	return parseConfigFile(configPath)
 }
 
-// parseConfigFile parses the configuration file and returns a list of tasks
+// parseConfigFile parses the configuration file and the files it includes,
+// applies the active profile and returns a list of tasks. Errors name the
+// file and line they were found on.
 func parseConfigFile(configPath string) (tasks []Task, err error) {
+	if config.IsYAMLFile(configPath) {
+		return parseYAMLConfigFile(configPath)
+	}
+
 	log.Info("Parsing configuration file", logger.Fields{"path": configPath})
 
-	file, err := os.Open(configPath)
-	if err != nil {
-		log.Error("Failed to open config file", logger.Fields{"path": configPath, "error": err.Error()})
-		return nil, errors.Wrap(errors.CategoryConfiguration, err, "failed to open config file")
+	c := newLineConfig()
+	if err := c.readFile(configPath); err != nil {
+		log.Error("Failed to read config file", logger.Fields{"path": configPath, "error": err.Error()})
+		return nil, errors.Wrap(errors.CategoryConfiguration, err, "failed to read config file")
 	}
+	tasks = c.tasks
+	parseErrors := c.errors
 
-	// Using named return values and multierror to preserve both errors
-	defer func() {
-		closeErr := file.Close()
-		if closeErr != nil {
-			log.Warn("Failed to close config file", logger.Fields{"error": closeErr.Error()})
-			// If we have both processing and close errors, combine them
-			if err != nil {
-				err = multierror.Append(
-					err,
-					fmt.Errorf("failed to close config file: %w", closeErr),
-				)
-			} else {
-				// If we only have close error, just use that
-				err = fmt.Errorf("failed to close config file: %w", closeErr)
-			}
-		}
-	}()
-
-	scanner := bufio.NewScanner(file)
-	lineNum := 0
-	var parseErrors *multierror.Error
+	providers, providerErr := resolveProviders(c.providers, tasks)
+	if providerErr != nil {
+		parseErrors = multierror.Append(parseErrors, providerErr)
+	}
 
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
+	// The active profile overrides tasks before they are validated
+	if profileErr := validateProfiles(c.profiles, providers); profileErr != nil {
+		parseErrors = multierror.Append(parseErrors, profileErr)
+	} else if profileErr := applyProfile(activeProfile(), c.profiles, tasks); profileErr != nil {
+		parseErrors = multierror.Append(parseErrors, profileErr)
+	}
 
-		task, err := parseConfigLine(line)
-		// Handle empty or comment lines
-		if task == nil && err == nil {
-			continue
-		}
-		// Handle parse errors
-		if err != nil {
-			parseErrors = multierror.Append(parseErrors, fmt.Errorf("line %d: %v", lineNum, err))
-			continue
+	names := make(map[string]location) // task name -> where it was defined
+	for i, task := range tasks {
+		if validateErr := validateNamedTask(task, c.taskLines[i], names); validateErr != nil {
+			parseErrors = multierror.Append(parseErrors, validateErr)
 		}
+	}
 
-		if task != nil {
-			// Validate the task
-			if validateErr := validateTask(task.Task, lineNum); validateErr != nil {
-				parseErrors = multierror.Append(parseErrors, validateErr)
-			}
-			tasks = append(tasks, task.Task)
-		}
+	if calendarErr := resolveCalendars(filepath.Dir(configPath), c.calendars, c.freezes, tasks, c.taskLines); calendarErr != nil {
+		parseErrors = multierror.Append(parseErrors, calendarErr)
+	}
+	if checkErr := resolveChecks(filepath.Dir(configPath), c.checks, tasks, c.taskLines); checkErr != nil {
+		parseErrors = multierror.Append(parseErrors, checkErr)
+	}
+	if budgetErr := resolveBudgets(c.budgets, providers, tasks); budgetErr != nil {
+		parseErrors = multierror.Append(parseErrors, budgetErr)
 	}
 
-	if err := scanner.Err(); err != nil {
-		log.Error("Error reading config file", logger.Fields{"path": configPath, "error": err.Error()})
-		return nil, errors.Wrap(errors.CategoryConfiguration, err, "error reading config file")
+	// Validate dependencies between tasks
+	if depErr := validateDependencies(tasks); depErr != nil {
+		parseErrors = multierror.Append(parseErrors, depErr)
 	}
 
 	// Check if we had any parse errors
@@ -462,28 +851,61 @@ func parseConfigFile(configPath string) (tasks []Task, err error) {
 
 // parseConfigLine parses a single line from the configuration file
 func parseConfigLine(line string) (*ScheduledTask, error) {
+	return parseLine(line)
+}
+
+// parseLine parses a single configuration line. Dynamic variable expressions
+// such as {{CURRENT_DATE}} are kept as written and evaluated when the task runs.
+// Errors that point at part of the line are columnErrors.
+func parseLine(line string) (*ScheduledTask, error) {
+	return parseTaskLine(line, nil)
+}
+
+// parseTaskLine parses a single configuration line like parseLine, also
+// accepting the named providers declared before it as models
+func parseTaskLine(line string, providers map[string]bool) (*ScheduledTask, error) {
 	// Skip empty lines and comments
-	line = strings.TrimSpace(line)
-	if line == "" {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
 		return nil, nil // Skip empty lines
 	}
-	if strings.HasPrefix(line, "#") {
+	if strings.HasPrefix(trimmed, "#") {
 		return nil, nil // Skip comment lines
 	}
+	if strings.HasPrefix(trimmed, "queue ") {
+		return nil, nil // Skip queue definitions, they are read by the queue service
+	}
+	if strings.HasPrefix(trimmed, "calendar ") || strings.HasPrefix(trimmed, "freeze ") || strings.HasPrefix(trimmed, "check ") ||
+		strings.HasPrefix(trimmed, "profile ") || strings.HasPrefix(trimmed, "provider ") || strings.HasPrefix(trimmed, "include ") {
+		return nil, nil // Skip calendar, freeze, check, profile, provider and include lines, parseConfigFile reads them
+	}
 
 	// Parse the line
-	parts := strings.Fields(line)
-	if len(parts) < 8 { // Need at least 8 fields: 5 for cron schedule + model + prompt + processor
-		return nil, fmt.Errorf("invalid format: insufficient fields (need at least 8, got %d)", len(parts))
+	fields, err := splitFields(line)
+	if err != nil {
+		return nil, err
+	}
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field.text
 	}
 
-	// Extract the cron schedule (first 5 parts)
-	schedule := strings.Join(parts[0:5], " ")
+	// Schedules are an optional CRON_TZ= prefix followed by a descriptor
+	// (@daily, @every 90s, @after) or a cron expression with optional seconds
+	scheduleFields := scheduleFieldCount(parts)
+	minFields := scheduleFields + 3 // schedule + model + prompt + processor
+	if len(parts) < minFields {
+		return nil, errorAt(line, len(strings.TrimRightFunc(line, unicode.IsSpace)),
+			fmt.Errorf("invalid format: insufficient fields (need at least %d, got %d)", minFields, len(parts)))
+	}
+
+	// Extract the schedule
+	schedule := strings.Join(parts[0:scheduleFields], " ")
 
 	// Extract model, prompt, and processor
-	modelPart := parts[5]
-	prompt := parts[6]
-	processor := parts[7]
+	modelPart := parts[scheduleFields]
+	prompt := parts[scheduleFields+1]
+	processor := parts[scheduleFields+2]
 
 	// Parse model and model parameters
 	var model string
@@ -500,44 +922,58 @@ func parseConfigLine(line string) (*ScheduledTask, error) {
 	}
 
 	// Validate model
-	if !isValidModel(model) {
-		return nil, fmt.Errorf("invalid model '%s'", model)
+	if !isValidModel(model) && !providers[model] {
+		return nil, errorAt(line, fields[scheduleFields].offset, fmt.Errorf("invalid model '%s'", model))
 	}
 
 	// Validate processor format
 	if !isValidProcessor(processor) {
-		return nil, fmt.Errorf("invalid processor format '%s'", processor)
+		return nil, errorAt(line, fields[scheduleFields+2].offset, fmt.Errorf("invalid processor format '%s'", processor))
 	}
 
-	// Parse optional variables
+	// Parse optional variables, the rest of the line after the processor
 	var variables map[string]string
 	var template string
-	if len(parts) > 8 {
-		// Check for variables
-		varString := strings.Join(parts[8:], " ")
-		if strings.Contains(varString, "=") {
-			variables = parseVariables(varString)
-			if variables == nil {
-				variables = make(map[string]string)
-			}
+	var options taskOptions
+	if len(fields) > minFields {
+		pairs, err := splitVariablePairs(line, fields[minFields].offset)
+		if err != nil {
+			return nil, err
+		}
+		variables = make(map[string]string, len(pairs))
+		offsets := make(map[string]int, len(pairs))
+		for _, pair := range pairs {
+			variables[pair.key] = pair.value
+			offsets[pair.key] = pair.offset
+		}
 
-			// Check if any variable has an invalid format
-			for _, part := range parts[8:] {
-				if strings.Contains(part, "=") {
-					keyValue := strings.SplitN(part, "=", 2)
-					if len(keyValue) != 2 {
-						return nil, fmt.Errorf("invalid variable format '%s'", part)
-					}
-				}
-			}
+		// Extract template from variables if present
+		if templateVar, ok := variables["template"]; ok {
+			template = templateVar
+		}
 
-			// Extract template from variables if present
-			if templateVar, ok := variables["template"]; ok {
-				template = templateVar
+		// Extract task options, they configure the task rather than the prompt
+		if options, err = extractTaskOptions(variables); err == nil {
+			err = validateVariables(variables)
+		}
+		if err == nil {
+			var optionKeys []string
+			for _, pair := range pairs {
+				if _, ok := variables[pair.key]; !ok {
+					optionKeys = append(optionKeys, pair.key)
+				}
 			}
-		} else {
-			// If there's a part without '=' and it's not a valid format, it's an error
-			return nil, fmt.Errorf("invalid variable format '%s'", varString)
+			err = checkOptionClash(prompt, optionKeys)
+		}
+		var varErr *variableError
+		if errors.As(err, &varErr) {
+			return nil, errorAt(line, offsets[varErr.key], varErr.err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(variables) == 0 {
+			variables = nil
 		}
 	}
 
@@ -551,49 +987,46 @@ func parseConfigLine(line string) (*ScheduledTask, error) {
 		ModelParams: modelParams,
 		Template:    template,
 		Task: Task{
-			Model:       model,
-			Prompt:      prompt,
-			Processor:   processor,
-			Variables:   variables,
-			ModelParams: modelParams,
-			Template:    template,
+			Schedule:     schedule,
+			Model:        model,
+			Prompt:       prompt,
+			Processor:    processor,
+			Variables:    variables,
+			ModelParams:  modelParams,
+			Template:     template,
+			Overlap:      options.Overlap,
+			Catchup:      options.Catchup,
+			Name:         options.Name,
+			After:        options.After,
+			On:           options.On,
+			Timeout:      options.Timeout,
+			Group:        options.Group,
+			SkipCalendar: options.SkipCalendar,
+			OnlyCalendar: options.OnlyCalendar,
+			When:         options.When,
+			Tags:         options.Tags,
+			Retry:        options.Retry,
+			Stream:       options.Stream,
 		},
 	}
 
-	return task, nil
-}
-
-// parseVariables parses a variable string and returns a map
-func parseVariables(varString string) map[string]string {
-	if varString == "" {
-		return nil
+	if schedule == ScheduleAfter && options.After == "" {
+		return nil, errorAt(line, fields[0].offset, fmt.Errorf("schedule %s requires an after=<task> option", ScheduleAfter))
 	}
 
-	variables := make(map[string]string)
-	for _, varPair := range strings.Split(varString, ",") {
-		keyValue := strings.SplitN(varPair, "=", 2)
-		if len(keyValue) == 2 {
-			key := strings.TrimSpace(keyValue[0])
-			value := strings.TrimSpace(keyValue[1])
-
-			// Handle special variables
-			switch value {
-			case "{{CURRENT_DATE}}":
-				value = time.Now().Format("2006-01-02")
-			case "{{CURRENT_TIME}}":
-				value = time.Now().Format("15:04:05")
-			case "{{CURRENT_DATETIME}}":
-				value = time.Now().Format("2006-01-02 15:04:05")
-			}
+	return task, nil
+}
 
-			variables[key] = value
+// validateVariables checks the dynamic expressions, such as {{date -7d}}, in
+// variable values. They are evaluated each time the task runs. Errors are
+// variableErrors naming the variable at fault.
+func validateVariables(variables map[string]string) error {
+	for key, value := range variables {
+		if err := variable.Validate(value); err != nil {
+			return &variableError{key: key, err: fmt.Errorf("invalid variable %s: %w", key, err)}
 		}
 	}
-
-	if len(variables) == 0 {
-		return make(map[string]string)
-	}
-	return variables
+	return nil
 }
 
 // Helper functions for validation
@@ -601,7 +1034,7 @@ func parseVariables(varString string) map[string]string {
 // isValidModel checks if the model is supported
 func isValidModel(model string) bool {
 	switch model {
-	case "openai", "claude", "gemini":
+	case "openai", "claude", "gemini", "ollama":
 		return true
 	default:
 		return false
@@ -648,21 +1081,43 @@ func parseProcessor(processorName string) (string, string) {
 	return "console", ""
 }
 
+// validateNamedTask validates a task and checks that its name, if any, hasn't
+// been used by a previous task. names maps the names seen so far to where
+// they were defined.
+func validateNamedTask(task Task, at location, names map[string]location) error {
+	var validateErrors *multierror.Error
+	if err := validateTask(task, at); err != nil {
+		validateErrors = multierror.Append(validateErrors, err)
+	}
+
+	// Task names must be unique so they can be referred to
+	if name := task.Name; name != "" {
+		if first, ok := names[name]; ok {
+			validateErrors = multierror.Append(validateErrors,
+				fmt.Errorf("%s: duplicate task name '%s' (first defined on %s)", at, name, first.relativeTo(at)))
+		} else {
+			names[name] = at
+		}
+	}
+
+	return validateErrors.ErrorOrNil()
+}
+
 // validateTask validates a task configuration
-func validateTask(task Task, lineNum int) error {
+func validateTask(task Task, at location) error {
 	var validateErrors *multierror.Error
 
 	// Validate cron schedule format
-	_, err := cron.ParseStandard(task.Schedule)
-	if err != nil {
+	_, err := parseSchedule(task.Schedule)
+	if err != nil && task.Schedule != ScheduleAfter {
 		validateErrors = multierror.Append(validateErrors,
-			fmt.Errorf("line %d: invalid cron schedule '%s': %w", lineNum, task.Schedule, err))
+			fmt.Errorf("%s: invalid cron schedule '%s': %w", at, task.Schedule, err))
 	}
 
 	// Validate model
-	if !isValidModel(task.Model) {
+	if !task.providers.isModel(task.Model) {
 		validateErrors = multierror.Append(validateErrors,
-			fmt.Errorf("line %d: unsupported model '%s' (supported: openai, claude, gemini)", lineNum, task.Model))
+			fmt.Errorf("%s: unsupported model '%s' (supported: %s)", at, task.Model, task.providers.supported()))
 	}
 
 	// Validate prompt file exists
@@ -674,27 +1129,36 @@ func validateTask(task Task, lineNum int) error {
 	_, err = os.Stat(fmt.Sprintf("cron_prompts/%s", promptPath))
 	if err != nil {
 		validateErrors = multierror.Append(validateErrors,
-			fmt.Errorf("line %d: prompt file 'cron_prompts/%s' not found: %w", lineNum, promptPath, err))
+			fmt.Errorf("%s: prompt file 'cron_prompts/%s' not found: %w", at, promptPath, err))
 	}
 
-	// Validate processor
-	if !isValidProcessor(task.Processor) {
-		validateErrors = multierror.Append(validateErrors,
-			fmt.Errorf("line %d: invalid processor '%s' (should start with slack-, email-, webhook-, or log-)",
-				lineNum, task.Processor))
+	// Validate processors
+	for _, procConfig := range task.processors() {
+		if !isValidProcessor(procConfig.Name) {
+			validateErrors = multierror.Append(validateErrors,
+				fmt.Errorf("%s: invalid processor '%s' (should start with slack-, email-, webhook-, or log-)",
+					at, procConfig.Name))
+		}
 	}
 
-	// Validate template if specified
-	if task.Template != "" {
+	// Validate templates if specified
+	checkedTemplates := make(map[string]bool)
+	for _, procConfig := range task.processors() {
+		templateName := procConfig.Template
+		if templateName == "" || checkedTemplates[templateName] {
+			continue
+		}
+		checkedTemplates[templateName] = true
+
 		// Check if template file exists (assuming they're in templates/ directory)
-		_, err = os.Stat(fmt.Sprintf("templates/%s.tmpl", task.Template))
+		_, err = os.Stat(fmt.Sprintf("templates/%s.tmpl", templateName))
 		if err != nil {
 			// Try checking library/ subdirectory
-			_, err = os.Stat(fmt.Sprintf("templates/library/%s.tmpl", task.Template))
+			_, err = os.Stat(fmt.Sprintf("templates/library/%s.tmpl", templateName))
 			if err != nil {
 				validateErrors = multierror.Append(validateErrors,
-					fmt.Errorf("line %d: template '%s.tmpl' not found in templates/ or templates/library/",
-						lineNum, task.Template))
+					fmt.Errorf("%s: template '%s.tmpl' not found in templates/ or templates/library/",
+						at, templateName))
 			}
 		}
 	}
@@ -705,10 +1169,10 @@ func validateTask(task Task, lineNum int) error {
 		params, err := config.ParseModelParams(task.ModelParams)
 		if err != nil {
 			validateErrors = multierror.Append(validateErrors,
-				fmt.Errorf("line %d: invalid model parameters: %w", lineNum, err))
+				fmt.Errorf("%s: invalid model parameters: %w", at, err))
 		} else if err := modelConfig.UpdateFromParams(params); err != nil {
 			validateErrors = multierror.Append(validateErrors,
-				fmt.Errorf("line %d: invalid model parameters: %w", lineNum, err))
+				fmt.Errorf("%s: invalid model parameters: %w", at, err))
 		}
 	}
 
@@ -716,8 +1180,8 @@ func validateTask(task Task, lineNum int) error {
 }
 
 // ProcessResponse processes a model response using the specified processor
-func (s *Service) ProcessResponse(processor processor.Processor, response *models.ModelResponse, templateName string) error {
-	return processor.Process(response, templateName)
+func (s *Service) ProcessResponse(ctx context.Context, processor processor.Processor, response *models.ModelResponse, templateName string) error {
+	return processor.Process(ctx, response, templateName)
 }
 
 // CreateProcessor creates a new processor instance
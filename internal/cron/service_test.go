@@ -1,16 +1,23 @@
 package cron
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/robfig/cron/v3"
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/history"
 	"github.com/rshade/cronai/internal/models"
 	"github.com/rshade/cronai/internal/processor"
 	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/pkg/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockPromptManager is a mock implementation of prompt.Manager
@@ -102,7 +109,7 @@ type mockProcessor struct {
 	processError error
 }
 
-func (m *mockProcessor) Process(_ *models.ModelResponse, _ string) error {
+func (m *mockProcessor) Process(_ context.Context, _ *models.ModelResponse, _ string) error {
 	return m.processError
 }
 
@@ -159,6 +166,7 @@ func TestParseConfigLine(t *testing.T) {
 				Processor: "slack-test",
 				Variables: nil,
 				Task: Task{
+					Schedule:  "* * * * *",
 					Model:     "openai",
 					Prompt:    "test",
 					Processor: "slack-test",
@@ -185,6 +193,7 @@ func TestParseConfigLine(t *testing.T) {
 				Processor: "slack-test",
 				Variables: map[string]string{"key": "value"},
 				Task: Task{
+					Schedule:  "* * * * *",
 					Model:     "openai",
 					Prompt:    "test",
 					Processor: "slack-test",
@@ -204,6 +213,7 @@ func TestParseConfigLine(t *testing.T) {
 				Processor: "slack-test",
 				Variables: map[string]string{"key": "value", "foo": "bar"},
 				Task: Task{
+					Schedule:  "* * * * *",
 					Model:     "openai",
 					Prompt:    "test",
 					Processor: "slack-test",
@@ -224,6 +234,7 @@ func TestParseConfigLine(t *testing.T) {
 				Variables:   nil,
 				ModelParams: "temperature=0.7",
 				Task: Task{
+					Schedule:    "* * * * *",
 					Model:       "openai",
 					Prompt:      "test",
 					Processor:   "slack-test",
@@ -245,6 +256,7 @@ func TestParseConfigLine(t *testing.T) {
 				Variables: map[string]string{"template": "custom_template"},
 				Template:  "custom_template",
 				Task: Task{
+					Schedule:  "* * * * *",
 					Model:     "openai",
 					Prompt:    "test",
 					Processor: "slack-test",
@@ -318,7 +330,7 @@ func TestScheduleTask(t *testing.T) {
 
 	// Mock the ExecuteModel function
 	oldExecuteModel := executeModel
-	executeModel = func(model, prompt string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+	executeModel = func(_ context.Context, model, prompt string, variables map[string]string, _ string) (*models.ModelResponse, error) {
 		return &models.ModelResponse{
 			Content:    "Test response",
 			Model:      model,
@@ -365,7 +377,7 @@ func TestRunTask(t *testing.T) {
 
 	// Mock the ExecuteModel function
 	oldExecuteModel := executeModel
-	executeModel = func(model, prompt string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+	executeModel = func(_ context.Context, model, prompt string, variables map[string]string, _ string) (*models.ModelResponse, error) {
 		return &models.ModelResponse{
 			Content:    "Test response",
 			Model:      model,
@@ -383,8 +395,68 @@ func TestRunTask(t *testing.T) {
 		Processor: "console",
 	}
 
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
 	err := service.RunTask(task)
 	assert.NoError(t, err)
+
+	// The execution is recorded in history
+	records, err := store.List(history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.SourceRun, records[0].Source)
+	assert.Equal(t, "test", records[0].Prompt)
+	assert.Equal(t, "openai", records[0].ModelUsed)
+	assert.Equal(t, history.StatusSuccess, records[0].Status)
+	assert.Equal(t, "Test response", records[0].Response)
+	assert.NotEmpty(t, records[0].PromptHash)
+}
+
+func TestDynamicVariables(t *testing.T) {
+	// Expressions are kept as written when the configuration is loaded
+	task, err := ParseConfigLine(`CRON_TZ=Asia/Tokyo 0 9 * * 1 openai test console name=weekly,week=week of {{week_start "Jan 2"}},since={{date -7d}}`)
+	require.NoError(t, err)
+	assert.Equal(t, `week of {{week_start "Jan 2"}}`, task.Variables["week"])
+
+	_, err = ParseConfigLine("0 9 * * 1 openai test console since={{date -7x}}")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid variable since")
+
+	// and evaluated in the task's timezone when it runs
+	now := time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC) // Monday morning in Tokyo
+	variables, err := task.expandVariables(now)
+	require.NoError(t, err)
+	assert.Equal(t, "week of Oct 19", variables["week"])
+	assert.Equal(t, "2026-10-12", variables["since"])
+	assert.Equal(t, `week of {{week_start "Jan 2"}}`, task.Variables["week"])
+
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("test", "This is a test prompt")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	var received map[string]string
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+		received = variables
+		return &models.ModelResponse{Content: "ok", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	service := NewCronService("test.config")
+	_, err = service.runTask(context.Background(), *task, history.SourceCron, map[string]string{"upstream_content": "{{date}}"})
+	require.NoError(t, err)
+	assert.Regexp(t, `^\d{4}-\d{2}-\d{2}$`, received["since"])
+	assert.Equal(t, "{{date}}", received["upstream_content"], "only the task's own variables are evaluated")
+
+	records, err := store.List(history.Filter{Task: "weekly"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, received["since"], records[0].Variables["since"])
 }
 
 func TestRunNonExistentPrompt(t *testing.T) {
@@ -446,7 +518,7 @@ func TestRunWithFailedProcessor(t *testing.T) {
 
 	// Mock the ExecuteModel function
 	oldExecuteModel := executeModel
-	executeModel = func(model, prompt string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+	executeModel = func(_ context.Context, model, prompt string, variables map[string]string, _ string) (*models.ModelResponse, error) {
 		return &models.ModelResponse{
 			Content:    "Test response",
 			Model:      model,
@@ -464,9 +536,20 @@ func TestRunWithFailedProcessor(t *testing.T) {
 		Processor: "console",
 	}
 
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
 	err := service.RunTask(task)
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "error processing response")
+
+	// The failure is recorded with the failing stage
+	records, err := store.List(history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.StatusFailed, records[0].Status)
+	assert.Equal(t, history.StageProcessor, records[0].Stage)
+	assert.Equal(t, history.OutcomeFailed, records[0].ProcessorOutcome)
 }
 
 func TestParseProcessor(t *testing.T) {
@@ -536,7 +619,7 @@ func TestService_ProcessResponse(t *testing.T) {
 	}
 
 	// Test with mock processor
-	err := service.ProcessResponse(mock, response, "")
+	err := service.ProcessResponse(context.Background(), mock, response, "")
 	if err != nil {
 		t.Errorf("ProcessResponse failed: %v", err)
 	}
@@ -591,3 +674,150 @@ func TestService_CreateProcessor(t *testing.T) {
 		t.Errorf("CreateProcessor returned wrong type: got %s, want console", proc.GetType())
 	}
 }
+
+func TestRunNamedTask(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("test_prompt", "This is a test prompt")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	processor.GetRegistry().RegisterFactory("console", func(_ processor.Config) (processor.Processor, error) {
+		return &mockProcessor{}, nil
+	})
+
+	var receivedParams string
+	var receivedVars map[string]string
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, variables map[string]string, params string) (*models.ModelResponse, error) {
+		receivedParams = params
+		receivedVars = variables
+		return &models.ModelResponse{Content: "Test response", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	configPath := filepath.Join(t.TempDir(), "cronai.config")
+	config := "0 8 * * * openai test_prompt console name=other\n" +
+		"0 9 * * * claude:temperature=0.2 test_prompt console name=daily_pm,team=ops\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0644))
+
+	service := NewCronService(configPath)
+	require.NoError(t, service.RunNamedTask(context.Background(), "daily_pm"))
+
+	// The configured task ran with its variables and model parameters
+	assert.Equal(t, "temperature=0.2", receivedParams)
+	assert.Equal(t, "ops", receivedVars["team"])
+	records, err := store.List(history.Filter{Task: "daily_pm"})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, history.SourceRun, records[0].Source)
+	assert.Equal(t, "claude", records[0].Model)
+
+	err = service.RunNamedTask(context.Background(), "missing")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, errors.ErrNotFound))
+}
+
+// templateProcessor keeps the response and template it is given
+type templateProcessor struct {
+	mockProcessor
+	response *models.ModelResponse
+	template string
+}
+
+func (p *templateProcessor) Process(_ context.Context, response *models.ModelResponse, templateName string) error {
+	p.response, p.template = response, templateName
+	return nil
+}
+
+func TestRunTaskTemplateAndVariables(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("test", "This is a test prompt")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	proc := &templateProcessor{}
+	processor.GetRegistry().RegisterFactory("console", func(_ processor.Config) (processor.Processor, error) {
+		return proc, nil
+	})
+
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		return &models.ModelResponse{Content: "Test response", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	service := &Service{entries: make(map[string]EntryMetadata)}
+	require.NoError(t, service.RunTask(Task{Model: "openai", Prompt: "test", Processor: "console", Template: "summary",
+		Variables: map[string]string{"team": "ops"}}))
+
+	// Processors render the task's template with the variables of the run
+	require.NotNil(t, proc.response)
+	assert.Equal(t, "summary", proc.template)
+	assert.Equal(t, "ops", proc.response.Variables["team"])
+
+	// A processor's own template takes precedence
+	require.NoError(t, service.RunTask(Task{Model: "openai", Prompt: "test", Template: "summary",
+		Processors: []config.ProcessorConfig{{Name: "console", Template: "detailed"}}}))
+	assert.Equal(t, "detailed", proc.template)
+}
+
+func TestParseConfigFileDuplicateNames(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	configPath := filepath.Join(t.TempDir(), "cronai.config")
+	config := "0 8 * * * openai test_prompt console name=daily_pm\n" +
+		"0 9 * * * openai test_prompt console name=daily_pm\n"
+	require.NoError(t, os.WriteFile(configPath, []byte(config), 0644))
+
+	_, err := parseConfigFile(configPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2: duplicate task name 'daily_pm' (first defined on line 1)")
+}
+
+func TestDeliveryUsage(t *testing.T) {
+	var delivered *models.ModelResponse
+	store, _ := setupRetryTest(t, &recordingProcessor{responses: &delivered})
+	executeModel = func(_ context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		return &models.ModelResponse{Content: "report", Model: "gpt-4o", Provider: model,
+			Usage: models.Usage{PromptTokens: 120, CompletionTokens: 30, CachedTokens: 100}, Cost: 0.0005}, nil
+	}
+
+	service := &Service{entries: make(map[string]EntryMetadata)}
+	require.NoError(t, service.RunTask(Task{Model: "openai", Prompt: "test", Processor: "console"}))
+
+	// Processors and the history receive the usage
+	require.NotNil(t, delivered)
+	assert.Equal(t, 150, delivered.Usage.TotalTokens())
+	assert.Equal(t, 0.0005, delivered.Cost)
+
+	records, err := store.List(history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, 120, records[0].PromptTokens)
+	assert.Equal(t, 30, records[0].CompletionTokens)
+	assert.Equal(t, 100, records[0].CachedTokens)
+	assert.Equal(t, 0.0005, records[0].Cost)
+}
+
+// recordingProcessor keeps the response it is given
+type recordingProcessor struct {
+	mockProcessor
+	responses **models.ModelResponse
+}
+
+func (p *recordingProcessor) Process(_ context.Context, response *models.ModelResponse, _ string) error {
+	*p.responses = response
+	return nil
+}
@@ -0,0 +1,177 @@
+package cron
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// EnvStatePath overrides where the cron service persists per-task state
+const EnvStatePath = "CRONAI_STATE_PATH"
+
+// DefaultStatePath is the default location of the persisted per-task state
+const DefaultStatePath = ".cronai/state.json"
+
+// statePathFromEnv returns the state file location configured in the environment
+func statePathFromEnv() string {
+	if path := os.Getenv(EnvStatePath); path != "" {
+		return path
+	}
+	return DefaultStatePath
+}
+
+// taskState is the persisted state of a single task
+type taskState struct {
+	LastRun time.Time         `json:"last_run,omitempty"`
+	Paused  bool              `json:"paused,omitempty"`
+	Checks  map[string]string `json:"checks,omitempty"` // Check name -> stamp of the file a changed check last saw
+}
+
+// stateStore persists per-task state, keyed by task ID, in a JSON file
+type stateStore struct {
+	path  string
+	mu    sync.Mutex
+	Tasks map[string]*taskState `json:"tasks"`
+}
+
+// loadState reads the state file at path. A missing file yields an empty state.
+func loadState(path string) (*stateStore, error) {
+	state := &stateStore{path: path, Tasks: make(map[string]*taskState)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+	if state.Tasks == nil {
+		state.Tasks = make(map[string]*taskState)
+	}
+	return state, nil
+}
+
+// LastRun returns when the task last fired
+func (s *stateStore) LastRun(taskID string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.Tasks[taskID]
+	if !ok || task.LastRun.IsZero() {
+		return time.Time{}, false
+	}
+	return task.LastRun, true
+}
+
+// SetLastRun records when the task last fired and persists the state
+func (s *stateStore) SetLastRun(taskID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.Tasks[taskID]
+	if !ok {
+		task = &taskState{}
+		s.Tasks[taskID] = task
+	}
+	task.LastRun = at
+	return s.save()
+}
+
+// AdvanceLastRun records when the task last fired, unless a later time is
+// already recorded, and persists the state
+func (s *stateStore) AdvanceLastRun(taskID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.Tasks[taskID]
+	if !ok {
+		task = &taskState{}
+		s.Tasks[taskID] = task
+	}
+	if !task.LastRun.Before(at) {
+		return nil
+	}
+	task.LastRun = at
+	return s.save()
+}
+
+// Paused reports whether the task is paused
+func (s *stateStore) Paused(taskID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.Tasks[taskID]
+	return ok && task.Paused
+}
+
+// SetPaused records whether the task is paused and persists the state
+func (s *stateStore) SetPaused(taskID string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.Tasks[taskID]
+	if !ok {
+		task = &taskState{}
+		s.Tasks[taskID] = task
+	}
+	task.Paused = paused
+	return s.save()
+}
+
+// CheckStamp returns the stamp a changed check of the task stored on its last
+// run, or an empty string
+func (s *stateStore) CheckStamp(taskID, check string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.Tasks[taskID]
+	if !ok {
+		return ""
+	}
+	return task.Checks[check]
+}
+
+// SetCheckStamp records the stamp a changed check of the task saw and persists the state
+func (s *stateStore) SetCheckStamp(taskID, check, stamp string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.Tasks[taskID]
+	if !ok {
+		task = &taskState{}
+		s.Tasks[taskID] = task
+	}
+	if task.Checks == nil {
+		task.Checks = make(map[string]string)
+	}
+	task.Checks[check] = stamp
+	return s.save()
+}
+
+// save writes the state file atomically. The caller must hold s.mu.
+func (s *stateStore) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
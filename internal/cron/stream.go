@@ -0,0 +1,188 @@
+package cron
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/rshade/cronai/internal/logger"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/processor"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// responseStreams shows a response on the task's streaming processors while
+// the model produces it. It is the models.StreamHandler of the model call.
+type responseStreams struct {
+	mu      sync.Mutex
+	content strings.Builder
+	stopped bool
+
+	// streams holds the stream of each of the task's processors, nil for
+	// processors that are delivered to once the response is complete
+	streams []*processorStream
+	wg      sync.WaitGroup
+}
+
+// processorStream is the delivery of a response in progress to one processor
+type processorStream struct {
+	name   string
+	stream processor.ResponseStream
+	notify chan struct{} // signals new content, holds at most one signal
+	failed error         // set by the update worker when an update fails
+}
+
+// beginStreams starts streaming the response to every processor of the task
+// that can. partial is the response without its content. It returns nil when
+// no processor streams.
+func beginStreams(ctx context.Context, task Task, partial *models.ModelResponse) *responseStreams {
+	procConfigs := task.processors()
+	streams := &responseStreams{streams: make([]*processorStream, len(procConfigs))}
+	started := false
+	for i, procConfig := range procConfigs {
+		proc, err := newProcessor(procConfig)
+		if err != nil {
+			// Reported when the response is delivered
+			continue
+		}
+		streaming, ok := proc.(processor.StreamingProcessor)
+		if !ok {
+			continue
+		}
+		stream, err := streaming.BeginStream(ctx, partial, procConfig.Template)
+		if err != nil {
+			log.Warn("Failed to start streaming the response, delivering it when complete", logger.Fields{
+				"execution_id": partial.ExecutionID,
+				"processor":    procConfig.Name,
+				"error":        err.Error(),
+			})
+			continue
+		}
+		if stream == nil {
+			continue
+		}
+
+		ps := &processorStream{name: procConfig.Name, stream: stream, notify: make(chan struct{}, 1)}
+		streams.streams[i] = ps
+		started = true
+		streams.wg.Add(1)
+		go streams.update(ctx, ps, partial.ExecutionID)
+	}
+	if !started {
+		return nil
+	}
+	return streams
+}
+
+// update passes new content to a stream until the streams are stopped.
+// Content arriving while an update is in flight is coalesced into the next
+// update, so slow processors don't hold up the model.
+func (r *responseStreams) update(ctx context.Context, ps *processorStream, executionID string) {
+	defer r.wg.Done()
+	for range ps.notify {
+		if ps.failed != nil {
+			continue
+		}
+		r.mu.Lock()
+		content := r.content.String()
+		r.mu.Unlock()
+		if err := ps.stream.Update(ctx, content); err != nil {
+			log.Warn("Failed to update streamed response, delivering it when complete", logger.Fields{
+				"execution_id": executionID,
+				"processor":    ps.name,
+				"error":        err.Error(),
+			})
+			ps.failed = err
+		}
+	}
+}
+
+// Delta adds the next piece of the response
+func (r *responseStreams) Delta(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	r.content.WriteString(text)
+	r.signal()
+}
+
+// Restart discards the content when the model starts over
+func (r *responseStreams) Restart() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	r.content.Reset()
+	r.signal()
+}
+
+// signal notifies the update workers of new content, r.mu must be held
+func (r *responseStreams) signal() {
+	for _, ps := range r.streams {
+		if ps == nil {
+			continue
+		}
+		select {
+		case ps.notify <- struct{}{}:
+		default:
+			// An update is already pending and will see the new content
+		}
+	}
+}
+
+// stop ends the updates once the model call returns, waiting for updates in
+// flight
+func (r *responseStreams) stop() {
+	r.mu.Lock()
+	if !r.stopped {
+		r.stopped = true
+		for _, ps := range r.streams {
+			if ps != nil {
+				close(ps.notify)
+			}
+		}
+	}
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// abort ends every stream of a run that won't deliver a response. It does
+// nothing when the task doesn't stream.
+func (r *responseStreams) abort(ctx context.Context, err error) {
+	if r == nil {
+		return
+	}
+	r.stop()
+	for _, ps := range r.streams {
+		if ps != nil {
+			ps.stream.Abort(ctx, err)
+		}
+	}
+}
+
+// finish completes the streamed delivery to the processor at index i of the
+// task with the assembled response. It reports whether the response was
+// delivered; when it wasn't, the stream has been aborted and the response is
+// to be delivered with the processor's Process method instead.
+func (r *responseStreams) finish(ctx context.Context, i int, procConfig config.ProcessorConfig, response *models.ModelResponse) bool {
+	if r == nil || r.streams[i] == nil {
+		return false
+	}
+	ps := r.streams[i]
+	err := ps.failed
+	if err == nil {
+		if err = ps.stream.Finish(ctx, response); err == nil {
+			return true
+		}
+	}
+	log.Warn("Streamed delivery failed, delivering the complete response", logger.Fields{
+		"execution_id": response.ExecutionID,
+		"processor":    procConfig.Name,
+		"error":        err.Error(),
+	})
+	ps.stream.Abort(ctx, err)
+	return false
+}
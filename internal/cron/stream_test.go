@@ -0,0 +1,149 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/processor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamingProcessor records the streams it is asked to deliver
+type streamingProcessor struct {
+	mockProcessor
+	finishError error
+
+	mu        sync.Mutex
+	updates   []string
+	finished  string
+	aborted   error
+	processed string
+}
+
+func (p *streamingProcessor) Process(_ context.Context, response *models.ModelResponse, _ string) error {
+	p.processed = response.Content
+	return nil
+}
+
+func (p *streamingProcessor) BeginStream(_ context.Context, _ *models.ModelResponse, _ string) (processor.ResponseStream, error) {
+	return p, nil
+}
+
+func (p *streamingProcessor) Update(_ context.Context, content string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.updates = append(p.updates, content)
+	return nil
+}
+
+func (p *streamingProcessor) Finish(_ context.Context, response *models.ModelResponse) error {
+	if p.finishError != nil {
+		return p.finishError
+	}
+	p.finished = response.Content
+	return nil
+}
+
+func (p *streamingProcessor) Abort(_ context.Context, err error) {
+	p.aborted = err
+}
+
+// mockExecuteModelStream streams deltas, then returns err or the assembled response
+func mockExecuteModelStream(t *testing.T, deltas []string, err error) {
+	t.Helper()
+	oldExecuteModelStream := executeModelStream
+	executeModelStream = func(_ context.Context, model, _ string, _ map[string]string, _ string, handler models.StreamHandler) (*models.ModelResponse, error) {
+		content := ""
+		for _, delta := range deltas {
+			handler.Delta(delta)
+			content += delta
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &models.ModelResponse{Content: content, Model: model}, nil
+	}
+	t.Cleanup(func() { executeModelStream = oldExecuteModelStream })
+}
+
+func TestStreamingTask(t *testing.T) {
+	proc := &streamingProcessor{}
+	store, calls := setupRetryTest(t, proc)
+	mockExecuteModelStream(t, []string{"Weekly", " report"}, nil)
+
+	service := &Service{entries: make(map[string]EntryMetadata)}
+	task := Task{Model: "openai", Prompt: "test", Processor: "console", Stream: true}
+	require.NoError(t, service.RunTask(task))
+
+	assert.Equal(t, int32(0), calls.Load(), "the model is called through the stream")
+	assert.Equal(t, "Weekly report", proc.finished)
+	assert.Empty(t, proc.processed)
+	require.NotEmpty(t, proc.updates)
+	assert.Equal(t, "Weekly report", proc.updates[len(proc.updates)-1])
+
+	records, err := store.List(history.Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Weekly report", records[0].Response)
+
+	// A stream that can't be finished falls back to delivering the response
+	proc = &streamingProcessor{finishError: fmt.Errorf("message not found")}
+	setupRetryTest(t, proc)
+	require.NoError(t, service.RunTask(task))
+	assert.Equal(t, "Weekly report", proc.processed)
+	assert.Error(t, proc.aborted)
+
+	// Streams of a failed model call are aborted
+	proc = &streamingProcessor{}
+	setupRetryTest(t, proc)
+	mockExecuteModelStream(t, []string{"Week"}, fmt.Errorf("connection reset"))
+	require.Error(t, service.RunTask(task))
+	assert.ErrorContains(t, proc.aborted, "connection reset")
+	assert.Empty(t, proc.finished)
+	assert.Empty(t, proc.processed)
+
+	// Tasks that don't stream call the model as before
+	proc = &streamingProcessor{}
+	_, calls = setupRetryTest(t, proc)
+	task.Stream = false
+	require.NoError(t, service.RunTask(task))
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, "response 1", proc.processed)
+	assert.Empty(t, proc.updates)
+}
+
+func TestParseStreamOption(t *testing.T) {
+	task, err := parseLine("0 9 * * 1 openai report console stream=true,team=ops")
+	require.NoError(t, err)
+	assert.True(t, task.Task.Stream)
+	assert.Equal(t, map[string]string{"team": "ops"}, task.Task.Variables)
+
+	_, err = parseLine("0 9 * * 1 openai report console stream=maybe")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid stream 'maybe'")
+
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+	tasks, err := parseConfigFile(writeYAMLConfig(t, `defaults:
+  model: openai
+  processors: [console]
+  stream: true
+tasks:
+  - name: inherits
+    schedule: "0 9 * * 1"
+    prompt: test_prompt
+  - name: batch
+    schedule: "0 10 * * 1"
+    prompt: test_prompt
+    stream: false
+`))
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.True(t, tasks[0].Stream)
+	assert.False(t, tasks[1].Stream)
+}
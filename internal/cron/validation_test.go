@@ -144,7 +144,7 @@ func TestValidateTask(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			err := validateTask(tc.task, 1)
+			err := validateTask(tc.task, location{line: 1})
 
 			if tc.expectError && err == nil {
 				t.Errorf("Expected error for %s but got nil", tc.name)
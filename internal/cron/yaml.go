@@ -0,0 +1,324 @@
+package cron
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/logger"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// parseYAMLConfigFile parses a structured YAML configuration file and returns
+// its tasks. Errors refer to the line each task is defined on.
+func parseYAMLConfigFile(configPath string) ([]Task, error) {
+	log.Info("Parsing YAML configuration file", logger.Fields{"path": configPath})
+
+	file, err := config.LoadFile(configPath)
+	if err != nil {
+		log.Error("Failed to load config file", logger.Fields{"path": configPath, "error": err.Error()})
+		return nil, errors.Wrap(errors.CategoryConfiguration, err, "failed to load config file")
+	}
+
+	var parseErrors *multierror.Error
+	tasks := make([]Task, 0, len(file.Tasks))
+	taskLines := make([]location, 0, len(file.Tasks))
+	for _, taskConfig := range file.Tasks {
+		task, err := taskFromConfig(file, taskConfig)
+		if err != nil {
+			parseErrors = multierror.Append(parseErrors, fmt.Errorf("line %d: %v", taskConfig.Line, err))
+			continue
+		}
+		tasks = append(tasks, task)
+		taskLines = append(taskLines, location{line: taskConfig.Line})
+	}
+
+	providers, providerErr := resolveProviders(file.Providers, tasks)
+	if providerErr != nil {
+		parseErrors = multierror.Append(parseErrors, providerErr)
+	}
+
+	// The active profile overrides tasks before they are validated
+	profiles := profilesFromConfig(file)
+	if profileErr := validateProfiles(profiles, providers); profileErr != nil {
+		parseErrors = multierror.Append(parseErrors, profileErr)
+	} else if profileErr := applyProfile(activeProfile(), profiles, tasks); profileErr != nil {
+		parseErrors = multierror.Append(parseErrors, profileErr)
+	}
+
+	names := make(map[string]location) // task name -> where it was defined
+	for i, task := range tasks {
+		if validateErr := validateNamedTask(task, taskLines[i], names); validateErr != nil {
+			parseErrors = multierror.Append(parseErrors, validateErr)
+		}
+	}
+
+	if calendarErr := resolveCalendars(filepath.Dir(configPath), file.Calendars, file.Freezes, tasks, taskLines); calendarErr != nil {
+		parseErrors = multierror.Append(parseErrors, calendarErr)
+	}
+	if checkErr := resolveChecks(filepath.Dir(configPath), file.Checks, tasks, taskLines); checkErr != nil {
+		parseErrors = multierror.Append(parseErrors, checkErr)
+	}
+	if budgetErr := resolveBudgets(file.Budgets, providers, tasks); budgetErr != nil {
+		parseErrors = multierror.Append(parseErrors, budgetErr)
+	}
+
+	// Validate dependencies between tasks
+	if depErr := validateDependencies(tasks); depErr != nil {
+		parseErrors = multierror.Append(parseErrors, depErr)
+	}
+
+	if parseErrors.ErrorOrNil() != nil {
+		log.Error("Configuration file contains errors", logger.Fields{
+			"path":        configPath,
+			"error_count": parseErrors.Len(),
+		})
+		return tasks, errors.Wrap(errors.CategoryConfiguration, parseErrors.ErrorOrNil(),
+			fmt.Sprintf("configuration file contains %d validation errors", parseErrors.Len()))
+	}
+
+	log.Info("Successfully parsed configuration file", logger.Fields{"path": configPath, "task_count": len(tasks)})
+	return tasks, nil
+}
+
+// taskFromConfig builds a task from its YAML definition, applying the file's
+// defaults and resolving model profiles
+func taskFromConfig(file *config.File, taskConfig config.TaskConfig) (Task, error) {
+	defaults := file.Defaults
+	task := Task{
+		Name:     taskConfig.Name,
+		Schedule: taskConfig.Schedule,
+		Prompt:   taskConfig.Prompt,
+		After:    taskConfig.After,
+		Template: firstNonEmpty(taskConfig.Template, defaults.Template),
+
+		Group:        taskConfig.Group,
+		SkipCalendar: taskConfig.SkipCalendar,
+		OnlyCalendar: taskConfig.OnlyCalendar,
+		When:         taskConfig.When,
+		Tags:         taskConfig.Tags,
+	}
+
+	if taskConfig.Prompt == "" {
+		return Task{}, fmt.Errorf("prompt is required")
+	}
+	if task.Name != "" {
+		if err := validateTaskName(task.Name); err != nil {
+			return Task{}, err
+		}
+	}
+	for _, tag := range task.Tags {
+		if err := validateTaskName(tag); err != nil {
+			return Task{}, fmt.Errorf("invalid tags: %w", err)
+		}
+	}
+
+	// A task without a schedule only runs after its upstream task
+	if task.Schedule == "" {
+		if task.After == "" {
+			return Task{}, fmt.Errorf("either schedule or after is required")
+		}
+		task.Schedule = ScheduleAfter
+	}
+	if timezone := firstNonEmpty(taskConfig.Timezone, defaults.Timezone); timezone != "" && task.Schedule != ScheduleAfter {
+		if prefix, _ := SplitTimezone(task.Schedule); prefix != "" {
+			// An explicit CRON_TZ= prefix wins over the default timezone
+			if taskConfig.Timezone != "" {
+				return Task{}, fmt.Errorf("timezone conflicts with the schedule's CRON_TZ=%s prefix", prefix)
+			}
+		} else {
+			if _, err := time.LoadLocation(timezone); err != nil {
+				return Task{}, fmt.Errorf("invalid timezone '%s': %w", timezone, err)
+			}
+			task.Schedule = withTimezone(timezone, task.Schedule)
+		}
+	}
+	if taskConfig.On != "" {
+		if task.After == "" {
+			return Task{}, fmt.Errorf("on requires after")
+		}
+		on, err := ParseTriggerOn(taskConfig.On)
+		if err != nil {
+			return Task{}, err
+		}
+		task.On = on
+	}
+
+	// Resolve the model, a profile supplies the provider and preset parameters
+	task.Model = firstNonEmpty(taskConfig.Model, defaults.Model)
+	if task.Model == "" {
+		return Task{}, fmt.Errorf("model is required (set it on the task or in defaults)")
+	}
+	params := mergeMaps(nil, defaults.ModelParams)
+	if profile, ok := file.Models[task.Model]; ok {
+		task.Model = profile.Provider
+		params = mergeMaps(params, profile.Params)
+	}
+	params = mergeMaps(params, taskConfig.ModelParams)
+	modelParams, err := config.FormatModelParams(params)
+	if err != nil {
+		return Task{}, err
+	}
+	task.ModelParams = modelParams
+
+	task.Processors = taskConfig.Processors
+	if len(task.Processors) == 0 {
+		task.Processors = defaults.Processors
+	}
+	if len(task.Processors) == 0 {
+		return Task{}, fmt.Errorf("at least one processor is required (set it on the task or in defaults)")
+	}
+	task.Processor = task.Processors[0].Name
+
+	if variables := mergeMaps(mergeMaps(nil, defaults.Variables), taskConfig.Variables); len(variables) > 0 {
+		if err := validateVariables(variables); err != nil {
+			return Task{}, err
+		}
+		task.Variables = variables
+	}
+
+	if overlap := firstNonEmpty(taskConfig.Overlap, defaults.Overlap); overlap != "" {
+		if task.Overlap, err = ParseOverlap(overlap); err != nil {
+			return Task{}, err
+		}
+	}
+	if catchup := firstNonEmpty(taskConfig.Catchup, defaults.Catchup); catchup != "" {
+		if task.Catchup, err = ParseCatchup(catchup); err != nil {
+			return Task{}, err
+		}
+	}
+	if timeout := firstNonEmpty(taskConfig.Timeout, defaults.Timeout); timeout != "" {
+		if task.Timeout, err = ParseTimeout(timeout); err != nil {
+			return Task{}, err
+		}
+	}
+	retryConfig := taskConfig.Retry
+	if retryConfig == nil {
+		retryConfig = defaults.Retry
+	}
+	if task.Retry, err = RetryFromConfig(retryConfig); err != nil {
+		return Task{}, err
+	}
+	task.Stream = defaults.Stream
+	if taskConfig.Stream != nil {
+		task.Stream = *taskConfig.Stream
+	}
+
+	return task, nil
+}
+
+// profilesFromConfig returns the profile entries of a YAML file with their
+// model profiles resolved, like the models of tasks
+func profilesFromConfig(file *config.File) []config.ProfileConfig {
+	profiles := make([]config.ProfileConfig, len(file.Profiles))
+	for i, profileConfig := range file.Profiles {
+		if modelProfile, ok := file.Models[profileConfig.Model]; ok {
+			profileConfig.Model = modelProfile.Provider
+			profileConfig.ModelParams = mergeMaps(mergeMaps(nil, modelProfile.Params), profileConfig.ModelParams)
+		}
+		profiles[i] = profileConfig
+	}
+	return profiles
+}
+
+// TaskConfig returns the YAML definition of a task
+func (t Task) TaskConfig() (config.TaskConfig, error) {
+	params, err := config.ParseModelParams(t.ModelParams)
+	if err != nil {
+		return config.TaskConfig{}, err
+	}
+
+	taskConfig := config.TaskConfig{
+		Name:       t.Name,
+		After:      t.After,
+		On:         string(t.On),
+		Model:      t.Model,
+		Prompt:     t.Prompt,
+		Processors: t.Processors,
+		Template:   t.Template,
+		Overlap:    string(t.Overlap),
+		Variables:  t.Variables,
+
+		Group:        t.Group,
+		SkipCalendar: t.SkipCalendar,
+		OnlyCalendar: t.OnlyCalendar,
+		When:         t.When,
+		Tags:         t.Tags,
+	}
+	taskConfig.Timezone, taskConfig.Schedule = SplitTimezone(t.Schedule)
+	if taskConfig.Schedule == ScheduleAfter {
+		taskConfig.Schedule = ""
+	}
+	if len(params) > 0 {
+		taskConfig.ModelParams = params
+	}
+	if len(taskConfig.Processors) == 0 {
+		taskConfig.Processors = []config.ProcessorConfig{{Name: t.Processor}}
+	}
+	if t.Catchup.Mode != "" {
+		taskConfig.Catchup = t.Catchup.String()
+	}
+	if t.Timeout > 0 {
+		taskConfig.Timeout = t.Timeout.String()
+	}
+	taskConfig.Retry = t.Retry.RetryConfig()
+	if t.Stream {
+		stream := true
+		taskConfig.Stream = &stream
+	}
+
+	// The line format keeps the template in the variables as well
+	if taskConfig.Template != "" && taskConfig.Variables["template"] == taskConfig.Template {
+		variables := mergeMaps(nil, taskConfig.Variables)
+		delete(variables, "template")
+		taskConfig.Variables = nil
+		if len(variables) > 0 {
+			taskConfig.Variables = variables
+		}
+	}
+
+	return taskConfig, nil
+}
+
+// ParseConfigLine parses a single line of the line-based configuration format.
+// Dynamic variables such as {{CURRENT_DATE}} are kept as written. It returns
+// nil for blank lines, comments and queue definitions.
+func ParseConfigLine(line string) (*Task, error) {
+	return ParseConfigLineWithProviders(line, nil)
+}
+
+// ParseConfigLineWithProviders parses a line like ParseConfigLine, also
+// accepting the named providers as models
+func ParseConfigLineWithProviders(line string, providers map[string]bool) (*Task, error) {
+	scheduled, err := parseTaskLine(line, providers)
+	if err != nil || scheduled == nil {
+		return nil, err
+	}
+	return &scheduled.Task, nil
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// mergeMaps copies src into dst, allocating dst if needed, and returns dst
+func mergeMaps(dst, src map[string]string) map[string]string {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]string, len(src))
+	}
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
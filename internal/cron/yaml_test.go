@@ -0,0 +1,227 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/rshade/cronai/internal/history"
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/processor"
+	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testYAMLConfig = `version: 1
+defaults:
+  model: openai
+  processors: [console]
+  variables:
+    team: platform
+models:
+  creative:
+    provider: claude
+    params:
+      temperature: 0.9
+tasks:
+  - name: daily_pm
+    schedule: "0 8 * * *"
+    prompt: test_prompt
+    overlap: skip
+    variables:
+      team: Product Management
+  - after: daily_pm
+    on: failure
+    model: creative
+    model_params:
+      max_tokens: 500
+    prompt: test_prompt
+    processors:
+      - slack-oncall
+      - name: file-/tmp/alerts.log
+        options:
+          format: markdown
+`
+
+func writeYAMLConfig(t *testing.T, content string) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "cronai.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(content), 0644))
+	return configPath
+}
+
+func TestParseYAMLConfigFile(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	tasks, err := parseConfigFile(writeYAMLConfig(t, testYAMLConfig))
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+
+	pm := tasks[0]
+	assert.Equal(t, "daily_pm", pm.Name)
+	assert.Equal(t, "0 8 * * *", pm.Schedule)
+	assert.Equal(t, "openai", pm.Model)
+	assert.Equal(t, "console", pm.Processor)
+	assert.Equal(t, OverlapSkip, pm.Overlap)
+	assert.Equal(t, map[string]string{"team": "Product Management"}, pm.Variables)
+
+	alert := tasks[1]
+	assert.Equal(t, ScheduleAfter, alert.Schedule)
+	assert.Equal(t, TriggerOnFailure, alert.On)
+	assert.Equal(t, "claude", alert.Model)
+	assert.Equal(t, "max_tokens=500,temperature=0.9", alert.ModelParams)
+	assert.Equal(t, "slack-oncall", alert.Processor)
+	assert.Equal(t, []config.ProcessorConfig{
+		{Name: "slack-oncall"},
+		{Name: "file-/tmp/alerts.log", Options: map[string]string{"format": "markdown"}},
+	}, alert.Processors)
+	assert.Equal(t, map[string]string{"team": "platform"}, alert.Variables)
+}
+
+func TestParseYAMLConfigFileErrors(t *testing.T) {
+	require.NoError(t, setupTestPromptFile(t))
+	defer cleanupTestPromptFile(t)
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "missing schedule",
+			content: "tasks:\n  - prompt: test_prompt\n    model: openai\n    processors: [console]\n",
+			wantErr: "line 2: either schedule or after is required",
+		},
+		{
+			name:    "missing model",
+			content: "tasks:\n  - schedule: '@daily'\n    prompt: test_prompt\n    processors: [console]\n",
+			wantErr: "line 2: model is required",
+		},
+		{
+			name: "invalid processor",
+			content: "defaults:\n  model: openai\ntasks:\n  - schedule: '@daily'\n    prompt: test_prompt\n" +
+				"    processors: [console, carrier-pigeon]\n",
+			wantErr: "line 4: invalid processor 'carrier-pigeon'",
+		},
+		{
+			name: "duplicate name",
+			content: "defaults:\n  model: openai\n  processors: [console]\ntasks:\n" +
+				"  - {name: a, schedule: '@daily', prompt: test_prompt}\n" +
+				"  - {name: a, schedule: '@hourly', prompt: test_prompt}\n",
+			wantErr: "line 6: duplicate task name 'a' (first defined on line 5)",
+		},
+		{
+			name:    "unknown field",
+			content: "tasks:\n  - prompt: test_prompt\n    proccessors: [console]\n",
+			wantErr: "proccessors",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseConfigFile(writeYAMLConfig(t, tt.content))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestTaskConfigRoundTrip(t *testing.T) {
+	lines := []string{
+		"0 8 * * * openai:temperature=0.5 product_manager slack-product name=daily_pm,overlap=skip,catchup=all:3,timeout=5m,date={{CURRENT_DATE}}",
+		"@after claude summary console after=daily_pm,on=failure,template=alert,when=down+errors,team=ops",
+	}
+
+	for _, line := range lines {
+		task, err := ParseConfigLine(line)
+		require.NoError(t, err)
+
+		taskConfig, err := task.TaskConfig()
+		require.NoError(t, err)
+		converted, err := taskFromConfig(&config.File{}, taskConfig)
+		require.NoError(t, err)
+
+		// The line format keeps the template among the variables
+		delete(task.Variables, "template")
+		assert.Equal(t, task.Name, converted.Name)
+		assert.Equal(t, task.Schedule, converted.Schedule)
+		assert.Equal(t, task.Model, converted.Model)
+		assert.Equal(t, task.ModelParams, converted.ModelParams)
+		assert.Equal(t, task.Processor, converted.Processor)
+		assert.Equal(t, task.Template, converted.Template)
+		assert.Equal(t, task.Overlap, converted.Overlap)
+		assert.Equal(t, task.Catchup, converted.Catchup)
+		assert.Equal(t, task.Timeout, converted.Timeout)
+		assert.Equal(t, task.After, converted.After)
+		assert.Equal(t, task.On, converted.On)
+		assert.Equal(t, task.When, converted.When)
+		assert.Equal(t, len(task.Variables), len(converted.Variables))
+	}
+
+	// Special variables are converted as written, not expanded
+	task, err := ParseConfigLine(lines[0])
+	require.NoError(t, err)
+	assert.Equal(t, "{{CURRENT_DATE}}", task.Variables["date"])
+}
+
+func TestRunTaskMultipleProcessors(t *testing.T) {
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+
+	mockPM := NewMockPromptManager()
+	mockPM.SetPrompt("report", "Write the report")
+	oldManager := prompt.PM
+	prompt.PM = mockPM
+	defer func() { prompt.PM = oldManager }()
+
+	var mu sync.Mutex
+	delivered := make(map[string]processor.Config)
+	registry := processor.GetRegistry()
+	registry.RegisterFactory("slack", func(cfg processor.Config) (processor.Processor, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered[cfg.Target] = cfg
+		if cfg.Target == "broken" {
+			return &mockProcessor{config: cfg, processError: fmt.Errorf("channel archived")}, nil
+		}
+		return &mockProcessor{config: cfg}, nil
+	})
+	defer registry.RegisterFactory("slack", processor.NewSlackProcessor)
+
+	oldExecuteModel := executeModel
+	executeModel = func(_ context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		return &models.ModelResponse{Content: "report", Model: model}, nil
+	}
+	defer func() { executeModel = oldExecuteModel }()
+
+	task := Task{
+		Schedule: "@daily",
+		Model:    "openai",
+		Prompt:   "report",
+		Template: "summary",
+		Processors: []config.ProcessorConfig{
+			{Name: "slack-broken"},
+			{Name: "slack-ops", Template: "detailed", Options: map[string]string{"thread": "true"}},
+		},
+	}
+
+	service := NewCronService("test.config")
+	record, err := service.runTask(context.Background(), task, history.SourceRun, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "channel archived")
+	assert.Equal(t, history.StatusFailed, record.Status)
+
+	// A failing processor doesn't stop delivery to the others
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, delivered, "ops")
+	assert.Equal(t, "detailed", delivered["ops"].TemplateName)
+	assert.Equal(t, "true", delivered["ops"].Options["thread"])
+	assert.Equal(t, "summary", delivered["broken"].TemplateName)
+}
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Dimensions cost reports group executions by
+const (
+	GroupTask   = "task"
+	GroupPrompt = "prompt"
+	GroupModel  = "model"
+)
+
+// CostSummary totals the model usage of a group of executions. Only the
+// fields of the dimensions grouped by are set.
+type CostSummary struct {
+	Task             string
+	Prompt           string
+	Model            string
+	Runs             int // Executions that called a model
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int
+	Cost             float64
+}
+
+// ParseGroupBy parses a comma-separated list of cost report dimensions
+func ParseGroupBy(value string) ([]string, error) {
+	var groupBy []string
+	for _, dimension := range strings.Split(value, ",") {
+		dimension = strings.ToLower(strings.TrimSpace(dimension))
+		switch dimension {
+		case GroupTask, GroupPrompt, GroupModel:
+			groupBy = append(groupBy, dimension)
+		default:
+			return nil, fmt.Errorf("invalid group '%s' (supported: task, prompt, model)", dimension)
+		}
+	}
+	return groupBy, nil
+}
+
+// SummarizeCost totals the usage of the executions that called a model,
+// grouped by the given dimensions. Summaries are ordered by cost, highest
+// first. Executions are grouped by the model version that answered, which
+// determines the price.
+func SummarizeCost(records []*Record, groupBy []string) []CostSummary {
+	index := make(map[CostSummary]int)
+	var summaries []CostSummary
+	for _, r := range records {
+		if r.ModelUsed == "" && r.ModelVersion == "" {
+			// The model wasn't called
+			continue
+		}
+
+		var key CostSummary
+		for _, dimension := range groupBy {
+			switch dimension {
+			case GroupTask:
+				key.Task = r.Task
+			case GroupPrompt:
+				key.Prompt = r.Prompt
+			case GroupModel:
+				key.Model = recordModel(r)
+			}
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, key)
+		}
+
+		summary := &summaries[i]
+		summary.Runs++
+		summary.PromptTokens += r.PromptTokens
+		summary.CompletionTokens += r.CompletionTokens
+		summary.CachedTokens += r.CachedTokens
+		summary.Cost += r.Cost
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Cost > summaries[j].Cost
+	})
+	return summaries
+}
+
+// recordModel returns the most specific name of the model that answered
+func recordModel(r *Record) string {
+	if r.ModelVersion != "" {
+		return r.ModelVersion
+	}
+	if r.ModelUsed != "" {
+		return r.ModelUsed
+	}
+	return r.Model
+}
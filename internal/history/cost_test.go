@@ -0,0 +1,48 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeCost(t *testing.T) {
+	records := []*Record{
+		{Task: "weekly", Prompt: "report", ModelUsed: "openai", ModelVersion: "gpt-4o", PromptTokens: 100, CompletionTokens: 20, Cost: 0.5},
+		{Task: "weekly", Prompt: "report", ModelUsed: "openai", ModelVersion: "gpt-4o", PromptTokens: 300, CachedTokens: 200, CompletionTokens: 40, Cost: 1.0},
+		{Task: "daily", Prompt: "report", ModelUsed: "claude", ModelVersion: "claude-3-5-sonnet", PromptTokens: 50, CompletionTokens: 10, Cost: 2.0},
+		// Skipped runs didn't call a model
+		{Task: "daily", Prompt: "report", Model: "claude", Status: StatusSkipped},
+	}
+
+	summaries := SummarizeCost(records, []string{GroupTask, GroupModel})
+	require.Len(t, summaries, 2)
+	assert.Equal(t, CostSummary{Task: "daily", Model: "claude-3-5-sonnet", Runs: 1, PromptTokens: 50, CompletionTokens: 10, Cost: 2.0}, summaries[0])
+	assert.Equal(t, CostSummary{Task: "weekly", Model: "gpt-4o", Runs: 2, PromptTokens: 400, CachedTokens: 200, CompletionTokens: 60, Cost: 1.5}, summaries[1])
+
+	summaries = SummarizeCost(records, []string{GroupPrompt})
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "report", summaries[0].Prompt)
+	assert.Empty(t, summaries[0].Task)
+	assert.Equal(t, 3, summaries[0].Runs)
+	assert.InDelta(t, 3.5, summaries[0].Cost, 1e-9)
+}
+
+func TestParseGroupBy(t *testing.T) {
+	groupBy, err := ParseGroupBy("Task, model")
+	require.NoError(t, err)
+	assert.Equal(t, []string{GroupTask, GroupModel}, groupBy)
+
+	_, err = ParseGroupBy("task,day")
+	assert.ErrorContains(t, err, "invalid group 'day'")
+}
+
+func TestRecordSetUsage(t *testing.T) {
+	record := New(SourceRun)
+	record.SetUsage(120, 30, 100, 0.25)
+	assert.Equal(t, 120, record.PromptTokens)
+	assert.Equal(t, 30, record.CompletionTokens)
+	assert.Equal(t, 100, record.CachedTokens)
+	assert.Equal(t, 0.25, record.Cost)
+}
@@ -0,0 +1,335 @@
+// Package history records task executions so past runs can be listed and inspected.
+package history
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// Environment variables controlling the default store
+const (
+	// EnvHistoryStore selects the store backend: file (default), memory or none
+	EnvHistoryStore = "CRONAI_HISTORY_STORE"
+	// EnvHistoryPath sets the location of the file store
+	EnvHistoryPath = "CRONAI_HISTORY_PATH"
+
+	// EnvHistoryMaxRecords caps the records the file store keeps, 0 keeps all
+	EnvHistoryMaxRecords = "CRONAI_HISTORY_MAX_RECORDS"
+	// EnvHistoryMaxAge drops records older than a duration such as 720h or 30d
+	EnvHistoryMaxAge = "CRONAI_HISTORY_MAX_AGE"
+
+	// DefaultHistoryPath is the default location of the file store
+	DefaultHistoryPath = ".cronai/history.jsonl"
+	// DefaultHistoryMaxRecords is the number of records the file store keeps by default
+	DefaultHistoryMaxRecords = 10000
+)
+
+// Default logger for the history package
+var log = logger.DefaultLogger()
+
+// Source identifies what triggered an execution
+const (
+	SourceCron  = "cron"
+	SourceQueue = "queue"
+	SourceRun   = "run"
+	// SourceCatchup is a cron run missed during downtime and executed on startup
+	SourceCatchup = "catchup"
+	// SourceDependency is a task triggered by the completion of its upstream task
+	SourceDependency = "dependency"
+	// SourceTrigger is a task triggered on a running service through its control socket
+	SourceTrigger = "trigger"
+)
+
+// Status is the overall outcome of an execution
+type Status string
+
+// Execution statuses
+const (
+	StatusSuccess Status = "success"
+	StatusFailed  Status = "failed"
+	StatusSkipped Status = "skipped"
+	// StatusInterrupted is an execution cancelled because the service stopped
+	StatusInterrupted Status = "interrupted"
+)
+
+// Stages of an execution, used to report where a failure happened
+const (
+	StageCheck     = "check"
+	StagePrompt    = "prompt"
+	StageModel     = "model"
+	StageProcessor = "processor"
+)
+
+// Processor outcomes
+const (
+	OutcomeDelivered = "delivered"
+	OutcomeFailed    = "failed"
+	OutcomeNotRun    = "not_run"
+)
+
+// Record describes a single task execution
+type Record struct {
+	ID               string            `json:"id"`
+	Source           string            `json:"source"`
+	Task             string            `json:"task,omitempty"`
+	Schedule         string            `json:"schedule,omitempty"`
+	Prompt           string            `json:"prompt"`
+	PromptHash       string            `json:"prompt_hash,omitempty"`
+	Model            string            `json:"model"`                   // Model requested by the task
+	ModelUsed        string            `json:"model_used,omitempty"`    // Model that answered after fallback
+	ModelVersion     string            `json:"model_version,omitempty"` // Provider-reported model name
+	Processor        string            `json:"processor"`
+	Variables        map[string]string `json:"variables,omitempty"`
+	StartedAt        time.Time         `json:"started_at"`
+	Duration         time.Duration     `json:"duration"`
+	Status           Status            `json:"status"`
+	Stage            string            `json:"stage,omitempty"` // Stage that failed, if any
+	ProcessorOutcome string            `json:"processor_outcome"`
+	DeliveryRetries  int               `json:"delivery_retries,omitempty"`  // Deliveries retried after a processor failed
+	PromptTokens     int               `json:"prompt_tokens,omitempty"`     // Tokens of the prompt, including cached ones
+	CompletionTokens int               `json:"completion_tokens,omitempty"` // Tokens of the response
+	CachedTokens     int               `json:"cached_tokens,omitempty"`     // Prompt tokens read from the provider's cache
+	Cost             float64           `json:"cost,omitempty"`              // Estimated cost of the model call in USD
+	Response         string            `json:"response,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	Reason           string            `json:"reason,omitempty"` // Why the execution was skipped or interrupted
+	ErrorCategory    string            `json:"error_category,omitempty"`
+}
+
+// New starts a record for an execution triggered by source
+func New(source string) *Record {
+	return &Record{
+		ID:               NewID(),
+		Source:           source,
+		StartedAt:        time.Now(),
+		ProcessorOutcome: OutcomeNotRun,
+	}
+}
+
+// SetPrompt records the hash of the rendered prompt
+func (r *Record) SetPrompt(content string) {
+	sum := sha256.Sum256([]byte(content))
+	r.PromptHash = hex.EncodeToString(sum[:])
+}
+
+// SetUsage records the tokens the model call used and its estimated cost
+func (r *Record) SetUsage(promptTokens, completionTokens, cachedTokens int, cost float64) {
+	r.PromptTokens = promptTokens
+	r.CompletionTokens = completionTokens
+	r.CachedTokens = cachedTokens
+	r.Cost = cost
+}
+
+// Fail marks the record as failed at the given stage
+func (r *Record) Fail(stage string, err error) {
+	r.Status = StatusFailed
+	r.Stage = stage
+	if err != nil {
+		r.Error = err.Error()
+		r.ErrorCategory = errors.GetCategory(err).String()
+	}
+	if stage == StageProcessor {
+		r.ProcessorOutcome = OutcomeFailed
+	}
+}
+
+// Skip marks the record as an execution that was not started
+func (r *Record) Skip(reason string) {
+	r.Status = StatusSkipped
+	r.Reason = reason
+}
+
+// Interrupt marks a record that failed because the service stopped while it
+// was running. The stage and error of the failure are kept.
+func (r *Record) Interrupt(reason string) {
+	r.Status = StatusInterrupted
+	r.Reason = reason
+}
+
+// Finish completes the record, marking it successful unless it already failed
+func (r *Record) Finish() {
+	r.Duration = time.Since(r.StartedAt)
+	if r.Status == "" {
+		r.Status = StatusSuccess
+		r.ProcessorOutcome = OutcomeDelivered
+	}
+}
+
+// NewID returns a unique, time-sortable execution identifier
+func NewID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		// crypto/rand never fails on supported platforms; fall back to the clock
+		return time.Now().UTC().Format("20060102T150405.000000000")
+	}
+	return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405"), hex.EncodeToString(b))
+}
+
+// Filter selects records when listing history
+type Filter struct {
+	Task   string
+	Prompt string
+	Source string
+	Status Status
+	Model  string
+	Since  time.Time
+	Until  time.Time
+	Limit  int // Maximum number of records, newest first; 0 means no limit
+}
+
+// Matches reports whether a record satisfies the filter
+func (f Filter) Matches(r *Record) bool {
+	if f.Task != "" && r.Task != f.Task {
+		return false
+	}
+	if f.Prompt != "" && r.Prompt != f.Prompt {
+		return false
+	}
+	if f.Source != "" && r.Source != f.Source {
+		return false
+	}
+	if f.Status != "" && r.Status != f.Status {
+		return false
+	}
+	if f.Model != "" && r.Model != f.Model && r.ModelUsed != f.Model {
+		return false
+	}
+	if !f.Since.IsZero() && r.StartedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !r.StartedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Store persists execution records
+type Store interface {
+	// Save appends a record to the store
+	Save(record *Record) error
+
+	// List returns records matching the filter, newest first
+	List(filter Filter) ([]*Record, error)
+
+	// Get returns the record with the given ID or unique ID prefix
+	Get(id string) (*Record, error)
+}
+
+var (
+	store     Store
+	storeOnce sync.Once
+	storeMu   sync.RWMutex
+)
+
+// GetStore returns the default store, creating it from the environment on first use
+func GetStore() Store {
+	storeOnce.Do(func() {
+		storeMu.Lock()
+		defer storeMu.Unlock()
+		if store == nil {
+			store = newStoreFromEnv()
+		}
+	})
+
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return store
+}
+
+// SetStore replaces the default store
+func SetStore(s Store) {
+	storeOnce.Do(func() {})
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	store = s
+}
+
+// Save records an execution in the default store
+func Save(record *Record) error {
+	return GetStore().Save(record)
+}
+
+// newStoreFromEnv creates the store selected by the environment
+func newStoreFromEnv() Store {
+	switch strings.ToLower(os.Getenv(EnvHistoryStore)) {
+	case "memory":
+		return NewMemoryStore()
+	case "none", "off":
+		return NewNopStore()
+	default:
+		path := os.Getenv(EnvHistoryPath)
+		if path == "" {
+			path = DefaultHistoryPath
+		}
+		return NewFileStore(path, retentionFromEnv()...)
+	}
+}
+
+// retentionFromEnv returns the retention limits of the file store set by
+// the environment, ignoring invalid values
+func retentionFromEnv() []FileStoreOption {
+	opts := []FileStoreOption{WithMaxRecords(DefaultHistoryMaxRecords)}
+	if value := os.Getenv(EnvHistoryMaxRecords); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			log.Warn("Invalid history record limit, keeping the default", logger.Fields{"value": value})
+		} else {
+			opts = append(opts, WithMaxRecords(n))
+		}
+	}
+	if value := os.Getenv(EnvHistoryMaxAge); value != "" {
+		age, err := parseAge(value)
+		if err != nil {
+			log.Warn("Invalid history age limit, keeping records of any age", logger.Fields{"value": value, "error": err.Error()})
+		} else {
+			opts = append(opts, WithMaxAge(age))
+		}
+	}
+	return opts
+}
+
+// parseAge parses a duration such as 720h, or a number of days such as 30d
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid number of days '%s'", value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid duration '%s' (use a duration such as 720h or days such as 30d)", value)
+	}
+	return age, nil
+}
+
+// findByID returns the record matching id exactly or by unique prefix
+func findByID(records []*Record, id string) (*Record, error) {
+	var match *Record
+	for _, r := range records {
+		if r.ID == id {
+			return r, nil
+		}
+		if strings.HasPrefix(r.ID, id) {
+			if match != nil {
+				return nil, fmt.Errorf("execution id '%s' is ambiguous", id)
+			}
+			match = r
+		}
+	}
+	if match == nil {
+		return nil, errors.Wrap(errors.CategoryValidation, errors.ErrNotFound, fmt.Sprintf("execution '%s'", id))
+	}
+	return match, nil
+}
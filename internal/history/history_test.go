@@ -0,0 +1,284 @@
+package history
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLifecycle(t *testing.T) {
+	record := New(SourceCron)
+	assert.NotEmpty(t, record.ID)
+	assert.Equal(t, OutcomeNotRun, record.ProcessorOutcome)
+
+	record.SetPrompt("hello")
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", record.PromptHash)
+
+	record.Finish()
+	assert.Equal(t, StatusSuccess, record.Status)
+	assert.Equal(t, OutcomeDelivered, record.ProcessorOutcome)
+}
+
+func TestRecordFail(t *testing.T) {
+	record := New(SourceQueue)
+	record.Fail(StageProcessor, errors.Wrap(errors.CategoryExternal, fmt.Errorf("502 bad gateway"), "error processing response"))
+	record.Finish()
+
+	assert.Equal(t, StatusFailed, record.Status)
+	assert.Equal(t, StageProcessor, record.Stage)
+	assert.Equal(t, OutcomeFailed, record.ProcessorOutcome)
+	assert.Equal(t, "EXTERNAL", record.ErrorCategory)
+	assert.Contains(t, record.Error, "502 bad gateway")
+
+	modelFailure := New(SourceRun)
+	modelFailure.Fail(StageModel, fmt.Errorf("timeout"))
+	modelFailure.Finish()
+	assert.Equal(t, OutcomeNotRun, modelFailure.ProcessorOutcome)
+	assert.Equal(t, "UNKNOWN", modelFailure.ErrorCategory)
+}
+
+func TestRecordInterrupt(t *testing.T) {
+	record := New(SourceCron)
+	record.Fail(StageModel, fmt.Errorf("context canceled"))
+	record.Interrupt("service shutting down")
+	record.Finish()
+
+	assert.Equal(t, StatusInterrupted, record.Status)
+	assert.Equal(t, StageModel, record.Stage)
+	assert.Equal(t, "service shutting down", record.Reason)
+	assert.Contains(t, record.Error, "context canceled")
+}
+
+func TestNewIDUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id := NewID()
+		assert.False(t, seen[id], "duplicate id %s", id)
+		seen[id] = true
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	base := time.Date(2026, 10, 13, 8, 0, 0, 0, time.UTC)
+	record := &Record{
+		Task:      "daily_pm",
+		Prompt:    "product_manager",
+		Source:    SourceCron,
+		Status:    StatusSuccess,
+		Model:     "openai",
+		ModelUsed: "claude",
+		StartedAt: base,
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty filter", Filter{}, true},
+		{"task", Filter{Task: "daily_pm"}, true},
+		{"other task", Filter{Task: "other"}, false},
+		{"prompt", Filter{Prompt: "product_manager"}, true},
+		{"source", Filter{Source: SourceQueue}, false},
+		{"status", Filter{Status: StatusFailed}, false},
+		{"requested model", Filter{Model: "openai"}, true},
+		{"fallback model", Filter{Model: "claude"}, true},
+		{"unused model", Filter{Model: "gemini"}, false},
+		{"since before", Filter{Since: base.Add(-time.Hour)}, true},
+		{"since after", Filter{Since: base.Add(time.Hour)}, false},
+		{"until after", Filter{Until: base.Add(time.Hour)}, true},
+		{"until exclusive", Filter{Until: base}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(record))
+		})
+	}
+}
+
+func testStore(t *testing.T, store Store) {
+	base := time.Date(2026, 10, 13, 8, 0, 0, 0, time.UTC)
+	for i, prompt := range []string{"a", "b", "a"} {
+		record := &Record{
+			ID:        fmt.Sprintf("id-%d", i),
+			Prompt:    prompt,
+			StartedAt: base.Add(time.Duration(i) * time.Hour),
+			Response:  fmt.Sprintf("response %d", i),
+		}
+		require.NoError(t, store.Save(record))
+	}
+
+	records, err := store.List(Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "id-2", records[0].ID, "newest first")
+
+	records, err = store.List(Filter{Prompt: "a", Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "id-2", records[0].ID)
+
+	record, err := store.Get("id-1")
+	require.NoError(t, err)
+	assert.Equal(t, "response 1", record.Response)
+
+	_, err = store.Get("id-")
+	assert.Error(t, err, "ambiguous prefix")
+
+	_, err = store.Get("missing")
+	assert.True(t, errors.Is(err, errors.ErrNotFound))
+}
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "nested", "history.jsonl"))
+
+	// Listing before anything was recorded is not an error
+	records, err := store.List(Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	testStore(t, store)
+
+	// A second store on the same file sees the persisted records
+	reopened := NewFileStore(store.Path())
+	records, err = reopened.List(Filter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 3)
+}
+
+func TestFileStoreRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+	save := func(store Store, id string, started time.Time) {
+		t.Helper()
+		require.NoError(t, store.Save(&Record{ID: id, StartedAt: started}))
+	}
+
+	// Records written before limits were set
+	unlimited := NewFileStore(path)
+	save(unlimited, "expired", now.Add(-72*time.Hour))
+	for i := 0; i < 4; i++ {
+		save(unlimited, fmt.Sprintf("old-%d", i), now.Add(time.Duration(i-10)*time.Hour))
+	}
+
+	// The first record saved with limits compacts the file
+	store := NewFileStore(path, WithMaxRecords(3), WithMaxAge(48*time.Hour))
+	save(store, "new-0", now)
+	records, err := store.List(Filter{})
+	require.NoError(t, err)
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	assert.Equal(t, []string{"new-0", "old-3", "old-2"}, ids)
+
+	// Then once every compactInterval records, listings apply the limits in between
+	for i := 1; i < compactInterval; i++ {
+		save(store, fmt.Sprintf("new-%d", i), now.Add(time.Duration(i)*time.Second))
+	}
+	records, err = store.List(Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, fmt.Sprintf("new-%d", compactInterval-1), records[0].ID)
+	records, err = unlimited.List(Filter{})
+	require.NoError(t, err)
+	assert.Len(t, records, compactInterval+2)
+	save(store, "last", now.Add(time.Hour))
+	records, err = unlimited.List(Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, "last", records[0].ID)
+
+	matches, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	assert.Empty(t, matches, "no temporary files are left behind")
+}
+
+func TestFileStoreRetentionOnRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+	unlimited := NewFileStore(path)
+	require.NoError(t, unlimited.Save(&Record{ID: "expired", StartedAt: now.Add(-72 * time.Hour)}))
+	for i := 0; i < 4; i++ {
+		require.NoError(t, unlimited.Save(&Record{ID: fmt.Sprintf("run-%d", i), StartedAt: now.Add(time.Duration(i) * time.Minute)}))
+	}
+
+	// A new process with limits sees them applied before it saves anything
+	store := NewFileStore(path, WithMaxRecords(2), WithMaxAge(48*time.Hour))
+	records, err := store.List(Filter{})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "run-3", records[0].ID)
+	assert.Equal(t, "run-2", records[1].ID)
+	_, err = store.Get("expired")
+	assert.Error(t, err)
+
+	store = NewFileStore(path, WithMaxAge(48*time.Hour))
+	records, err = store.List(Filter{})
+	require.NoError(t, err)
+	assert.Len(t, records, 4)
+}
+
+func TestParseAge(t *testing.T) {
+	age, err := parseAge("30d")
+	require.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, age)
+
+	age, err = parseAge("36h")
+	require.NoError(t, err)
+	assert.Equal(t, 36*time.Hour, age)
+
+	for _, value := range []string{"d", "-1d", "month", "-5h"} {
+		_, err := parseAge(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestNopStore(t *testing.T) {
+	store := NewNopStore()
+	require.NoError(t, store.Save(New(SourceRun)))
+	records, err := store.List(Filter{})
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestNewStoreFromEnv(t *testing.T) {
+	t.Setenv(EnvHistoryStore, "memory")
+	assert.IsType(t, &MemoryStore{}, newStoreFromEnv())
+
+	t.Setenv(EnvHistoryStore, "none")
+	assert.IsType(t, &NopStore{}, newStoreFromEnv())
+
+	path := filepath.Join(t.TempDir(), "h.jsonl")
+	t.Setenv(EnvHistoryStore, "")
+	t.Setenv(EnvHistoryPath, path)
+	store, ok := newStoreFromEnv().(*FileStore)
+	require.True(t, ok)
+	assert.Equal(t, path, store.Path())
+	assert.Equal(t, DefaultHistoryMaxRecords, store.maxRecords)
+	assert.Zero(t, store.maxAge)
+
+	t.Setenv(EnvHistoryMaxRecords, "0")
+	t.Setenv(EnvHistoryMaxAge, "90d")
+	store, ok = newStoreFromEnv().(*FileStore)
+	require.True(t, ok)
+	assert.Zero(t, store.maxRecords)
+	assert.Equal(t, 90*24*time.Hour, store.maxAge)
+
+	t.Setenv(EnvHistoryMaxRecords, "many")
+	t.Setenv(EnvHistoryMaxAge, "forever")
+	store, ok = newStoreFromEnv().(*FileStore)
+	require.True(t, ok)
+	assert.Equal(t, DefaultHistoryMaxRecords, store.maxRecords)
+	assert.Zero(t, store.maxAge)
+}
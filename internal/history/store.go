@@ -0,0 +1,310 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// compactInterval is the number of records saved between compactions of
+// a file store with retention limits
+const compactInterval = 100
+
+// FileStore keeps records as JSON lines in a local file. With retention
+// limits the file is compacted when the store first saves a record and
+// every compactInterval records after that, so it doesn't grow without
+// limit and listings stay quick. Listings only return the records within
+// the limits, whether or not the file was compacted yet.
+type FileStore struct {
+	path       string
+	maxRecords int           // newest records kept, 0 keeps all
+	maxAge     time.Duration // age past which records are dropped, 0 keeps all
+	saved      int           // records saved since the last compaction
+	compacted  bool          // whether the file was compacted since the store was created
+	mu         sync.Mutex
+}
+
+// FileStoreOption is a functional option for configuring a file store
+type FileStoreOption func(*FileStore)
+
+// WithMaxRecords keeps only the newest n records. Zero or less keeps all.
+func WithMaxRecords(n int) FileStoreOption {
+	return func(s *FileStore) {
+		s.maxRecords = max(n, 0)
+	}
+}
+
+// WithMaxAge drops records that started longer than age ago. Zero or less
+// keeps records of any age.
+func WithMaxAge(age time.Duration) FileStoreOption {
+	return func(s *FileStore) {
+		s.maxAge = max(age, 0)
+	}
+}
+
+// NewFileStore creates a store backed by the file at path
+func NewFileStore(path string, opts ...FileStoreOption) *FileStore {
+	s := &FileStore{path: path}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Path returns the location of the history file
+func (s *FileStore) Path() string {
+	return s.path
+}
+
+// Save appends a record to the history file
+func (s *FileStore) Save(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode history record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history file: %w", err)
+	}
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		_ = file.Close() //nolint:errcheck // the write error is more relevant
+		return fmt.Errorf("failed to write history record: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	// The record is saved, a failed compaction is tried again later
+	s.saved++
+	if !s.compacted || s.saved >= compactInterval {
+		if err := s.compact(time.Now()); err != nil {
+			log.Warn("Failed to apply history retention", logger.Fields{"path": s.path, "error": err.Error()})
+		}
+	}
+	return nil
+}
+
+// compact rewrites the history file without the records past the retention
+// limits. The new file replaces the old one in a single rename, so readers
+// never see a partial file. The caller holds s.mu.
+func (s *FileStore) compact(now time.Time) error {
+	if s.maxRecords == 0 && s.maxAge == 0 {
+		return nil
+	}
+	s.saved, s.compacted = 0, true
+
+	records, err := s.read()
+	if err != nil {
+		return err
+	}
+	kept := s.retain(records, now)
+	if len(kept) == len(records) {
+		return nil
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to compact history file: %w", err)
+	}
+	writer := bufio.NewWriter(temp)
+	encoder := json.NewEncoder(writer)
+	for _, r := range kept {
+		if err = encoder.Encode(r); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), s.path)
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name()) //nolint:errcheck // the compaction error is more relevant
+		return fmt.Errorf("failed to compact history file: %w", err)
+	}
+	return nil
+}
+
+// retain returns the records within the retention limits at now
+func (s *FileStore) retain(records []*Record, now time.Time) []*Record {
+	kept := records
+	if s.maxAge > 0 {
+		cutoff := now.Add(-s.maxAge)
+		kept = kept[:0:0]
+		for _, r := range records {
+			if !r.StartedAt.Before(cutoff) {
+				kept = append(kept, r)
+			}
+		}
+	}
+	if s.maxRecords > 0 && len(kept) > s.maxRecords {
+		// Records are appended as they finish, keep the ones that started last
+		kept = append(kept[:0:0], kept...)
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].StartedAt.Before(kept[j].StartedAt) })
+		kept = kept[len(kept)-s.maxRecords:]
+	}
+	return kept
+}
+
+// List returns records matching the filter, newest first
+func (s *FileStore) List(filter Filter) ([]*Record, error) {
+	records, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	return applyFilter(records, filter), nil
+}
+
+// Get returns the record with the given ID or unique ID prefix
+func (s *FileStore) Get(id string) (*Record, error) {
+	records, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	return findByID(records, id)
+}
+
+// readAll loads the records within the retention limits from the history
+// file. The file keeps expired records until it is next compacted.
+func (s *FileStore) readAll() ([]*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	records, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	return s.retain(records, time.Now()), nil
+}
+
+// read loads every record from the history file. The caller holds s.mu.
+func (s *FileStore) read() ([]*Record, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %w", err)
+	}
+	defer func() {
+		_ = file.Close() //nolint:errcheck // read-only file
+	}()
+
+	var records []*Record
+	scanner := bufio.NewScanner(file)
+	// Responses can be long, allow large lines
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			// Skip a partially written line rather than failing the whole listing
+			continue
+		}
+		records = append(records, &record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %w", err)
+	}
+	return records, nil
+}
+
+// MemoryStore keeps records in memory, mainly for tests
+type MemoryStore struct {
+	records []*Record
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Save appends a copy of the record
+func (s *MemoryStore) Save(record *Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *record
+	s.records = append(s.records, &copied)
+	return nil
+}
+
+// List returns records matching the filter, newest first
+func (s *MemoryStore) List(filter Filter) ([]*Record, error) {
+	s.mu.RLock()
+	records := make([]*Record, len(s.records))
+	copy(records, s.records)
+	s.mu.RUnlock()
+	return applyFilter(records, filter), nil
+}
+
+// Get returns the record with the given ID or unique ID prefix
+func (s *MemoryStore) Get(id string) (*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return findByID(s.records, id)
+}
+
+// NopStore discards all records
+type NopStore struct{}
+
+// NewNopStore creates a store that keeps nothing
+func NewNopStore() *NopStore {
+	return &NopStore{}
+}
+
+// Save discards the record
+func (s *NopStore) Save(_ *Record) error {
+	return nil
+}
+
+// List always returns no records
+func (s *NopStore) List(_ Filter) ([]*Record, error) {
+	return nil, nil
+}
+
+// Get always reports the record as missing
+func (s *NopStore) Get(id string) (*Record, error) {
+	return findByID(nil, id)
+}
+
+// applyFilter filters records and orders them newest first
+func applyFilter(records []*Record, filter Filter) []*Record {
+	matched := make([]*Record, 0, len(records))
+	for _, r := range records {
+		if filter.Matches(r) {
+			matched = append(matched, r)
+		}
+	}
+
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].StartedAt.After(matched[j].StartedAt)
+	})
+
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched
+}
@@ -120,7 +120,7 @@ func TestIntegration_EndToEnd(t *testing.T) {
 	require.NoError(t, err)
 
 	// Execute with model (mocked in test mode)
-	response, err := openAIClient.Execute(promptContent)
+	response, err := openAIClient.Execute(context.Background(), promptContent)
 
 	// In test mode, we might need to create a simulated response
 	if err != nil || response == nil {
@@ -141,7 +141,7 @@ func TestIntegration_EndToEnd(t *testing.T) {
 	fileProcessor, err := processor.NewFileProcessor(fileProcessorConfig)
 	require.NoError(t, err)
 
-	err = fileProcessor.Process(response, "")
+	err = fileProcessor.Process(context.Background(), response, "")
 	require.NoError(t, err)
 
 	// Verify the output exists in the directory
@@ -184,7 +184,7 @@ func TestIntegration_EndToEnd(t *testing.T) {
 			t.Logf("Warning: Could not fetch initial comments for verification: %v", err)
 		}
 
-		err = githubProcessor.Process(response, "")
+		err = githubProcessor.Process(context.Background(), response, "")
 		require.NoError(t, err)
 
 		// Verify the comment was actually created
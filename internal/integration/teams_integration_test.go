@@ -3,6 +3,7 @@ package integration
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -119,7 +120,7 @@ func TestTeamsIntegration(t *testing.T) {
 		for _, tt := range tests {
 			t.Run(tt.name, func(t *testing.T) {
 				// Create processor
-				err := processor.ProcessResponse(tt.processor, tt.response, tt.templateName)
+				err := processor.ProcessResponse(context.Background(), tt.processor, tt.response, tt.templateName)
 				if err != nil {
 					t.Fatalf("Failed to process response: %v", err)
 				}
@@ -155,7 +156,7 @@ func TestTeamsIntegration(t *testing.T) {
 			}
 
 			// Process the response
-			err = proc.Process(tt.response, tt.templateName)
+			err = proc.Process(context.Background(), tt.response, tt.templateName)
 			if err != nil {
 				t.Fatalf("Failed to process response: %v", err)
 			}
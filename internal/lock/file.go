@@ -0,0 +1,255 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// lockSuffix is the extension of lease files
+const lockSuffix = ".lock"
+
+// takeoverSuffix is the extension of the guard file held while an expired
+// lease file is replaced
+const takeoverSuffix = ".takeover"
+
+// staleTakeover is how old a takeover guard must be before it is considered
+// left behind by a crashed instance
+const staleTakeover = 10 * time.Second
+
+// pruneInterval is how often expired lease files are removed from the directory
+const pruneInterval = time.Minute
+
+// lease is the content of a lease file
+type lease struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// FileLocker keeps leases as files in a directory, typically on a volume
+// shared by every instance. Lease files are created with a hard link, so they
+// appear with their full content or not at all, and an expired lease is only
+// replaced by the instance holding its takeover guard.
+type FileLocker struct {
+	dir string
+	now func() time.Time
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewFileLocker creates a locker keeping its lease files in dir
+func NewFileLocker(dir string) *FileLocker {
+	return &FileLocker{dir: dir, now: time.Now}
+}
+
+// Dir returns the directory holding the lease files
+func (l *FileLocker) Dir() string {
+	return l.dir
+}
+
+// Acquire takes or extends the lease on key for owner
+func (l *FileLocker) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return false, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	l.prune()
+
+	path := l.path(key)
+	now := l.now()
+	held := lease{Owner: owner, Expires: now.Add(ttl)}
+
+	created, err := createLease(path, held)
+	if err != nil || created {
+		return created, err
+	}
+
+	current, err := readLease(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		// Released since the create attempt
+		return createLease(path, held)
+	case err != nil:
+		return false, err
+	case current.Owner == owner:
+		return true, writeLease(path, held)
+	case now.Before(current.Expires):
+		return false, nil
+	}
+
+	removed, err := l.removeExpired(path, current)
+	if err != nil || !removed {
+		return false, err
+	}
+	return createLease(path, held)
+}
+
+// Release removes the lease file of key if owner holds the lease
+func (l *FileLocker) Release(_ context.Context, key, owner string) error {
+	path := l.path(key)
+	current, err := readLease(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if current.Owner != owner {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// path returns the lease file of key. Keys are escaped so that any key makes
+// a valid file name on every platform.
+func (l *FileLocker) path(key string) string {
+	return filepath.Join(l.dir, url.QueryEscape(key)+lockSuffix)
+}
+
+// removeExpired removes the lease file at path if it still holds the expired
+// lease seen. It returns false when another instance is replacing the file or
+// the lease changed in the meantime.
+func (l *FileLocker) removeExpired(path string, seen lease) (bool, error) {
+	guard := path + takeoverSuffix
+	file, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if errors.Is(err, os.ErrExist) {
+		// Clear a guard left behind by a crash so the lease isn't stuck
+		info, statErr := os.Stat(guard)
+		if statErr != nil || l.now().Sub(info.ModTime()) <= staleTakeover {
+			return false, nil
+		}
+		if err := os.Remove(guard); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Errorf("failed to remove stale lock takeover guard: %w", err)
+		}
+		file, err = os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to create lock takeover guard: %w", err)
+	}
+	_ = file.Close()                        //nolint:errcheck // the guard is empty
+	defer func() { _ = os.Remove(guard) }() //nolint:errcheck // a leftover guard is cleared once stale
+
+	current, err := readLease(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	if err != nil || current != seen {
+		return false, err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("failed to remove expired lock file: %w", err)
+	}
+	return true, nil
+}
+
+// prune removes expired lease files, at most once per pruneInterval, so that
+// leases that are never released don't accumulate
+func (l *FileLocker) prune() {
+	l.mu.Lock()
+	now := l.now()
+	if now.Sub(l.lastPrune) < pruneInterval {
+		l.mu.Unlock()
+		return
+	}
+	l.lastPrune = now
+	l.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(l.dir, "*"+lockSuffix))
+	if err != nil {
+		return
+	}
+	for _, path := range paths {
+		current, err := readLease(path)
+		if err != nil || now.Before(current.Expires) {
+			continue
+		}
+		if _, err := l.removeExpired(path, current); err != nil {
+			log.Debug("Failed to remove expired lock file", logger.Fields{"path": path, "error": err.Error()})
+		}
+	}
+}
+
+// createLease creates the lease file at path unless it exists and reports
+// whether it did. The file is written in full before it is linked into place.
+func createLease(path string, held lease) (bool, error) {
+	tmp, err := writeTemp(path, held)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = os.Remove(tmp) }() //nolint:errcheck // best-effort cleanup
+
+	if err := os.Link(tmp, path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create lock file: %w", err)
+	}
+	return true, nil
+}
+
+// writeLease replaces the lease file at path
+func writeLease(path string, held lease) error {
+	tmp, err := writeTemp(path, held)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp) //nolint:errcheck // the rename error is more relevant
+		return fmt.Errorf("failed to replace lock file: %w", err)
+	}
+	return nil
+}
+
+// writeTemp writes held to a uniquely named temporary file next to path
+func writeTemp(path string, held lease) (string, error) {
+	data, err := json.Marshal(held)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode lease: %w", err)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to name lock file: %w", err)
+	}
+	tmp := path + "." + hex.EncodeToString(suffix) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write lock file: %w", err)
+	}
+	return tmp, nil
+}
+
+// readLease reads the lease file at path
+func readLease(path string) (lease, error) {
+	var current lease
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return current, err
+		}
+		return current, fmt.Errorf("failed to read lock file: %w", err)
+	}
+	if err := json.Unmarshal(data, &current); err != nil {
+		return current, fmt.Errorf("failed to parse lock file %s: %w", strings.TrimSuffix(filepath.Base(path), lockSuffix), err)
+	}
+	return current, nil
+}
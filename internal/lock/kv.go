@@ -0,0 +1,117 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// KV is the part of a Redis- or etcd-style key-value store a KVLocker needs.
+// Each operation must be atomic in the store: SET NX PX and small scripts
+// comparing the value in Redis, transactions on a lease in etcd.
+type KV interface {
+	// SetNX stores value under key for ttl unless key exists and reports
+	// whether it was stored
+	SetNX(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// Refresh resets the time to live of key to ttl if it holds value and
+	// reports whether it did
+	Refresh(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+	// DeleteIf removes key if it holds value and reports whether it did
+	DeleteIf(ctx context.Context, key, value string) (bool, error)
+}
+
+// KVLocker keeps leases in a key-value store, storing the owner under the key
+type KVLocker struct {
+	kv     KV
+	prefix string
+}
+
+// NewKVLocker creates a locker keeping its leases in kv, under keys starting
+// with prefix
+func NewKVLocker(kv KV, prefix string) *KVLocker {
+	return &KVLocker{kv: kv, prefix: prefix}
+}
+
+// Acquire takes or extends the lease on key for owner
+func (l *KVLocker) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	stored, err := l.kv.SetNX(ctx, l.prefix+key, owner, ttl)
+	if err != nil || stored {
+		return stored, err
+	}
+	return l.kv.Refresh(ctx, l.prefix+key, owner, ttl)
+}
+
+// Release gives up the lease on key if owner holds it
+func (l *KVLocker) Release(ctx context.Context, key, owner string) error {
+	_, err := l.kv.DeleteIf(ctx, l.prefix+key, owner)
+	return err
+}
+
+// memoryEntry is a value held by a MemoryKV
+type memoryEntry struct {
+	value   string
+	expires time.Time
+}
+
+// MemoryKV is an in-process KV, a stand-in for a shared store in tests and
+// single-host setups
+type MemoryKV struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+	now     func() time.Time
+}
+
+// NewMemoryKV creates an empty in-process store
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{entries: make(map[string]memoryEntry), now: time.Now}
+}
+
+// SetNX stores value under key for ttl unless key exists
+func (m *MemoryKV) SetNX(_ context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.get(key); ok {
+		return false, nil
+	}
+	m.entries[key] = memoryEntry{value: value, expires: m.now().Add(ttl)}
+	return true, nil
+}
+
+// Refresh resets the time to live of key if it holds value
+func (m *MemoryKV) Refresh(_ context.Context, key, value string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current, ok := m.get(key); !ok || current != value {
+		return false, nil
+	}
+	m.entries[key] = memoryEntry{value: value, expires: m.now().Add(ttl)}
+	return true, nil
+}
+
+// DeleteIf removes key if it holds value
+func (m *MemoryKV) DeleteIf(_ context.Context, key, value string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if current, ok := m.get(key); !ok || current != value {
+		return false, nil
+	}
+	delete(m.entries, key)
+	return true, nil
+}
+
+// get returns the unexpired value under key, dropping it once expired. The
+// caller must hold m.mu.
+func (m *MemoryKV) get(key string) (string, bool) {
+	entry, ok := m.entries[key]
+	if !ok {
+		return "", false
+	}
+	if !m.now().Before(entry.expires) {
+		delete(m.entries, key)
+		return "", false
+	}
+	return entry.value, true
+}
@@ -0,0 +1,48 @@
+// Package lock provides leases that keep several cronai instances sharing a
+// backend from running the same scheduled work, either through a directory on
+// a shared volume or a Redis- or etcd-style key-value store.
+package lock
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// EnvInstanceID overrides the name this instance holds leases under
+const EnvInstanceID = "CRONAI_INSTANCE_ID"
+
+// Default logger for the lock package
+var log = logger.DefaultLogger()
+
+// SetLogger sets the logger for the lock package
+func SetLogger(l *logger.Logger) {
+	log = l
+}
+
+// Locker hands out named leases. A lease belongs to its owner until the time
+// to live expires or the owner releases it. Acquiring a lease the owner
+// already holds extends it.
+type Locker interface {
+	// Acquire takes or extends the lease on key for owner. It returns false,
+	// without an error, when another owner holds the lease.
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// Release gives up the lease on key if owner holds it
+	Release(ctx context.Context, key, owner string) error
+}
+
+// InstanceID returns the name this instance holds leases under: the value of
+// CRONAI_INSTANCE_ID or, by default, the host name and process ID
+func InstanceID() string {
+	if id := os.Getenv(EnvInstanceID); id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "cronai"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,169 @@
+package lock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a settable time source shared by a locker under test
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// lockers returns every backend, driven by clock
+func lockers(t *testing.T, clock *fakeClock) map[string]Locker {
+	t.Helper()
+	file := NewFileLocker(filepath.Join(t.TempDir(), "locks"))
+	file.now = clock.Now
+	kv := NewMemoryKV()
+	kv.now = clock.Now
+	return map[string]Locker{
+		"file": file,
+		"kv":   NewKVLocker(kv, "cronai:"),
+	}
+}
+
+func TestLockerLease(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	ctx := context.Background()
+
+	for name, locker := range lockers(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			ok, err := locker.Acquire(ctx, "leader", "a", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			// Held by a, extended by a only
+			ok, err = locker.Acquire(ctx, "leader", "b", time.Minute)
+			require.NoError(t, err)
+			assert.False(t, ok)
+			clock.Advance(40 * time.Second)
+			ok, err = locker.Acquire(ctx, "leader", "a", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			clock.Advance(40 * time.Second)
+			ok, err = locker.Acquire(ctx, "leader", "b", time.Minute)
+			require.NoError(t, err)
+			assert.False(t, ok, "the extended lease is still held")
+
+			// b takes over once the lease expires
+			clock.Advance(time.Minute)
+			ok, err = locker.Acquire(ctx, "leader", "b", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok)
+
+			// Releasing someone else's lease does nothing
+			require.NoError(t, locker.Release(ctx, "leader", "a"))
+			ok, err = locker.Acquire(ctx, "leader", "a", time.Minute)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			require.NoError(t, locker.Release(ctx, "leader", "b"))
+			ok, err = locker.Acquire(ctx, "leader", "a", time.Minute)
+			require.NoError(t, err)
+			assert.True(t, ok)
+		})
+	}
+}
+
+func TestLockerConcurrentAcquire(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+
+	for name, locker := range lockers(t, clock) {
+		t.Run(name, func(t *testing.T) {
+			var winners atomic.Int32
+			var wg sync.WaitGroup
+			for _, owner := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ok, err := locker.Acquire(context.Background(), "run:report:2026-10-16T09:00", owner, time.Minute)
+					assert.NoError(t, err)
+					if ok {
+						winners.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, int32(1), winners.Load())
+		})
+	}
+}
+
+func TestFileLockerPrunesExpiredLeases(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)}
+	locker := NewFileLocker(t.TempDir())
+	locker.now = clock.Now
+	ctx := context.Background()
+
+	for _, key := range []string{"run:report:09:00", "run:report:09:01"} {
+		ok, err := locker.Acquire(ctx, key, "a", time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+
+	clock.Advance(2 * time.Minute)
+	ok, err := locker.Acquire(ctx, "run:report:09:03", "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	entries, err := os.ReadDir(locker.Dir())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "run%3Areport%3A09%3A03.lock", entries[0].Name())
+}
+
+func TestFileLockerStaleTakeoverGuard(t *testing.T) {
+	clock := &fakeClock{now: time.Now()}
+	locker := NewFileLocker(t.TempDir())
+	locker.now = clock.Now
+	ctx := context.Background()
+
+	ok, err := locker.Acquire(ctx, "leader", "a", time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Another instance is taking over the expired lease
+	clock.Advance(2 * time.Minute)
+	guard := locker.path("leader") + takeoverSuffix
+	require.NoError(t, os.WriteFile(guard, nil, 0644))
+	require.NoError(t, os.Chtimes(guard, clock.Now(), clock.Now()))
+	ok, err = locker.Acquire(ctx, "leader", "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// It crashed, its guard is cleared once stale
+	clock.Advance(staleTakeover + time.Second)
+	ok, err = locker.Acquire(ctx, "leader", "b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestInstanceID(t *testing.T) {
+	t.Setenv(EnvInstanceID, "replica-1")
+	assert.Equal(t, "replica-1", InstanceID())
+
+	t.Setenv(EnvInstanceID, "")
+	assert.Contains(t, InstanceID(), "-")
+}
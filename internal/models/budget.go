@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"log"
+
+	"github.com/rshade/cronai/internal/budget"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// checkBudget checks the model about to be called against the budgets of the
+// execution, if any. It returns the model to call, which is the fallback of
+// a downgrading budget once that budget is exhausted, or an
+// *budget.ExceededError when an exhausted budget refuses the call.
+func checkBudget(ctx context.Context, modelName string, modelConfig *config.ModelConfig) (string, error) {
+	guard, request, ok := budget.FromContext(ctx)
+	if !ok {
+		return modelName, nil
+	}
+
+	request.Provider = modelName
+	decision := guard.Check(ctx, request)
+	switch decision.Policy {
+	case budget.PolicyWarn:
+		log.Printf("Warning: %v, calling %s anyway", decision.Err(), modelName)
+		return modelName, nil
+	case budget.PolicyRefuse:
+		return "", decision.Err()
+	case budget.PolicyDowngrade:
+	default:
+		return modelName, nil
+	}
+
+	// The fallback is checked too, but a call is downgraded only once
+	provider, model := decision.Budget.FallbackModel()
+	request.Provider = provider
+	next := guard.Check(ctx, request)
+	switch {
+	case next.Policy == budget.PolicyRefuse,
+		next.Policy == budget.PolicyDowngrade && next.Budget.Fallback != decision.Budget.Fallback:
+		return "", next.Err()
+	case next.Policy == budget.PolicyWarn:
+		log.Printf("Warning: %v, calling %s anyway", next.Err(), provider)
+	}
+
+	if _, named := providerFromContext(ctx, provider); named && model != "" {
+		modelConfig.Model = model
+	} else if model != "" {
+		if err := modelConfig.UpdateFromParams(map[string]string{provider + ".model": model}); err != nil {
+			return "", err
+		}
+	}
+	log.Printf("%v, downgrading %s to %s", decision.Err(), modelName, decision.Budget.Fallback)
+	return provider, nil
+}
+
+// BudgetAllows reports whether the budgets of the execution let a model be
+// called without downgrading it. Fallback models, and model clients that
+// can't switch to another model, aren't downgraded: a call a downgrading
+// budget would change is refused like one a refusing budget stops.
+func BudgetAllows(ctx context.Context, modelName string) error {
+	guard, request, ok := budget.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	request.Provider = modelName
+	decision := guard.Check(ctx, request)
+	switch decision.Policy {
+	case budget.PolicyRefuse, budget.PolicyDowngrade:
+		return decision.Err()
+	case budget.PolicyWarn:
+		log.Printf("Warning: %v, calling %s anyway", decision.Err(), modelName)
+	}
+	return nil
+}
+
+// RecordSpend counts the cost of a response against the budgets of the execution
+func RecordSpend(ctx context.Context, response *ModelResponse) {
+	guard, request, ok := budget.FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	request.Provider = response.Provider
+	if err := guard.Record(ctx, request, response.Cost); err != nil {
+		log.Printf("Failed to record the spend of %s: %v", response.Provider, err)
+	}
+}
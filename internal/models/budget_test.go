@@ -0,0 +1,94 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/rshade/cronai/internal/budget"
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecuteModelBudget(t *testing.T) {
+	originalCreateModelClient := createModelClient
+	defer func() { createModelClient = originalCreateModelClient }()
+	t.Setenv("MODEL_PRICES", "")
+
+	// Every call costs $1 at gpt-4o prices, or the named model's
+	var called []string
+	createModelClient = func(modelName string, modelConfig *config.ModelConfig) (ModelClient, error) {
+		model := modelName
+		if modelName == "openai" {
+			model = modelConfig.OpenAIConfig.Model
+		}
+		called = append(called, model)
+		return &MockModelClient{Content: "ok", Model: "gpt-4o", Usage: Usage{PromptTokens: 400_000}}, nil
+	}
+
+	openai := &budget.Budget{Scope: budget.ScopeProvider, Name: "openai", Limit: 1, Period: budget.PeriodDay,
+		Policy: budget.PolicyDowngrade, Fallback: "openai:gpt-4o-mini"}
+	weekly := &budget.Budget{Scope: budget.ScopeTask, Name: "weekly", Limit: 2, Period: budget.PeriodDay, Policy: budget.PolicyRefuse}
+	var exhausted []string
+	guard := &budget.Guard{
+		Budgets: []*budget.Budget{openai, weekly},
+		Ledger:  budget.NewLedger(""),
+		Notify: func(_ context.Context, exhaustion budget.Exhaustion) {
+			exhausted = append(exhausted, exhaustion.Budget.Key())
+		},
+	}
+	ctx := budget.WithGuard(context.Background(), guard, budget.Request{Task: "weekly"})
+
+	// The spend of the call is recorded
+	response, err := ExecuteModel(ctx, "openai", "test prompt", nil, "")
+	require.NoError(t, err)
+	assert.InDelta(t, 1, response.Cost, 1e-9)
+	assert.InDelta(t, 1, guard.Ledger.Spent("provider:openai", budget.PeriodDay, response.Timestamp), 1e-9)
+	assert.Equal(t, []string{"provider:openai"}, exhausted)
+
+	// The exhausted provider budget downgrades the next call
+	called = nil
+	response, err = ExecuteModel(ctx, "openai", "test prompt", nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4o-mini"}, called)
+	assert.Equal(t, "openai", response.Provider)
+	assert.Equal(t, []string{"provider:openai", "task:weekly"}, exhausted)
+
+	// The exhausted task budget refuses the next one without calling a model
+	called = nil
+	_, err = ExecuteModel(ctx, "openai", "test prompt", nil, "")
+	var exceeded *budget.ExceededError
+	require.True(t, errors.As(err, &exceeded), "got %v", err)
+	assert.Equal(t, weekly, exceeded.Budget)
+	assert.Empty(t, called)
+
+	// Calls outside the budgeted execution aren't limited
+	_, err = ExecuteModel(context.Background(), "openai", "test prompt", nil, "")
+	require.NoError(t, err)
+}
+
+func TestExecuteModelBudgetSkipsFallbacks(t *testing.T) {
+	originalCreateModelClient := createModelClient
+	defer func() { createModelClient = originalCreateModelClient }()
+
+	var called []string
+	createModelClient = func(modelName string, _ *config.ModelConfig) (ModelClient, error) {
+		called = append(called, modelName)
+		if modelName == "openai" {
+			return &MockModelClient{ShouldFail: true, ErrorMessage: "unavailable"}, nil
+		}
+		return &MockModelClient{Content: "ok", Model: modelName}, nil
+	}
+
+	claude := &budget.Budget{Scope: budget.ScopeProvider, Name: "claude", Limit: 1, Period: budget.PeriodMonth, Policy: budget.PolicyRefuse}
+	guard := &budget.Guard{Budgets: []*budget.Budget{claude}, Ledger: budget.NewLedger("")}
+	require.NoError(t, guard.Record(context.Background(), budget.Request{Provider: "claude"}, 1))
+	ctx := budget.WithGuard(context.Background(), guard, budget.Request{Task: "daily"})
+
+	// A failing primary falls back past the provider whose budget is exhausted
+	response, err := ExecuteModel(ctx, "openai", "test prompt", nil, "max_retries=1")
+	require.NoError(t, err)
+	assert.Equal(t, "gemini", response.Provider)
+	assert.Equal(t, []string{"openai", "gemini"}, called)
+}
@@ -132,30 +132,15 @@ func NewClaudeClient(modelConfig *config.ModelConfig) (*ClaudeClient, error) {
 }
 
 // Execute sends a prompt to Claude and returns the model response
-func (c *ClaudeClient) Execute(promptContent string) (*ModelResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+func (c *ClaudeClient) Execute(ctx context.Context, promptContent string) (*ModelResponse, error) {
+	ctx, cancel := requestContext(ctx, c.config)
 	defer cancel()
 
-	modelName := c.getModelName()
-	systemMessage := c.getSystemMessage()
-
-	// Convert MaxTokens to int64
-	maxTokens := int64(c.config.MaxTokens)
-
 	// Create the message request
-	request := &anthropic.MessageNewParams{
-		Model:       anthropic.Model(modelName),
-		MaxTokens:   maxTokens,
-		Temperature: anthropic.Float(c.config.Temperature),
-		TopP:        anthropic.Float(c.config.TopP),
-		System:      []anthropic.TextBlockParam{{Text: systemMessage}},
-		Messages: []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(promptContent)),
-		},
-	}
+	request := c.request(promptContent)
 
 	// Send the request to Claude API
-	resp, err := c.client.Messages.New(ctx, *request)
+	resp, err := c.client.Messages.New(ctx, request)
 	if err != nil {
 		return nil, fmt.Errorf("claude API error: %w", err)
 	}
@@ -183,6 +168,7 @@ func (c *ClaudeClient) Execute(promptContent string) (*ModelResponse, error) {
 		Content:   content,
 		Model:     string(resp.Model),
 		Timestamp: time.Now(),
+		Usage:     claudeUsage(resp.Usage),
 	}
 
 	// Add additional metadata
@@ -192,6 +178,90 @@ func (c *ClaudeClient) Execute(promptContent string) (*ModelResponse, error) {
 	return modelResponse, nil
 }
 
+// ExecuteStream sends a prompt to Claude, passing the response to handler as
+// it arrives, and returns the assembled model response
+func (c *ClaudeClient) ExecuteStream(ctx context.Context, promptContent string, handler StreamHandler) (*ModelResponse, error) {
+	ctx, touch, cancel := streamContext(ctx, c.config)
+	defer cancel()
+
+	stream := c.client.Messages.NewStreaming(ctx, c.request(promptContent))
+	defer func() {
+		if closeErr := stream.Close(); closeErr != nil {
+			log.Printf("Warning: failed to close Claude stream: %v", closeErr)
+		}
+	}()
+
+	message := anthropic.Message{}
+	for stream.Next() {
+		touch()
+		event := stream.Current()
+		if err := message.Accumulate(event); err != nil {
+			return nil, fmt.Errorf("claude API error: %w", err)
+		}
+		if delta, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent); ok {
+			if text, ok := delta.Delta.AsAny().(anthropic.TextDelta); ok {
+				handler.Delta(text.Text)
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("claude API error: %w", streamError(ctx, err))
+	}
+
+	var content strings.Builder
+	for _, block := range message.Content {
+		if block.Type == "text" {
+			content.WriteString(block.Text)
+		}
+	}
+	if content.Len() == 0 {
+		return nil, fmt.Errorf("no text content in Claude response")
+	}
+	return &ModelResponse{
+		Content:     content.String(),
+		Model:       string(message.Model),
+		Timestamp:   time.Now(),
+		PromptName:  "direct", // Will be overridden by the caller if needed
+		ExecutionID: generateExecutionID("claude", "direct"),
+		Usage:       claudeUsage(message.Usage),
+	}, nil
+}
+
+// claudeUsage converts the token counts of a Claude response. Claude counts
+// the prompt tokens read from and written to its cache apart from the others.
+func claudeUsage(usage anthropic.Usage) Usage {
+	return Usage{
+		PromptTokens:     int(usage.InputTokens + usage.CacheReadInputTokens + usage.CacheCreationInputTokens),
+		CompletionTokens: int(usage.OutputTokens),
+		CachedTokens:     int(usage.CacheReadInputTokens),
+	}
+}
+
+// request returns the message request for a prompt
+func (c *ClaudeClient) request(promptContent string) anthropic.MessageNewParams {
+	modelName := c.getModelName()
+	systemMessage := c.getSystemMessage()
+
+	// Convert MaxTokens to int64
+	maxTokens := int64(c.config.MaxTokens)
+
+	return anthropic.MessageNewParams{
+		Model:       anthropic.Model(modelName),
+		MaxTokens:   maxTokens,
+		Temperature: anthropic.Float(c.config.Temperature),
+		TopP:        anthropic.Float(c.config.TopP),
+		System:      []anthropic.TextBlockParam{{Text: systemMessage}},
+		Messages: []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(promptContent)),
+		},
+	}
+}
+
+// ProviderName returns the provider this client calls
+func (c *ClaudeClient) ProviderName() string {
+	return "claude"
+}
+
 // getModelName returns the Claude model name to use
 func (c *ClaudeClient) getModelName() string {
 	if c.config != nil && c.config.ClaudeConfig != nil && c.config.ClaudeConfig.Model != "" {
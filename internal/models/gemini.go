@@ -2,12 +2,15 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/generative-ai-go/genai"
 	"github.com/rshade/cronai/pkg/config"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
@@ -37,8 +40,8 @@ func NewGeminiClient(modelConfig *config.ModelConfig) (*GeminiClient, error) {
 }
 
 // Execute sends a prompt to Gemini and returns the model response
-func (c *GeminiClient) Execute(promptContent string) (*ModelResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+func (c *GeminiClient) Execute(ctx context.Context, promptContent string) (*ModelResponse, error) {
+	ctx, cancel := requestContext(ctx, c.config)
 	defer cancel()
 
 	// Before we clean up resources
@@ -50,7 +53,114 @@ func (c *GeminiClient) Execute(promptContent string) (*ModelResponse, error) {
 	}()
 
 	modelName := c.getModelName()
+	model := c.generativeModel(modelName)
 
+	// Generate content from the prompt
+	resp, err := model.GenerateContent(ctx, genai.Text(promptContent))
+	if err != nil {
+		return nil, fmt.Errorf("gemini API error: %w", err)
+	}
+
+	// Extract the response text
+	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("no response from Gemini")
+	}
+
+	// Extract text from the response
+	var content string
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if text, ok := part.(genai.Text); ok {
+			content += string(text)
+		}
+	}
+
+	if content == "" {
+		return nil, fmt.Errorf("no text content in Gemini response")
+	}
+
+	// Create the model response
+	modelResponse := &ModelResponse{
+		Content:   content,
+		Model:     modelName,
+		Timestamp: time.Now(),
+		Usage:     geminiUsage(resp.UsageMetadata),
+	}
+
+	// Add additional metadata
+	modelResponse.PromptName = "direct" // Will be overridden by the caller if needed
+	modelResponse.ExecutionID = generateExecutionID("gemini", modelResponse.PromptName)
+
+	return modelResponse, nil
+}
+
+// ExecuteStream sends a prompt to Gemini, passing the response to handler as
+// it arrives, and returns the assembled model response
+func (c *GeminiClient) ExecuteStream(ctx context.Context, promptContent string, handler StreamHandler) (*ModelResponse, error) {
+	ctx, touch, cancel := streamContext(ctx, c.config)
+	defer cancel()
+
+	defer func() {
+		if err := c.client.Close(); err != nil {
+			fmt.Printf("Warning: Failed to close Gemini client: %v\n", err)
+		}
+	}()
+
+	modelName := c.getModelName()
+	iter := c.generativeModel(modelName).GenerateContentStream(ctx, genai.Text(promptContent))
+
+	var content strings.Builder
+	var usage Usage
+	for {
+		resp, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gemini API error: %w", streamError(ctx, err))
+		}
+		touch()
+		// Every chunk reports the tokens used so far
+		if resp.UsageMetadata != nil {
+			usage = geminiUsage(resp.UsageMetadata)
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if text, ok := part.(genai.Text); ok && text != "" {
+				content.WriteString(string(text))
+				handler.Delta(string(text))
+			}
+		}
+	}
+
+	if content.Len() == 0 {
+		return nil, fmt.Errorf("no text content in Gemini response")
+	}
+	return &ModelResponse{
+		Content:     content.String(),
+		Model:       modelName,
+		Timestamp:   time.Now(),
+		PromptName:  "direct", // Will be overridden by the caller if needed
+		ExecutionID: generateExecutionID("gemini", "direct"),
+		Usage:       usage,
+	}, nil
+}
+
+// geminiUsage converts the token counts of a Gemini response
+func geminiUsage(usage *genai.UsageMetadata) Usage {
+	if usage == nil {
+		return Usage{}
+	}
+	return Usage{
+		PromptTokens:     int(usage.PromptTokenCount),
+		CompletionTokens: int(usage.CandidatesTokenCount),
+		CachedTokens:     int(usage.CachedContentTokenCount),
+	}
+}
+
+// generativeModel returns the named model with the configured parameters
+func (c *GeminiClient) generativeModel(modelName string) *genai.GenerativeModel {
 	// Create the generative model with the specified model name
 	model := c.client.GenerativeModel(modelName)
 
@@ -86,42 +196,12 @@ func (c *GeminiClient) Execute(promptContent string) (*ModelResponse, error) {
 		}
 		model.SafetySettings = safetySettings
 	}
+	return model
+}
 
-	// Generate content from the prompt
-	resp, err := model.GenerateContent(ctx, genai.Text(promptContent))
-	if err != nil {
-		return nil, fmt.Errorf("gemini API error: %w", err)
-	}
-
-	// Extract the response text
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no response from Gemini")
-	}
-
-	// Extract text from the response
-	var content string
-	for _, part := range resp.Candidates[0].Content.Parts {
-		if text, ok := part.(genai.Text); ok {
-			content += string(text)
-		}
-	}
-
-	if content == "" {
-		return nil, fmt.Errorf("no text content in Gemini response")
-	}
-
-	// Create the model response
-	modelResponse := &ModelResponse{
-		Content:   content,
-		Model:     modelName,
-		Timestamp: time.Now(),
-	}
-
-	// Add additional metadata
-	modelResponse.PromptName = "direct" // Will be overridden by the caller if needed
-	modelResponse.ExecutionID = generateExecutionID("gemini", modelResponse.PromptName)
-
-	return modelResponse, nil
+// ProviderName returns the provider this client calls
+func (c *GeminiClient) ProviderName() string {
+	return "gemini"
 }
 
 // getModelName returns the Gemini model name to use
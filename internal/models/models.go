@@ -1,10 +1,12 @@
 package models
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/rshade/cronai/internal/pool"
 	"github.com/rshade/cronai/pkg/config"
 )
 
@@ -40,15 +42,64 @@ type ModelResponse struct {
 	Variables   map[string]string // Variables used in the prompt
 	Timestamp   time.Time         // When the response was generated
 	ExecutionID string            // Unique execution identifier
+	Provider    string            // Provider that produced the response after any fallback
+	Usage       Usage             // Tokens the request used, as reported by the provider
+	Cost        float64           // Estimated cost in USD, zero when the model has no price
+}
+
+// Usage holds the token counts of a request
+type Usage struct {
+	PromptTokens     int // Tokens of the prompt, including CachedTokens
+	CompletionTokens int // Tokens of the response
+	CachedTokens     int // Prompt tokens read from the provider's prompt cache
+}
+
+// TotalTokens returns the prompt and completion tokens together
+func (u Usage) TotalTokens() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// estimateCost returns the cost of a response with the configured prices,
+// zero when its model has no price
+func estimateCost(prices config.ModelPrices, response *ModelResponse) float64 {
+	price, ok := prices.Lookup(response.Model)
+	if !ok {
+		return 0
+	}
+	return price.Cost(response.Usage.PromptTokens, response.Usage.CompletionTokens, response.Usage.CachedTokens)
 }
 
 // ModelClient defines the interface for AI model clients
 type ModelClient interface {
-	Execute(promptContent string) (*ModelResponse, error)
+	// Execute sends the prompt to the model. Cancelling ctx aborts the request.
+	Execute(ctx context.Context, promptContent string) (*ModelResponse, error)
 }
 
-// ExecuteModel executes a prompt using the specified model and returns the response
-func ExecuteModel(modelName string, promptContent string, variables map[string]string, modelParams string) (*ModelResponse, error) {
+// ProviderNamer is implemented by model clients that report which provider they call
+type ProviderNamer interface {
+	ProviderName() string
+}
+
+// ProviderOf returns the provider behind a model client, or "" if it doesn't report one
+func ProviderOf(client ModelClient) string {
+	if namer, ok := client.(ProviderNamer); ok {
+		return namer.ProviderName()
+	}
+	return ""
+}
+
+// ExecuteModel executes a prompt using the specified model and returns the
+// response. Cancelling ctx aborts the request in flight and skips the
+// remaining retries and fallback models.
+// The budgets of a budget.Guard attached to ctx are checked before and
+// charged after the call.
+func ExecuteModel(ctx context.Context, modelName string, promptContent string, variables map[string]string, modelParams string) (*ModelResponse, error) {
+	return executeModel(ctx, modelName, promptContent, variables, modelParams, nil)
+}
+
+// executeModel executes a prompt, streaming the response to handler when
+// one is given
+func executeModel(ctx context.Context, modelName string, promptContent string, variables map[string]string, modelParams string, handler StreamHandler) (*ModelResponse, error) {
 	// Parse model parameters if provided
 	params, err := config.ParseModelParams(modelParams)
 	if err != nil {
@@ -60,6 +111,7 @@ func ExecuteModel(modelName string, promptContent string, variables map[string]s
 
 	// Load configuration from environment variables
 	modelConfig.LoadFromEnvironment()
+	modelConfig.NamedProviders = providerNames(ctx)
 
 	// Update configuration with any provided parameters
 	if err := modelConfig.UpdateFromParams(params); err != nil {
@@ -71,6 +123,12 @@ func ExecuteModel(modelName string, promptContent string, variables map[string]s
 		return nil, fmt.Errorf("invalid model configuration: %w", err)
 	}
 
+	// Apply the budgets of the execution to the model before calling it
+	modelName, err = checkBudget(ctx, modelName, modelConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get the prompt name from variables if available
 	promptName := ""
 	if promptNameVal, exists := variables["promptName"]; exists {
@@ -78,7 +136,7 @@ func ExecuteModel(modelName string, promptContent string, variables map[string]s
 	}
 
 	// Execute with fallback support
-	result := executeWithFallback(modelName, promptContent, variables, modelConfig, promptName)
+	result := executeWithFallbackStream(ctx, modelName, promptContent, variables, modelConfig, promptName, handler)
 
 	// If we got a successful response, return it
 	if result.Response != nil {
@@ -89,9 +147,15 @@ func ExecuteModel(modelName string, promptContent string, variables map[string]s
 				log.Printf("  Attempt %d: %v", i+1, err)
 			}
 		}
+		RecordSpend(ctx, result.Response)
 		return result.Response, nil
 	}
 
+	// Cancellation stops the fallback sequence, report it as such
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("model execution aborted after %d attempts: %w", len(result.Errors), context.Cause(ctx))
+	}
+
 	// All models failed, return comprehensive error
 	errorMsg := fmt.Sprintf("all models failed after %d attempts", len(result.Errors))
 	for i, err := range result.Errors {
@@ -101,7 +165,13 @@ func ExecuteModel(modelName string, promptContent string, variables map[string]s
 }
 
 // executeWithFallback executes a model with fallback support
-func executeWithFallback(primaryModel string, promptContent string, variables map[string]string, modelConfig *config.ModelConfig, promptName string) *ModelFallbackResult {
+func executeWithFallback(ctx context.Context, primaryModel string, promptContent string, variables map[string]string, modelConfig *config.ModelConfig, promptName string) *ModelFallbackResult {
+	return executeWithFallbackStream(ctx, primaryModel, promptContent, variables, modelConfig, promptName, nil)
+}
+
+// executeWithFallbackStream executes a model with fallback support,
+// streaming each attempt to handler when one is given
+func executeWithFallbackStream(ctx context.Context, primaryModel string, promptContent string, variables map[string]string, modelConfig *config.ModelConfig, promptName string, handler StreamHandler) *ModelFallbackResult {
 	result := &ModelFallbackResult{
 		Errors: []ModelError{},
 	}
@@ -109,19 +179,39 @@ func executeWithFallback(primaryModel string, promptContent string, variables ma
 	// Build the list of models to try (primary + fallbacks)
 	modelsToTry := []string{primaryModel}
 
-	// Add configured fallback models
+	// Add configured fallback models. Named providers often front private
+	// deployments, so their prompts only go elsewhere when configured to.
 	if len(modelConfig.FallbackModels) > 0 {
 		modelsToTry = append(modelsToTry, modelConfig.FallbackModels...)
-	} else {
+	} else if _, named := providerFromContext(ctx, primaryModel); !named {
 		// Default fallback sequence if not configured
 		modelsToTry = append(modelsToTry, getDefaultFallbackSequence(primaryModel)...)
 	}
 
 	// Try each model with retries
 	for modelIndex, modelName := range modelsToTry {
+		// Skip fallback models whose budget is exhausted
+		if modelIndex > 0 {
+			if err := BudgetAllows(ctx, modelName); err != nil {
+				result.Errors = append(result.Errors, ModelError{
+					Model:   modelName,
+					Message: err.Error(),
+					Err:     err,
+					Time:    time.Now(),
+				})
+				log.Printf("Skipping fallback model %s: %v", modelName, err)
+				continue
+			}
+		}
+
 		for retry := 0; retry < modelConfig.MaxRetries; retry++ {
+			// Don't start another attempt once the execution is cancelled
+			if ctx.Err() != nil {
+				return result
+			}
+
 			// Create the client for this model
-			client, err := createModelClient(modelName, modelConfig)
+			client, err := newModelClient(ctx, modelName, modelConfig)
 			if err != nil {
 				result.Errors = append(result.Errors, ModelError{
 					Model:   modelName,
@@ -134,8 +224,16 @@ func executeWithFallback(primaryModel string, promptContent string, variables ma
 				continue
 			}
 
+			// Each provider called, fallbacks included, stays within its own
+			// cap of the execution pool
+			release, err := pool.Default().AcquireProvider(ctx, modelName)
+			if err != nil {
+				return result
+			}
+
 			// Execute the prompt
-			response, err := client.Execute(promptContent)
+			response, err := executeAttempt(ctx, client, promptContent, handler)
+			release()
 			if err != nil {
 				result.Errors = append(result.Errors, ModelError{
 					Model:   modelName,
@@ -153,6 +251,8 @@ func executeWithFallback(primaryModel string, promptContent string, variables ma
 			response.Timestamp = time.Now()
 			response.PromptName = promptName
 			response.ExecutionID = generateExecutionID(modelName, promptName)
+			response.Provider = modelName
+			response.Cost = estimateCost(modelConfig.Prices, response)
 			result.Response = response
 			result.FinalModel = modelName
 
@@ -182,6 +282,8 @@ func defaultCreateModelClient(modelName string, modelConfig *config.ModelConfig)
 		return NewClaudeClient(modelConfig)
 	case "gemini":
 		return NewGeminiClient(modelConfig)
+	case "ollama":
+		return NewOllamaClient(modelConfig)
 	default:
 		return nil, fmt.Errorf("unsupported model: %s", modelName)
 	}
@@ -197,6 +299,10 @@ func getDefaultFallbackSequence(primaryModel string) []string {
 		return []string{"openai", "gemini"}
 	case "gemini":
 		return []string{"openai", "claude"}
+	case "ollama":
+		// Prompts meant for a local model only leave the machine when
+		// fallback models are configured
+		return nil
 	default:
 		// If unknown model, try all known models
 		return []string{"openai", "claude", "gemini"}
@@ -208,3 +314,12 @@ func generateExecutionID(modelName, promptName string) string {
 	timestamp := time.Now().Format("20060102150405")
 	return fmt.Sprintf("%s-%s-%s", modelName, promptName, timestamp)
 }
+
+// requestContext limits a single API request to the configured request timeout
+func requestContext(ctx context.Context, modelConfig *config.ModelConfig) (context.Context, context.CancelFunc) {
+	timeout := config.DefaultRequestTimeout
+	if modelConfig != nil && modelConfig.RequestTimeout > 0 {
+		timeout = modelConfig.RequestTimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -1,14 +1,17 @@
 package models
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/rshade/cronai/internal/pool"
 	"github.com/rshade/cronai/pkg/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // MockModelClient is a simple mocked version of the ModelClient interface
@@ -19,9 +22,10 @@ type MockModelClient struct {
 	Model        string
 	ExecuteCount int
 	Variables    map[string]string
+	Usage        Usage
 }
 
-func (m *MockModelClient) Execute(_ string) (*ModelResponse, error) {
+func (m *MockModelClient) Execute(_ context.Context, _ string) (*ModelResponse, error) {
 	m.ExecuteCount++
 	if m.ShouldFail {
 		return nil, fmt.Errorf("%s", m.ErrorMessage)
@@ -31,6 +35,7 @@ func (m *MockModelClient) Execute(_ string) (*ModelResponse, error) {
 		Model:     m.Model,
 		Variables: m.Variables,
 		Timestamp: time.Now(),
+		Usage:     m.Usage,
 	}, nil
 }
 
@@ -38,7 +43,7 @@ func (m *MockModelClient) Execute(_ string) (*ModelResponse, error) {
 func TestExecuteModelBasic(t *testing.T) {
 	t.Run("should fail when model config is invalid", func(t *testing.T) {
 		// Execute with invalid parameters
-		response, err := ExecuteModel("openai", "test prompt", nil, "temperature=2.0") // Invalid temperature
+		response, err := ExecuteModel(context.Background(), "openai", "test prompt", nil, "temperature=2.0") // Invalid temperature
 
 		// Assertions
 		assert.Error(t, err)
@@ -69,7 +74,7 @@ func TestExecuteModelBasic(t *testing.T) {
 		defer func() { createModelClient = originalCreateModelClient }()
 
 		// Execute
-		response, err := ExecuteModel("openai", "test prompt", nil, "temperature=0.5")
+		response, err := ExecuteModel(context.Background(), "openai", "test prompt", nil, "temperature=0.5")
 
 		// Assertions
 		assert.NoError(t, err)
@@ -101,7 +106,7 @@ func TestExecuteModelBasic(t *testing.T) {
 
 		// Execute with variables
 		variables := map[string]string{"key": "value"}
-		response, err := ExecuteModel("openai", "test prompt", variables, "")
+		response, err := ExecuteModel(context.Background(), "openai", "test prompt", variables, "")
 
 		// Assertions
 		assert.NoError(t, err)
@@ -174,6 +179,12 @@ func TestCreateModelClient(t *testing.T) {
 		assert.NotNil(t, client)
 		assert.IsType(t, &GeminiClient{}, client)
 	})
+
+	t.Run("should create Ollama client", func(t *testing.T) {
+		client, err := defaultCreateModelClient("ollama", &config.ModelConfig{})
+		assert.NoError(t, err)
+		assert.IsType(t, &OllamaClient{}, client)
+	})
 }
 
 // TestFallbackMechanism tests the fallback mechanism
@@ -217,7 +228,7 @@ func TestFallbackMechanism(t *testing.T) {
 			return nil, errors.New("should not be called")
 		}
 
-		result := executeWithFallback("openai", "test prompt", nil, modelConfig, "test")
+		result := executeWithFallback(context.Background(), "openai", "test prompt", nil, modelConfig, "test")
 
 		assert.NotNil(t, result.Response)
 		assert.Equal(t, "OpenAI success", result.Response.Content)
@@ -245,7 +256,7 @@ func TestFallbackMechanism(t *testing.T) {
 			}
 		}
 
-		result := executeWithFallback("openai", "test prompt", nil, modelConfig, "test")
+		result := executeWithFallback(context.Background(), "openai", "test prompt", nil, modelConfig, "test")
 
 		assert.NotNil(t, result.Response)
 		assert.Equal(t, "Claude success", result.Response.Content)
@@ -265,7 +276,7 @@ func TestFallbackMechanism(t *testing.T) {
 			}, nil
 		}
 
-		result := executeWithFallback("openai", "test prompt", nil, modelConfig, "test")
+		result := executeWithFallback(context.Background(), "openai", "test prompt", nil, modelConfig, "test")
 
 		assert.Nil(t, result.Response)
 		assert.Len(t, result.Errors, 6) // 2 attempts per model * 3 models
@@ -300,7 +311,7 @@ func TestFallbackMechanism(t *testing.T) {
 			return nil, fmt.Errorf("unexpected model: %s", modelName)
 		}
 
-		result := executeWithFallback("openai", "test prompt", nil, modelConfig, "test")
+		result := executeWithFallback(context.Background(), "openai", "test prompt", nil, modelConfig, "test")
 
 		assert.NotNil(t, result.Response)
 		assert.Equal(t, "OpenAI retry success", result.Response.Content)
@@ -539,6 +550,11 @@ func TestGetDefaultFallbackSequence(t *testing.T) {
 		expected := []string{"claude", "gemini"}
 		assert.Equal(t, expected, sequence)
 	})
+
+	t.Run("ollama model has no default fallback", func(t *testing.T) {
+		sequence := getDefaultFallbackSequence("ollama")
+		assert.Empty(t, sequence)
+	})
 }
 
 // TestExecuteModelEdgeCases tests edge cases in ExecuteModel
@@ -552,7 +568,7 @@ func TestExecuteModelEdgeCases(t *testing.T) {
 			return nil, errors.New("failed to create client")
 		}
 
-		response, err := ExecuteModel("openai", "test prompt", nil, "")
+		response, err := ExecuteModel(context.Background(), "openai", "test prompt", nil, "")
 		assert.Error(t, err)
 		assert.Nil(t, response)
 		assert.Contains(t, err.Error(), "failed to create client")
@@ -567,9 +583,122 @@ func TestExecuteModelEdgeCases(t *testing.T) {
 			}, nil
 		}
 
-		response, err := ExecuteModel("openai", "test prompt", nil, "")
+		response, err := ExecuteModel(context.Background(), "openai", "test prompt", nil, "")
 		assert.Error(t, err)
 		assert.Nil(t, response)
 		assert.Contains(t, err.Error(), "all models failed")
 	})
+
+	t.Run("cancellation aborts the request and skips fallbacks", func(t *testing.T) {
+		var attempts []string
+		createModelClient = func(modelName string, _ *config.ModelConfig) (ModelClient, error) {
+			attempts = append(attempts, modelName)
+			return blockingModelClient{}, nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		response, err := ExecuteModel(ctx, "openai", "test prompt", nil, "")
+		assert.Nil(t, response)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Contains(t, err.Error(), "aborted after 1 attempts")
+		assert.Equal(t, []string{"openai"}, attempts)
+	})
+}
+
+// blockingModelClient answers only when its context ends
+type blockingModelClient struct{}
+
+func (blockingModelClient) Execute(ctx context.Context, _ string) (*ModelResponse, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestRequestContext(t *testing.T) {
+	modelConfig := config.DefaultModelConfig()
+	modelConfig.RequestTimeout = time.Minute
+
+	ctx, cancel := requestContext(context.Background(), modelConfig)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// A shorter deadline of the caller wins
+	parent, parentCancel := context.WithTimeout(context.Background(), time.Second)
+	defer parentCancel()
+	ctx, cancel = requestContext(parent, nil)
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	assert.WithinDuration(t, time.Now().Add(time.Second), deadline, time.Second)
+}
+
+func TestProviderOf(t *testing.T) {
+	assert.Equal(t, "openai", ProviderOf(&OpenAIClient{}))
+	assert.Equal(t, "claude", ProviderOf(&ClaudeClient{}))
+	assert.Equal(t, "gemini", ProviderOf(&GeminiClient{}))
+	assert.Equal(t, "", ProviderOf(&MockModelClient{}))
+}
+
+func TestExecuteModelCost(t *testing.T) {
+	originalCreateModelClient := createModelClient
+	defer func() { createModelClient = originalCreateModelClient }()
+
+	usage := Usage{PromptTokens: 200_000, CompletionTokens: 50_000, CachedTokens: 100_000}
+	client := &MockModelClient{Content: "Weekly report", Model: "gpt-4o-2024-08-06", Usage: usage}
+	createModelClient = func(_ string, _ *config.ModelConfig) (ModelClient, error) {
+		return client, nil
+	}
+	t.Setenv("MODEL_PRICES", "")
+
+	// 100k prompt tokens at $2.50, 100k cached at $1.25 and 50k completion at $10 per million
+	response, err := ExecuteModel(context.Background(), "openai", "test prompt", nil, "")
+	assert.NoError(t, err)
+	assert.Equal(t, usage, response.Usage)
+	assert.InDelta(t, 0.25+0.125+0.5, response.Cost, 1e-9)
+
+	// Models without a price have no cost
+	client.Model = "llama3"
+	response, err = ExecuteModel(context.Background(), "openai", "test prompt", nil, "")
+	assert.NoError(t, err)
+	assert.Zero(t, response.Cost)
+
+	// MODEL_PRICES prices them
+	t.Setenv("MODEL_PRICES", "llama3=1:2")
+	response, err = ExecuteModel(context.Background(), "openai", "test prompt", nil, "")
+	assert.NoError(t, err)
+	assert.InDelta(t, 0.2+0.1, response.Cost, 1e-9)
+}
+
+func TestFallbackProviderLimit(t *testing.T) {
+	originalCreateModelClient := createModelClient
+	defer func() { createModelClient = originalCreateModelClient }()
+	originalPool := pool.Default()
+	defer pool.SetDefault(originalPool)
+	pool.SetDefault(pool.New(pool.WithProviderLimit("claude", 1)))
+
+	claude := &MockModelClient{Content: "from claude", Model: "claude"}
+	createModelClient = func(modelName string, _ *config.ModelConfig) (ModelClient, error) {
+		if modelName == "claude" {
+			return claude, nil
+		}
+		return &MockModelClient{ShouldFail: true, ErrorMessage: "rate limited"}, nil
+	}
+
+	// Another execution holds the only claude slot
+	release, err := pool.Default().AcquireProvider(context.Background(), "claude")
+	require.NoError(t, err)
+
+	// Falling back from openai waits for it rather than exceeding the cap
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = ExecuteModel(ctx, "openai", "test prompt", nil, "fallback_models=claude")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, claude.ExecuteCount)
+
+	release()
+	response, err := ExecuteModel(context.Background(), "openai", "test prompt", nil, "fallback_models=claude")
+	require.NoError(t, err)
+	assert.Equal(t, "from claude", response.Content)
+	assert.Equal(t, 1, claude.ExecuteCount)
 }
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// OllamaClient handles interactions with a local Ollama server
+type OllamaClient struct {
+	httpClient *http.Client
+	baseURL    string
+	config     *config.ModelConfig
+}
+
+// ollamaMessage is a message of an Ollama chat
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ollamaChatRequest is the body of an Ollama /api/chat request
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ollamaChatResponse is an Ollama /api/chat response, or a chunk of a
+// streamed one. The final chunk is done and reports the token counts.
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error"`
+}
+
+// NewOllamaClient creates a new Ollama client. Ollama runs locally, so no
+// API key is needed.
+func NewOllamaClient(modelConfig *config.ModelConfig) (*OllamaClient, error) {
+	baseURL := config.DefaultOllamaURL
+	if modelConfig != nil && modelConfig.OllamaConfig != nil && modelConfig.OllamaConfig.BaseURL != "" {
+		baseURL = modelConfig.OllamaConfig.BaseURL
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid Ollama base URL: %s", baseURL)
+	}
+
+	return &OllamaClient{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		config:     modelConfig,
+	}, nil
+}
+
+// Execute sends a prompt to Ollama and returns the model response
+func (c *OllamaClient) Execute(ctx context.Context, promptContent string) (*ModelResponse, error) {
+	ctx, cancel := requestContext(ctx, c.config)
+	defer cancel()
+
+	body, err := c.chat(ctx, promptContent, false)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+
+	var resp ollamaChatResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("ollama API error: invalid response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("ollama API error: %s", resp.Error)
+	}
+	if resp.Message.Content == "" {
+		return nil, fmt.Errorf("no response from Ollama")
+	}
+
+	return &ModelResponse{
+		Content:     resp.Message.Content,
+		Model:       c.getModelName(),
+		Timestamp:   time.Now(),
+		PromptName:  "direct", // Will be overridden by the caller if needed
+		ExecutionID: generateExecutionID("ollama", "direct"),
+		Usage:       ollamaUsage(resp),
+	}, nil
+}
+
+// ExecuteStream sends a prompt to Ollama, passing the response to handler as
+// it arrives, and returns the assembled model response
+func (c *OllamaClient) ExecuteStream(ctx context.Context, promptContent string, handler StreamHandler) (*ModelResponse, error) {
+	ctx, touch, cancel := streamContext(ctx, c.config)
+	defer cancel()
+
+	body, err := c.chat(ctx, promptContent, true)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("ollama API error: %w", context.Cause(ctx))
+		}
+		return nil, err
+	}
+	defer func() { _ = body.Close() }()
+
+	// The stream is a JSON object per line
+	var content strings.Builder
+	var usage Usage
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		touch()
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("ollama API error: invalid stream data: %w", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama API error: %s", chunk.Error)
+		}
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			handler.Delta(chunk.Message.Content)
+		}
+		if chunk.Done {
+			usage = ollamaUsage(chunk)
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ollama API error: %w", streamError(ctx, err))
+	}
+
+	if content.Len() == 0 {
+		return nil, fmt.Errorf("no response from Ollama")
+	}
+	return &ModelResponse{
+		Content:     content.String(),
+		Model:       c.getModelName(),
+		Timestamp:   time.Now(),
+		PromptName:  "direct", // Will be overridden by the caller if needed
+		ExecutionID: generateExecutionID("ollama", "direct"),
+		Usage:       usage,
+	}, nil
+}
+
+// chat posts a chat request to the Ollama server and returns the body of its
+// successful response
+func (c *OllamaClient) chat(ctx context.Context, promptContent string, stream bool) (io.ReadCloser, error) {
+	payload, err := json.Marshal(c.chatRequest(promptContent, stream))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode Ollama request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama API error: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		var failure ollamaChatResponse
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		if json.Unmarshal(data, &failure) == nil && failure.Error != "" {
+			return nil, fmt.Errorf("ollama API error: %s (status %d)", failure.Error, resp.StatusCode)
+		}
+		return nil, fmt.Errorf("ollama API error: status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return resp.Body, nil
+}
+
+// chatRequest builds the chat request of a prompt with the configured
+// parameters
+func (c *OllamaClient) chatRequest(promptContent string, stream bool) ollamaChatRequest {
+	var messages []ollamaMessage
+	if c.config != nil && c.config.OllamaConfig != nil && c.config.OllamaConfig.SystemMessage != "" {
+		messages = append(messages, ollamaMessage{Role: "system", Content: c.config.OllamaConfig.SystemMessage})
+	}
+	messages = append(messages, ollamaMessage{Role: "user", Content: promptContent})
+
+	return ollamaChatRequest{
+		Model:    c.getModelName(),
+		Messages: messages,
+		Stream:   stream,
+		Options:  c.options(),
+	}
+}
+
+// options returns the model options of a request. Unset values are left to
+// the model's own defaults.
+func (c *OllamaClient) options() map[string]interface{} {
+	if c.config == nil {
+		return nil
+	}
+
+	options := map[string]interface{}{
+		"temperature": c.config.Temperature,
+		"top_p":       c.config.TopP,
+	}
+	if c.config.MaxTokens > 0 {
+		options["num_predict"] = c.config.MaxTokens
+	}
+	if c.config.FrequencyPenalty != 0 {
+		options["frequency_penalty"] = c.config.FrequencyPenalty
+	}
+	if c.config.PresencePenalty != 0 {
+		options["presence_penalty"] = c.config.PresencePenalty
+	}
+	if ollamaConfig := c.config.OllamaConfig; ollamaConfig != nil {
+		if ollamaConfig.NumCtx > 0 {
+			options["num_ctx"] = ollamaConfig.NumCtx
+		}
+		if ollamaConfig.Temperature != nil {
+			options["temperature"] = *ollamaConfig.Temperature
+		}
+	}
+	return options
+}
+
+// ollamaUsage converts the token counts of the final Ollama response
+func ollamaUsage(resp ollamaChatResponse) Usage {
+	return Usage{
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+	}
+}
+
+// ProviderName returns the provider this client calls
+func (c *OllamaClient) ProviderName() string {
+	return "ollama"
+}
+
+// getModelName returns the Ollama model name to use
+func (c *OllamaClient) getModelName() string {
+	if c.config != nil && c.config.OllamaConfig != nil && c.config.OllamaConfig.Model != "" {
+		return c.config.OllamaConfig.Model
+	}
+	// Default to a reasonable model if not specified
+	return "llama3.2"
+}
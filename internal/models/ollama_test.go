@@ -0,0 +1,174 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ollamaServer stands in for an Ollama server, recording the chat requests
+// it receives and answering them with lines
+func ollamaServer(t *testing.T, status int, lines []string) (*httptest.Server, *[]ollamaChatRequest) {
+	t.Helper()
+	var requests []ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/chat" {
+			http.NotFound(w, r)
+			return
+		}
+		var request ollamaChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, request)
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(status)
+		for _, line := range lines {
+			_, _ = fmt.Fprintln(w, line)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// ollamaConfig returns the default configuration for the server at baseURL
+func ollamaConfig(baseURL string) *config.ModelConfig {
+	modelConfig := config.DefaultModelConfig()
+	modelConfig.OllamaConfig.BaseURL = baseURL
+	return modelConfig
+}
+
+func TestNewOllamaClient(t *testing.T) {
+	client, err := NewOllamaClient(&config.ModelConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultOllamaURL, client.baseURL)
+	assert.Equal(t, "llama3.2", client.getModelName())
+	assert.Equal(t, "ollama", client.ProviderName())
+
+	for _, baseURL := range []string{"localhost:11434", "ftp://ollama", "http://"} {
+		_, err := NewOllamaClient(ollamaConfig(baseURL))
+		assert.ErrorContains(t, err, "invalid Ollama base URL", baseURL)
+	}
+}
+
+func TestOllamaClientExecute(t *testing.T) {
+	server, requests := ollamaServer(t, http.StatusOK, []string{
+		`{"model":"mistral","message":{"role":"assistant","content":"Weekly report"},"done":true,"prompt_eval_count":26,"eval_count":3}`,
+	})
+	modelConfig := ollamaConfig(server.URL + "/")
+	require.NoError(t, modelConfig.UpdateFromParams(map[string]string{
+		"max_tokens":            "512",
+		"ollama.model":          "mistral",
+		"ollama.num_ctx":        "8192",
+		"ollama.temperature":    "1.2",
+		"ollama.system_message": "Be brief.",
+	}))
+
+	client, err := NewOllamaClient(modelConfig)
+	require.NoError(t, err)
+	response, err := client.Execute(context.Background(), "test prompt")
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly report", response.Content)
+	assert.Equal(t, "mistral", response.Model)
+	assert.Equal(t, Usage{PromptTokens: 26, CompletionTokens: 3}, response.Usage)
+
+	// The options are sent with the request, numbers decode as float64
+	require.Len(t, *requests, 1)
+	request := (*requests)[0]
+	assert.Equal(t, "mistral", request.Model)
+	assert.False(t, request.Stream)
+	assert.Equal(t, []ollamaMessage{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "test prompt"}}, request.Messages)
+	assert.Equal(t, map[string]interface{}{
+		"temperature": 1.2,
+		"top_p":       1.0,
+		"num_predict": 512.0,
+		"num_ctx":     8192.0,
+	}, request.Options)
+}
+
+func TestOllamaClientExecuteStream(t *testing.T) {
+	server, requests := ollamaServer(t, http.StatusOK, []string{
+		`{"model":"llama3.2","message":{"role":"assistant","content":"Weekly"},"done":false}`,
+		``,
+		`{"model":"llama3.2","message":{"role":"assistant","content":" report"},"done":false}`,
+		`{"model":"llama3.2","message":{"role":"assistant","content":""},"done":true,"prompt_eval_count":12,"eval_count":2}`,
+	})
+
+	client, err := NewOllamaClient(ollamaConfig(server.URL))
+	require.NoError(t, err)
+	handler := &recordingHandler{}
+	response, err := client.ExecuteStream(context.Background(), "test prompt", handler)
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly report", response.Content)
+	assert.Equal(t, "llama3.2", response.Model)
+	assert.Equal(t, Usage{PromptTokens: 12, CompletionTokens: 2}, response.Usage)
+	assert.Equal(t, []string{"Weekly", " report"}, handler.events)
+	require.Len(t, *requests, 1)
+	assert.True(t, (*requests)[0].Stream)
+	assert.Equal(t, 0.7, (*requests)[0].Options["temperature"])
+}
+
+func TestOllamaClientErrors(t *testing.T) {
+	// A model that hasn't been pulled
+	server, _ := ollamaServer(t, http.StatusNotFound, []string{`{"error":"model \"mistral\" not found, try pulling it first"}`})
+	client, err := NewOllamaClient(ollamaConfig(server.URL))
+	require.NoError(t, err)
+	_, err = client.Execute(context.Background(), "test prompt")
+	assert.EqualError(t, err, `ollama API error: model "mistral" not found, try pulling it first (status 404)`)
+
+	// An error part way through a stream
+	server, _ = ollamaServer(t, http.StatusOK, []string{
+		`{"model":"llama3.2","message":{"role":"assistant","content":"Wee"},"done":false}`,
+		`{"error":"out of memory"}`,
+	})
+	client, err = NewOllamaClient(ollamaConfig(server.URL))
+	require.NoError(t, err)
+	handler := &recordingHandler{}
+	_, err = client.ExecuteStream(context.Background(), "test prompt", handler)
+	assert.EqualError(t, err, "ollama API error: out of memory")
+	assert.Equal(t, []string{"Wee"}, handler.events)
+
+	// No server listening
+	server.Close()
+	_, err = client.Execute(context.Background(), "test prompt")
+	assert.ErrorContains(t, err, "ollama API error")
+}
+
+func TestOllamaStaysLocal(t *testing.T) {
+	originalCreateModelClient := createModelClient
+	defer func() { createModelClient = originalCreateModelClient }()
+	var hosted []string
+	createModelClient = func(modelName string, modelConfig *config.ModelConfig) (ModelClient, error) {
+		if modelName == "ollama" {
+			return originalCreateModelClient(modelName, modelConfig)
+		}
+		hosted = append(hosted, modelName)
+		return &MockModelClient{Model: modelName, Content: "from " + modelName}, nil
+	}
+
+	server, requests := ollamaServer(t, http.StatusInternalServerError, []string{`{"error":"model runner crashed"}`})
+	t.Setenv("OLLAMA_HOST", server.URL)
+	t.Setenv("MODEL_FALLBACK_MODELS", "")
+
+	// A failing local model doesn't send the prompt to a hosted provider
+	_, err := ExecuteModel(context.Background(), "ollama", "test prompt", nil, "")
+	assert.ErrorContains(t, err, "model runner crashed")
+	assert.Len(t, *requests, 1)
+	assert.Empty(t, hosted)
+
+	// unless fallback models are configured
+	response, err := ExecuteModel(context.Background(), "ollama", "test prompt", nil, "fallback_models=claude")
+	require.NoError(t, err)
+	assert.Equal(t, "from claude", response.Content)
+	assert.Equal(t, []string{"claude"}, hosted)
+}
@@ -2,8 +2,12 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rshade/cronai/pkg/config"
@@ -12,8 +16,9 @@ import (
 
 // OpenAIClient handles interactions with OpenAI API
 type OpenAIClient struct {
-	client *openai.Client
-	config *config.ModelConfig
+	client   *openai.Client
+	config   *config.ModelConfig
+	provider string // name of the named provider the client calls, empty for OpenAI itself
 }
 
 // NewOpenAIClient creates a new OpenAI client
@@ -42,29 +47,12 @@ func NewOpenAIClient(modelConfig *config.ModelConfig) (*OpenAIClient, error) {
 }
 
 // Execute sends a prompt to OpenAI and returns the model response
-func (c *OpenAIClient) Execute(promptContent string) (*ModelResponse, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+func (c *OpenAIClient) Execute(ctx context.Context, promptContent string) (*ModelResponse, error) {
+	ctx, cancel := requestContext(ctx, c.config)
 	defer cancel()
 
 	// Create the chat completion request
-	req := openai.ChatCompletionRequest{
-		Model:       c.getModelName(),
-		Temperature: float32(c.config.Temperature),
-		MaxTokens:   c.config.MaxTokens,
-		TopP:        float32(c.config.TopP),
-		Messages: []openai.ChatCompletionMessage{
-			{
-				Role:    openai.ChatMessageRoleSystem,
-				Content: c.getSystemMessage(),
-			},
-			{
-				Role:    openai.ChatMessageRoleUser,
-				Content: promptContent,
-			},
-		},
-		FrequencyPenalty: float32(c.config.FrequencyPenalty),
-		PresencePenalty:  float32(c.config.PresencePenalty),
-	}
+	req := c.request(promptContent)
 
 	// Make the API call
 	resp, err := c.client.CreateChatCompletion(ctx, req)
@@ -82,15 +70,112 @@ func (c *OpenAIClient) Execute(promptContent string) (*ModelResponse, error) {
 		Content:   resp.Choices[0].Message.Content,
 		Model:     resp.Model,
 		Timestamp: time.Now(),
+		Usage:     openAIUsage(resp.Usage),
 	}
 
 	// Add additional metadata
 	modelResponse.PromptName = "direct" // Will be overridden by the caller if needed
-	modelResponse.ExecutionID = generateExecutionID("openai", modelResponse.PromptName)
+	modelResponse.ExecutionID = generateExecutionID(c.ProviderName(), modelResponse.PromptName)
 
 	return modelResponse, nil
 }
 
+// ExecuteStream sends a prompt to OpenAI, passing the response to handler as
+// it arrives, and returns the assembled model response
+func (c *OpenAIClient) ExecuteStream(ctx context.Context, promptContent string, handler StreamHandler) (*ModelResponse, error) {
+	ctx, touch, cancel := streamContext(ctx, c.config)
+	defer cancel()
+
+	req := c.request(promptContent)
+	req.Stream = true
+	// The last chunk reports the tokens used
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
+	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("openai API error: %w", streamError(ctx, err))
+	}
+	defer func() {
+		if closeErr := stream.Close(); closeErr != nil {
+			log.Printf("Warning: failed to close OpenAI stream: %v", closeErr)
+		}
+	}()
+
+	var content strings.Builder
+	var model string
+	var usage Usage
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("openai API error: %w", streamError(ctx, err))
+		}
+		touch()
+		if chunk.Model != "" {
+			model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			usage = openAIUsage(*chunk.Usage)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			handler.Delta(chunk.Choices[0].Delta.Content)
+		}
+	}
+
+	if content.Len() == 0 {
+		return nil, fmt.Errorf("no response from OpenAI")
+	}
+	return &ModelResponse{
+		Content:     content.String(),
+		Model:       model,
+		Timestamp:   time.Now(),
+		PromptName:  "direct", // Will be overridden by the caller if needed
+		ExecutionID: generateExecutionID(c.ProviderName(), "direct"),
+		Usage:       usage,
+	}, nil
+}
+
+// openAIUsage converts the token counts of an OpenAI response
+func openAIUsage(usage openai.Usage) Usage {
+	u := Usage{PromptTokens: usage.PromptTokens, CompletionTokens: usage.CompletionTokens}
+	if usage.PromptTokensDetails != nil {
+		u.CachedTokens = usage.PromptTokensDetails.CachedTokens
+	}
+	return u
+}
+
+// request returns the chat completion request for a prompt
+func (c *OpenAIClient) request(promptContent string) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model:       c.getModelName(),
+		Temperature: float32(c.config.Temperature),
+		MaxTokens:   c.config.MaxTokens,
+		TopP:        float32(c.config.TopP),
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: c.getSystemMessage(),
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: promptContent,
+			},
+		},
+		FrequencyPenalty: float32(c.config.FrequencyPenalty),
+		PresencePenalty:  float32(c.config.PresencePenalty),
+	}
+}
+
+// ProviderName returns the provider this client calls
+func (c *OpenAIClient) ProviderName() string {
+	if c.provider != "" {
+		return c.provider
+	}
+	return "openai"
+}
+
 // getModelName returns the OpenAI model name to use
 func (c *OpenAIClient) getModelName() string {
 	if c.config != nil && c.config.OpenAIConfig != nil && c.config.OpenAIConfig.Model != "" {
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/sashabaranov/go-openai"
+)
+
+// providersKey is the context key of the named providers of an execution
+type providersKey struct{}
+
+// WithProviders returns a context whose model calls can use the named
+// providers, by name, in addition to the built-in ones
+func WithProviders(ctx context.Context, providers map[string]config.ProviderConfig) context.Context {
+	return context.WithValue(ctx, providersKey{}, providers)
+}
+
+// providerFromContext returns the named provider of the execution called name
+func providerFromContext(ctx context.Context, name string) (config.ProviderConfig, bool) {
+	providers, _ := ctx.Value(providersKey{}).(map[string]config.ProviderConfig)
+	provider, ok := providers[name]
+	return provider, ok
+}
+
+// providerNames returns the names of the named providers of the execution
+func providerNames(ctx context.Context) map[string]bool {
+	providers, _ := ctx.Value(providersKey{}).(map[string]config.ProviderConfig)
+	if len(providers) == 0 {
+		return nil
+	}
+	names := make(map[string]bool, len(providers))
+	for name := range providers {
+		names[name] = true
+	}
+	return names
+}
+
+// newModelClient creates the client of a named provider of the execution,
+// or else of a built-in provider
+func newModelClient(ctx context.Context, modelName string, modelConfig *config.ModelConfig) (ModelClient, error) {
+	if provider, ok := providerFromContext(ctx, modelName); ok {
+		return NewProviderClient(provider, modelConfig)
+	}
+	return createModelClient(modelName, modelConfig)
+}
+
+// NewProviderClient creates a client for a named OpenAI-compatible or Azure
+// OpenAI provider. It calls the model given by the model parameter, or else
+// the provider's own model, with the OpenAI settings of modelConfig.
+func NewProviderClient(provider config.ProviderConfig, modelConfig *config.ModelConfig) (*OpenAIClient, error) {
+	var apiKey string
+	if provider.KeyEnv != "" {
+		if apiKey = os.Getenv(provider.KeyEnv); apiKey == "" {
+			return nil, fmt.Errorf("%s environment variable not set", provider.KeyEnv)
+		}
+	}
+
+	model := provider.Model
+	if modelConfig != nil && modelConfig.Model != "" {
+		model = modelConfig.Model
+	}
+
+	var clientConfig openai.ClientConfig
+	switch provider.Type {
+	case config.ProviderOpenAICompatible:
+		clientConfig = openai.DefaultConfig(apiKey)
+		clientConfig.BaseURL = provider.BaseURL
+	case config.ProviderAzureOpenAI:
+		if apiKey == "" {
+			return nil, fmt.Errorf("provider %s: azure-openai needs an API key, set key_env", provider.Name)
+		}
+		clientConfig = openai.DefaultAzureConfig(apiKey, provider.BaseURL)
+		clientConfig.APIVersion = config.DefaultAzureAPIVersion
+		if provider.APIVersion != "" {
+			clientConfig.APIVersion = provider.APIVersion
+		}
+		// Azure routes requests by deployment rather than model. Without a
+		// deployment of its own the provider sends each model to the
+		// deployment of the same name.
+		deployment := provider.Deployment
+		clientConfig.AzureModelMapperFunc = func(model string) string {
+			if deployment != "" {
+				return deployment
+			}
+			return model
+		}
+		if model == "" {
+			model = deployment
+		}
+	default:
+		return nil, fmt.Errorf("provider %s: unknown type '%s'", provider.Name, provider.Type)
+	}
+	if model == "" {
+		return nil, fmt.Errorf("provider %s has no model, set model on the provider or the model parameter", provider.Name)
+	}
+
+	// The other OpenAI settings, such as the system message, apply as they are
+	callConfig := config.ModelConfig{}
+	if modelConfig != nil {
+		callConfig = *modelConfig
+	}
+	openAIConfig := config.OpenAIConfig{}
+	if callConfig.OpenAIConfig != nil {
+		openAIConfig = *callConfig.OpenAIConfig
+	}
+	openAIConfig.Model = model
+	callConfig.OpenAIConfig = &openAIConfig
+
+	return &OpenAIClient{
+		client:   openai.NewClientWithConfig(clientConfig),
+		config:   &callConfig,
+		provider: provider.Name,
+	}, nil
+}
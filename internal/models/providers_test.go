@@ -0,0 +1,171 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chatRequest is a chat completion request as a provider receives it
+type chatRequest struct {
+	Path       string
+	APIVersion string
+	Auth       string
+	APIKey     string
+	Model      string
+}
+
+// providerServer stands in for an OpenAI-compatible server, recording the
+// chat completion requests it receives and answering them with status
+func providerServer(t *testing.T, status int) (*httptest.Server, *[]chatRequest) {
+	t.Helper()
+	var requests []chatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		requests = append(requests, chatRequest{
+			Path:       r.URL.Path,
+			APIVersion: r.URL.Query().Get("api-version"),
+			Auth:       r.Header.Get("Authorization"),
+			APIKey:     r.Header.Get("api-key"),
+			Model:      body.Model,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = w.Write([]byte(`{"error":{"message":"upstream unavailable","type":"server_error"}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"model":"` + body.Model + `","choices":[{"index":0,"message":{"role":"assistant","content":"Weekly report"}}],"usage":{"prompt_tokens":20,"completion_tokens":3}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestNewProviderClient(t *testing.T) {
+	t.Setenv("GATEWAY_KEY", "")
+	gateway := config.ProviderConfig{Name: "gateway", Type: config.ProviderOpenAICompatible, BaseURL: "https://gateway.internal/v1", KeyEnv: "GATEWAY_KEY"}
+
+	_, err := NewProviderClient(gateway, config.DefaultModelConfig())
+	assert.EqualError(t, err, "GATEWAY_KEY environment variable not set")
+
+	t.Setenv("GATEWAY_KEY", "secret")
+	_, err = NewProviderClient(gateway, config.DefaultModelConfig())
+	assert.ErrorContains(t, err, "provider gateway has no model")
+
+	gateway.Model = "llama3-8b"
+	client, err := NewProviderClient(gateway, config.DefaultModelConfig())
+	require.NoError(t, err)
+	assert.Equal(t, "gateway", client.ProviderName())
+	assert.Equal(t, "llama3-8b", client.config.OpenAIConfig.Model)
+
+	// The model parameter picks another model of the provider
+	modelConfig := config.DefaultModelConfig()
+	modelConfig.Model = "llama3-70b"
+	client, err = NewProviderClient(gateway, modelConfig)
+	require.NoError(t, err)
+	assert.Equal(t, "llama3-70b", client.config.OpenAIConfig.Model)
+	assert.Equal(t, "gpt-3.5-turbo", modelConfig.OpenAIConfig.Model, "the OpenAI settings are left alone")
+
+	_, err = NewProviderClient(config.ProviderConfig{Name: "azure", Type: config.ProviderAzureOpenAI, BaseURL: "https://example.openai.azure.com"}, modelConfig)
+	assert.EqualError(t, err, "provider azure: azure-openai needs an API key, set key_env")
+
+	_, err = NewProviderClient(config.ProviderConfig{Name: "vllm", Type: "vllm"}, modelConfig)
+	assert.EqualError(t, err, "provider vllm: unknown type 'vllm'")
+}
+
+func TestExecuteModelNamedProvider(t *testing.T) {
+	server, requests := providerServer(t, http.StatusOK)
+	t.Setenv("GATEWAY_KEY", "secret")
+	ctx := WithProviders(context.Background(), map[string]config.ProviderConfig{
+		"gateway": {Name: "gateway", Type: config.ProviderOpenAICompatible, BaseURL: server.URL + "/v1", KeyEnv: "GATEWAY_KEY"},
+	})
+
+	response, err := ExecuteModel(ctx, "gateway", "test prompt", nil, "model=llama3-70b,temperature=0.2")
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly report", response.Content)
+	assert.Equal(t, "llama3-70b", response.Model)
+	assert.Equal(t, "gateway", response.Provider)
+	assert.Equal(t, Usage{PromptTokens: 20, CompletionTokens: 3}, response.Usage)
+	require.Len(t, *requests, 1)
+	assert.Equal(t, chatRequest{Path: "/v1/chat/completions", Auth: "Bearer secret", Model: "llama3-70b"}, (*requests)[0])
+}
+
+func TestExecuteModelAzureProvider(t *testing.T) {
+	server, requests := providerServer(t, http.StatusOK)
+	t.Setenv("AZURE_KEY", "secret")
+	providers := map[string]config.ProviderConfig{
+		"azure": {Name: "azure", Type: config.ProviderAzureOpenAI, BaseURL: server.URL, KeyEnv: "AZURE_KEY", Deployment: "gpt-4o-prod"},
+		"azure-next": {Name: "azure-next", Type: config.ProviderAzureOpenAI, BaseURL: server.URL, KeyEnv: "AZURE_KEY",
+			APIVersion: "2024-10-21"},
+	}
+	ctx := WithProviders(context.Background(), providers)
+
+	// Requests go to the deployment of the provider, at the default API version
+	response, err := ExecuteModel(ctx, "azure", "test prompt", nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "azure", response.Provider)
+
+	// Without a deployment, the model names the deployment
+	_, err = ExecuteModel(ctx, "azure-next", "test prompt", nil, "model=gpt-4o-mini")
+	require.NoError(t, err)
+
+	require.Len(t, *requests, 2)
+	assert.Equal(t, chatRequest{
+		Path: "/openai/deployments/gpt-4o-prod/chat/completions", APIVersion: config.DefaultAzureAPIVersion, APIKey: "secret", Model: "gpt-4o-prod",
+	}, (*requests)[0])
+	assert.Equal(t, chatRequest{
+		Path: "/openai/deployments/gpt-4o-mini/chat/completions", APIVersion: "2024-10-21", APIKey: "secret", Model: "gpt-4o-mini",
+	}, (*requests)[1])
+}
+
+func TestNamedProviderFallback(t *testing.T) {
+	originalCreateModelClient := createModelClient
+	defer func() { createModelClient = originalCreateModelClient }()
+	var created []string
+	createModelClient = func(modelName string, _ *config.ModelConfig) (ModelClient, error) {
+		created = append(created, modelName)
+		return &MockModelClient{Model: modelName, Content: "from " + modelName}, nil
+	}
+
+	server, requests := providerServer(t, http.StatusServiceUnavailable)
+	ctx := WithProviders(context.Background(), map[string]config.ProviderConfig{
+		"gateway": {Name: "gateway", Type: config.ProviderOpenAICompatible, BaseURL: server.URL + "/v1", Model: "llama3-70b"},
+	})
+
+	// Prompts for a named provider stay there unless fallbacks are configured
+	_, err := ExecuteModel(ctx, "gateway", "test prompt", nil, "")
+	require.Error(t, err)
+	assert.Empty(t, created)
+	assert.NotEmpty(t, *requests)
+
+	response, err := ExecuteModel(ctx, "gateway", "test prompt", nil, "fallback_models=claude")
+	require.NoError(t, err)
+	assert.Equal(t, "from claude", response.Content)
+	assert.Equal(t, []string{"claude"}, created)
+
+	// Named providers can be fallbacks of the built-in ones
+	server, requests = providerServer(t, http.StatusOK)
+	ctx = WithProviders(context.Background(), map[string]config.ProviderConfig{
+		"gateway": {Name: "gateway", Type: config.ProviderOpenAICompatible, BaseURL: server.URL + "/v1", Model: "llama3-70b"},
+	})
+	createModelClient = func(modelName string, _ *config.ModelConfig) (ModelClient, error) {
+		return &MockModelClient{Model: modelName, ShouldFail: true, ErrorMessage: "rate limited"}, nil
+	}
+	response, err = ExecuteModel(ctx, "openai", "test prompt", nil, "fallback_models=gateway")
+	require.NoError(t, err)
+	assert.Equal(t, "gateway", response.Provider)
+	assert.Len(t, *requests, 1)
+}
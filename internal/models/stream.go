@@ -0,0 +1,109 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// StreamHandler receives the text of a response while the model produces it
+type StreamHandler interface {
+	// Delta receives the next piece of the response
+	Delta(text string)
+
+	// Restart discards the text received so far. It is called when an
+	// attempt fails part way and a retry or fallback model starts over.
+	Restart()
+}
+
+// StreamingClient is implemented by model clients that can stream their
+// response as it is produced
+type StreamingClient interface {
+	// ExecuteStream sends the prompt to the model, passing each piece of the
+	// response to handler as it arrives, and returns the assembled response.
+	// Cancelling ctx aborts the request.
+	ExecuteStream(ctx context.Context, promptContent string, handler StreamHandler) (*ModelResponse, error)
+}
+
+// ExecuteModelStream executes a prompt like ExecuteModel, passing the
+// response to handler as the model produces it. Models whose client can't
+// stream pass their whole response at once. Failed attempts that already
+// produced text are announced with Restart before the next attempt.
+func ExecuteModelStream(ctx context.Context, modelName string, promptContent string, variables map[string]string, modelParams string, handler StreamHandler) (*ModelResponse, error) {
+	return executeModel(ctx, modelName, promptContent, variables, modelParams, handler)
+}
+
+// executeAttempt runs a single attempt with client, streaming it to handler
+// when one is given
+func executeAttempt(ctx context.Context, client ModelClient, promptContent string, handler StreamHandler) (*ModelResponse, error) {
+	if handler == nil {
+		return client.Execute(ctx, promptContent)
+	}
+
+	tracked := &trackedHandler{handler: handler}
+	var response *ModelResponse
+	var err error
+	if streaming, ok := client.(StreamingClient); ok {
+		response, err = streaming.ExecuteStream(ctx, promptContent, tracked)
+	} else if response, err = client.Execute(ctx, promptContent); err == nil {
+		tracked.Delta(response.Content)
+	}
+	if err != nil && tracked.started {
+		handler.Restart()
+	}
+	return response, err
+}
+
+// trackedHandler records whether an attempt passed any text to its handler
+type trackedHandler struct {
+	handler StreamHandler
+	started bool
+}
+
+// Delta passes text on to the handler
+func (h *trackedHandler) Delta(text string) {
+	if text == "" {
+		return
+	}
+	h.started = true
+	h.handler.Delta(text)
+}
+
+// Restart passes the restart on to the handler
+func (h *trackedHandler) Restart() {
+	h.started = false
+	h.handler.Restart()
+}
+
+// streamContext limits a streamed request to the configured request timeout
+// without new data, rather than in total, so long responses aren't cut off
+// while the model is still producing them. Calling touch restarts the
+// timeout.
+func streamContext(ctx context.Context, modelConfig *config.ModelConfig) (streamCtx context.Context, touch func(), cancel context.CancelFunc) {
+	timeout := config.DefaultRequestTimeout
+	if modelConfig != nil && modelConfig.RequestTimeout > 0 {
+		timeout = modelConfig.RequestTimeout
+	}
+
+	streamCtx, cancelCause := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(timeout, func() {
+		cancelCause(fmt.Errorf("no response data for %s: %w", timeout, context.DeadlineExceeded))
+	})
+	touch = func() { timer.Reset(timeout) }
+	cancel = func() {
+		timer.Stop()
+		cancelCause(context.Canceled)
+	}
+	return streamCtx, touch, cancel
+}
+
+// streamError returns the reason a streamed request was cancelled, such as
+// its idle timeout, in place of the error the cancellation caused
+func streamError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return context.Cause(ctx)
+	}
+	return err
+}
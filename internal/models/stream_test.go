@@ -0,0 +1,157 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHandler records the stream it receives, "|" marks a restart
+type recordingHandler struct {
+	events []string
+}
+
+func (h *recordingHandler) Delta(text string) { h.events = append(h.events, text) }
+func (h *recordingHandler) Restart()          { h.events = append(h.events, "|") }
+
+// mockStreamingClient streams its deltas, failing after them when failAfter is set
+type mockStreamingClient struct {
+	MockModelClient
+	deltas    []string
+	failAfter bool
+}
+
+func (m *mockStreamingClient) ExecuteStream(_ context.Context, _ string, handler StreamHandler) (*ModelResponse, error) {
+	m.ExecuteCount++
+	for _, delta := range m.deltas {
+		handler.Delta(delta)
+	}
+	if m.failAfter {
+		return nil, fmt.Errorf("connection reset")
+	}
+	return &ModelResponse{Content: strings.Join(m.deltas, ""), Model: m.Model}, nil
+}
+
+func TestExecuteModelStream(t *testing.T) {
+	originalCreateModelClient := createModelClient
+	defer func() { createModelClient = originalCreateModelClient }()
+
+	// A stream that fails part way is restarted on the fallback model,
+	// which can't stream and passes its whole response at once
+	clients := map[string]ModelClient{
+		"openai": &mockStreamingClient{deltas: []string{"Wee", "kly"}, failAfter: true},
+		"claude": &MockModelClient{Content: "Weekly report", Model: "claude"},
+	}
+	createModelClient = func(modelName string, _ *config.ModelConfig) (ModelClient, error) {
+		if client, ok := clients[modelName]; ok {
+			return client, nil
+		}
+		return nil, fmt.Errorf("unsupported model: %s", modelName)
+	}
+	t.Setenv("MODEL_FALLBACK_MODELS", "claude")
+	t.Setenv("MODEL_MAX_RETRIES", "1")
+
+	handler := &recordingHandler{}
+	response, err := ExecuteModelStream(context.Background(), "openai", "test prompt", nil, "", handler)
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly report", response.Content)
+	assert.Equal(t, "claude", response.Provider)
+	assert.Equal(t, []string{"Wee", "kly", "|", "Weekly report"}, handler.events)
+
+	// Failures before any text don't restart the stream
+	clients["openai"] = &mockStreamingClient{failAfter: true}
+	clients["claude"] = &mockStreamingClient{deltas: []string{"Weekly", " report"}, MockModelClient: MockModelClient{Model: "claude"}}
+	handler = &recordingHandler{}
+	response, err = ExecuteModelStream(context.Background(), "openai", "test prompt", nil, "", handler)
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly report", response.Content)
+	assert.Equal(t, []string{"Weekly", " report"}, handler.events)
+}
+
+func TestStreamContext(t *testing.T) {
+	ctx, touch, cancel := streamContext(context.Background(), &config.ModelConfig{RequestTimeout: 50 * time.Millisecond})
+	defer cancel()
+
+	// Data arriving in time keeps the request going past the timeout
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		touch()
+	}
+	require.NoError(t, ctx.Err())
+
+	<-ctx.Done()
+	assert.ErrorIs(t, context.Cause(ctx), context.DeadlineExceeded)
+	assert.Contains(t, streamError(ctx, context.Canceled).Error(), "no response data for 50ms")
+}
+
+// sseServer serves a single server-sent event stream
+func sseServer(t *testing.T, path string, events []string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, path) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			_, _ = fmt.Fprintf(w, "%s\n\n", event)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAIClientExecuteStream(t *testing.T) {
+	server := sseServer(t, "/chat/completions", []string{
+		`data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+		`data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Weekly"}}]}`,
+		`data: {"model":"gpt-4o","choices":[{"index":0,"delta":{"content":" report"}}]}`,
+		`data: {"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":12,"completion_tokens":2,"prompt_tokens_details":{"cached_tokens":8}}}`,
+		`data: [DONE]`,
+	})
+	t.Setenv("OPENAI_API_KEY", "test-key")
+	t.Setenv("OPENAI_BASE_URL", server.URL)
+
+	client, err := NewOpenAIClient(config.DefaultModelConfig())
+	require.NoError(t, err)
+	handler := &recordingHandler{}
+	response, err := client.ExecuteStream(context.Background(), "test prompt", handler)
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly report", response.Content)
+	assert.Equal(t, "gpt-4o", response.Model)
+	assert.Equal(t, Usage{PromptTokens: 12, CompletionTokens: 2, CachedTokens: 8}, response.Usage)
+	assert.Equal(t, []string{"Weekly", " report"}, handler.events)
+}
+
+func TestClaudeClientExecuteStream(t *testing.T) {
+	server := sseServer(t, "/v1/messages", []string{
+		"event: message_start\ndata: " + `{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-sonnet-latest","content":[],"stop_reason":null,"usage":{"input_tokens":5,"cache_read_input_tokens":20,"output_tokens":0}}}`,
+		"event: content_block_start\ndata: " + `{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		"event: content_block_delta\ndata: " + `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Weekly"}}`,
+		"event: content_block_delta\ndata: " + `{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" report"}}`,
+		"event: content_block_stop\ndata: " + `{"type":"content_block_stop","index":0}`,
+		"event: message_delta\ndata: " + `{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+		"event: message_stop\ndata: " + `{"type":"message_stop"}`,
+	})
+	t.Setenv("ANTHROPIC_API_KEY", "test-key")
+	t.Setenv("ANTHROPIC_BASE_URL", server.URL)
+
+	client, err := NewClaudeClient(config.DefaultModelConfig())
+	require.NoError(t, err)
+	handler := &recordingHandler{}
+	response, err := client.ExecuteStream(context.Background(), "test prompt", handler)
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly report", response.Content)
+	assert.Equal(t, "claude-3-5-sonnet-latest", response.Model)
+	assert.Equal(t, Usage{PromptTokens: 25, CompletionTokens: 2, CachedTokens: 20}, response.Usage)
+	assert.Equal(t, []string{"Weekly", " report"}, handler.events)
+}
@@ -0,0 +1,264 @@
+// Package pool limits how many task executions run at the same time, both
+// overall and per model provider, so bursts of work stay within provider rate limits.
+package pool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rshade/cronai/internal/logger"
+)
+
+// Environment variables configuring the default pool
+const (
+	// EnvMaxConcurrency caps the number of executions running at once; 0 means unlimited
+	EnvMaxConcurrency = "CRONAI_MAX_CONCURRENCY"
+	// EnvProviderLimits caps executions per provider, e.g. "claude=2,openai=4"
+	EnvProviderLimits = "CRONAI_PROVIDER_LIMITS"
+)
+
+// Default logger for the pool package
+var log = logger.DefaultLogger()
+
+// SetLogger sets the logger for the pool package
+func SetLogger(l *logger.Logger) {
+	log = l
+}
+
+// Pool hands out execution slots. A nil semaphore means no limit.
+type Pool struct {
+	global    chan struct{}
+	providers map[string]chan struct{}
+}
+
+// Option is a functional option for configuring a pool
+type Option func(*Pool)
+
+// WithMaxConcurrency caps the total number of concurrent executions.
+// A zero or negative value means no limit.
+func WithMaxConcurrency(n int) Option {
+	return func(p *Pool) {
+		p.global = newSemaphore(n)
+	}
+}
+
+// WithProviderLimit caps the number of concurrent executions for a provider.
+// A zero or negative value means no limit.
+func WithProviderLimit(provider string, n int) Option {
+	return func(p *Pool) {
+		provider = strings.ToLower(provider)
+		if sem := newSemaphore(n); sem != nil {
+			p.providers[provider] = sem
+		} else {
+			delete(p.providers, provider)
+		}
+	}
+}
+
+// New creates a pool; without options it places no limits
+func New(opts ...Option) *Pool {
+	p := &Pool{providers: make(map[string]chan struct{})}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// newSemaphore returns a semaphore with n slots, or nil for no limit
+func newSemaphore(n int) chan struct{} {
+	if n <= 0 {
+		return nil
+	}
+	return make(chan struct{}, n)
+}
+
+// Acquire waits for a slot for an execution against provider and returns a
+// function that releases it. The provider slot is taken before the global one
+// so that work queued for a busy provider doesn't hold up other providers.
+// It returns the context's error if ctx is done before a slot frees up.
+func (p *Pool) Acquire(ctx context.Context, provider string) (release func(), err error) {
+	releaseProvider, err := p.AcquireProvider(ctx, provider)
+	if err != nil {
+		return nil, err
+	}
+	releaseGlobal, err := p.AcquireGlobal(ctx)
+	if err != nil {
+		releaseProvider()
+		return nil, err
+	}
+	return func() {
+		releaseGlobal()
+		releaseProvider()
+	}, nil
+}
+
+// AcquireGlobal waits for a global slot only. Executions that may call
+// several providers in turn hold it throughout and take the slot of each
+// provider with AcquireProvider as they call it.
+func (p *Pool) AcquireGlobal(ctx context.Context) (release func(), err error) {
+	return p.take(ctx, p.global, "")
+}
+
+// AcquireProvider waits for a slot of provider only
+func (p *Pool) AcquireProvider(ctx context.Context, provider string) (release func(), err error) {
+	return p.take(ctx, p.providers[strings.ToLower(provider)], provider)
+}
+
+// take acquires a slot from sem and returns a function releasing it once
+func (p *Pool) take(ctx context.Context, sem chan struct{}, provider string) (func(), error) {
+	if err := acquire(ctx, sem, provider); err != nil {
+		return nil, err
+	}
+	var once sync.Once
+	return func() {
+		once.Do(func() { releaseSlot(sem) })
+	}, nil
+}
+
+// acquire takes a slot from sem, waiting if none is free
+func acquire(ctx context.Context, sem chan struct{}, provider string) error {
+	if sem == nil {
+		return nil
+	}
+
+	// Fast path when a slot is free
+	select {
+	case sem <- struct{}{}:
+		return nil
+	default:
+	}
+
+	log.Debug("Waiting for execution slot", logger.Fields{
+		"provider": provider,
+		"capacity": cap(sem),
+	})
+	select {
+	case sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for execution slot: %w", ctx.Err())
+	}
+}
+
+// releaseSlot returns a slot to sem
+func releaseSlot(sem chan struct{}) {
+	if sem != nil {
+		<-sem
+	}
+}
+
+// Limits describes the configured caps; zero means unlimited
+type Limits struct {
+	MaxConcurrency int
+	Providers      map[string]int
+}
+
+// Limits returns the configured caps of the pool
+func (p *Pool) Limits() Limits {
+	limits := Limits{
+		MaxConcurrency: cap(p.global),
+		Providers:      make(map[string]int, len(p.providers)),
+	}
+	for provider, sem := range p.providers {
+		limits.Providers[provider] = cap(sem)
+	}
+	return limits
+}
+
+// ParseProviderLimits parses per-provider caps in the form "claude=2,openai=4"
+func ParseProviderLimits(value string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		provider, n, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid provider limit '%s' (expected provider=N)", pair)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid limit for provider '%s': %s", provider, n)
+		}
+		limits[strings.ToLower(strings.TrimSpace(provider))] = limit
+	}
+	return limits, nil
+}
+
+// optionsFromEnv builds pool options from the environment, ignoring invalid values
+func optionsFromEnv() []Option {
+	var opts []Option
+
+	if value := os.Getenv(EnvMaxConcurrency); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			log.Warn("Invalid max concurrency, running without a global limit", logger.Fields{
+				"value": value,
+				"error": err.Error(),
+			})
+		} else {
+			opts = append(opts, WithMaxConcurrency(n))
+		}
+	}
+
+	if value := os.Getenv(EnvProviderLimits); value != "" {
+		limits, err := ParseProviderLimits(value)
+		if err != nil {
+			log.Warn("Invalid provider limits, running without provider limits", logger.Fields{
+				"value": value,
+				"error": err.Error(),
+			})
+		}
+		providers := make([]string, 0, len(limits))
+		for provider := range limits {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+		for _, provider := range providers {
+			opts = append(opts, WithProviderLimit(provider, limits[provider]))
+		}
+	}
+
+	return opts
+}
+
+var (
+	defaultPool *Pool
+	defaultOnce sync.Once
+	defaultMu   sync.RWMutex
+)
+
+// Default returns the pool shared by the cron, queue and bot modes, configured
+// from the environment on first use
+func Default() *Pool {
+	defaultOnce.Do(func() {
+		defaultMu.Lock()
+		defer defaultMu.Unlock()
+		if defaultPool == nil {
+			defaultPool = New(optionsFromEnv()...)
+			limits := defaultPool.Limits()
+			log.Info("Execution pool configured", logger.Fields{
+				"max_concurrency": limits.MaxConcurrency,
+				"provider_limits": limits.Providers,
+			})
+		}
+	})
+
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultPool
+}
+
+// SetDefault replaces the shared pool
+func SetDefault(p *Pool) {
+	defaultOnce.Do(func() {})
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultPool = p
+}
@@ -0,0 +1,166 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runConcurrently runs n executions against provider and returns the highest
+// number that were running at the same time
+func runConcurrently(t *testing.T, p *Pool, provider string, n int) int32 {
+	t.Helper()
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := p.Acquire(context.Background(), provider)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer release()
+
+			current := atomic.AddInt32(&running, 1)
+			for {
+				old := atomic.LoadInt32(&peak)
+				if current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+	wg.Wait()
+	return peak
+}
+
+func TestPoolUnlimited(t *testing.T) {
+	p := New()
+	assert.Equal(t, int32(10), runConcurrently(t, p, "openai", 10))
+}
+
+func TestPoolGlobalLimit(t *testing.T) {
+	p := New(WithMaxConcurrency(3))
+	assert.LessOrEqual(t, runConcurrently(t, p, "openai", 10), int32(3))
+}
+
+func TestPoolProviderLimit(t *testing.T) {
+	p := New(WithMaxConcurrency(10), WithProviderLimit("Claude", 2))
+	assert.LessOrEqual(t, runConcurrently(t, p, "claude", 8), int32(2))
+
+	// Providers without a cap only share the global limit
+	assert.Greater(t, runConcurrently(t, p, "openai", 8), int32(2))
+}
+
+func TestPoolBusyProviderDoesNotBlockOthers(t *testing.T) {
+	p := New(WithMaxConcurrency(2), WithProviderLimit("claude", 1))
+
+	release, err := p.Acquire(context.Background(), "claude")
+	require.NoError(t, err)
+	defer release()
+
+	// A second claude execution waits for the provider slot without holding a global slot
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if release, err := p.Acquire(ctx, "claude"); err == nil {
+			release()
+		}
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	openaiCtx, openaiCancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer openaiCancel()
+	releaseOpenAI, err := p.Acquire(openaiCtx, "openai")
+	require.NoError(t, err)
+	releaseOpenAI()
+}
+
+func TestPoolAcquireCancelled(t *testing.T) {
+	p := New(WithMaxConcurrency(1))
+
+	release, err := p.Acquire(context.Background(), "openai")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = p.Acquire(ctx, "openai")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Releasing twice must not free a slot held by someone else
+	release()
+	release()
+	release, err = p.Acquire(context.Background(), "openai")
+	require.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = p.Acquire(ctx, "openai")
+	assert.Error(t, err)
+	release()
+}
+
+func TestPoolAcquireGlobalAndProvider(t *testing.T) {
+	p := New(WithMaxConcurrency(1), WithProviderLimit("claude", 1))
+
+	// A global slot held across calls leaves the provider slots to each call
+	releaseGlobal, err := p.AcquireGlobal(context.Background())
+	require.NoError(t, err)
+	releaseClaude, err := p.AcquireProvider(context.Background(), "claude")
+	require.NoError(t, err)
+	releaseOpenAI, err := p.AcquireProvider(context.Background(), "openai")
+	require.NoError(t, err)
+	releaseOpenAI()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = p.AcquireProvider(ctx, "claude")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	_, err = p.AcquireGlobal(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	releaseClaude()
+	releaseGlobal()
+	release, err := p.Acquire(context.Background(), "claude")
+	require.NoError(t, err)
+	release()
+}
+
+func TestParseProviderLimits(t *testing.T) {
+	limits, err := ParseProviderLimits("claude=2, OpenAI=4,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"claude": 2, "openai": 4}, limits)
+
+	_, err = ParseProviderLimits("claude")
+	assert.Error(t, err)
+
+	_, err = ParseProviderLimits("claude=two")
+	assert.Error(t, err)
+
+	_, err = ParseProviderLimits("claude=-1")
+	assert.Error(t, err)
+}
+
+func TestOptionsFromEnv(t *testing.T) {
+	t.Setenv(EnvMaxConcurrency, "5")
+	t.Setenv(EnvProviderLimits, "claude=2,openai=4")
+
+	limits := New(optionsFromEnv()...).Limits()
+	assert.Equal(t, 5, limits.MaxConcurrency)
+	assert.Equal(t, map[string]int{"claude": 2, "openai": 4}, limits.Providers)
+
+	t.Setenv(EnvMaxConcurrency, "lots")
+	t.Setenv(EnvProviderLimits, "claude")
+	limits = New(optionsFromEnv()...).Limits()
+	assert.Equal(t, 0, limits.MaxConcurrency)
+	assert.Empty(t, limits.Providers)
+}
@@ -2,9 +2,14 @@
 package processor
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/rshade/cronai/internal/errors"
 	"github.com/rshade/cronai/internal/logger"
 	"github.com/rshade/cronai/internal/models"
 	"github.com/rshade/cronai/internal/processor/template"
@@ -15,6 +20,12 @@ type ConsoleProcessor struct {
 	config Config
 }
 
+// consoleOutput is where streamed responses are printed
+var consoleOutput io.Writer = os.Stdout
+
+// consoleRule separates the parts of the default console output
+const consoleRule = "=========================================================="
+
 // NewConsoleProcessor creates a new console processor
 func NewConsoleProcessor(config Config) (Processor, error) {
 	return &ConsoleProcessor{
@@ -23,7 +34,7 @@ func NewConsoleProcessor(config Config) (Processor, error) {
 }
 
 // Process handles the model response with optional template
-func (c *ConsoleProcessor) Process(response *models.ModelResponse, templateName string) error {
+func (c *ConsoleProcessor) Process(_ context.Context, response *models.ModelResponse, templateName string) error {
 	// Create template data
 	tmplData := template.Data{
 		Content:     response.Content,
@@ -40,6 +51,7 @@ func (c *ConsoleProcessor) Process(response *models.ModelResponse, templateName
 	tmplData.Metadata["date"] = response.Timestamp.Format("2006-01-02")
 	tmplData.Metadata["time"] = response.Timestamp.Format("15:04:05")
 	tmplData.Metadata["execution_id"] = response.ExecutionID
+	addUsageMetadata(tmplData.Metadata, response)
 	tmplData.Metadata["processor"] = c.GetType()
 	if templateName != "" {
 		tmplData.Metadata["template"] = templateName
@@ -109,3 +121,55 @@ Time: {{.Timestamp.Format "2006-01-02 15:04:05"}}
 	fmt.Println(output)
 	return nil
 }
+
+// BeginStream prints the response as the model produces it. Only the default
+// console format can be streamed, responses with a template are printed once
+// they are complete.
+func (c *ConsoleProcessor) BeginStream(_ context.Context, response *models.ModelResponse, templateName string) (ResponseStream, error) {
+	if templateName != "" {
+		return nil, nil
+	}
+	if _, err := fmt.Fprintf(consoleOutput, "\n%s\nAI Response: %s\n%s\n\n", consoleRule, response.PromptName, consoleRule); err != nil {
+		return nil, errors.Wrap(errors.CategorySystem, err, "failed to print response")
+	}
+	return &consoleStream{out: consoleOutput}, nil
+}
+
+// consoleStream prints a response as it is produced
+type consoleStream struct {
+	out     io.Writer
+	printed string
+}
+
+// Update prints the content that wasn't printed yet. When the model starts
+// over, the new content follows a notice.
+func (s *consoleStream) Update(_ context.Context, content string) error {
+	if !strings.HasPrefix(content, s.printed) {
+		if _, err := fmt.Fprint(s.out, "\n\n[response restarted]\n\n"); err != nil {
+			return errors.Wrap(errors.CategorySystem, err, "failed to print response")
+		}
+		s.printed = ""
+	}
+	if _, err := fmt.Fprint(s.out, content[len(s.printed):]); err != nil {
+		return errors.Wrap(errors.CategorySystem, err, "failed to print response")
+	}
+	s.printed = content
+	return nil
+}
+
+// Finish prints the rest of the response and the model that produced it
+func (s *consoleStream) Finish(ctx context.Context, response *models.ModelResponse) error {
+	if err := s.Update(ctx, response.Content); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.out, "\n\n%s\nModel: %s\nTime: %s\n%s\n",
+		consoleRule, response.Model, response.Timestamp.Format("2006-01-02 15:04:05"), consoleRule); err != nil {
+		return errors.Wrap(errors.CategorySystem, err, "failed to print response")
+	}
+	return nil
+}
+
+// Abort notes that the printed response is incomplete
+func (s *consoleStream) Abort(_ context.Context, err error) {
+	_, _ = fmt.Fprintf(s.out, "\n\n[response incomplete: %v]\n%s\n", err, consoleRule)
+}
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"time"
@@ -24,7 +25,7 @@ func NewEmailProcessor(config Config) (Processor, error) {
 }
 
 // Process handles the model response with optional template
-func (e *EmailProcessor) Process(response *models.ModelResponse, templateName string) error {
+func (e *EmailProcessor) Process(_ context.Context, response *models.ModelResponse, templateName string) error {
 	// Create template data
 	tmplData := template.Data{
 		Content:     response.Content,
@@ -41,6 +42,7 @@ func (e *EmailProcessor) Process(response *models.ModelResponse, templateName st
 	tmplData.Metadata["date"] = response.Timestamp.Format("2006-01-02")
 	tmplData.Metadata["time"] = response.Timestamp.Format("15:04:05")
 	tmplData.Metadata["execution_id"] = response.ExecutionID
+	addUsageMetadata(tmplData.Metadata, response)
 	tmplData.Metadata["processor"] = e.GetType()
 	if templateName != "" {
 		tmplData.Metadata["template"] = templateName
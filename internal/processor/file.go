@@ -1,7 +1,9 @@
 package processor
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -26,7 +28,12 @@ func NewFileProcessor(config Config) (Processor, error) {
 }
 
 // Process handles the model response with optional template
-func (f *FileProcessor) Process(response *models.ModelResponse, templateName string) error {
+func (f *FileProcessor) Process(_ context.Context, response *models.ModelResponse, templateName string) error {
+	return f.processFileWithTemplate(f.templateData(response, templateName), templateName)
+}
+
+// templateData returns the template data of a response
+func (f *FileProcessor) templateData(response *models.ModelResponse, templateName string) template.Data {
 	// Create template data
 	tmplData := template.Data{
 		Content:     response.Content,
@@ -43,12 +50,12 @@ func (f *FileProcessor) Process(response *models.ModelResponse, templateName str
 	tmplData.Metadata["date"] = response.Timestamp.Format("2006-01-02")
 	tmplData.Metadata["time"] = response.Timestamp.Format("15:04:05")
 	tmplData.Metadata["execution_id"] = response.ExecutionID
+	addUsageMetadata(tmplData.Metadata, response)
 	tmplData.Metadata["processor"] = f.GetType()
 	if templateName != "" {
 		tmplData.Metadata["template"] = templateName
 	}
-
-	return f.processFileWithTemplate(tmplData, templateName)
+	return tmplData
 }
 
 // Validate checks if the processor is properly configured
@@ -70,6 +77,59 @@ func (f *FileProcessor) GetConfig() Config {
 
 // processFileWithTemplate saves response to file using template
 func (f *FileProcessor) processFileWithTemplate(data template.Data, templateName string) error {
+	_, err := f.writeFile(data, templateName)
+	return err
+}
+
+// writeFile saves response to file using template and returns the file written
+func (f *FileProcessor) writeFile(data template.Data, templateName string) (string, error) {
+	// Use default template if none specified
+	if templateName == "" {
+		templateName = "default_file"
+	}
+
+	safeFilename, err := f.outputPath(data, templateName)
+	if err != nil {
+		return "", err
+	}
+
+	// Execute content template
+	contentTemplateName := templateName + "_content"
+	content := template.GetManager().SafeExecute(contentTemplateName, data)
+	if content == "" {
+		log.Warn("Empty content generated from template", logger.Fields{
+			"template": templateName,
+			"filename": safeFilename,
+		})
+		// Use raw content as fallback
+		content = data.Content
+	}
+
+	// Add to metadata for logging
+	data.Metadata["filename_template"] = templateName + "_filename"
+	data.Metadata["content_template"] = contentTemplateName
+	data.Metadata["output_file"] = safeFilename
+
+	// Write to file - using the validated and sanitized filename
+	err = os.WriteFile(safeFilename, []byte(content), 0644)
+	if err != nil {
+		log.Error("Failed to write response to file", logger.Fields{
+			"filename": safeFilename,
+			"error":    err.Error(),
+		})
+		return "", errors.Wrap(errors.CategorySystem, err, "failed to write response to file")
+	}
+
+	log.Info("Response saved to file", logger.Fields{
+		"filename":    safeFilename,
+		"content_len": len(content),
+	})
+	return safeFilename, nil
+}
+
+// outputPath returns the validated file a response is saved to, creating its
+// directory if needed
+func (f *FileProcessor) outputPath(data template.Data, templateName string) (string, error) {
 	// Create logs directory if it doesn't exist
 	logsDir := GetEnvWithDefault(EnvLogsDirectory, DefaultLogsDirectory)
 
@@ -79,25 +139,17 @@ func (f *FileProcessor) processFileWithTemplate(data template.Data, templateName
 			"directory": logsDir,
 			"error":     err.Error(),
 		})
-		return errors.Wrap(errors.CategorySystem, err, "failed to create logs directory")
-	}
-
-	// Get template manager
-	manager := template.GetManager()
-
-	// Use default template if none specified
-	if templateName == "" {
-		templateName = "default_file"
+		return "", errors.Wrap(errors.CategorySystem, err, "failed to create logs directory")
 	}
 
 	// Execute filename template
 	filenameTemplateName := templateName + "_filename"
-	filename := manager.SafeExecute(filenameTemplateName, data)
+	filename := template.GetManager().SafeExecute(filenameTemplateName, data)
 	if filename == "" {
 		log.Error("Failed to generate filename", logger.Fields{
 			"template": templateName,
 		})
-		return errors.Wrap(errors.CategoryApplication,
+		return "", errors.Wrap(errors.CategoryApplication,
 			fmt.Errorf("empty filename generated from template %s", templateName),
 			"filename generation failed")
 	}
@@ -109,26 +161,9 @@ func (f *FileProcessor) processFileWithTemplate(data template.Data, templateName
 			"original_filename": filename,
 			"error":             err.Error(),
 		})
-		return errors.Wrap(errors.CategorySecurity, err, "filename validation failed")
+		return "", errors.Wrap(errors.CategorySecurity, err, "filename validation failed")
 	}
 
-	// Execute content template
-	contentTemplateName := templateName + "_content"
-	content := manager.SafeExecute(contentTemplateName, data)
-	if content == "" {
-		log.Warn("Empty content generated from template", logger.Fields{
-			"template": templateName,
-			"filename": safeFilename,
-		})
-		// Use raw content as fallback
-		content = data.Content
-	}
-
-	// Add to metadata for logging
-	data.Metadata["filename_template"] = filenameTemplateName
-	data.Metadata["content_template"] = contentTemplateName
-	data.Metadata["output_file"] = safeFilename
-
 	// Create parent directory if needed - ensure we only work with the sanitized, validated path
 	// The safeFilename has been validated to be within the base directory by sanitizeFilename
 	parentDir := filepath.Dir(safeFilename)
@@ -139,34 +174,94 @@ func (f *FileProcessor) processFileWithTemplate(data template.Data, templateName
 				"directory": parentDir,
 				"error":     err.Error(),
 			})
-			return errors.Wrap(errors.CategorySystem, err, "failed to create parent directory for output file")
+			return "", errors.Wrap(errors.CategorySystem, err, "failed to create parent directory for output file")
 		}
 	}
 
-	// Write to file - using the validated and sanitized filename
-	// The safeFilename has been validated to be within the base directory by sanitizeFilename
 	// Additional safety check: ensure filename doesn't contain path traversal patterns
 	if strings.Contains(safeFilename, "..") {
-		return errors.Wrap(errors.CategorySecurity,
+		return "", errors.Wrap(errors.CategorySecurity,
 			fmt.Errorf("sanitized filename still contains path traversal patterns: %s", safeFilename),
 			"invalid sanitized filename")
 	}
-	err = os.WriteFile(safeFilename, []byte(content), 0644)
+	return safeFilename, nil
+}
+
+// BeginStream writes the response to its file as the model produces it. The
+// file holds the raw content until the response is complete and formatted
+// with the template.
+func (f *FileProcessor) BeginStream(_ context.Context, response *models.ModelResponse, templateName string) (ResponseStream, error) {
+	filenameTemplate := templateName
+	if filenameTemplate == "" {
+		filenameTemplate = "default_file"
+	}
+	path, err := f.outputPath(f.templateData(response, templateName), filenameTemplate)
 	if err != nil {
-		log.Error("Failed to write response to file", logger.Fields{
-			"filename": safeFilename,
-			"error":    err.Error(),
-		})
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, errors.Wrap(errors.CategorySystem, err, "failed to create output file")
+	}
+	return &fileStream{processor: f, templateName: templateName, path: path, file: file}, nil
+}
+
+// fileStream writes a response to its file as it is produced
+type fileStream struct {
+	processor    *FileProcessor
+	templateName string
+	path         string
+	file         *os.File
+	written      string
+}
+
+// Update appends the content that wasn't written yet, or rewrites the file
+// when the model starts over
+func (s *fileStream) Update(_ context.Context, content string) error {
+	pending := strings.TrimPrefix(content, s.written)
+	if !strings.HasPrefix(content, s.written) {
+		if err := s.file.Truncate(0); err != nil {
+			return errors.Wrap(errors.CategorySystem, err, "failed to rewrite output file")
+		}
+		if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+			return errors.Wrap(errors.CategorySystem, err, "failed to rewrite output file")
+		}
+		pending = content
+	}
+	if _, err := s.file.WriteString(pending); err != nil {
 		return errors.Wrap(errors.CategorySystem, err, "failed to write response to file")
 	}
+	s.written = content
+	return nil
+}
 
-	log.Info("Response saved to file", logger.Fields{
-		"filename":    safeFilename,
-		"content_len": len(content),
-	})
+// Finish replaces the streamed content with the response formatted by the
+// template
+func (s *fileStream) Finish(_ context.Context, response *models.ModelResponse) error {
+	if err := s.file.Close(); err != nil {
+		return errors.Wrap(errors.CategorySystem, err, "failed to close output file")
+	}
+	path, err := s.processor.writeFile(s.processor.templateData(response, s.templateName), s.templateName)
+	if err != nil {
+		return err
+	}
+	// A filename that depends on the content leaves the streamed file behind
+	if path != s.path {
+		if err := os.Remove(s.path); err != nil {
+			log.Warn("Failed to remove streamed output file", logger.Fields{"filename": s.path, "error": err.Error()})
+		}
+	}
 	return nil
 }
 
+// Abort removes the incomplete file
+func (s *fileStream) Abort(_ context.Context, _ error) {
+	_ = s.file.Close()
+	if err := os.Remove(s.path); err != nil {
+		log.Warn("Failed to remove incomplete output file", logger.Fields{"filename": s.path, "error": err.Error()})
+	}
+}
+
 // sanitizeFilename validates and sanitizes a filename to prevent path traversal attacks
 func (f *FileProcessor) sanitizeFilename(filename, baseDir string) (string, error) {
 	// First normalize path separators to handle Windows-style paths on any OS
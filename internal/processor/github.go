@@ -53,7 +53,7 @@ func NewGitHubProcessor(config Config) (Processor, error) {
 }
 
 // Process handles the model response with optional template
-func (g *GitHubProcessor) Process(response *models.ModelResponse, templateName string) error {
+func (g *GitHubProcessor) Process(ctx context.Context, response *models.ModelResponse, templateName string) error {
 	// Create template data
 	tmplData := template.Data{
 		Content:     response.Content,
@@ -70,12 +70,13 @@ func (g *GitHubProcessor) Process(response *models.ModelResponse, templateName s
 	tmplData.Metadata["date"] = response.Timestamp.Format("2006-01-02")
 	tmplData.Metadata["time"] = response.Timestamp.Format("15:04:05")
 	tmplData.Metadata["execution_id"] = response.ExecutionID
+	addUsageMetadata(tmplData.Metadata, response)
 	tmplData.Metadata["processor"] = g.GetType()
 	if templateName != "" {
 		tmplData.Metadata["template"] = templateName
 	}
 
-	return g.processGitHubWithTemplate(g.config.Target, tmplData, templateName)
+	return g.processGitHubWithTemplate(ctx, g.config.Target, tmplData, templateName)
 }
 
 // Validate checks if the processor is properly configured
@@ -154,7 +155,7 @@ func prepareJSONPayload(data map[string]interface{}) (map[string]interface{}, er
 }
 
 // processGitHubWithTemplate processes GitHub operations with templates
-func (g *GitHubProcessor) processGitHubWithTemplate(target string, data template.Data, templateName string) error {
+func (g *GitHubProcessor) processGitHubWithTemplate(ctx context.Context, target string, data template.Data, templateName string) error {
 	// Ensure client is initialized
 	if g.client == nil {
 		// Check if running in test mode
@@ -172,11 +173,10 @@ func (g *GitHubProcessor) processGitHubWithTemplate(target string, data template
 					"missing GitHub configuration")
 			}
 
-			ctx := context.Background()
 			ts := oauth2.StaticTokenSource(
 				&oauth2.Token{AccessToken: token},
 			)
-			tc := oauth2.NewClient(ctx, ts)
+			tc := oauth2.NewClient(context.Background(), ts)
 			g.client = github.NewClient(tc)
 		}
 	}
@@ -240,7 +240,7 @@ func (g *GitHubProcessor) processGitHubWithTemplate(target string, data template
 		}
 
 		// Process the action
-		return g.processGitHubComment(repoInfo, jsonPayload)
+		return g.processGitHubComment(ctx, repoInfo, jsonPayload)
 
 	case "issue":
 		// Use the FormatGitHubMessage helper to generate standardized content
@@ -390,7 +390,7 @@ func (g *GitHubProcessor) processGitHubIssue(repo string, payload map[string]int
 }
 
 // processGitHubComment adds a comment to an existing issue
-func (g *GitHubProcessor) processGitHubComment(repoInfo string, payload map[string]interface{}) error {
+func (g *GitHubProcessor) processGitHubComment(ctx context.Context, repoInfo string, payload map[string]interface{}) error {
 	// Parse repo and issue number (format: owner/repo#123)
 	parts := strings.Split(repoInfo, "#")
 	if len(parts) != 2 {
@@ -443,7 +443,6 @@ func (g *GitHubProcessor) processGitHubComment(repoInfo string, payload map[stri
 	}
 
 	// Create the comment
-	ctx := context.Background()
 	comment, _, err := g.client.Issues.CreateComment(ctx, owner, repoName, issueNumber, commentRequest)
 	if err != nil {
 		log.Error("Failed to add GitHub comment", logger.Fields{
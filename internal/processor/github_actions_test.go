@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -105,7 +106,7 @@ func TestGitHubProcessor_ProcessGitHubWithTemplate(t *testing.T) {
 			// Test process with template
 			gitHubProcessor, ok := processor.(*GitHubProcessor)
 			require.True(t, ok, "Failed to cast processor to GitHubProcessor")
-			err = gitHubProcessor.processGitHubWithTemplate(tt.target, data, tt.templateName)
+			err = gitHubProcessor.processGitHubWithTemplate(context.Background(), tt.target, data, tt.templateName)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -167,20 +168,20 @@ func TestGitHubProcessor_Comment(t *testing.T) {
 		"body": "Test Comment",
 	}
 
-	err = gitHubProcessor.processGitHubComment("owner/repo#123", payload)
+	err = gitHubProcessor.processGitHubComment(context.Background(), "owner/repo#123", payload)
 	assert.NoError(t, err)
 
 	// Test with missing body
 	invalidPayload := map[string]interface{}{}
-	err = gitHubProcessor.processGitHubComment("owner/repo#123", invalidPayload)
+	err = gitHubProcessor.processGitHubComment(context.Background(), "owner/repo#123", invalidPayload)
 	assert.Error(t, err)
 
 	// Test with invalid repo format
-	err = gitHubProcessor.processGitHubComment("invalid", payload)
+	err = gitHubProcessor.processGitHubComment(context.Background(), "invalid", payload)
 	assert.Error(t, err)
 
 	// Test with invalid issue number
-	err = gitHubProcessor.processGitHubComment("owner/repo#abc", payload)
+	err = gitHubProcessor.processGitHubComment(context.Background(), "owner/repo#abc", payload)
 	assert.Error(t, err)
 }
 
@@ -279,7 +280,7 @@ func TestGitHubProcessor_Process_Integration(t *testing.T) {
 			}
 
 			// Process the response
-			err = processor.Process(response, "")
+			err = processor.Process(context.Background(), response, "")
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -117,7 +118,7 @@ func TestGitHubProcessor_Process(t *testing.T) {
 		Timestamp:  time.Now(),
 		PromptName: "test-prompt",
 	}
-	err = processor.Process(response, "")
+	err = processor.Process(context.Background(), response, "")
 	if err != nil {
 		t.Errorf("Process failed: %v", err)
 	}
@@ -260,7 +261,7 @@ func TestGitHubProcessor_TargetParsing(t *testing.T) {
 				ExecutionID: "test-execution-id",
 			}
 
-			err = processor.Process(response, "")
+			err = processor.Process(context.Background(), response, "")
 
 			if tt.wantErr {
 				assert.Error(t, err)
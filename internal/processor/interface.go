@@ -1,13 +1,16 @@
 package processor
 
 import (
+	"context"
+
 	"github.com/rshade/cronai/internal/models"
 )
 
 // Processor defines the interface for all response processors
 type Processor interface {
-	// Process handles the model response with optional template
-	Process(response *models.ModelResponse, templateName string) error
+	// Process handles the model response with optional template. Processors
+	// that call external services stop when ctx is cancelled.
+	Process(ctx context.Context, response *models.ModelResponse, templateName string) error
 
 	// Validate checks if the processor is properly configured
 	Validate() error
@@ -2,6 +2,7 @@
 package processor
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -17,7 +18,7 @@ type MockProcessor struct {
 	validateCalled bool
 }
 
-func (m *MockProcessor) Process(_ *models.ModelResponse, _ string) error {
+func (m *MockProcessor) Process(_ context.Context, _ *models.ModelResponse, _ string) error {
 	m.processCalled = true
 	return m.processError
 }
@@ -52,7 +53,7 @@ func TestProcessorInterface(t *testing.T) {
 		Timestamp:  time.Now(),
 		PromptName: "test-prompt",
 	}
-	err := processor.Process(response, "")
+	err := processor.Process(context.Background(), response, "")
 	if err != nil {
 		t.Errorf("Process failed: %v", err)
 	}
@@ -100,7 +101,7 @@ func TestMockProcessor(t *testing.T) {
 	response := &models.ModelResponse{
 		Content: "test content",
 	}
-	err := mock.Process(response, "test-template")
+	err := mock.Process(context.Background(), response, "test-template")
 	if err != nil {
 		t.Errorf("Process returned unexpected error: %v", err)
 	}
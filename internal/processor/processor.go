@@ -2,7 +2,9 @@
 package processor
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/rshade/cronai/internal/errors"
@@ -24,8 +26,24 @@ type Options struct {
 	TemplateDir string // Directory containing custom templates
 }
 
+// addUsageMetadata adds the tokens and estimated cost of a response to the
+// metadata of its template data. Responses without token counts add nothing.
+func addUsageMetadata(metadata map[string]string, response *models.ModelResponse) {
+	usage := response.Usage
+	if usage.TotalTokens() == 0 {
+		return
+	}
+	metadata["prompt_tokens"] = strconv.Itoa(usage.PromptTokens)
+	metadata["completion_tokens"] = strconv.Itoa(usage.CompletionTokens)
+	metadata["cached_tokens"] = strconv.Itoa(usage.CachedTokens)
+	metadata["total_tokens"] = strconv.Itoa(usage.TotalTokens())
+	if response.Cost > 0 {
+		metadata["cost"] = fmt.Sprintf("%.6f", response.Cost)
+	}
+}
+
 // ProcessResponse processes a model response using the specified processor
-func ProcessResponse(processorName string, response *models.ModelResponse, templateName string) error {
+func ProcessResponse(ctx context.Context, processorName string, response *models.ModelResponse, templateName string) error {
 	log.Info("Processing response", logger.Fields{
 		"processor":   processorName,
 		"model":       response.Model,
@@ -95,7 +113,7 @@ func ProcessResponse(processorName string, response *models.ModelResponse, templ
 	}
 
 	// Process the response
-	return processor.Process(response, templateName)
+	return processor.Process(ctx, response, templateName)
 }
 
 // InitTemplates initializes the template system
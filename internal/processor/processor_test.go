@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -288,7 +289,7 @@ func TestProcessResponse(t *testing.T) {
 			}
 
 			// Run the processor
-			err = ProcessResponse(tc.processor, tc.response, tc.templateName)
+			err = ProcessResponse(context.Background(), tc.processor, tc.response, tc.templateName)
 
 			// Check error result
 			if tc.expectedError && err == nil {
@@ -466,3 +467,30 @@ func TestTemplateLoading(t *testing.T) {
 		t.Errorf("Expected template result %q but got %q", expected, result)
 	}
 }
+
+func TestAddUsageMetadata(t *testing.T) {
+	metadata := make(map[string]string)
+	addUsageMetadata(metadata, &models.ModelResponse{
+		Usage: models.Usage{PromptTokens: 1200, CompletionTokens: 300, CachedTokens: 1000},
+		Cost:  0.0042,
+	})
+	expected := map[string]string{
+		"prompt_tokens":     "1200",
+		"completion_tokens": "300",
+		"cached_tokens":     "1000",
+		"total_tokens":      "1500",
+		"cost":              "0.004200",
+	}
+	for key, value := range expected {
+		if metadata[key] != value {
+			t.Errorf("Metadata[%q] = %q, want %q", key, metadata[key], value)
+		}
+	}
+
+	// Responses without token counts add nothing
+	metadata = make(map[string]string)
+	addUsageMetadata(metadata, &models.ModelResponse{})
+	if len(metadata) != 0 {
+		t.Errorf("Expected no metadata, got %v", metadata)
+	}
+}
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -171,7 +172,7 @@ func TestRegistryWithMockProcessors(t *testing.T) {
 		ExecutionID: "test-exec",
 	}
 
-	err = processor.Process(response, "test-template")
+	err = processor.Process(context.Background(), response, "test-template")
 	if err != nil {
 		t.Errorf("Process failed: %v", err)
 	}
@@ -2,6 +2,7 @@ package processor
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,7 +30,7 @@ func NewSlackProcessor(config Config) (Processor, error) {
 }
 
 // Process handles the model response with optional template
-func (s *SlackProcessor) Process(response *models.ModelResponse, templateName string) error {
+func (s *SlackProcessor) Process(ctx context.Context, response *models.ModelResponse, templateName string) error {
 	// Create template data
 	tmplData := template.Data{
 		Content:     response.Content,
@@ -46,12 +47,13 @@ func (s *SlackProcessor) Process(response *models.ModelResponse, templateName st
 	tmplData.Metadata["date"] = response.Timestamp.Format("2006-01-02")
 	tmplData.Metadata["time"] = response.Timestamp.Format("15:04:05")
 	tmplData.Metadata["execution_id"] = response.ExecutionID
+	addUsageMetadata(tmplData.Metadata, response)
 	tmplData.Metadata["processor"] = s.GetType()
 	if templateName != "" {
 		tmplData.Metadata["template"] = templateName
 	}
 
-	return s.processSlackWithTemplate(s.config.Target, tmplData, templateName)
+	return s.processSlackWithTemplate(ctx, s.config.Target, tmplData, templateName)
 }
 
 // Validate checks if the processor is properly configured
@@ -86,7 +88,7 @@ func (s *SlackProcessor) GetConfig() Config {
 }
 
 // processSlackWithTemplate sends formatted messages to Slack
-func (s *SlackProcessor) processSlackWithTemplate(channel string, data template.Data, templateName string) error {
+func (s *SlackProcessor) processSlackWithTemplate(ctx context.Context, channel string, data template.Data, templateName string) error {
 	// Check for Slack configuration
 	slackToken := os.Getenv(EnvSlackToken)
 	slackWebhookURL := os.Getenv(EnvSlackWebhookURL)
@@ -100,6 +102,24 @@ func (s *SlackProcessor) processSlackWithTemplate(channel string, data template.
 			"missing Slack configuration")
 	}
 
+	payloadBytes, err := s.payload(channel, data, templateName)
+	if err != nil {
+		return err
+	}
+
+	// Send to Slack using appropriate method
+	if slackWebhookURL != "" {
+		// Use webhook method
+		return s.sendViaWebhook(ctx, slackWebhookURL, payloadBytes)
+	}
+
+	// Use OAuth token method
+	return s.sendViaOAuth(ctx, slackToken, payloadBytes)
+}
+
+// payload renders the message of a response with its template, adding the
+// channel unless the template sets one
+func (s *SlackProcessor) payload(channel string, data template.Data, templateName string) ([]byte, error) {
 	// Get template manager
 	manager := template.GetManager()
 
@@ -124,7 +144,7 @@ func (s *SlackProcessor) processSlackWithTemplate(channel string, data template.
 			"template": templateName,
 			"channel":  channel,
 		})
-		return errors.Wrap(errors.CategoryApplication, fmt.Errorf("empty payload generated from template %s", templateName),
+		return nil, errors.Wrap(errors.CategoryApplication, fmt.Errorf("empty payload generated from template %s", templateName),
 			"Slack message generation failed")
 	}
 
@@ -139,7 +159,7 @@ func (s *SlackProcessor) processSlackWithTemplate(channel string, data template.
 			"template": templateName,
 			"error":    err.Error(),
 		})
-		return errors.Wrap(errors.CategoryApplication, err, "Slack payload is not valid JSON")
+		return nil, errors.Wrap(errors.CategoryApplication, err, "Slack payload is not valid JSON")
 	}
 
 	// Add channel to payload if not present
@@ -150,22 +170,14 @@ func (s *SlackProcessor) processSlackWithTemplate(channel string, data template.
 	// Convert back to JSON
 	payloadBytes, err := json.Marshal(jsonPayload)
 	if err != nil {
-		return errors.Wrap(errors.CategoryApplication, err, "failed to marshal Slack payload")
-	}
-
-	// Send to Slack using appropriate method
-	if slackWebhookURL != "" {
-		// Use webhook method
-		return s.sendViaWebhook(slackWebhookURL, payloadBytes)
+		return nil, errors.Wrap(errors.CategoryApplication, err, "failed to marshal Slack payload")
 	}
-
-	// Use OAuth token method
-	return s.sendViaOAuth(slackToken, payloadBytes)
+	return payloadBytes, nil
 }
 
 // sendViaWebhook sends the message using a webhook URL
-func (s *SlackProcessor) sendViaWebhook(webhookURL string, payload []byte) error {
-	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(payload))
+func (s *SlackProcessor) sendViaWebhook(ctx context.Context, webhookURL string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(payload))
 	if err != nil {
 		return errors.Wrap(errors.CategoryApplication, err, "failed to create webhook request")
 	}
@@ -210,16 +222,41 @@ func (s *SlackProcessor) sendViaWebhook(webhookURL string, payload []byte) error
 	return nil
 }
 
+// slackAPIURL is the base URL of the Slack Web API
+var slackAPIURL = "https://slack.com/api"
+
 // sendViaOAuth sends the message using OAuth token and the Slack Web API
-func (s *SlackProcessor) sendViaOAuth(token string, payload []byte) error {
-	return s.sendViaOAuthWithURL(token, payload, "https://slack.com/api/chat.postMessage")
+func (s *SlackProcessor) sendViaOAuth(ctx context.Context, token string, payload []byte) error {
+	return s.sendViaOAuthWithURL(ctx, token, payload, slackAPIURL+"/chat.postMessage")
 }
 
 // sendViaOAuthWithURL allows testing with custom API endpoint
-func (s *SlackProcessor) sendViaOAuthWithURL(token string, payload []byte, apiURL string) error {
-	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(payload))
+func (s *SlackProcessor) sendViaOAuthWithURL(ctx context.Context, token string, payload []byte, apiURL string) error {
+	apiResp, err := s.callAPI(ctx, token, payload, apiURL)
 	if err != nil {
-		return errors.Wrap(errors.CategoryApplication, err, "failed to create Slack API request")
+		return err
+	}
+
+	log.Info("Successfully sent message to Slack via API", logger.Fields{
+		"ok": apiResp.OK,
+	})
+
+	return nil
+}
+
+// slackAPIResponse is the part of a Slack Web API response the processor uses
+type slackAPIResponse struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	TS      string `json:"ts,omitempty"` // identifies a posted message
+}
+
+// callAPI calls a Slack Web API method with a JSON payload
+func (s *SlackProcessor) callAPI(ctx context.Context, token string, payload []byte, apiURL string) (*slackAPIResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, errors.Wrap(errors.CategoryApplication, err, "failed to create Slack API request")
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -228,7 +265,7 @@ func (s *SlackProcessor) sendViaOAuthWithURL(token string, payload []byte, apiUR
 	client := http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
 	if err != nil {
-		return errors.Wrap(errors.CategoryExternal, err, "Slack API request failed")
+		return nil, errors.Wrap(errors.CategoryExternal, err, "Slack API request failed")
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
@@ -240,34 +277,132 @@ func (s *SlackProcessor) sendViaOAuthWithURL(token string, payload []byte, apiUR
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return errors.Wrap(errors.CategoryExternal, err, "failed to read API response")
+		return nil, errors.Wrap(errors.CategoryExternal, err, "failed to read API response")
 	}
 
 	if resp.StatusCode >= 400 {
-		return errors.Wrap(errors.CategoryExternal,
+		return nil, errors.Wrap(errors.CategoryExternal,
 			fmt.Errorf("slack API error: %d - %s", resp.StatusCode, string(body)),
 			"failed to send message via API")
 	}
 
 	// Parse the API response
-	var apiResp struct {
-		OK    bool   `json:"ok"`
-		Error string `json:"error,omitempty"`
-	}
-
+	var apiResp slackAPIResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return errors.Wrap(errors.CategoryApplication, err, "failed to parse Slack API response")
+		return nil, errors.Wrap(errors.CategoryApplication, err, "failed to parse Slack API response")
 	}
 
 	if !apiResp.OK {
-		return errors.Wrap(errors.CategoryExternal,
+		return nil, errors.Wrap(errors.CategoryExternal,
 			fmt.Errorf("slack API returned error: %s", apiResp.Error),
 			"Slack API request failed")
 	}
 
-	log.Info("Successfully sent message to Slack via API", logger.Fields{
-		"ok": apiResp.OK,
+	return &apiResp, nil
+}
+
+// slackStreamInterval limits how often a streamed message is updated, to stay
+// within the rate limits of chat.update
+var slackStreamInterval = time.Second
+
+// BeginStream posts a message that is updated with chat.update as the model
+// produces the response. Messages sent through a webhook can't be updated, so
+// only the OAuth token method streams.
+func (s *SlackProcessor) BeginStream(ctx context.Context, response *models.ModelResponse, templateName string) (ResponseStream, error) {
+	token := os.Getenv(EnvSlackToken)
+	if token == "" || os.Getenv(EnvSlackWebhookURL) != "" {
+		return nil, nil
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"channel": s.config.Target,
+		"text":    fmt.Sprintf("_%s: generating…_", response.PromptName),
 	})
+	if err != nil {
+		return nil, errors.Wrap(errors.CategoryApplication, err, "failed to marshal Slack payload")
+	}
+	apiResp, err := s.callAPI(ctx, token, payload, slackAPIURL+"/chat.postMessage")
+	if err != nil {
+		return nil, err
+	}
+	return &slackStream{processor: s, token: token, templateName: templateName, channel: apiResp.Channel, ts: apiResp.TS}, nil
+}
 
-	return nil
+// slackStream updates a posted message as the response is produced
+type slackStream struct {
+	processor    *SlackProcessor
+	token        string
+	templateName string
+	channel      string
+	ts           string
+	updated      time.Time
+}
+
+// Update shows the content produced so far, at most once per
+// slackStreamInterval
+func (s *slackStream) Update(ctx context.Context, content string) error {
+	if content == "" || time.Since(s.updated) < slackStreamInterval {
+		return nil
+	}
+	s.updated = time.Now()
+	return s.update(ctx, map[string]interface{}{"text": content + " …"})
+}
+
+// Finish replaces the message with the response formatted by the template
+func (s *slackStream) Finish(ctx context.Context, response *models.ModelResponse) error {
+	data := template.Data{
+		Content:     response.Content,
+		Model:       response.Model,
+		Timestamp:   response.Timestamp,
+		PromptName:  response.PromptName,
+		Variables:   response.Variables,
+		ExecutionID: response.ExecutionID,
+		Metadata: map[string]string{
+			"timestamp":    response.Timestamp.Format(time.RFC3339),
+			"date":         response.Timestamp.Format("2006-01-02"),
+			"time":         response.Timestamp.Format("15:04:05"),
+			"execution_id": response.ExecutionID,
+			"processor":    s.processor.GetType(),
+		},
+	}
+	addUsageMetadata(data.Metadata, response)
+	if s.templateName != "" {
+		data.Metadata["template"] = s.templateName
+	}
+
+	payloadBytes, err := s.processor.payload(s.channel, data, s.templateName)
+	if err != nil {
+		return err
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return errors.Wrap(errors.CategoryApplication, err, "Slack payload is not valid JSON")
+	}
+	return s.update(ctx, payload)
+}
+
+// Abort deletes the incomplete message
+func (s *slackStream) Abort(ctx context.Context, _ error) {
+	payload, err := json.Marshal(map[string]string{"channel": s.channel, "ts": s.ts})
+	if err == nil {
+		_, err = s.processor.callAPI(context.WithoutCancel(ctx), s.token, payload, slackAPIURL+"/chat.delete")
+	}
+	if err != nil {
+		log.Warn("Failed to delete incomplete Slack message", logger.Fields{
+			"channel": s.channel,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// update replaces the posted message with payload
+func (s *slackStream) update(ctx context.Context, payload map[string]interface{}) error {
+	payload["channel"] = s.channel
+	payload["ts"] = s.ts
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(errors.CategoryApplication, err, "failed to marshal Slack payload")
+	}
+	_, err = s.processor.callAPI(ctx, s.token, payloadBytes, slackAPIURL+"/chat.update")
+	return err
 }
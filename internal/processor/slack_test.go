@@ -2,6 +2,7 @@
 package processor
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -256,7 +257,7 @@ func TestSlackProcessor_Process(t *testing.T) {
 				t.Skip("OAuth tests require Slack API mocking - skipping")
 			}
 
-			err = processor.Process(tt.response, tt.templateName)
+			err = processor.Process(context.Background(), tt.response, tt.templateName)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Process() error = %v, wantErr %v", err, tt.wantErr)
@@ -317,7 +318,7 @@ func TestSlackProcessor_sendViaWebhook(t *testing.T) {
 				},
 			}
 
-			err := processor.sendViaWebhook(mockServer.URL, tt.payload)
+			err := processor.sendViaWebhook(context.Background(), mockServer.URL, tt.payload)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("sendViaWebhook() error = %v, wantErr %v", err, tt.wantErr)
@@ -435,7 +436,7 @@ func TestSlackProcessor_sendViaOAuth(t *testing.T) {
 				},
 			}
 
-			err := processor.sendViaOAuthWithURL(tt.token, tt.payload, mockServer.URL)
+			err := processor.sendViaOAuthWithURL(context.Background(), tt.token, tt.payload, mockServer.URL)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("sendViaOAuthWithURL() error = %v, wantErr %v", err, tt.wantErr)
@@ -457,7 +458,7 @@ func TestSlackProcessor_sendViaOAuth_NetworkErrors(t *testing.T) {
 	}
 
 	// Test with invalid URL to simulate network error
-	err := processor.sendViaOAuthWithURL("xoxb-test", []byte(`{}`), "http://invalid-url-that-does-not-exist.local")
+	err := processor.sendViaOAuthWithURL(context.Background(), "xoxb-test", []byte(`{}`), "http://invalid-url-that-does-not-exist.local")
 	if err == nil {
 		t.Error("Expected network error, got nil")
 	}
@@ -475,7 +476,7 @@ func TestSlackProcessor_sendViaOAuth_RequestCreationError(t *testing.T) {
 	}
 
 	// Test with invalid URL to cause request creation error
-	err := processor.sendViaOAuthWithURL("test", []byte(`{}`), "://invalid-url")
+	err := processor.sendViaOAuthWithURL(context.Background(), "test", []byte(`{}`), "://invalid-url")
 	if err == nil {
 		t.Error("Expected request creation error, got nil")
 	}
@@ -484,6 +485,33 @@ func TestSlackProcessor_sendViaOAuth_RequestCreationError(t *testing.T) {
 	}
 }
 
+func TestSlackProcessor_sendViaWebhook_Cancelled(t *testing.T) {
+	requests := make(chan struct{}, 1)
+	finished := make(chan struct{})
+	mockServer := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		requests <- struct{}{}
+		<-finished
+	}))
+	defer mockServer.Close()
+	defer close(finished)
+
+	processor := &SlackProcessor{config: Config{Type: "slack", Target: "#general"}}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-requests
+		cancel()
+	}()
+
+	// Cancelling the context aborts the request in flight
+	err := processor.sendViaWebhook(ctx, mockServer.URL, []byte(`{"text":"test"}`))
+	if err == nil {
+		t.Fatal("Expected error for cancelled request, got nil")
+	}
+	if !strings.Contains(err.Error(), "context canceled") {
+		t.Errorf("Expected 'context canceled' error, got: %v", err)
+	}
+}
+
 func TestSlackProcessor_GetType(t *testing.T) {
 	processor := &SlackProcessor{
 		config: Config{
@@ -592,7 +620,7 @@ func TestSlackProcessor_JSONValidation(t *testing.T) {
 				t.Fatalf("Failed to create processor: %v", err)
 			}
 
-			err = slackProcessor.Process(tt.response, tt.templateName)
+			err = slackProcessor.Process(context.Background(), tt.response, tt.templateName)
 			if err != nil && tt.wantValid {
 				t.Errorf("Process() returned error for valid JSON: %v", err)
 			}
@@ -0,0 +1,34 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/rshade/cronai/internal/models"
+)
+
+// StreamingProcessor is implemented by processors that can show a response
+// while the model produces it
+type StreamingProcessor interface {
+	Processor
+
+	// BeginStream starts delivering a response whose content is still being
+	// produced. response carries everything but the content. A nil stream
+	// means the processor can't stream with this configuration, the
+	// response is then delivered with Process once it is complete.
+	BeginStream(ctx context.Context, response *models.ModelResponse, templateName string) (ResponseStream, error)
+}
+
+// ResponseStream is the delivery of a response in progress
+type ResponseStream interface {
+	// Update shows the content produced so far. Content that doesn't extend
+	// the previous update replaces it, as when the model starts over.
+	Update(ctx context.Context, content string) error
+
+	// Finish completes the delivery with the assembled response, formatted
+	// as Process would
+	Finish(ctx context.Context, response *models.ModelResponse) error
+
+	// Abort ends a delivery that won't be finished and removes what it
+	// showed where possible
+	Abort(ctx context.Context, err error)
+}
@@ -0,0 +1,156 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/processor/template"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsoleStream(t *testing.T) {
+	var out bytes.Buffer
+	oldOutput := consoleOutput
+	consoleOutput = &out
+	defer func() { consoleOutput = oldOutput }()
+
+	proc := &ConsoleProcessor{config: Config{Type: "console"}}
+	response := &models.ModelResponse{PromptName: "weekly", Model: "openai", Timestamp: time.Now()}
+
+	stream, err := proc.BeginStream(context.Background(), response, "")
+	require.NoError(t, err)
+	require.NotNil(t, stream)
+	require.NoError(t, stream.Update(context.Background(), "Wee"))
+	require.NoError(t, stream.Update(context.Background(), "Weekly"))
+	require.NoError(t, stream.Update(context.Background(), "Monthly"))
+	response.Content = "Monthly report"
+	require.NoError(t, stream.Finish(context.Background(), response))
+
+	printed := out.String()
+	assert.Contains(t, printed, "AI Response: weekly")
+	assert.Contains(t, printed, "Weekly\n\n[response restarted]\n\nMonthly report")
+	assert.Contains(t, printed, "Model: openai")
+
+	// Templated output is printed once it is complete
+	stream, err = proc.BeginStream(context.Background(), response, "custom")
+	require.NoError(t, err)
+	assert.Nil(t, stream)
+}
+
+func TestFileStream(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv(EnvLogsDirectory, tempDir)
+	manager := template.GetManager()
+	require.NoError(t, manager.RegisterTemplate("stream_file_filename", "{{.PromptName}}-{{.ExecutionID}}.txt"))
+	require.NoError(t, manager.RegisterTemplate("stream_file_content", "# {{.PromptName}}\n{{.Content}}"))
+
+	proc := &FileProcessor{config: Config{Type: "file"}}
+	response := &models.ModelResponse{PromptName: "weekly", ExecutionID: "exec-1", Timestamp: time.Now()}
+	path := filepath.Join(tempDir, "weekly-exec-1.txt")
+
+	// The file shows the raw content while it is produced
+	stream, err := proc.BeginStream(context.Background(), response, "stream_file")
+	require.NoError(t, err)
+	require.NoError(t, stream.Update(context.Background(), "Week"))
+	require.NoError(t, stream.Update(context.Background(), "Weekly"))
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly", string(content))
+
+	// Restarts rewrite it
+	require.NoError(t, stream.Update(context.Background(), "Monthly"))
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Monthly", string(content))
+
+	// Finishing formats it with the template
+	response.Content = "Monthly report"
+	require.NoError(t, stream.Finish(context.Background(), response))
+	content, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "# weekly\nMonthly report", string(content))
+
+	// Aborted streams leave no file behind
+	response.ExecutionID = "exec-2"
+	stream, err = proc.BeginStream(context.Background(), response, "stream_file")
+	require.NoError(t, err)
+	require.NoError(t, stream.Update(context.Background(), "Week"))
+	stream.Abort(context.Background(), fmt.Errorf("model failed"))
+	assert.NoFileExists(t, filepath.Join(tempDir, "weekly-exec-2.txt"))
+}
+
+// slackAPIServer records the Slack Web API calls it receives
+type slackAPIServer struct {
+	mu    sync.Mutex
+	calls []string
+	texts []string
+}
+
+func newSlackAPIServer(t *testing.T) *slackAPIServer {
+	t.Helper()
+	api := &slackAPIServer{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		api.mu.Lock()
+		api.calls = append(api.calls, fmt.Sprintf("%s %v", r.URL.Path, payload["ts"]))
+		if text, ok := payload["text"].(string); ok {
+			api.texts = append(api.texts, text)
+		}
+		api.mu.Unlock()
+		_, _ = fmt.Fprint(w, `{"ok":true,"channel":"C123","ts":"1700000000.000100"}`)
+	}))
+	t.Cleanup(server.Close)
+
+	oldURL, oldInterval := slackAPIURL, slackStreamInterval
+	slackAPIURL, slackStreamInterval = server.URL, 0
+	t.Cleanup(func() { slackAPIURL, slackStreamInterval = oldURL, oldInterval })
+	return api
+}
+
+func TestSlackStream(t *testing.T) {
+	template.GetManager()
+	api := newSlackAPIServer(t)
+	t.Setenv(EnvSlackToken, "xoxb-test")
+	t.Setenv(EnvSlackWebhookURL, "")
+
+	proc := &SlackProcessor{config: Config{Type: "slack", Target: "#reports"}}
+	response := &models.ModelResponse{PromptName: "weekly", Model: "openai", Timestamp: time.Now()}
+
+	// The posted message is updated in place
+	stream, err := proc.BeginStream(context.Background(), response, "")
+	require.NoError(t, err)
+	require.NotNil(t, stream)
+	require.NoError(t, stream.Update(context.Background(), "Weekly"))
+	response.Content = "Weekly report"
+	require.NoError(t, stream.Finish(context.Background(), response))
+	assert.Equal(t, []string{
+		"/chat.postMessage <nil>",
+		"/chat.update 1700000000.000100",
+		"/chat.update 1700000000.000100",
+	}, api.calls)
+	assert.Equal(t, "Weekly …", api.texts[1])
+
+	// Incomplete messages are deleted
+	stream, err = proc.BeginStream(context.Background(), response, "")
+	require.NoError(t, err)
+	stream.Abort(context.Background(), fmt.Errorf("model failed"))
+	assert.Equal(t, "/chat.delete 1700000000.000100", api.calls[len(api.calls)-1])
+
+	// Webhook messages can't be updated
+	t.Setenv(EnvSlackWebhookURL, "https://hooks.slack.com/services/test")
+	stream, err = proc.BeginStream(context.Background(), response, "")
+	require.NoError(t, err)
+	assert.Nil(t, stream)
+}
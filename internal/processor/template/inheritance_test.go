@@ -279,7 +279,7 @@ Content: {{.Content}}
 
 // Helper function to check if string contains substring
 func contains(s, substr string) bool {
-	return s != "" && s != substr && len(s) >= len(substr) && s != substr && substring(s, substr) >= 0
+	return s != "" && s != substr && len(s) >= len(substr) && substring(s, substr) >= 0
 }
 
 func substring(s, substr string) int {
@@ -1,6 +1,7 @@
 package processor
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -25,7 +26,7 @@ func NewWebhookProcessor(config Config) (Processor, error) {
 }
 
 // Process handles the model response with optional template
-func (w *WebhookProcessor) Process(response *models.ModelResponse, templateName string) error {
+func (w *WebhookProcessor) Process(_ context.Context, response *models.ModelResponse, templateName string) error {
 	// Create template data
 	tmplData := template.Data{
 		Content:     response.Content,
@@ -42,6 +43,7 @@ func (w *WebhookProcessor) Process(response *models.ModelResponse, templateName
 	tmplData.Metadata["date"] = response.Timestamp.Format("2006-01-02")
 	tmplData.Metadata["time"] = response.Timestamp.Format("15:04:05")
 	tmplData.Metadata["execution_id"] = response.ExecutionID
+	addUsageMetadata(tmplData.Metadata, response)
 	tmplData.Metadata["processor"] = w.GetType()
 	if templateName != "" {
 		tmplData.Metadata["template"] = templateName
@@ -186,7 +188,7 @@ func (w *WebhookProcessor) processWebhookWithTemplate(webhookType string, data t
 
 	// For production implementation:
 	/*
-		req, err := http.NewRequest(webhookMethod, webhookURL, bytes.NewBuffer([]byte(payload)))
+		req, err := http.NewRequestWithContext(ctx, webhookMethod, webhookURL, bytes.NewBuffer([]byte(payload)))
 		if err != nil {
 			return errors.Wrap(errors.CategoryApplication, err, "failed to create webhook request")
 		}
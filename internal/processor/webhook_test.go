@@ -2,6 +2,7 @@
 package processor
 
 import (
+	"context"
 	"os"
 	"testing"
 	"time"
@@ -190,7 +191,7 @@ func TestTeamsWebhookProcessor(t *testing.T) {
 			}()
 
 			// Execute the processor
-			err := ProcessResponse(tt.processor, tt.response, tt.templateName)
+			err := ProcessResponse(context.Background(), tt.processor, tt.response, tt.templateName)
 
 			// Validate results
 			if tt.expectedError && err == nil {
@@ -450,3 +450,20 @@ func GetPromptInfo(name string) (Info, error) {
 
 	return info, nil
 }
+
+// Category returns the category budgets count the calls of a prompt under:
+// the category of its metadata, or else the directory of cron_prompts it is
+// in. It returns an empty string for prompts that can't be found.
+func Category(name string) string {
+	info, err := GetPromptInfo(name)
+	if err != nil {
+		return ""
+	}
+	if info.Category != "" {
+		return info.Category
+	}
+	if dir := filepath.Base(filepath.Dir(info.Path)); dir != "cron_prompts" {
+		return dir
+	}
+	return ""
+}
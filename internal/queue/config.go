@@ -9,6 +9,13 @@ import (
 	"time"
 
 	"github.com/kballard/go-shellquote"
+	"github.com/rshade/cronai/pkg/config"
+)
+
+// Default retry settings for queue consumers
+const (
+	DefaultRetryLimit = 3
+	DefaultRetryDelay = 5 * time.Second
 )
 
 // Task represents a task configuration from the queue section
@@ -53,8 +60,8 @@ func ParseQueueConfig(line string) (*Task, error) {
 		Connection: parts[3],
 		Queue:      parts[4],
 		Options:    nil,
-		RetryLimit: 3,               // Default retry limit
-		RetryDelay: 5 * time.Second, // Default retry delay
+		RetryLimit: DefaultRetryLimit,
+		RetryDelay: DefaultRetryDelay,
 	}
 
 	// Parse optional configuration
@@ -150,3 +157,69 @@ func IsQueueConfig(line string) bool {
 	line = strings.TrimSpace(line)
 	return strings.HasPrefix(line, "queue ")
 }
+
+// TasksFromFile returns the queue consumers defined in a structured configuration file
+func TasksFromFile(file *config.File) ([]*Task, error) {
+	tasks := make([]*Task, 0, len(file.Queues))
+	for _, queueConfig := range file.Queues {
+		if queueConfig.Name == "" || queueConfig.Type == "" || queueConfig.Queue == "" {
+			return nil, fmt.Errorf("invalid queue %q: name, type and queue are required", queueConfig.Name)
+		}
+
+		task := &Task{
+			Name:       queueConfig.Name,
+			Type:       queueConfig.Type,
+			Connection: queueConfig.Connection,
+			Queue:      queueConfig.Queue,
+			RetryLimit: DefaultRetryLimit,
+			RetryDelay: DefaultRetryDelay,
+		}
+		if queueConfig.RetryLimit != nil {
+			task.RetryLimit = *queueConfig.RetryLimit
+		}
+		if queueConfig.RetryDelay != "" {
+			delay, err := time.ParseDuration(queueConfig.RetryDelay)
+			if err != nil {
+				return nil, fmt.Errorf("invalid queue %q: invalid retry_delay: %s", queueConfig.Name, queueConfig.RetryDelay)
+			}
+			task.RetryDelay = delay
+		}
+		if len(queueConfig.Options) > 0 {
+			task.Options = make(map[string]interface{}, len(queueConfig.Options))
+			for k, v := range queueConfig.Options {
+				task.Options[k] = v
+			}
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// QueueConfig returns the structured configuration of a queue task
+func (t *Task) QueueConfig() config.QueueConfig {
+	queueConfig := config.QueueConfig{
+		Name:       t.Name,
+		Type:       t.Type,
+		Connection: t.Connection,
+		Queue:      t.Queue,
+	}
+	if t.RetryLimit != DefaultRetryLimit {
+		limit := t.RetryLimit
+		queueConfig.RetryLimit = &limit
+	}
+	if t.RetryDelay != DefaultRetryDelay {
+		queueConfig.RetryDelay = t.RetryDelay.String()
+	}
+
+	// Retry settings are fields of their own, everything else is provider-specific
+	for k, v := range t.Options {
+		if k == "retry_limit" || k == "retry_delay" {
+			continue
+		}
+		if queueConfig.Options == nil {
+			queueConfig.Options = make(map[string]string)
+		}
+		queueConfig.Options[k] = fmt.Sprint(v)
+	}
+	return queueConfig
+}
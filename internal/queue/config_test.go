@@ -6,6 +6,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/rshade/cronai/pkg/config"
 )
 
 func TestParseQueueConfig(t *testing.T) {
@@ -365,3 +367,55 @@ func TestIsQueueConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestTasksFromFile(t *testing.T) {
+	limit := 0
+	file := &config.File{Queues: []config.QueueConfig{
+		{Name: "main", Type: "rabbitmq", Connection: "amqp://localhost:5672", Queue: "tasks",
+			RetryLimit: &limit, RetryDelay: "1m", Options: map[string]string{"prefetch": "10"}},
+		{Name: "dev", Type: "memory", Queue: "dev-tasks"},
+	}}
+
+	tasks, err := TasksFromFile(file)
+	if err != nil {
+		t.Fatalf("TasksFromFile failed: %v", err)
+	}
+	expected := []*Task{
+		{Name: "main", Type: "rabbitmq", Connection: "amqp://localhost:5672", Queue: "tasks",
+			Options: map[string]interface{}{"prefetch": "10"}, RetryLimit: 0, RetryDelay: time.Minute},
+		{Name: "dev", Type: "memory", Queue: "dev-tasks", RetryLimit: DefaultRetryLimit, RetryDelay: DefaultRetryDelay},
+	}
+	if !reflect.DeepEqual(tasks, expected) {
+		t.Errorf("TasksFromFile() = %+v, expected %+v", tasks, expected)
+	}
+
+	// Converting back only writes settings that differ from the defaults
+	if got := tasks[1].QueueConfig(); !reflect.DeepEqual(got, file.Queues[1]) {
+		t.Errorf("QueueConfig() = %+v, expected %+v", got, file.Queues[1])
+	}
+
+	if _, err := TasksFromFile(&config.File{Queues: []config.QueueConfig{{Name: "bad", Type: "memory"}}}); err == nil {
+		t.Error("Expected an error for a queue without a queue name")
+	}
+	if _, err := TasksFromFile(&config.File{Queues: []config.QueueConfig{
+		{Name: "bad", Type: "memory", Queue: "q", RetryDelay: "soon"},
+	}}); err == nil {
+		t.Error("Expected an error for an invalid retry delay")
+	}
+}
+
+func TestQueueConfigFromLine(t *testing.T) {
+	task, err := ParseQueueConfig("queue main rabbitmq amqp://localhost:5672 tasks retry_limit=5,prefetch=10")
+	if err != nil {
+		t.Fatalf("ParseQueueConfig failed: %v", err)
+	}
+
+	limit := 5
+	expected := config.QueueConfig{
+		Name: "main", Type: "rabbitmq", Connection: "amqp://localhost:5672", Queue: "tasks",
+		RetryLimit: &limit, Options: map[string]string{"prefetch": "10"},
+	}
+	if got := task.QueueConfig(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("QueueConfig() = %+v, expected %+v", got, expected)
+	}
+}
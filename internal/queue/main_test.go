@@ -0,0 +1,14 @@
+package queue
+
+import (
+	"os"
+	"testing"
+
+	"github.com/rshade/cronai/internal/history"
+)
+
+// TestMain keeps execution history in memory so tests don't write to the working directory
+func TestMain(m *testing.M) {
+	history.SetStore(history.NewMemoryStore())
+	os.Exit(m.Run())
+}
@@ -7,6 +7,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/rshade/cronai/internal/variable"
 )
 
 // DefaultMessageParser implements the MessageParser interface
@@ -122,6 +124,7 @@ func (p *DefaultMessageParser) Validate(task *TaskMessage) error {
 		"openai": true,
 		"claude": true,
 		"gemini": true,
+		"ollama": true,
 	}
 
 	if !validModels[strings.ToLower(task.Model)] {
@@ -144,6 +147,9 @@ func (p *DefaultMessageParser) Validate(task *TaskMessage) error {
 		if value == "" {
 			return fmt.Errorf("variable value for key '%s' cannot be empty", key)
 		}
+		if err := variable.Validate(value); err != nil {
+			return fmt.Errorf("variable '%s': %w", key, err)
+		}
 	}
 
 	return nil
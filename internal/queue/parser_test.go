@@ -215,6 +215,19 @@ func TestDefaultMessageParser_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "invalid variable expression",
+			task: &TaskMessage{
+				Model:     "openai",
+				Prompt:    "test_prompt",
+				Processor: "console",
+				Variables: map[string]string{
+					"since": "{{date -7x}}",
+				},
+			},
+			wantErr: true,
+			errMsg:  "variable 'since': {{date -7x}}: invalid offset '-7x'",
+		},
 		{
 			name: "empty model",
 			task: &TaskMessage{
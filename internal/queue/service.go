@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/rshade/cronai/internal/logger"
+	cfgfile "github.com/rshade/cronai/pkg/config"
 )
 
 // Service manages the queue operation mode
@@ -112,14 +113,26 @@ func (s *Service) Start(configPath string) error {
 }
 
 // loadConfig loads queue configuration from file or environment
-func (s *Service) loadConfig(_ string) (*ServiceConfig, error) {
-	// For now, we'll use environment variables for configuration
-	// In the future, this could be extended to support config files
-
+func (s *Service) loadConfig(configPath string) (*ServiceConfig, error) {
 	config := &ServiceConfig{
 		Consumers: []ConsumerConfig{},
 	}
 
+	// Queues defined in a structured YAML configuration file
+	if cfgfile.IsYAMLFile(configPath) {
+		file, err := cfgfile.LoadFile(configPath)
+		if err != nil {
+			return nil, err
+		}
+		tasks, err := TasksFromFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			config.Consumers = append(config.Consumers, *CreateConsumerConfig(task))
+		}
+	}
+
 	// Check for environment-based configuration
 	if queueType := os.Getenv("QUEUE_TYPE"); queueType != "" {
 		consumerConfig := ConsumerConfig{
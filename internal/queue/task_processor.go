@@ -5,18 +5,39 @@ package queue
 
 import (
 	"context"
-	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/rshade/cronai/internal/budget"
+	"github.com/rshade/cronai/internal/errors"
+	"github.com/rshade/cronai/internal/history"
 	"github.com/rshade/cronai/internal/logger"
 	"github.com/rshade/cronai/internal/models"
+	"github.com/rshade/cronai/internal/pool"
 	"github.com/rshade/cronai/internal/processor"
 	"github.com/rshade/cronai/internal/prompt"
+	"github.com/rshade/cronai/internal/variable"
 )
 
+// EnvAllowedEnv lists, comma-separated, the environment variables that env
+// expressions in task messages may read
+const EnvAllowedEnv = "QUEUE_ALLOWED_ENV"
+
 // executeModel is a variable function for mocking in tests
 var executeModel = models.ExecuteModel
 
+// allowedEnv returns the environment variables task messages may read
+func allowedEnv() []string {
+	var names []string
+	for _, name := range strings.Split(os.Getenv(EnvAllowedEnv), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // DefaultTaskProcessor implements the TaskProcessor interface
 type DefaultTaskProcessor struct {
 	promptManager prompt.Manager
@@ -30,7 +51,7 @@ func NewTaskProcessor() TaskProcessor {
 }
 
 // Process processes a task message
-func (p *DefaultTaskProcessor) Process(_ context.Context, task *TaskMessage) error {
+func (p *DefaultTaskProcessor) Process(ctx context.Context, task *TaskMessage) (err error) {
 	startTime := time.Now()
 
 	log.Info("Processing queue task", logger.Fields{
@@ -40,15 +61,43 @@ func (p *DefaultTaskProcessor) Process(_ context.Context, task *TaskMessage) err
 		"isInline":  task.IsInline,
 	})
 
+	record := history.New(history.SourceQueue)
+	record.Model = task.Model
+	record.Processor = task.Processor
+	record.Prompt = task.Prompt
+	if task.IsInline {
+		record.Prompt = "inline"
+	}
+	defer func() {
+		record.Finish()
+		if saveErr := history.Save(record); saveErr != nil {
+			log.Warn("Failed to record execution history", logger.Fields{
+				"execution_id": record.ID,
+				"error":        saveErr.Error(),
+			})
+		}
+	}()
+
+	// Evaluate dynamic expressions such as {{date -7d}}. Messages come from
+	// outside the configuration, so env only reads the allowed variables.
+	evaluator := &variable.Evaluator{Now: time.Now(), LookupEnv: variable.LookupAllowed(allowedEnv())}
+	variables, err := evaluator.ExpandAll(task.Variables)
+	if err != nil {
+		err = errors.Wrap(errors.CategoryValidation, err, "failed to evaluate variables")
+		record.Fail(history.StagePrompt, err)
+		return err
+	}
+	task.Variables = variables
+
 	// Add the prompt name to variables for tracking before loading
 	if task.Variables == nil {
 		task.Variables = make(map[string]string)
 	}
 	task.Variables["promptName"] = task.Prompt
+	record.Variables = task.Variables
 
 	// Load or use the prompt content
 	var promptContent string
-	var err error
 
 	if task.IsInline {
 		// Use the prompt field directly as content
@@ -68,20 +117,59 @@ func (p *DefaultTaskProcessor) Process(_ context.Context, task *TaskMessage) err
 		}
 
 		if err != nil {
-			return fmt.Errorf("failed to load prompt: %w", err)
+			err = errors.Wrap(errors.CategoryConfiguration, err, "failed to load prompt")
+			record.Fail(history.StagePrompt, err)
+			return err
 		}
 	}
+	record.SetPrompt(promptContent)
 
 	log.Debug("Executing model", logger.Fields{
 		"model":     task.Model,
 		"var_count": len(task.Variables),
 	})
 
-	response, err := executeModel(task.Model, promptContent, task.Variables, "")
+	// Wait for a slot in the execution pool shared with the cron and bot
+	// modes. It is only held for the model call, which takes the slot of
+	// each provider it tries.
+	release, err := pool.Default().AcquireGlobal(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to execute model: %w", err)
+		err = errors.Wrap(errors.CategorySystem, err, "no execution slot available")
+		record.Fail(history.StageModel, err)
+		return err
 	}
 
+	// Check the model call against the budgets of the configuration, which
+	// count queue messages by their prompt's category and provider
+	if guard := budget.Default(); guard != nil {
+		var category string
+		if !task.IsInline {
+			category = prompt.Category(task.Prompt)
+		}
+		ctx = budget.WithGuard(ctx, guard, budget.Request{Category: category})
+	}
+	response, err := executeModel(ctx, task.Model, promptContent, task.Variables, "")
+	release()
+	var exceeded *budget.ExceededError
+	if errors.As(err, &exceeded) {
+		// An exhausted budget refused the call; retrying the message won't help
+		record.Skip(err.Error())
+		log.Warn("Queue task refused by budget", logger.Fields{"execution_id": record.ID, "reason": err.Error()})
+		return nil
+	}
+	if err != nil {
+		err = errors.Wrap(errors.CategoryExternal, err, "failed to execute model")
+		record.Fail(history.StageModel, err)
+		return err
+	}
+	record.ModelUsed = response.Provider
+	if record.ModelUsed == "" {
+		record.ModelUsed = task.Model
+	}
+	record.ModelVersion = response.Model
+	record.SetUsage(response.Usage.PromptTokens, response.Usage.CompletionTokens, response.Usage.CachedTokens, response.Cost)
+	record.Response = response.Content
+
 	// Process the response
 	log.Debug("Processing response", logger.Fields{"processor": task.Processor})
 
@@ -97,29 +185,40 @@ func (p *DefaultTaskProcessor) Process(_ context.Context, task *TaskMessage) err
 	registry := processor.GetRegistry()
 	proc, err := registry.CreateProcessor(procType, procConfig)
 	if err != nil {
-		return fmt.Errorf("failed to create processor: %w", err)
+		err = errors.Wrap(errors.CategoryConfiguration, err, "failed to create processor")
+		record.Fail(history.StageProcessor, err)
+		return err
 	}
 
 	// Create a model response
-	currentTime := time.Now()
 	modelResponse := &models.ModelResponse{
 		Model:       task.Model,
 		PromptName:  task.Prompt,
 		Content:     response.Content,
-		Timestamp:   currentTime,
-		ExecutionID: fmt.Sprintf("queue-%d", currentTime.UnixNano()),
+		Timestamp:   time.Now(),
+		ExecutionID: record.ID,
+		Provider:    record.ModelUsed,
+		Usage:       response.Usage,
+		Cost:        response.Cost,
 	}
 
 	// Process the response
-	if err := proc.Process(modelResponse, ""); err != nil {
-		return fmt.Errorf("failed to process response: %w", err)
+	if err = proc.Process(ctx, modelResponse, ""); err != nil {
+		err = errors.Wrap(errors.CategoryExternal, err, "failed to process response")
+		record.Fail(history.StageProcessor, err)
+		return err
 	}
 
 	duration := time.Since(startTime)
 	log.Info("Queue task completed successfully", logger.Fields{
-		"duration":  duration.String(),
-		"model":     task.Model,
-		"processor": task.Processor,
+		"duration":          duration.String(),
+		"execution_id":      record.ID,
+		"model":             task.Model,
+		"processor":         task.Processor,
+		"prompt_tokens":     record.PromptTokens,
+		"completion_tokens": record.CompletionTokens,
+		"cached_tokens":     record.CachedTokens,
+		"cost":              record.Cost,
 	})
 
 	return nil
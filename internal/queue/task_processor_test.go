@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/rshade/cronai/internal/budget"
+	"github.com/rshade/cronai/internal/history"
 	"github.com/rshade/cronai/internal/models"
 	"github.com/rshade/cronai/internal/prompt"
 )
@@ -105,7 +109,7 @@ Include the following:
 
 	// Setup mock model execution
 	originalExecute := executeModel
-	executeModel = func(_, _ string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+	executeModel = func(_ context.Context, _, _ string, variables map[string]string, _ string) (*models.ModelResponse, error) {
 		return &models.ModelResponse{
 			Model:      variables["model"],
 			PromptName: variables["promptName"],
@@ -327,7 +331,7 @@ func TestDefaultTaskProcessor_ProcessWithModelError(t *testing.T) {
 	defer cleanup()
 
 	// Override model execution to return error
-	executeModel = func(_, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+	executeModel = func(_ context.Context, _, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
 		return nil, fmt.Errorf("model execution failed")
 	}
 
@@ -383,7 +387,7 @@ End of prompt.`
 	var capturedPrompt string
 
 	// Override model execution to capture the prompt
-	executeModel = func(_, prompt string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+	executeModel = func(_ context.Context, _, prompt string, _ map[string]string, _ string) (*models.ModelResponse, error) {
 		capturedPrompt = prompt
 		return &models.ModelResponse{
 			Content: "test response",
@@ -426,7 +430,7 @@ func TestDefaultTaskProcessor_InlinePromptNameVariable(t *testing.T) {
 	var capturedVariables map[string]string
 
 	// Override model execution to capture the variables
-	executeModel = func(_, _ string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+	executeModel = func(_ context.Context, _, _ string, variables map[string]string, _ string) (*models.ModelResponse, error) {
 		capturedVariables = variables
 		return &models.ModelResponse{
 			Content: "test response",
@@ -464,3 +468,108 @@ func TestDefaultTaskProcessor_InlinePromptNameVariable(t *testing.T) {
 		t.Errorf("expected promptName to be %q, got %q", task.Prompt, promptName)
 	}
 }
+
+// TestDefaultTaskProcessor_DynamicVariables tests that expressions in message variables are
+// evaluated and that env expressions only read the allowed variables
+func TestDefaultTaskProcessor_DynamicVariables(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+	t.Setenv("CRONAI_TEST_REGION", "eu-west-1")
+	t.Setenv("CRONAI_TEST_SECRET", "hunter2")
+	t.Setenv(EnvAllowedEnv, "CRONAI_TEST_REGION")
+
+	var capturedVariables map[string]string
+	executeModel = func(_ context.Context, _, _ string, variables map[string]string, _ string) (*models.ModelResponse, error) {
+		capturedVariables = variables
+		return &models.ModelResponse{Content: "test response"}, nil
+	}
+
+	processor := &DefaultTaskProcessor{promptManager: &testPromptManager{}}
+	task := &TaskMessage{
+		Model:     "openai",
+		Prompt:    "test_prompt",
+		Processor: "console",
+		Variables: map[string]string{"date": "{{CURRENT_DATE}}", "region": `{{env "CRONAI_TEST_REGION"}}`},
+	}
+	if err := processor.Process(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if capturedVariables["date"] != time.Now().Format("2006-01-02") {
+		t.Errorf("expected today's date, got %q", capturedVariables["date"])
+	}
+	if capturedVariables["region"] != "eu-west-1" {
+		t.Errorf("expected the allowed variable, got %q", capturedVariables["region"])
+	}
+
+	task.Variables = map[string]string{"secret": `{{env "CRONAI_TEST_SECRET"}}`}
+	if err := processor.Process(context.Background(), task); err == nil {
+		t.Error("expected reading a variable that isn't allowed to fail")
+	}
+}
+
+// TestDefaultTaskProcessor_Budgets tests that queue messages are checked
+// against the budgets of the configuration and counted in its ledger
+func TestDefaultTaskProcessor_Budgets(t *testing.T) {
+	_, cleanup := setupTestEnvironment(t)
+	defer cleanup()
+
+	store := history.NewMemoryStore()
+	history.SetStore(store)
+	defer history.SetStore(history.NewMemoryStore())
+
+	ledgerPath := filepath.Join(t.TempDir(), "budget.json")
+	ledger, err := budget.LoadLedger(ledgerPath)
+	if err != nil {
+		t.Fatalf("failed to load ledger: %v", err)
+	}
+	budget.SetDefault(&budget.Guard{
+		Budgets: []*budget.Budget{{Scope: budget.ScopeProvider, Name: "openai", Limit: 5, Period: budget.PeriodDay, Policy: budget.PolicyRefuse}},
+		Ledger:  ledger,
+	})
+	defer budget.SetDefault(nil)
+
+	// The model call applies the guard it is given, like models.ExecuteModel
+	originalExecute := executeModel
+	defer func() { executeModel = originalExecute }()
+	calls := 0
+	executeModel = func(ctx context.Context, model, _ string, _ map[string]string, _ string) (*models.ModelResponse, error) {
+		if err := models.BudgetAllows(ctx, model); err != nil {
+			return nil, err
+		}
+		calls++
+		response := &models.ModelResponse{Content: "ok", Model: model, Provider: model, Cost: 5}
+		models.RecordSpend(ctx, response)
+		return response, nil
+	}
+
+	processor := &DefaultTaskProcessor{promptManager: &testPromptManager{}}
+	task := &TaskMessage{Model: "openai", Prompt: "test_prompt", Processor: "console"}
+	if err := processor.Process(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The spend is saved for the cron service and later runs
+	saved, err := budget.LoadLedger(ledgerPath)
+	if err != nil {
+		t.Fatalf("failed to load ledger: %v", err)
+	}
+	if spent := saved.Spent("provider:openai", budget.PeriodDay, time.Now()); spent != 5 {
+		t.Errorf("expected $5 saved for provider:openai, got $%.2f", spent)
+	}
+
+	// The exhausted budget refuses the next message without failing it
+	task = &TaskMessage{Model: "openai", Prompt: "test_prompt", Processor: "console"}
+	if err := processor.Process(context.Background(), task); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the model to be called once, got %d calls", calls)
+	}
+	records, err := store.List(history.Filter{Status: history.StatusSkipped})
+	if err != nil {
+		t.Fatalf("failed to list history: %v", err)
+	}
+	if len(records) != 1 || !strings.Contains(records[0].Reason, "budget provider:openai $5.00/day exhausted") {
+		t.Errorf("expected one run skipped by the budget, got %+v", records)
+	}
+}
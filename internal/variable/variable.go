@@ -0,0 +1,268 @@
+// Package variable evaluates the dynamic expressions task variables may
+// contain, such as {{date -7d "2006-01-02"}} or {{env "REGION"}}. Expressions
+// are evaluated each time a task runs, so dates refer to the run rather than
+// to when the configuration was loaded.
+package variable
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// Default layouts of the date functions
+const (
+	DateLayout     = "2006-01-02"
+	TimeLayout     = "15:04:05"
+	DatetimeLayout = "2006-01-02 15:04:05"
+)
+
+// expressionPattern matches an expression and captures its contents
+var expressionPattern = regexp.MustCompile(`{{\s*(.*?)\s*}}`)
+
+// offsetPattern matches a date offset such as -7d or +1m
+var offsetPattern = regexp.MustCompile(`^[+-][0-9]+[hdwmy]$`)
+
+// function evaluates an expression with its arguments
+type function func(e *Evaluator, args []string) (string, error)
+
+// functions are the expressions an evaluator understands, by name
+var functions = map[string]function{
+	"date":        dateFunc(DateLayout, nil),
+	"time":        dateFunc(TimeLayout, nil),
+	"datetime":    dateFunc(DatetimeLayout, nil),
+	"week_start":  dateFunc(DateLayout, weekStart),
+	"week_end":    dateFunc(DateLayout, func(t time.Time) time.Time { return weekStart(t).AddDate(0, 0, 6) }),
+	"month_start": dateFunc(DateLayout, monthStart),
+	"month_end":   dateFunc(DateLayout, func(t time.Time) time.Time { return monthStart(t).AddDate(0, 1, -1) }),
+	"month_name":  fixedDateFunc("January"),
+	"weekday":     fixedDateFunc("Monday"),
+	"year":        fixedDateFunc("2006"),
+	"env":         envFunc,
+	"hostname":    hostnameFunc,
+
+	// Kept from the original fixed special values
+	"CURRENT_DATE":     fixedDateFunc(DateLayout),
+	"CURRENT_TIME":     fixedDateFunc(TimeLayout),
+	"CURRENT_DATETIME": fixedDateFunc(DatetimeLayout),
+}
+
+// Evaluator evaluates expressions at a point in time
+type Evaluator struct {
+	Now time.Time // Time dates are relative to, in the timezone they are formatted in
+
+	// LookupEnv reads the variables of env expressions, os.LookupEnv when nil
+	LookupEnv func(name string) (string, bool)
+}
+
+// LookupAllowed returns a LookupEnv that only reads the named variables, for
+// expressions that come from outside the configuration
+func LookupAllowed(names []string) func(name string) (string, bool) {
+	return func(name string) (string, bool) {
+		for _, allowed := range names {
+			if name == allowed {
+				return os.LookupEnv(name)
+			}
+		}
+		return "", false
+	}
+}
+
+// New returns an evaluator for the given time that reads the process environment
+func New(now time.Time) *Evaluator {
+	return &Evaluator{Now: now}
+}
+
+// Expand replaces every expression in value with its result. Text between
+// braces that doesn't name a known function, such as {{.Name}}, is kept as
+// written.
+func (e *Evaluator) Expand(value string) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+
+	var expandErr error
+	expanded := expressionPattern.ReplaceAllStringFunc(value, func(expression string) string {
+		if expandErr != nil {
+			return expression
+		}
+		result, ok, err := e.evaluate(expressionPattern.FindStringSubmatch(expression)[1])
+		if err != nil {
+			expandErr = fmt.Errorf("%s: %w", expression, err)
+			return expression
+		}
+		if !ok {
+			return expression
+		}
+		return result
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
+
+// ExpandAll returns a copy of values with every value expanded
+func (e *Evaluator) ExpandAll(values map[string]string) (map[string]string, error) {
+	if values == nil {
+		return nil, nil
+	}
+	expanded := make(map[string]string, len(values))
+	for key, value := range values {
+		result, err := e.Expand(value)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s: %w", key, err)
+		}
+		expanded[key] = result
+	}
+	return expanded, nil
+}
+
+// Validate checks the expressions in value without reading the environment,
+// so configuration errors surface when the configuration is loaded
+func Validate(value string) error {
+	e := &Evaluator{Now: time.Now(), LookupEnv: func(string) (string, bool) { return "", true }}
+	_, err := e.Expand(value)
+	return err
+}
+
+// evaluate runs a single expression. It reports false for expressions that
+// don't name a known function.
+func (e *Evaluator) evaluate(expression string) (string, bool, error) {
+	fields, err := shellquote.Split(expression)
+	if err != nil || len(fields) == 0 {
+		return "", false, nil
+	}
+	fn, ok := functions[fields[0]]
+	if !ok {
+		return "", false, nil
+	}
+	result, err := fn(e, fields[1:])
+	return result, true, err
+}
+
+// dateFunc returns a function that applies offsets to the evaluator's time,
+// then adjust, and formats the result with a layout given as an argument or
+// the default layout
+func dateFunc(layout string, adjust func(time.Time) time.Time) function {
+	return func(e *Evaluator, args []string) (string, error) {
+		t, rest, err := applyOffsets(e.Now, args)
+		if err != nil {
+			return "", err
+		}
+		format := layout
+		switch len(rest) {
+		case 0:
+		case 1:
+			format = rest[0]
+		default:
+			return "", fmt.Errorf("unexpected arguments %q (use offsets such as -7d and a layout such as \"2006-01-02\")", rest[1:])
+		}
+		if adjust != nil {
+			t = adjust(t)
+		}
+		return t.Format(format), nil
+	}
+}
+
+// fixedDateFunc returns a function that applies offsets to the evaluator's
+// time and formats the result with layout
+func fixedDateFunc(layout string) function {
+	return func(e *Evaluator, args []string) (string, error) {
+		t, rest, err := applyOffsets(e.Now, args)
+		if err != nil {
+			return "", err
+		}
+		if len(rest) > 0 {
+			return "", fmt.Errorf("unexpected arguments %q (only offsets such as -1m are allowed)", rest)
+		}
+		return t.Format(layout), nil
+	}
+}
+
+// applyOffsets adds the leading offset arguments to t and returns the
+// remaining arguments. Offsets are a signed number of hours (h), days (d),
+// weeks (w), months (m) or years (y). Month and year offsets keep the day
+// within the target month, so -1m on March 31 is the last day of February.
+func applyOffsets(t time.Time, args []string) (time.Time, []string, error) {
+	for i, arg := range args {
+		if !offsetPattern.MatchString(arg) {
+			if strings.HasPrefix(arg, "-") || strings.HasPrefix(arg, "+") {
+				return t, nil, fmt.Errorf("invalid offset '%s' (use a signed number of h, d, w, m or y such as -7d)", arg)
+			}
+			return t, args[i:], nil
+		}
+		n, err := strconv.Atoi(arg[:len(arg)-1])
+		if err != nil {
+			return t, nil, fmt.Errorf("invalid offset '%s': %w", arg, err)
+		}
+		switch arg[len(arg)-1] {
+		case 'h':
+			t = t.Add(time.Duration(n) * time.Hour)
+		case 'd':
+			t = t.AddDate(0, 0, n)
+		case 'w':
+			t = t.AddDate(0, 0, 7*n)
+		case 'm':
+			t = addMonths(t, n)
+		case 'y':
+			t = addMonths(t, 12*n)
+		}
+	}
+	return t, nil, nil
+}
+
+// addMonths adds n months to t, moving the day back to the end of the target
+// month when that month is shorter
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, n, 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// weekStart returns the Monday of t's week
+func weekStart(t time.Time) time.Time {
+	daysSinceMonday := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -daysSinceMonday)
+}
+
+// monthStart returns the first day of t's month
+func monthStart(t time.Time) time.Time {
+	return t.AddDate(0, 0, 1-t.Day())
+}
+
+// envFunc returns an environment variable, or the default given as the
+// second argument when it is unset
+func envFunc(e *Evaluator, args []string) (string, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", fmt.Errorf("env needs a variable name and an optional default")
+	}
+	lookup := e.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	if value, ok := lookup(args[0]); ok {
+		return value, nil
+	}
+	if len(args) == 2 {
+		return args[1], nil
+	}
+	return "", fmt.Errorf("environment variable %s is not set", args[0])
+}
+
+// hostnameFunc returns the name of the host
+func hostnameFunc(_ *Evaluator, args []string) (string, error) {
+	if len(args) > 0 {
+		return "", fmt.Errorf("hostname takes no arguments")
+	}
+	return os.Hostname()
+}
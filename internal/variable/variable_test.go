@@ -0,0 +1,100 @@
+package variable
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpand(t *testing.T) {
+	// The last day of a month, a Tuesday
+	now := time.Date(2026, 3, 31, 14, 5, 9, 0, time.UTC)
+	e := &Evaluator{Now: now, LookupEnv: func(name string) (string, bool) {
+		if name == "REGION" {
+			return "eu-west-1", true
+		}
+		return "", false
+	}}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"{{CURRENT_DATE}}", "2026-03-31"},
+		{"{{CURRENT_TIME}}", "14:05:09"},
+		{"{{CURRENT_DATETIME}}", "2026-03-31 14:05:09"},
+		{"{{date}}", "2026-03-31"},
+		{`{{date -7d "Jan 2, 2006"}}`, "Mar 24, 2026"},
+		{"{{ date +1w }}", "2026-04-07"},
+		{"{{datetime -3h}}", "2026-03-31 11:05:09"},
+		{"{{date -1m}}", "2026-02-28"},
+		{"{{date -1y -1m}}", "2025-02-28"},
+		{"{{week_start}}", "2026-03-30"},
+		{"{{week_end}}", "2026-04-05"},
+		{`{{week_start -1w "Jan 2"}}`, "Mar 23"},
+		{"{{month_start}}", "2026-03-01"},
+		{"{{month_end -1m}}", "2026-02-28"},
+		{"{{month_name -1m}}", "February"},
+		{"{{weekday}}", "Tuesday"},
+		{"{{year +1y}}", "2027"},
+		{`{{env "REGION"}}`, "eu-west-1"},
+		{`{{env "ZONE" "a"}}`, "a"},
+		{"week of {{week_start}} to {{week_end}}", "week of 2026-03-30 to 2026-04-05"},
+		{"plain value", "plain value"},
+		{"{{.Variables.name}} and {{unknown}}", "{{.Variables.name}} and {{unknown}}"},
+	}
+	for _, tt := range tests {
+		got, err := e.Expand(tt.value)
+		require.NoError(t, err, tt.value)
+		assert.Equal(t, tt.want, got, tt.value)
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	e := &Evaluator{Now: time.Now(), LookupEnv: func(string) (string, bool) { return "", false }}
+	for _, value := range []string{
+		"{{date -7x}}",
+		`{{date "2006" "01"}}`,
+		`{{month_name "January"}}`,
+		`{{env "REGION"}}`,
+		"{{env}}",
+		"{{hostname -1d}}",
+	} {
+		_, err := e.Expand(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestExpandAll(t *testing.T) {
+	e := New(time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC))
+	values := map[string]string{"since": "{{date -7d}}", "team": "ops"}
+
+	expanded, err := e.ExpandAll(values)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"since": "2026-10-09", "team": "ops"}, expanded)
+	assert.Equal(t, "{{date -7d}}", values["since"])
+
+	_, err = e.ExpandAll(map[string]string{"since": "{{date -7q}}"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "variable since")
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate(`{{env "NOT_SET_ANYWHERE"}} {{date -1d}}`))
+	assert.Error(t, Validate("{{week_start -1q}}"))
+	assert.Error(t, Validate("{{date +1}}"))
+}
+
+func TestLookupAllowed(t *testing.T) {
+	t.Setenv("CRONAI_TEST_REGION", "eu")
+	t.Setenv("CRONAI_TEST_SECRET", "hunter2")
+	lookup := LookupAllowed([]string{"CRONAI_TEST_REGION"})
+
+	value, ok := lookup("CRONAI_TEST_REGION")
+	assert.True(t, ok)
+	assert.Equal(t, "eu", value)
+	_, ok = lookup("CRONAI_TEST_SECRET")
+	assert.False(t, ok)
+}
@@ -0,0 +1,399 @@
+// Package config provides configuration management for AI models, including OpenAI, Claude, and Gemini.
+// This file defines the structured YAML configuration format, an alternative to the
+// crontab-style line format that can describe tasks, queues, bot rules, model
+// profiles and defaults.
+package config
+
+import (
+	"bytes"
+	_ "embed" // embeds the published JSON Schema
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileVersion is the version of the structured configuration format
+const FileVersion = 1
+
+//go:embed schema.json
+var schema []byte
+
+// Schema returns the JSON Schema describing the structured configuration format
+func Schema() []byte {
+	return schema
+}
+
+// File is a structured configuration file
+type File struct {
+	Version   int                     `yaml:"version"`
+	Defaults  Defaults                `yaml:"defaults,omitempty"`
+	Models    map[string]ModelProfile `yaml:"models,omitempty"`
+	Providers []ProviderConfig        `yaml:"providers,omitempty"`
+	Tasks     []TaskConfig            `yaml:"tasks,omitempty"`
+	Calendars []CalendarConfig        `yaml:"calendars,omitempty"`
+	Freezes   []FreezeConfig          `yaml:"freezes,omitempty"`
+	Checks    []CheckConfig           `yaml:"checks,omitempty"`
+	Budgets   []BudgetConfig          `yaml:"budgets,omitempty"`
+	Profiles  []ProfileConfig         `yaml:"profiles,omitempty"`
+	Queues    []QueueConfig           `yaml:"queues,omitempty"`
+	Bot       *BotConfig              `yaml:"bot,omitempty"`
+}
+
+// Defaults holds values applied to every task that doesn't set them itself
+type Defaults struct {
+	Model       string            `yaml:"model,omitempty"`
+	ModelParams map[string]string `yaml:"model_params,omitempty"`
+	Processors  []ProcessorConfig `yaml:"processors,omitempty"`
+	Template    string            `yaml:"template,omitempty"`
+	Variables   map[string]string `yaml:"variables,omitempty"`
+	Overlap     string            `yaml:"overlap,omitempty"`
+	Catchup     string            `yaml:"catchup,omitempty"`
+	Timezone    string            `yaml:"timezone,omitempty"`
+	Timeout     string            `yaml:"timeout,omitempty"`
+	Retry       *RetryConfig      `yaml:"retry,omitempty"`
+	Stream      bool              `yaml:"stream,omitempty"`
+}
+
+// ModelProfile is a named model with preset parameters. Tasks refer to a
+// profile by using its name as their model.
+type ModelProfile struct {
+	Provider string            `yaml:"provider"`
+	Params   map[string]string `yaml:"params,omitempty"`
+}
+
+// Types of named providers
+const (
+	ProviderOpenAICompatible = "openai-compatible" // any server speaking the OpenAI chat completions API
+	ProviderAzureOpenAI      = "azure-openai"      // Azure OpenAI, which routes models to deployments
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when a provider doesn't set one
+const DefaultAzureAPIVersion = "2024-06-01"
+
+// ProviderConfig is a named provider instance, such as an internal
+// OpenAI-compatible gateway. Tasks use it by setting its name as their model.
+type ProviderConfig struct {
+	Name       string `yaml:"name"`
+	Type       string `yaml:"type"` // openai-compatible or azure-openai
+	BaseURL    string `yaml:"base_url"`
+	KeyEnv     string `yaml:"key_env,omitempty"`     // environment variable holding the API key
+	Model      string `yaml:"model,omitempty"`       // model of tasks that don't name one
+	Deployment string `yaml:"deployment,omitempty"`  // azure-openai: deployment every model is sent to
+	APIVersion string `yaml:"api_version,omitempty"` // azure-openai: API version, DefaultAzureAPIVersion by default
+
+	Line int    `yaml:"-"` // line of the file the provider is defined on
+	File string `yaml:"-"` // line configuration file it is defined in, empty in YAML
+}
+
+// TaskConfig is a scheduled task
+type TaskConfig struct {
+	Name         string            `yaml:"name,omitempty"`
+	Schedule     string            `yaml:"schedule,omitempty"`
+	Timezone     string            `yaml:"timezone,omitempty"` // IANA timezone the schedule is evaluated in
+	After        string            `yaml:"after,omitempty"`
+	On           string            `yaml:"on,omitempty"`
+	Model        string            `yaml:"model,omitempty"`
+	ModelParams  map[string]string `yaml:"model_params,omitempty"`
+	Prompt       string            `yaml:"prompt"`
+	Processors   []ProcessorConfig `yaml:"processors,omitempty"`
+	Template     string            `yaml:"template,omitempty"`
+	Variables    map[string]string `yaml:"variables,omitempty"`
+	Overlap      string            `yaml:"overlap,omitempty"`
+	Catchup      string            `yaml:"catchup,omitempty"`
+	Timeout      string            `yaml:"timeout,omitempty"`       // limit for a single run, such as 90s
+	Group        string            `yaml:"group,omitempty"`         // group paused by freeze windows
+	SkipCalendar string            `yaml:"skip_calendar,omitempty"` // don't run on the days of this calendar
+	OnlyCalendar string            `yaml:"only_calendar,omitempty"` // only run on the days of this calendar
+	When         []string          `yaml:"when,omitempty"`          // checks that must pass before the model is called
+	Tags         []string          `yaml:"tags,omitempty"`          // labels profiles select tasks by
+	Retry        *RetryConfig      `yaml:"retry,omitempty"`         // retries of failed deliveries
+	Stream       *bool             `yaml:"stream,omitempty"`        // show the response while the model produces it
+
+	Line int `yaml:"-"` // line of the file the task is defined on
+}
+
+// RetryConfig retries the delivery of a response to a processor that fails,
+// without calling the model again
+type RetryConfig struct {
+	Limit    int    `yaml:"limit"`
+	Backoff  string `yaml:"backoff,omitempty"`   // exponential (default) or linear
+	Delay    string `yaml:"delay,omitempty"`     // delay before the first retry, such as 5s
+	MaxDelay string `yaml:"max_delay,omitempty"` // longest exponential delay
+}
+
+// CalendarConfig is a named calendar read from an ICS file or a date list.
+// Relative paths are relative to the configuration file.
+type CalendarConfig struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+
+	Line int    `yaml:"-"` // line of the file the calendar is defined on
+	File string `yaml:"-"` // line configuration file it is defined in, empty in YAML
+}
+
+// FreezeConfig pauses tasks during a window, given either by start and end or
+// by the entries of a calendar. Without groups it pauses every task.
+type FreezeConfig struct {
+	Name     string   `yaml:"name"`
+	Start    string   `yaml:"start,omitempty"`
+	End      string   `yaml:"end,omitempty"`
+	Calendar string   `yaml:"calendar,omitempty"`
+	Groups   []string `yaml:"groups,omitempty"`
+
+	Line int    `yaml:"-"` // line of the file the freeze window is defined on
+	File string `yaml:"-"` // line configuration file it is defined in, empty in YAML
+}
+
+// CheckConfig is a named precondition that tasks refer to with when. A check
+// runs a command, probes a URL or looks for a changed file; a task only calls
+// its model when all of its checks pass.
+type CheckConfig struct {
+	Name    string   `yaml:"name"`
+	Type    string   `yaml:"type"`              // command, http or changed
+	Command []string `yaml:"command,omitempty"` // command: program and arguments, run without a shell
+	Exit    string   `yaml:"exit,omitempty"`    // command: exit codes that pass, such as 1 or 1-2 (default 0)
+	URL     string   `yaml:"url,omitempty"`     // http: address requested with GET
+	Status  string   `yaml:"status,omitempty"`  // http: status codes that pass, such as 500-599 (default 200-299)
+	Match   string   `yaml:"match,omitempty"`   // http: regular expression the body must match
+	Path    string   `yaml:"path,omitempty"`    // changed: file that must have changed since the last run
+	Timeout string   `yaml:"timeout,omitempty"` // limit for the probe, such as 10s
+
+	Line int    `yaml:"-"` // line of the file the check is defined on
+	File string `yaml:"-"` // line configuration file it is defined in, empty in YAML
+}
+
+// BudgetConfig limits the estimated spend on the model calls of a task, a
+// prompt category or a provider. Exactly one of Task, Category and Provider
+// is set.
+type BudgetConfig struct {
+	Task     string `yaml:"task,omitempty"`
+	Category string `yaml:"category,omitempty"` // category of the prompts' metadata
+	Provider string `yaml:"provider,omitempty"`
+	Limit    string `yaml:"limit"`              // USD per day or month, such as $20/day
+	Policy   string `yaml:"policy,omitempty"`   // refuse (default), downgrade or warn once exhausted
+	Fallback string `yaml:"fallback,omitempty"` // downgrade: cheaper model called instead, as provider or provider:model
+	Notify   string `yaml:"notify,omitempty"`   // processor told when the budget is exhausted
+
+	Line int    `yaml:"-"` // line of the file the budget is defined on
+	File string `yaml:"-"` // line configuration file it is defined in, empty in YAML
+}
+
+// ProfileConfig overrides the model, processors or variables of the tasks it
+// selects, by name pattern or tag, when its profile is active. Entries of the
+// same profile apply in order, so later entries win.
+type ProfileConfig struct {
+	Name        string            `yaml:"name"`
+	Task        string            `yaml:"task,omitempty"` // pattern of task names, such as weekly_*
+	Tag         string            `yaml:"tag,omitempty"`  // tag the tasks carry
+	Model       string            `yaml:"model,omitempty"`
+	ModelParams map[string]string `yaml:"model_params,omitempty"`
+	Processors  []ProcessorConfig `yaml:"processors,omitempty"`
+	Variables   map[string]string `yaml:"variables,omitempty"`
+
+	Line int    `yaml:"-"` // line of the file the profile entry is defined on
+	File string `yaml:"-"` // line configuration file it is defined in, empty in YAML
+}
+
+// ProcessorConfig is a processor a response is delivered to, with its own
+// template and options. In YAML it can be written as just the processor name.
+type ProcessorConfig struct {
+	Name     string            `yaml:"name"`
+	Template string            `yaml:"template,omitempty"`
+	Options  map[string]string `yaml:"options,omitempty"`
+}
+
+// processorConfig is ProcessorConfig without its YAML methods
+type processorConfig ProcessorConfig
+
+// UnmarshalYAML accepts either a processor name or a mapping
+func (p *ProcessorConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.Name = node.Value
+		return nil
+	}
+	return node.Decode((*processorConfig)(p))
+}
+
+// MarshalYAML writes processors without a template or options as just their name
+func (p ProcessorConfig) MarshalYAML() (interface{}, error) {
+	if p.Template == "" && len(p.Options) == 0 {
+		return p.Name, nil
+	}
+	return processorConfig(p), nil
+}
+
+// QueueConfig is a queue consumer
+type QueueConfig struct {
+	Name       string            `yaml:"name"`
+	Type       string            `yaml:"type"`
+	Connection string            `yaml:"connection,omitempty"`
+	Queue      string            `yaml:"queue"`
+	RetryLimit *int              `yaml:"retry_limit,omitempty"`
+	RetryDelay string            `yaml:"retry_delay,omitempty"`
+	Options    map[string]string `yaml:"options,omitempty"`
+}
+
+// BotConfig configures bot mode
+type BotConfig struct {
+	Port      string    `yaml:"port,omitempty"`
+	Model     string    `yaml:"model,omitempty"`
+	Processor string    `yaml:"processor,omitempty"`
+	Rules     []BotRule `yaml:"rules,omitempty"`
+}
+
+// BotRule routes a GitHub event type to a model
+type BotRule struct {
+	Event string `yaml:"event"`
+	Model string `yaml:"model,omitempty"`
+}
+
+// IsYAMLFile reports whether a configuration file uses the structured YAML format
+func IsYAMLFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// LoadFile reads and parses a structured configuration file
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := ParseFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
+
+// ParseFile parses a structured configuration document. Unknown fields are
+// rejected so that typos don't silently change behaviour.
+func ParseFile(data []byte) (*File, error) {
+	file := &File{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(file); err != nil {
+		return nil, err
+	}
+
+	if file.Version == 0 {
+		file.Version = FileVersion
+	}
+	if file.Version != FileVersion {
+		return nil, fmt.Errorf("unsupported configuration version %d (supported: %d)", file.Version, FileVersion)
+	}
+
+	// Record where each task, provider, calendar, freeze window, check, budget and profile is defined for error messages
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for i, line := range itemLines(&doc, "tasks") {
+		if i < len(file.Tasks) {
+			file.Tasks[i].Line = line
+		}
+	}
+	for i, line := range itemLines(&doc, "providers") {
+		if i < len(file.Providers) {
+			file.Providers[i].Line = line
+		}
+	}
+	for i, line := range itemLines(&doc, "calendars") {
+		if i < len(file.Calendars) {
+			file.Calendars[i].Line = line
+		}
+	}
+	for i, line := range itemLines(&doc, "freezes") {
+		if i < len(file.Freezes) {
+			file.Freezes[i].Line = line
+		}
+	}
+	for i, line := range itemLines(&doc, "checks") {
+		if i < len(file.Checks) {
+			file.Checks[i].Line = line
+		}
+	}
+	for i, line := range itemLines(&doc, "budgets") {
+		if i < len(file.Budgets) {
+			file.Budgets[i].Line = line
+		}
+	}
+	for i, line := range itemLines(&doc, "profiles") {
+		if i < len(file.Profiles) {
+			file.Profiles[i].Line = line
+		}
+	}
+
+	return file, nil
+}
+
+// itemLines returns the line of each item of a top-level sequence
+func itemLines(doc *yaml.Node, key string) []int {
+	sequence := mappingValue(doc, key)
+	if sequence == nil || sequence.Kind != yaml.SequenceNode {
+		return nil
+	}
+	lines := make([]int, len(sequence.Content))
+	for i, node := range sequence.Content {
+		lines[i] = node.Line
+	}
+	return lines
+}
+
+// Marshal encodes the configuration as YAML
+func (f *File) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(f); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mappingValue returns the value of key in the document's top-level mapping
+func mappingValue(doc *yaml.Node, key string) *yaml.Node {
+	node := doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// FormatModelParams is the inverse of ParseModelParams, with keys in sorted order
+func FormatModelParams(params map[string]string) (string, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := params[key]
+		if strings.Contains(value, ",") {
+			return "", fmt.Errorf("model parameter %s: values cannot contain commas", key)
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	return strings.Join(pairs, ","), nil
+}
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const sampleFile = `version: 1
+defaults:
+  model: openai
+  overlap: skip
+models:
+  creative:
+    provider: claude
+    params:
+      temperature: 0.9
+tasks:
+  - name: daily_pm
+    schedule: "0 8 * * *"
+    prompt: product_manager
+    processors:
+      - slack-product
+      - name: file-reports
+        template: daily
+        options:
+          format: markdown
+    variables:
+      team: Product Management
+  - after: daily_pm
+    on: failure
+    model: creative
+    prompt: alert
+    processors: [console]
+queues:
+  - name: main
+    type: rabbitmq
+    connection: amqp://localhost:5672
+    queue: tasks
+    retry_limit: 0
+bot:
+  port: 9090
+  rules:
+    - event: issues
+      model: claude
+`
+
+func TestParseFile(t *testing.T) {
+	file, err := ParseFile([]byte(sampleFile))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	if file.Version != FileVersion {
+		t.Errorf("Expected version %d, got %d", FileVersion, file.Version)
+	}
+	if file.Defaults.Model != "openai" || file.Defaults.Overlap != "skip" {
+		t.Errorf("Unexpected defaults: %+v", file.Defaults)
+	}
+	if got := file.Models["creative"]; got.Provider != "claude" || got.Params["temperature"] != "0.9" {
+		t.Errorf("Unexpected model profile: %+v", got)
+	}
+
+	if len(file.Tasks) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(file.Tasks))
+	}
+	pm := file.Tasks[0]
+	if pm.Line != 11 || file.Tasks[1].Line != 22 {
+		t.Errorf("Expected tasks on lines 11 and 22, got %d and %d", pm.Line, file.Tasks[1].Line)
+	}
+	expected := []ProcessorConfig{
+		{Name: "slack-product"},
+		{Name: "file-reports", Template: "daily", Options: map[string]string{"format": "markdown"}},
+	}
+	if !reflect.DeepEqual(pm.Processors, expected) {
+		t.Errorf("Expected processors %+v, got %+v", expected, pm.Processors)
+	}
+	if pm.Variables["team"] != "Product Management" {
+		t.Errorf("Expected variable with spaces, got %q", pm.Variables["team"])
+	}
+	if file.Tasks[1].On != "failure" {
+		t.Errorf("Expected on=failure, got %q", file.Tasks[1].On)
+	}
+
+	if len(file.Queues) != 1 || file.Queues[0].RetryLimit == nil || *file.Queues[0].RetryLimit != 0 {
+		t.Errorf("Unexpected queues: %+v", file.Queues)
+	}
+	if file.Bot == nil || file.Bot.Port != "9090" || len(file.Bot.Rules) != 1 {
+		t.Errorf("Unexpected bot config: %+v", file.Bot)
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"unknown field", "tasks:\n  - prompt: report\n    shedule: \"0 8 * * *\"\n", "shedule"},
+		{"bot rule processor", "bot:\n  rules:\n    - event: issues\n      processor: console\n", "processor"},
+		{"unsupported version", "version: 2\n", "unsupported configuration version"},
+		{"invalid yaml", "tasks: [\n", "yaml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFileMarshalRoundTrip(t *testing.T) {
+	file, err := ParseFile([]byte(sampleFile))
+	if err != nil {
+		t.Fatalf("ParseFile failed: %v", err)
+	}
+
+	data, err := file.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), "- slack-product\n") {
+		t.Errorf("Expected processors without options to be written by name:\n%s", data)
+	}
+
+	again, err := ParseFile(data)
+	if err != nil {
+		t.Fatalf("ParseFile of marshalled config failed: %v", err)
+	}
+	for i := range again.Tasks {
+		again.Tasks[i].Line = file.Tasks[i].Line
+	}
+	if !reflect.DeepEqual(file, again) {
+		t.Errorf("Round trip changed the configuration:\n%s", data)
+	}
+}
+
+func TestFormatModelParams(t *testing.T) {
+	params, err := FormatModelParams(map[string]string{"temperature": "0.7", "max_tokens": "1024"})
+	if err != nil {
+		t.Fatalf("FormatModelParams failed: %v", err)
+	}
+	if params != "max_tokens=1024,temperature=0.7" {
+		t.Errorf("Unexpected model params %q", params)
+	}
+
+	if _, err := FormatModelParams(map[string]string{"system_message": "Be brief, be clear"}); err == nil {
+		t.Error("Expected an error for a value containing a comma")
+	}
+}
+
+func TestIsYAMLFile(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"cronai.yaml":    true,
+		"cronai.YML":     true,
+		"cronai.config":  false,
+		"/etc/cronai/my": false,
+	} {
+		if IsYAMLFile(path) != expected {
+			t.Errorf("IsYAMLFile(%q) = %v, expected %v", path, !expected, expected)
+		}
+	}
+}
+
+// TestSchemaMatchesFile keeps the published schema in sync with the Go types
+func TestSchemaMatchesFile(t *testing.T) {
+	var doc struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema(), &doc); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+
+	checks := map[string]struct {
+		typ    reflect.Type
+		schema map[string]json.RawMessage
+	}{
+		"file":     {reflect.TypeOf(File{}), doc.Properties},
+		"task":     {reflect.TypeOf(TaskConfig{}), doc.Defs["task"].Properties},
+		"queue":    {reflect.TypeOf(QueueConfig{}), doc.Defs["queue"].Properties},
+		"calendar": {reflect.TypeOf(CalendarConfig{}), doc.Defs["calendar"].Properties},
+		"freeze":   {reflect.TypeOf(FreezeConfig{}), doc.Defs["freeze"].Properties},
+		"check":    {reflect.TypeOf(CheckConfig{}), doc.Defs["check"].Properties},
+		"budget":   {reflect.TypeOf(BudgetConfig{}), doc.Defs["budget"].Properties},
+		"endpoint": {reflect.TypeOf(ProviderConfig{}), doc.Defs["endpoint"].Properties},
+		"profile":  {reflect.TypeOf(ProfileConfig{}), doc.Defs["profile"].Properties},
+		"retry":    {reflect.TypeOf(RetryConfig{}), doc.Defs["retry"].Properties},
+		"bot":      {reflect.TypeOf(BotConfig{}), doc.Defs["bot"].Properties},
+	}
+	for name, check := range checks {
+		var fields, properties []string
+		for i := 0; i < check.typ.NumField(); i++ {
+			tag := strings.Split(check.typ.Field(i).Tag.Get("yaml"), ",")[0]
+			if tag != "" && tag != "-" {
+				fields = append(fields, tag)
+			}
+		}
+		for property := range check.schema {
+			properties = append(properties, property)
+		}
+		sort.Strings(fields)
+		sort.Strings(properties)
+		if !reflect.DeepEqual(fields, properties) {
+			t.Errorf("%s: schema properties %v don't match fields %v", name, properties, fields)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-// Package config provides configuration management for AI models, including OpenAI, Claude, and Gemini.
+// Package config provides configuration management for AI models, including OpenAI, Claude, Gemini and Ollama.
 // It handles model-specific settings, environment variable loading, and parameter validation.
 package config
 
@@ -7,8 +7,16 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// DefaultRequestTimeout caps a single model API request unless
+// MODEL_REQUEST_TIMEOUT or the request_timeout parameter sets another limit
+const DefaultRequestTimeout = 120 * time.Second
+
+// DefaultOllamaURL is the address of a local Ollama server
+const DefaultOllamaURL = "http://localhost:11434"
+
 // ModelConfig defines common configuration parameters for AI models
 type ModelConfig struct {
 	// Common parameters
@@ -22,10 +30,18 @@ type ModelConfig struct {
 	FallbackModels []string // Models to fallback to if primary fails
 	MaxRetries     int      // Maximum retry attempts for each model
 
+	RequestTimeout time.Duration // Time limit of a single API request
+
+	Prices ModelPrices // Prices the cost of responses is estimated with
+
+	Model          string          // Model given by the generic model parameter, the model named providers call
+	NamedProviders map[string]bool // Named providers of the configuration file, valid fallback models too
+
 	// Model-specific configurations
 	OpenAIConfig *OpenAIConfig
 	ClaudeConfig *ClaudeConfig
 	GeminiConfig *GeminiConfig
+	OllamaConfig *OllamaConfig
 }
 
 // OpenAIConfig holds OpenAI-specific configuration
@@ -46,6 +62,15 @@ type GeminiConfig struct {
 	SafetySettings map[string]string // Safety settings for Gemini
 }
 
+// OllamaConfig holds the configuration of a local Ollama server
+type OllamaConfig struct {
+	Model         string   // Local model to use (e.g., "llama3.2", "mistral")
+	BaseURL       string   // Address of the Ollama server
+	SystemMessage string   // System message for chat requests
+	NumCtx        int      // Context window in tokens, the model's own default when zero
+	Temperature   *float64 // Temperature for Ollama only, the common temperature when nil
+}
+
 // DefaultModelConfig returns default configuration values
 func DefaultModelConfig() *ModelConfig {
 	return &ModelConfig{
@@ -56,6 +81,8 @@ func DefaultModelConfig() *ModelConfig {
 		PresencePenalty:  0.0,
 		FallbackModels:   []string{},
 		MaxRetries:       1,
+		RequestTimeout:   DefaultRequestTimeout,
+		Prices:           DefaultModelPrices(),
 		OpenAIConfig: &OpenAIConfig{
 			Model:         "gpt-3.5-turbo",
 			SystemMessage: "You are a helpful assistant.",
@@ -68,6 +95,11 @@ func DefaultModelConfig() *ModelConfig {
 			Model:          "gemini-pro",
 			SafetySettings: make(map[string]string),
 		},
+		OllamaConfig: &OllamaConfig{
+			Model:         "llama3.2",
+			BaseURL:       DefaultOllamaURL,
+			SystemMessage: "You are a helpful assistant.",
+		},
 	}
 }
 
@@ -102,6 +134,18 @@ func (mc *ModelConfig) LoadFromEnvironment() {
 	if maxRetries, err := strconv.Atoi(os.Getenv("MODEL_MAX_RETRIES")); err == nil && maxRetries > 0 {
 		mc.MaxRetries = maxRetries
 	}
+	if timeout, err := time.ParseDuration(os.Getenv("MODEL_REQUEST_TIMEOUT")); err == nil && timeout > 0 {
+		mc.RequestTimeout = timeout
+	}
+	// Format: MODEL_PRICES=model=input:output[:cached],... in USD per million tokens
+	if prices, err := ParseModelPrices(os.Getenv("MODEL_PRICES")); err == nil {
+		if mc.Prices == nil {
+			mc.Prices = make(ModelPrices, len(prices))
+		}
+		for model, price := range prices {
+			mc.Prices[model] = price
+		}
+	}
 
 	// OpenAI specific
 	if model := os.Getenv("OPENAI_MODEL"); model != "" {
@@ -149,6 +193,33 @@ func (mc *ModelConfig) LoadFromEnvironment() {
 			}
 		}
 	}
+
+	// Ollama specific
+	if mc.OllamaConfig == nil {
+		mc.OllamaConfig = &OllamaConfig{BaseURL: DefaultOllamaURL}
+	}
+	if model := os.Getenv("OLLAMA_MODEL"); model != "" {
+		mc.OllamaConfig.Model = model
+	}
+	// OLLAMA_HOST is also read by the ollama CLI, it may omit the scheme
+	if host := os.Getenv("OLLAMA_HOST"); host != "" {
+		mc.OllamaConfig.BaseURL = ollamaURL(host)
+	}
+	if sysMsg := os.Getenv("OLLAMA_SYSTEM_MESSAGE"); sysMsg != "" {
+		mc.OllamaConfig.SystemMessage = sysMsg
+	}
+	if numCtx, err := strconv.Atoi(os.Getenv("OLLAMA_NUM_CTX")); err == nil && numCtx > 0 {
+		mc.OllamaConfig.NumCtx = numCtx
+	}
+}
+
+// ollamaURL returns the address of an Ollama server given as a URL or as
+// host:port
+func ollamaURL(host string) string {
+	if !strings.Contains(host, "://") {
+		host = "http://" + host
+	}
+	return strings.TrimRight(host, "/")
 }
 
 // ParseModelParams parses model parameters from a comma-separated string
@@ -249,11 +320,22 @@ func (mc *ModelConfig) UpdateFromParams(params map[string]string) error {
 			}
 			mc.MaxRetries = retries
 
+		case "request_timeout", "requesttimeout":
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid request_timeout value: %s", value)
+			}
+			if timeout <= 0 {
+				return fmt.Errorf("request_timeout must be positive, got: %s", value)
+			}
+			mc.RequestTimeout = timeout
+
 		case "model":
 			// Apply model to all model configs to handle the generic case
 			// The actual use will be determined by which client is selected
 			// Note: If a model-specific parameter is also provided (e.g. openai.model),
 			// it will override this generic setting for that specific model client
+			mc.Model = value
 			if mc.OpenAIConfig != nil {
 				mc.OpenAIConfig.Model = value
 			}
@@ -263,6 +345,9 @@ func (mc *ModelConfig) UpdateFromParams(params map[string]string) error {
 			if mc.GeminiConfig != nil {
 				mc.GeminiConfig.Model = value
 			}
+			if mc.OllamaConfig != nil {
+				mc.OllamaConfig.Model = value
+			}
 
 			// Safe logging for debugging if needed
 			// log.Printf("Generic model parameter applied to all model configurations")
@@ -277,6 +362,9 @@ func (mc *ModelConfig) UpdateFromParams(params map[string]string) error {
 			if mc.ClaudeConfig != nil {
 				mc.ClaudeConfig.SystemMessage = value
 			}
+			if mc.OllamaConfig != nil {
+				mc.OllamaConfig.SystemMessage = value
+			}
 
 			// Safe logging for debugging if needed
 			// log.Printf("Generic system message applied to applicable model configurations")
@@ -303,7 +391,7 @@ func (mc *ModelConfig) UpdateFromParams(params map[string]string) error {
 
 // handleModelSpecificParam processes model-specific parameters with prefixes
 func (mc *ModelConfig) handleModelSpecificParam(key, value string) error {
-	// Process parameters with prefixes: openai.*, claude.*, gemini.*
+	// Process parameters with prefixes: openai.*, claude.*, gemini.*, ollama.*
 	parts := strings.SplitN(strings.ToLower(key), ".", 2)
 	if len(parts) != 2 {
 		return fmt.Errorf("unrecognized parameter: %s", key)
@@ -328,6 +416,11 @@ func (mc *ModelConfig) handleModelSpecificParam(key, value string) error {
 			mc.GeminiConfig = &GeminiConfig{}
 		}
 		return mc.handleGeminiParam(paramName, value)
+	case "ollama":
+		if mc.OllamaConfig == nil {
+			mc.OllamaConfig = &OllamaConfig{BaseURL: DefaultOllamaURL}
+		}
+		return mc.handleOllamaParam(paramName, value)
 	default:
 		return fmt.Errorf("unknown model prefix: %s", modelPrefix)
 	}
@@ -380,6 +473,33 @@ func (mc *ModelConfig) handleGeminiParam(param, value string) error {
 	return nil
 }
 
+// handleOllamaParam handles Ollama-specific parameters
+func (mc *ModelConfig) handleOllamaParam(param, value string) error {
+	switch param {
+	case "model":
+		mc.OllamaConfig.Model = value
+	case "base_url", "baseurl":
+		mc.OllamaConfig.BaseURL = ollamaURL(value)
+	case "system_message", "systemmessage":
+		mc.OllamaConfig.SystemMessage = value
+	case "num_ctx", "numctx":
+		numCtx, err := strconv.Atoi(value)
+		if err != nil || numCtx <= 0 {
+			return fmt.Errorf("invalid num_ctx value: %s", value)
+		}
+		mc.OllamaConfig.NumCtx = numCtx
+	case "temperature":
+		temp, err := strconv.ParseFloat(value, 64)
+		if err != nil || temp < 0 || temp > 2 {
+			return fmt.Errorf("invalid Ollama temperature value: %s", value)
+		}
+		mc.OllamaConfig.Temperature = &temp
+	default:
+		return fmt.Errorf("unknown Ollama parameter: %s", param)
+	}
+	return nil
+}
+
 // Validate validates the configuration values
 func (mc *ModelConfig) Validate() error {
 	if mc.Temperature < 0 || mc.Temperature > 1 {
@@ -412,10 +532,11 @@ func (mc *ModelConfig) Validate() error {
 		"openai": true,
 		"claude": true,
 		"gemini": true,
+		"ollama": true,
 	}
 
 	for _, fallbackModel := range mc.FallbackModels {
-		if !validModels[fallbackModel] {
+		if !validModels[fallbackModel] && !mc.NamedProviders[fallbackModel] {
 			return fmt.Errorf("unsupported fallback model: %s", fallbackModel)
 		}
 	}
@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaultModelConfig(t *testing.T) {
@@ -20,6 +21,9 @@ func TestDefaultModelConfig(t *testing.T) {
 	if config.TopP != 1.0 {
 		t.Errorf("Expected default TopP to be 1.0, got %f", config.TopP)
 	}
+	if config.RequestTimeout != DefaultRequestTimeout {
+		t.Errorf("Expected default RequestTimeout to be %s, got %s", DefaultRequestTimeout, config.RequestTimeout)
+	}
 
 	// Test model-specific parameters
 	if config.OpenAIConfig.Model != "gpt-3.5-turbo" {
@@ -31,6 +35,34 @@ func TestDefaultModelConfig(t *testing.T) {
 	if config.GeminiConfig.Model != "gemini-pro" {
 		t.Errorf("Expected default Gemini model to be gemini-pro, got %s", config.GeminiConfig.Model)
 	}
+	if config.OllamaConfig.Model != "llama3.2" || config.OllamaConfig.BaseURL != DefaultOllamaURL {
+		t.Errorf("Expected default Ollama model llama3.2 at %s, got %s at %s", DefaultOllamaURL, config.OllamaConfig.Model, config.OllamaConfig.BaseURL)
+	}
+}
+
+func TestLoadOllamaFromEnvironment(t *testing.T) {
+	t.Setenv("OLLAMA_MODEL", "mistral")
+	t.Setenv("OLLAMA_HOST", "gpu-box:11434")
+	t.Setenv("OLLAMA_NUM_CTX", "8192")
+
+	config := DefaultModelConfig()
+	config.LoadFromEnvironment()
+	if config.OllamaConfig.Model != "mistral" {
+		t.Errorf("Expected Ollama model to be mistral, got %s", config.OllamaConfig.Model)
+	}
+	// OLLAMA_HOST may omit the scheme, like for the ollama CLI
+	if config.OllamaConfig.BaseURL != "http://gpu-box:11434" {
+		t.Errorf("Expected Ollama base URL to be http://gpu-box:11434, got %s", config.OllamaConfig.BaseURL)
+	}
+	if config.OllamaConfig.NumCtx != 8192 {
+		t.Errorf("Expected Ollama num_ctx to be 8192, got %d", config.OllamaConfig.NumCtx)
+	}
+
+	t.Setenv("OLLAMA_HOST", "https://ollama.internal/")
+	config.LoadFromEnvironment()
+	if config.OllamaConfig.BaseURL != "https://ollama.internal" {
+		t.Errorf("Expected Ollama base URL to be https://ollama.internal, got %s", config.OllamaConfig.BaseURL)
+	}
 }
 
 func TestLoadFromEnvironment(t *testing.T) {
@@ -434,6 +466,16 @@ func TestUpdateFromParams(t *testing.T) {
 			},
 			errMessage: "Common parameters not updated correctly",
 		},
+		{
+			name: "request timeout",
+			params: map[string]string{
+				"request_timeout": "45s",
+			},
+			checkFunc: func(c *ModelConfig) bool {
+				return c.RequestTimeout == 45*time.Second
+			},
+			errMessage: "Request timeout not updated correctly",
+		},
 		{
 			name: "invalid temperature",
 			params: map[string]string{
@@ -458,7 +500,8 @@ func TestUpdateFromParams(t *testing.T) {
 			checkFunc: func(c *ModelConfig) bool {
 				return c.OpenAIConfig.Model == "gpt-4" &&
 					c.ClaudeConfig.Model == "gpt-4" &&
-					c.GeminiConfig.Model == "gpt-4"
+					c.GeminiConfig.Model == "gpt-4" &&
+					c.OllamaConfig.Model == "gpt-4"
 			},
 			errMessage: "Model parameter not applied to all models",
 		},
@@ -498,6 +541,34 @@ func TestUpdateFromParams(t *testing.T) {
 			},
 			errMessage: "Gemini specific parameters not updated correctly",
 		},
+		{
+			name: "Ollama specific parameters",
+			params: map[string]string{
+				"ollama.model":       "mistral",
+				"ollama.base_url":    "http://gpu-box:11434/",
+				"ollama.num_ctx":     "8192",
+				"ollama.temperature": "1.5",
+			},
+			checkFunc: func(c *ModelConfig) bool {
+				return c.OllamaConfig.Model == "mistral" &&
+					c.OllamaConfig.BaseURL == "http://gpu-box:11434" &&
+					c.OllamaConfig.NumCtx == 8192 &&
+					c.OllamaConfig.Temperature != nil && *c.OllamaConfig.Temperature == 1.5 &&
+					c.Temperature == 0.7
+			},
+			errMessage: "Ollama specific parameters not updated correctly",
+		},
+		{
+			name: "Invalid Ollama options are ignored",
+			params: map[string]string{
+				"ollama.num_ctx":     "-1",
+				"ollama.temperature": "3",
+			},
+			checkFunc: func(c *ModelConfig) bool {
+				return c.OllamaConfig.NumCtx == 0 && c.OllamaConfig.Temperature == nil
+			},
+			errMessage: "Invalid Ollama options should be ignored",
+		},
 		{
 			name: "Multiple safety settings",
 			params: map[string]string{
@@ -596,6 +667,18 @@ func TestUpdateFromParamsErrorHandling(t *testing.T) {
 				"max_tokens": "-10",
 			},
 		},
+		{
+			name: "invalid request timeout",
+			params: map[string]string{
+				"request_timeout": "soon",
+			},
+		},
+		{
+			name: "zero request timeout",
+			params: map[string]string{
+				"request_timeout": "0s",
+			},
+		},
 		{
 			name: "invalid top_p",
 			params: map[string]string{
@@ -902,6 +985,21 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "named provider fallback",
+			configure: func(c *ModelConfig) {
+				c.FallbackModels = []string{"gateway"}
+				c.NamedProviders = map[string]bool{"gateway": true}
+			},
+			wantErr: false,
+		},
+		{
+			name: "undeclared provider fallback",
+			configure: func(c *ModelConfig) {
+				c.FallbackModels = []string{"gateway"}
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range testCases {
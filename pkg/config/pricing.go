@@ -0,0 +1,108 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ModelPrice is the price of a model in USD per million tokens
+type ModelPrice struct {
+	Input       float64 // Prompt tokens
+	Output      float64 // Completion tokens
+	CachedInput float64 // Prompt tokens read from the provider's cache, Input when zero
+}
+
+// Cost returns the estimated cost in USD of a request. promptTokens includes
+// the cachedTokens read from the provider's cache.
+func (p ModelPrice) Cost(promptTokens, completionTokens, cachedTokens int) float64 {
+	cachedPrice := p.CachedInput
+	if cachedPrice == 0 {
+		cachedPrice = p.Input
+	}
+	cost := float64(promptTokens-cachedTokens)*p.Input +
+		float64(cachedTokens)*cachedPrice +
+		float64(completionTokens)*p.Output
+	return cost / 1_000_000
+}
+
+// ModelPrices maps model names to their price. A name also prices the model
+// versions it is a prefix of, such as gpt-4o for gpt-4o-2024-08-06.
+type ModelPrices map[string]ModelPrice
+
+// DefaultModelPrices returns the list prices of common models. MODEL_PRICES
+// adds models or overrides these.
+func DefaultModelPrices() ModelPrices {
+	return ModelPrices{
+		"gpt-3.5-turbo":     {Input: 0.5, Output: 1.5},
+		"gpt-4":             {Input: 30, Output: 60},
+		"gpt-4-turbo":       {Input: 10, Output: 30},
+		"gpt-4o":            {Input: 2.5, Output: 10, CachedInput: 1.25},
+		"gpt-4o-mini":       {Input: 0.15, Output: 0.6, CachedInput: 0.075},
+		"claude-3-haiku":    {Input: 0.25, Output: 1.25, CachedInput: 0.03},
+		"claude-3-sonnet":   {Input: 3, Output: 15, CachedInput: 0.3},
+		"claude-3-opus":     {Input: 15, Output: 75, CachedInput: 1.5},
+		"claude-3-5-haiku":  {Input: 0.8, Output: 4, CachedInput: 0.08},
+		"claude-3-5-sonnet": {Input: 3, Output: 15, CachedInput: 0.3},
+		"gemini-pro":        {Input: 0.5, Output: 1.5},
+		"gemini-1.5-flash":  {Input: 0.075, Output: 0.3},
+		"gemini-1.5-pro":    {Input: 1.25, Output: 5},
+	}
+}
+
+// ParseModelPrices parses a price table such as
+// "gpt-4o=2.5:10:1.25,claude-3-5-sonnet=3:15", giving the input, output and
+// optional cached input price of each model in USD per million tokens
+func ParseModelPrices(value string) (ModelPrices, error) {
+	prices := make(ModelPrices)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		model, priceList, ok := strings.Cut(entry, "=")
+		model = strings.TrimSpace(model)
+		if !ok || model == "" {
+			return nil, fmt.Errorf("invalid model price '%s' (use model=input:output[:cached])", entry)
+		}
+
+		parts := strings.Split(priceList, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid price for model %s: '%s' (use input:output[:cached])", model, priceList)
+		}
+		values := make([]float64, len(parts))
+		for i, part := range parts {
+			price, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || price < 0 {
+				return nil, fmt.Errorf("invalid price for model %s: '%s' is not a price in USD per million tokens", model, part)
+			}
+			values[i] = price
+		}
+
+		price := ModelPrice{Input: values[0], Output: values[1]}
+		if len(values) == 3 {
+			price.CachedInput = values[2]
+		}
+		prices[model] = price
+	}
+	return prices, nil
+}
+
+// Lookup returns the price of a model, matching the longest model name the
+// given name starts with
+func (p ModelPrices) Lookup(model string) (ModelPrice, bool) {
+	if price, ok := p[model]; ok {
+		return price, true
+	}
+	names := make([]string, 0, len(p))
+	for name := range p {
+		if strings.HasPrefix(model, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ModelPrice{}, false
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	return p[names[0]], true
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModelPrices(t *testing.T) {
+	prices, err := ParseModelPrices("gpt-4o=2.5:10:1.25, llama3=0:0")
+	require.NoError(t, err)
+	assert.Equal(t, ModelPrices{
+		"gpt-4o": {Input: 2.5, Output: 10, CachedInput: 1.25},
+		"llama3": {},
+	}, prices)
+
+	for value, want := range map[string]string{
+		"gpt-4o":            "invalid model price 'gpt-4o'",
+		"gpt-4o=2.5":        "invalid price for model gpt-4o: '2.5'",
+		"gpt-4o=2.5:10:1:1": "invalid price for model gpt-4o",
+		"gpt-4o=cheap:10":   "'cheap' is not a price",
+		"gpt-4o=-1:10":      "'-1' is not a price",
+	} {
+		_, err := ParseModelPrices(value)
+		require.Error(t, err, value)
+		assert.Contains(t, err.Error(), want, value)
+	}
+}
+
+func TestModelPricesLookup(t *testing.T) {
+	prices := DefaultModelPrices()
+
+	// Versions are priced by the longest model name they start with
+	price, ok := prices.Lookup("gpt-4o-mini-2024-07-18")
+	require.True(t, ok)
+	assert.Equal(t, prices["gpt-4o-mini"], price)
+	price, ok = prices.Lookup("gpt-4o-2024-08-06")
+	require.True(t, ok)
+	assert.Equal(t, prices["gpt-4o"], price)
+
+	_, ok = prices.Lookup("llama3")
+	assert.False(t, ok)
+}
+
+func TestModelPriceCost(t *testing.T) {
+	price := ModelPrice{Input: 2.5, Output: 10, CachedInput: 1.25}
+	// 600k uncached and 400k cached prompt tokens, 100k completion tokens
+	assert.InDelta(t, 1.5+0.5+1.0, price.Cost(1_000_000, 100_000, 400_000), 1e-9)
+
+	// Cached tokens cost the input price when no cached price is set
+	price = ModelPrice{Input: 2, Output: 4}
+	assert.InDelta(t, 2.0, price.Cost(1_000_000, 0, 500_000), 1e-9)
+}
+
+func TestLoadModelPricesFromEnvironment(t *testing.T) {
+	t.Setenv("MODEL_PRICES", "gpt-4o=5:15,llama3=0.1:0.2")
+	mc := DefaultModelConfig()
+	mc.LoadFromEnvironment()
+	assert.Equal(t, ModelPrice{Input: 5, Output: 15}, mc.Prices["gpt-4o"])
+	assert.Equal(t, ModelPrice{Input: 0.1, Output: 0.2}, mc.Prices["llama3"])
+	assert.Equal(t, DefaultModelPrices()["claude-3-opus"], mc.Prices["claude-3-opus"])
+
+	// Invalid prices are ignored like other invalid settings
+	t.Setenv("MODEL_PRICES", "gpt-4o=free")
+	mc = DefaultModelConfig()
+	mc.LoadFromEnvironment()
+	assert.Equal(t, DefaultModelPrices(), mc.Prices)
+}